@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/3leaps/sfetch/pkg/update"
+)
+
+// versionPattern finds a semver-like version in a release tag
+// ("tool-v1.2.3") or in a binary's version output ("tool version 1.2.3 (abc)").
+var versionPattern = regexp.MustCompile(`v?\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`)
+
+// versionCommands are the ways --assert-version asks a binary for its
+// version, tried in order until one prints a version.
+var versionCommands = [][]string{{"--version"}, {"version"}}
+
+// versionCommandTimeout bounds each version command, so a binary that
+// ignores the arguments and waits for input cannot hang the install.
+const versionCommandTimeout = 10 * time.Second
+
+// findVersion returns the first version in s that update.NormalizeVersion
+// accepts, without its "v" prefix.
+func findVersion(s string) (string, bool) {
+	for _, m := range versionPattern.FindAllString(s, -1) {
+		if v, ok := update.NormalizeVersion(m); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// sameVersion compares normalized versions the way semver does: build
+// metadata is ignored and a missing patch number is 0.
+func sameVersion(a, b string) bool {
+	canonical := func(v string) string {
+		v, _, _ = strings.Cut(v, "+")
+		core, pre, hasPre := strings.Cut(v, "-")
+		if strings.Count(core, ".") == 1 {
+			core += ".0"
+		}
+		if hasPre {
+			return core + "-" + pre
+		}
+		return core
+	}
+	return canonical(a) == canonical(b)
+}
+
+// reportedVersion runs the binary at path with each of versionCommands and
+// returns the first version it prints.
+func reportedVersion(ctx context.Context, path string) (string, error) {
+	var tried []string
+	for _, args := range versionCommands {
+		tried = append(tried, strings.Join(args, " "))
+		cmdCtx, cancel := context.WithTimeout(ctx, versionCommandTimeout)
+		// #nosec G204 -- --assert-version runs the binary the user just installed
+		cmd := exec.CommandContext(cmdCtx, path, args...)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		err := cmd.Run()
+		cancel()
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("run %s: %w", path, err)
+		}
+		if err == nil {
+			if v, ok := findVersion(out.String()); ok {
+				return v, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s printed no version (tried %s)", path, strings.Join(tried, ", "))
+}
+
+// assertVersion checks that the binary at path reports the version in tag.
+// It returns the reported version.
+func assertVersion(ctx context.Context, path, tag string) (string, error) {
+	want, ok := findVersion(tag)
+	if !ok {
+		return "", fmt.Errorf("release tag %q has no version to compare", tag)
+	}
+	got, err := reportedVersion(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	if !sameVersion(got, want) {
+		return got, fmt.Errorf("%s reports version %s, but the release tag is %s", path, got, tag)
+	}
+	return got, nil
+}
+
+// assertInstalledVersions runs assertVersion on every installed binary and
+// returns exitTrust at the first mismatch. The mismatched binary stays
+// installed; with --backup the previous one can be restored.
+func assertInstalledVersions(ctx context.Context, installed []installedBinary, tag string, backup bool, logs outputLog) int {
+	for _, b := range installed {
+		reported, err := assertVersion(ctx, b.path, tag)
+		if err != nil {
+			_, _ = fmt.Fprintf(logs.Error, "error: --assert-version: %v\n", err) //nolint:errcheck
+			if backup {
+				_, _ = fmt.Fprintf(logs.Error, "  the previous %s, if any, is kept as %s.bak; --rollback restores it\n", b.name, b.finalPath) //nolint:errcheck
+			}
+			return exitTrust
+		}
+		_, _ = fmt.Fprintf(logs.Verbose, "%s reports version %s, matching %s\n", b.name, reported, tag) //nolint:errcheck
+	}
+	return exitOK
+}
+
+// embeddedVersionError refuses a self-update whose new binary does not embed
+// the version of the release it came from.
+type embeddedVersionError struct {
+	reason string
+}
+
+func (e *embeddedVersionError) Error() string {
+	return "error: refusing to self-update: " + e.reason
+}
+
+// checkEmbeddedVersion compares the version the new sfetch binary at path
+// was built as with the release tag, catching a mis-tagged release before
+// the running binary is replaced. This trusts the binary's own build info
+// rather than the tag alone.
+func checkEmbeddedVersion(path, tag string) error {
+	want, ok := findVersion(tag)
+	if !ok {
+		return &embeddedVersionError{reason: fmt.Sprintf("release tag %q has no version to compare", tag)}
+	}
+	embedded, err := selfUpdateEmbeddedVersion(path)
+	if err != nil {
+		return &embeddedVersionError{reason: err.Error()}
+	}
+	if embedded == "" {
+		return &embeddedVersionError{reason: fmt.Sprintf("%s embeds no version to compare with %s", filepath.Base(path), tag)}
+	}
+	got, ok := findVersion(embedded)
+	if !ok || !sameVersion(got, want) {
+		return &embeddedVersionError{reason: fmt.Sprintf("the new binary was built as version %s, but the release tag is %s", embedded, tag)}
+	}
+	return nil
+}
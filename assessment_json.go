@@ -0,0 +1,182 @@
+package main
+
+import (
+	_ "embed"
+)
+
+// assessmentSchemaJSON is the schema AssessmentRecord must satisfy; the tests
+// validate emitted records against this embedded copy.
+//
+//go:embed schemas/assessment.schema.json
+var assessmentSchemaJSON []byte
+
+const (
+	assessmentSchemaID      = "https://github.com/3leaps/sfetch/schemas/assessment.schema.json"
+	assessmentSchemaVersion = "1.0.0"
+)
+
+// AssessmentRecord is the dry-run assessment as --dry-run --json reports it,
+// under "assessment" in the run result. It describes what would be verified
+// before anything is downloaded.
+// Schema: schemas/assessment.schema.json
+type AssessmentRecord struct {
+	Schema     string                `json:"$schema"`
+	Version    string                `json:"version"`
+	Source     AssessmentSource      `json:"source"`
+	Asset      *AssessmentAsset      `json:"asset,omitempty"`
+	Workflow   string                `json:"workflow"`
+	Signature  AssessmentSignature   `json:"signature"`
+	Checksum   AssessmentChecksum    `json:"checksum"`
+	Trust      TrustScore            `json:"trust"`
+	Warnings   []string              `json:"warnings"`
+	SelfUpdate *AssessmentSelfUpdate `json:"selfUpdate,omitempty"`
+}
+
+// AssessmentSource identifies what was assessed: a GitHub release
+// ("github"), a --url download ("url") or a --github-raw file ("github-raw").
+type AssessmentSource struct {
+	Type        string   `json:"type"`
+	Repository  string   `json:"repository,omitempty"`
+	Tag         string   `json:"tag,omitempty"`
+	Ref         string   `json:"ref,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	FinalURL    string   `json:"finalUrl,omitempty"`
+	Redirects   []string `json:"redirects,omitempty"`
+	ContentType string   `json:"contentType,omitempty"`
+}
+
+// AssessmentAsset is the file that would be downloaded.
+type AssessmentAsset struct {
+	Name string `json:"name"`
+	Size int64  `json:"size,omitempty"`
+	URL  string `json:"url,omitempty"`
+	// Digest is the "sha256:<hex>" digest the release API lists, checked
+	// after download.
+	Digest string `json:"digest,omitempty"`
+}
+
+// AssessmentSignature describes the signature the plan would verify.
+// Additional lists the other checksum signatures found, which
+// --verify-all-signatures also checks.
+type AssessmentSignature struct {
+	Available  bool                       `json:"available"`
+	Format     string                     `json:"format,omitempty"`
+	File       string                     `json:"file,omitempty"`
+	Scope      string                     `json:"scope,omitempty"` // checksum or asset
+	Discovered bool                       `json:"discovered,omitempty"`
+	Additional []AssessmentSignatureCheck `json:"additional,omitempty"`
+}
+
+// AssessmentSignatureCheck is one additional checksum signature.
+type AssessmentSignatureCheck struct {
+	File       string `json:"file"`
+	Format     string `json:"format"`
+	Verifiable bool   `json:"verifiable"`
+}
+
+// AssessmentChecksum describes the checksum the plan would verify.
+type AssessmentChecksum struct {
+	Available bool   `json:"available"`
+	Algorithm string `json:"algorithm,omitempty"`
+	File      string `json:"file,omitempty"`
+	Type      string `json:"type,omitempty"` // per-asset or consolidated
+	// Discovered is set when File was found by scanning asset names
+	// rather than from config.
+	Discovered bool `json:"discovered,omitempty"`
+}
+
+// AssessmentSelfUpdate is the --self-update version check.
+type AssessmentSelfUpdate struct {
+	CurrentVersion string `json:"currentVersion"`
+	TargetVersion  string `json:"targetVersion"`
+	Decision       string `json:"decision"`
+}
+
+// newAssessmentRecord fills the source-independent parts of a record.
+func newAssessmentRecord(source AssessmentSource, assessment *VerificationAssessment) *AssessmentRecord {
+	record := &AssessmentRecord{
+		Schema:   assessmentSchemaID,
+		Version:  assessmentSchemaVersion,
+		Source:   source,
+		Workflow: assessment.Workflow,
+		Trust:    assessment.Trust,
+		Warnings: append([]string{}, assessment.Warnings...),
+	}
+	if a := assessment.SelectedAsset; a != nil {
+		record.Asset = &AssessmentAsset{Name: a.Name, Size: a.Size, URL: a.BrowserDownloadUrl}
+		if digest := listedSHA256(a); digest != "" {
+			record.Asset.Digest = "sha256:" + digest
+		}
+	}
+	if assessment.SignatureAvailable {
+		record.Signature = AssessmentSignature{
+			Available:  true,
+			Format:     assessment.SignatureFormat,
+			File:       assessment.SignatureFile,
+			Scope:      "asset",
+			Discovered: assessment.SignatureDiscovered,
+		}
+		if assessment.SignatureIsChecksum {
+			record.Signature.Scope = "checksum"
+		}
+		for _, check := range assessment.SignatureChecks {
+			if check.File == assessment.SignatureFile {
+				continue
+			}
+			record.Signature.Additional = append(record.Signature.Additional, AssessmentSignatureCheck{
+				File:       check.File,
+				Format:     check.Format,
+				Verifiable: check.Verifiable,
+			})
+		}
+	}
+	if assessment.ChecksumAvailable {
+		record.Checksum = AssessmentChecksum{
+			Available:  true,
+			Algorithm:  assessment.ChecksumAlgorithm,
+			File:       assessment.ChecksumFile,
+			Type:       assessment.ChecksumType,
+			Discovered: assessment.ChecksumDiscovered,
+		}
+	}
+	return record
+}
+
+// newReleaseAssessmentRecord is the --dry-run --json record for a release.
+func newReleaseAssessmentRecord(repo string, rel *Release, assessment *VerificationAssessment, selfUpdateInfo *SelfUpdateDryRunInfo) *AssessmentRecord {
+	record := newAssessmentRecord(AssessmentSource{Type: "github", Repository: repo, Tag: rel.TagName}, assessment)
+	if selfUpdateInfo != nil {
+		record.SelfUpdate = &AssessmentSelfUpdate{
+			CurrentVersion: selfUpdateInfo.CurrentVersion,
+			TargetVersion:  selfUpdateInfo.TargetVersion,
+			Decision:       string(selfUpdateInfo.Decision),
+		}
+	}
+	return record
+}
+
+// newURLAssessmentRecord is the --dry-run --json record for --url.
+func newURLAssessmentRecord(spec urlSpec, result urlFetchResult, assessment *VerificationAssessment) *AssessmentRecord {
+	source := AssessmentSource{
+		Type:        "url",
+		URL:         spec.URL,
+		Redirects:   result.redirects,
+		ContentType: result.contentType,
+	}
+	if result.finalURL != spec.URL {
+		source.FinalURL = result.finalURL
+	}
+	return newAssessmentRecord(source, assessment)
+}
+
+// newRawAssessmentRecord is the --dry-run --json record for --github-raw.
+func newRawAssessmentRecord(spec githubRawSpec, assessment *VerificationAssessment) *AssessmentRecord {
+	return newAssessmentRecord(AssessmentSource{
+		Type:       "github-raw",
+		Repository: spec.Repo,
+		Ref:        spec.Ref,
+		Path:       spec.Path,
+		URL:        spec.URL,
+	}, assessment)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// clockSkewThreshold is how far the system clock may drift from a server's
+// Date header before sfetch warns. Date has one-second resolution and
+// travels with network latency, so small offsets are noise.
+const clockSkewThreshold = 5 * time.Minute
+
+// clockSkewTransport compares the system clock with the Date header of the
+// first response that carries one, and warns once when they differ by more
+// than clockSkewThreshold. Signature and key timestamps are judged against
+// the system clock, so a wrong clock turns valid signatures into confusing
+// "not yet valid" or "expired" failures; the warning names the real cause.
+type clockSkewTransport struct {
+	next http.RoundTripper
+	warn io.Writer
+	now  func() time.Time // nil: time.Now
+
+	once sync.Once
+}
+
+func (t *clockSkewTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if serverTime, ok := responseTime(resp.Header); ok {
+		t.once.Do(func() { t.check(req.URL.Host, serverTime) })
+	}
+	return resp, nil
+}
+
+func (t *clockSkewTransport) check(host string, serverTime time.Time) {
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	skew := now().Sub(serverTime)
+	direction := "ahead of"
+	if skew < 0 {
+		skew, direction = -skew, "behind"
+	}
+	if skew <= clockSkewThreshold {
+		return
+	}
+	_, _ = fmt.Fprintf(t.warn, "warning: system clock is %s %s %s (per its Date header); signature and key timestamps may be misjudged as not yet valid or expired\n", skew.Round(time.Second), direction, host) //nolint:errcheck
+}
+
+// responseTime is the server's current time according to resp: its Date,
+// plus the Age a cache reports having held the response.
+func responseTime(h http.Header) (time.Time, bool) {
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	if age, err := strconv.Atoi(h.Get("Age")); err == nil && age > 0 {
+		date = date.Add(time.Duration(age) * time.Second)
+	}
+	return date, true
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/3leaps/sfetch/internal/cache"
+)
+
+// completionValues lists the fixed choices offered for flags that take one.
+var completionValues = map[string][]string{
+	"completion":        {"bash", "zsh", "fish"},
+	"channel":           {channelStable, channelPrerelease},
+	"asset-type":        {"archive", "raw", "package"},
+	"libc":              {libcAuto, "musl", "gnu"},
+	"provenance-format": {provenanceFormatSfetch, provenanceFormatInToto, provenanceFormatSLSA},
+	"log-level":         {"debug", "info", "warn", "error"},
+	"log-format":        {"text", "json"},
+}
+
+// completionDirFlags and completionFileFlags take a local path; the shells
+// complete directories or files for them.
+var (
+	completionDirFlags  = map[string]bool{"dest-dir": true, "cache-dir": true, "self-update-dir": true}
+	completionFileFlags = map[string]bool{
+		"output": true, "minisign-key": true, "pgp-key-file": true, "provenance-file": true,
+		"lockfile": true, "from-lockfile": true, "verify-minisign-pubkey": true, "gpg-bin": true,
+	}
+)
+
+// completionRepoLimit caps how many cached repos the scripts offer for
+// --repo.
+const completionRepoLimit = 20
+
+// completionRepoPattern is what a cached repo must look like to be written
+// into a script; anything else could break out of the shell quoting.
+var completionRepoPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*/[A-Za-z0-9._-]+$`)
+
+// completionRepos returns the repos to offer for --repo: those in the cache
+// index under cacheDir, most-fetched first. A missing or unreadable index
+// offers none.
+func completionRepos(cacheDir string) []string {
+	idx, err := cache.Load(cacheDir)
+	if err != nil {
+		return nil
+	}
+	var repos []string
+	for _, repo := range idx.Repos() {
+		if len(repos) == completionRepoLimit {
+			break
+		}
+		if completionRepoPattern.MatchString(repo) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+// completionFlag is one flag as the completion templates see it.
+type completionFlag struct {
+	Name   string
+	Usage  string
+	Bool   bool
+	Dir    bool
+	File   bool
+	Values []string
+}
+
+// completionFlags describes every flag registered on fs, in name order.
+// repos, when any, are offered as values for --repo.
+func completionFlags(fs *flag.FlagSet, repos []string) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+		values := completionValues[f.Name]
+		if f.Name == "repo" && len(repos) > 0 {
+			values = repos
+		}
+		flags = append(flags, completionFlag{
+			Name:   f.Name,
+			Usage:  f.Usage,
+			Bool:   ok && bf.IsBoolFlag(),
+			Dir:    completionDirFlags[f.Name],
+			File:   completionFileFlags[f.Name],
+			Values: values,
+		})
+	})
+	return flags
+}
+
+// writeCompletion prints the completion script for shell (bash, zsh or fish)
+// covering the flags registered on fs, with repos as --repo values.
+func writeCompletion(w io.Writer, shell string, fs *flag.FlagSet, repos []string) error {
+	tmpl, ok := completionTemplates[shell]
+	if !ok {
+		return fmt.Errorf("unsupported --completion shell %q (allowed: bash, zsh, fish)", shell)
+	}
+	t, err := template.New(shell).Funcs(template.FuncMap{
+		"join":       strings.Join,
+		"zshQuote":   zshQuote,
+		"fishQuote":  fishQuote,
+		"flagNames":  completionFlagNames,
+		"bashValues": bashValueFlags,
+	}).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parse %s completion template: %w", shell, err)
+	}
+	return t.Execute(w, completionFlags(fs, repos))
+}
+
+// completionFlagNames returns the --name spelling of every flag.
+func completionFlagNames(flags []completionFlag) string {
+	names := make([]string, 0, len(flags))
+	for _, f := range flags {
+		names = append(names, "--"+f.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+// bashValueFlags returns the "-a|--a|-b|--b" case pattern for flags whose
+// value is free text, so bash offers no flag names after them. Returns ""
+// when there are none.
+func bashValueFlags(flags []completionFlag) string {
+	var names []string
+	for _, f := range flags {
+		if !f.Bool && !f.Dir && !f.File && f.Values == nil {
+			names = append(names, "-"+f.Name, "--"+f.Name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// zshQuote escapes s for an _arguments description inside single quotes.
+func zshQuote(s string) string {
+	return strings.NewReplacer(`'`, `'\''`, `\`, `\\`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(s)
+}
+
+// fishQuote escapes s for a single-quoted fish string.
+func fishQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+var completionTemplates = map[string]string{
+	"bash": `# bash completion for sfetch (generated by sfetch --completion bash)
+_sfetch() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    COMPREPLY=()
+    case "$prev" in
+{{- range .}}{{if .Values}}
+        -{{.Name}}|--{{.Name}}) COMPREPLY=($(compgen -W "{{join .Values " "}}" -- "$cur")); return ;;
+{{- else if .Dir}}
+        -{{.Name}}|--{{.Name}}) COMPREPLY=($(compgen -d -- "$cur")); return ;;
+{{- else if .File}}
+        -{{.Name}}|--{{.Name}}) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+{{- end}}{{end}}
+{{- with bashValues .}}
+        {{.}}) return ;;
+{{- end}}
+    esac
+    COMPREPLY=($(compgen -W "{{flagNames .}}" -- "$cur"))
+}
+complete -o filenames -F _sfetch sfetch
+`,
+	"zsh": `#compdef sfetch
+# zsh completion for sfetch (generated by sfetch --completion zsh)
+_arguments \
+{{- range .}}
+{{- if .Bool}}
+  '--{{.Name}}[{{zshQuote .Usage}}]' \
+{{- else if .Values}}
+  '--{{.Name}}=[{{zshQuote .Usage}}]:{{.Name}}:({{join .Values " "}})' \
+{{- else if .Dir}}
+  '--{{.Name}}=[{{zshQuote .Usage}}]:directory:_files -/' \
+{{- else if .File}}
+  '--{{.Name}}=[{{zshQuote .Usage}}]:file:_files' \
+{{- else}}
+  '--{{.Name}}=[{{zshQuote .Usage}}]:{{.Name}}: ' \
+{{- end}}
+{{- end}}
+  && return 0
+`,
+	"fish": `# fish completion for sfetch (generated by sfetch --completion fish)
+complete -c sfetch -f
+{{- range .}}
+{{- if .Bool}}
+complete -c sfetch -l {{.Name}} -d '{{fishQuote .Usage}}'
+{{- else if .Values}}
+complete -c sfetch -l {{.Name}} -x -a '{{join .Values " "}}' -d '{{fishQuote .Usage}}'
+{{- else if .Dir}}
+complete -c sfetch -l {{.Name}} -x -a '(__fish_complete_directories)' -d '{{fishQuote .Usage}}'
+{{- else if .File}}
+complete -c sfetch -l {{.Name}} -r -F -d '{{fishQuote .Usage}}'
+{{- else}}
+complete -c sfetch -l {{.Name}} -x -d '{{fishQuote .Usage}}'
+{{- end}}
+{{- end}}
+`,
+}
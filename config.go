@@ -4,7 +4,7 @@ var defaults = RepoConfig{
 	BinaryName:        "sfetch",
 	HashAlgo:          "sha256",
 	ArchiveType:       "tar.gz",
-	ArchiveExtensions: []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".tbz2", ".tar", ".zip"},
+	ArchiveExtensions: []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".tbz2", ".tar", ".zip", ".7z"},
 	AssetPatterns: []string{
 		"(?i)^{{binary}}[_-]{{osToken}}[_-]{{archToken}}.*",
 		"(?i)^{{binary}}.*{{osToken}}.*{{archToken}}.*",
@@ -27,9 +27,17 @@ var defaults = RepoConfig{
 		"SHA256SUMS.txt",
 		"SHA256SUMS_64",
 		"sha256sum.txt",
+		"SHA3-256SUMS",
+		"SHA3-512SUMS",
+		"B2SUMS",
+		"BLAKE2SUMS",
 		"checksums.txt",
 		"CHECKSUMS",
 		"CHECKSUMS.txt",
+		"{{asset}}.sha1",
+		"{{asset}}.md5",
+		"SHA1SUMS",
+		"MD5SUMS",
 	},
 	ChecksumSigCandidates: []string{
 		"SHA2-256SUMS.minisig",
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Discovery is the fallback when no ChecksumCandidates, SignatureCandidates
+// or ChecksumSigCandidates name is in the release: it scans the asset list
+// for checksum and signature files named some other way (checksums_sha256.txt,
+// tool-v1.2.3.sha256sum, SUMS.asc). Files about the selected asset rank ahead
+// of consolidated manifests. Anything it finds is labeled in the assessment,
+// since it did not come from config.
+
+// discoveredChecksumSuffixes end a checksum file name, optionally followed by
+// ".txt".
+var discoveredChecksumSuffixes = []string{".sha256sum", ".sha512sum", ".sha256", ".sha512", ".checksum", ".sum"}
+
+// checksumTokenRegex finds a "sum"/"checksum" word in a lower-cased asset
+// name: SUMS, sha256sums, checksums_sha256, tool-v1.2.3.sha256sum. It does
+// not match inside words such as "summary" or "consumer".
+var checksumTokenRegex = regexp.MustCompile(`(^|[^a-z])(sha[0-9]*|b2|blake2b?|md5|[0-9]+)?(sums?|checksums?)([^a-z]|$)`)
+
+// Discovery ranks.
+const (
+	discoveryNone         = iota
+	discoveryAsset        // names the selected asset
+	discoveryConsolidated // a release-wide manifest
+)
+
+// trimChecksumSuffix returns name without a known checksum suffix, and
+// whether it had one.
+func trimChecksumSuffix(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	trimmed := strings.TrimSuffix(lower, ".txt")
+	for _, suffix := range discoveredChecksumSuffixes {
+		if strings.HasSuffix(trimmed, suffix) {
+			return name[:len(trimmed)-len(suffix)], true
+		}
+	}
+	return name, false
+}
+
+// signatureSuffixes are the signature extensions of cfg plus ".sig", longest
+// first so ".sig.asc" is trimmed before ".asc".
+func signatureSuffixes(cfg *RepoConfig) []string {
+	var suffixes []string
+	suffixes = append(suffixes, cfg.SignatureFormats.Minisign...)
+	suffixes = append(suffixes, cfg.SignatureFormats.PGP...)
+	suffixes = append(suffixes, cfg.SignatureFormats.Ed25519...)
+	suffixes = append(suffixes, ".sig")
+	slices.SortStableFunc(suffixes, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return suffixes
+}
+
+// trimSignatureSuffix returns name without a signature suffix, and whether it
+// had one.
+func trimSignatureSuffix(name string, cfg *RepoConfig) (string, bool) {
+	lower := strings.ToLower(name)
+	for _, suffix := range signatureSuffixes(cfg) {
+		if suffix != "" && strings.HasSuffix(lower, strings.ToLower(suffix)) {
+			return name[:len(name)-len(suffix)], true
+		}
+	}
+	return name, false
+}
+
+// namesSelectedAsset reports whether stem, a file name with its checksum or
+// signature suffix removed, is about the selected asset.
+func namesSelectedAsset(stem string, ctx templateContext) bool {
+	lower := strings.ToLower(stem)
+	asset, base := strings.ToLower(ctx.AssetName), strings.ToLower(ctx.BaseName)
+	return lower == asset || (base != "" && strings.Contains(lower, base))
+}
+
+// namesOtherAsset reports whether stem, taken from the asset file, is
+// another asset of the release, as in the per-asset checksum of a different
+// platform's archive.
+func namesOtherAsset(stem, file string, assets []Asset, ctx templateContext, cfg *RepoConfig) bool {
+	for i := range assets {
+		name := assets[i].Name
+		if name == file || strings.EqualFold(name, ctx.AssetName) {
+			continue
+		}
+		if strings.EqualFold(stem, name) || strings.EqualFold(stem, trimKnownExtension(name, cfg.ArchiveExtensions)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checksumDiscoveryRank ranks name as a checksum file for the selected asset.
+func checksumDiscoveryRank(name string, assets []Asset, ctx templateContext, cfg *RepoConfig) int {
+	if strings.EqualFold(name, ctx.AssetName) {
+		return discoveryNone
+	}
+	if _, isSig := trimSignatureSuffix(name, cfg); isSig {
+		return discoveryNone
+	}
+	if trimKnownExtension(name, cfg.ArchiveExtensions) != name {
+		return discoveryNone
+	}
+	stem, hasSuffix := trimChecksumSuffix(name)
+	if !hasSuffix && !checksumTokenRegex.MatchString(strings.ToLower(name)) {
+		return discoveryNone
+	}
+	if namesOtherAsset(stem, name, assets, ctx, cfg) {
+		return discoveryNone
+	}
+	if hasSuffix && namesSelectedAsset(stem, ctx) {
+		return discoveryAsset
+	}
+	return discoveryConsolidated
+}
+
+// discoverChecksumFile returns the best-ranked checksum file found by
+// scanning the asset names, or nil.
+func discoverChecksumFile(assets []Asset, ctx templateContext, cfg *RepoConfig) *Asset {
+	var best *Asset
+	bestRank := discoveryNone
+	for i := range assets {
+		rank := checksumDiscoveryRank(assets[i].Name, assets, ctx, cfg)
+		if rank != discoveryNone && (best == nil || rank < bestRank) {
+			best, bestRank = &assets[i], rank
+		}
+	}
+	if best != nil {
+		debugf("checksum discovered %s: not from config", best.Name)
+	}
+	return best
+}
+
+// discoverChecksumSignature returns a signature over a discovered checksum
+// manifest (Workflow A) and that manifest, or nil.
+func discoverChecksumSignature(assets []Asset, ctx templateContext, cfg *RepoConfig) (*Asset, *Asset) {
+	var bestSig, bestManifest *Asset
+	bestRank := discoveryNone
+	for i := range assets {
+		stem, isSig := trimSignatureSuffix(assets[i].Name, cfg)
+		if !isSig {
+			continue
+		}
+		manifest := findAssetByName(assets, stem)
+		if manifest == nil {
+			continue
+		}
+		rank := checksumDiscoveryRank(manifest.Name, assets, ctx, cfg)
+		if rank != discoveryNone && (bestSig == nil || rank < bestRank) {
+			bestSig, bestManifest, bestRank = &assets[i], manifest, rank
+		}
+	}
+	if bestSig != nil {
+		debugf("checksum signature discovered %s over %s: not from config", bestSig.Name, bestManifest.Name)
+	}
+	return bestSig, bestManifest
+}
+
+// discoverPerAssetSignature returns a signature over the selected asset
+// itself (Workflow B), or nil. An exact "<asset>.<ext>" name beats one that
+// only contains the asset's base name.
+func discoverPerAssetSignature(assets []Asset, ctx templateContext, cfg *RepoConfig) *Asset {
+	var best *Asset
+	for i := range assets {
+		stem, isSig := trimSignatureSuffix(assets[i].Name, cfg)
+		if !isSig || !namesSelectedAsset(stem, ctx) || namesOtherAsset(stem, assets[i].Name, assets, ctx, cfg) {
+			continue
+		}
+		if _, isChecksum := trimChecksumSuffix(stem); isChecksum || checksumTokenRegex.MatchString(strings.ToLower(stem)) {
+			continue
+		}
+		if strings.EqualFold(stem, ctx.AssetName) {
+			best = &assets[i]
+			break
+		}
+		if best == nil {
+			best = &assets[i]
+		}
+	}
+	if best != nil {
+		debugf("signature discovered %s: not from config", best.Name)
+	}
+	return best
+}
+
+// discoveredChecksumAlgorithm is detectChecksumAlgorithm, also reading the
+// algorithm from names like checksums_sha512.txt that only carry it as a
+// word.
+func discoveredChecksumAlgorithm(name, defaultAlgo string) string {
+	if algo := detectChecksumAlgorithm(name, ""); algo != "" {
+		return algo
+	}
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "sha512"):
+		return "sha512"
+	case strings.Contains(lower, "sha256"):
+		return "sha256"
+	}
+	return defaultAlgo
+}
+
+// assessDiscovered fills assessment from artifacts found by scanning the
+// release when no configured name matched, in the usual Workflow A, B, C
+// order. It reports whether it found anything.
+func assessDiscovered(assessment *VerificationAssessment, assets []Asset, ctx templateContext, cfg *RepoConfig, flags assessmentFlags) bool {
+	if !flags.skipSig {
+		if sig, manifest := discoverChecksumSignature(assets, ctx, cfg); sig != nil {
+			assessment.SignatureAvailable = true
+			assessment.SignatureFile = sig.Name
+			assessment.SignatureFormat = signatureFormatFromExtension(sig.Name, cfg.SignatureFormats)
+			assessment.SignatureIsChecksum = true
+			assessment.SignatureDiscovered = true
+			assessment.ChecksumFileForSig = manifest.Name
+
+			assessment.ChecksumAvailable = true
+			assessment.ChecksumFile = manifest.Name
+			assessment.ChecksumType = "consolidated"
+			assessment.ChecksumAlgorithm = discoveredChecksumAlgorithm(manifest.Name, cfg.HashAlgo)
+			assessment.ChecksumDiscovered = true
+
+			assessment.Workflow = workflowA
+			if flags.skipChecksum {
+				assessment.Warnings = append(assessment.Warnings, "Checksum verification skipped (--skip-checksum flag)")
+			}
+			return true
+		}
+	}
+
+	var checksumAsset *Asset
+	if !flags.skipChecksum {
+		checksumAsset = discoverChecksumFile(assets, ctx, cfg)
+	}
+	setChecksum := func() {
+		assessment.ChecksumAvailable = true
+		assessment.ChecksumFile = checksumAsset.Name
+		assessment.ChecksumType = "consolidated"
+		if checksumDiscoveryRank(checksumAsset.Name, assets, ctx, cfg) == discoveryAsset {
+			assessment.ChecksumType = "per-asset"
+		}
+		assessment.ChecksumAlgorithm = discoveredChecksumAlgorithm(checksumAsset.Name, cfg.HashAlgo)
+		assessment.ChecksumDiscovered = true
+	}
+
+	if !flags.skipSig {
+		if sig := discoverPerAssetSignature(assets, ctx, cfg); sig != nil {
+			assessment.SignatureAvailable = true
+			assessment.SignatureFile = sig.Name
+			assessment.SignatureFormat = signatureFormatFromExtension(sig.Name, cfg.SignatureFormats)
+			assessment.SignatureDiscovered = true
+			if checksumAsset != nil {
+				setChecksum()
+			} else if flags.skipChecksum {
+				assessment.Warnings = append(assessment.Warnings, "Checksum verification skipped (--skip-checksum flag)")
+			} else {
+				assessment.Warnings = append(assessment.Warnings, "No checksum file found")
+			}
+			assessment.Workflow = workflowB
+			return true
+		}
+	}
+
+	if checksumAsset == nil {
+		return false
+	}
+	setChecksum()
+	assessment.Workflow = workflowC
+	if !flags.checksumOnly {
+		assessment.Warnings = append(assessment.Warnings, "No signature available; authenticity cannot be proven")
+	}
+	return true
+}
+
+// discoveredLabel marks a dry-run line for an artifact found by scanning.
+func discoveredLabel(discovered bool) string {
+	if discovered {
+		return "; discovered, not from config"
+	}
+	return ""
+}
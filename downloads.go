@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultMaxConcurrency bounds how many release files are fetched at once
+// unless --max-concurrency says otherwise.
+const defaultMaxConcurrency = 4
+
+// downloadJob is one release asset to fetch into path.
+type downloadJob struct {
+	asset *Asset
+	path  string
+	// prefetch marks a job whose failure is not fatal: the file is fetched
+	// again when it is needed, which retries the download and reports a
+	// failure where it matters (a release key, to key resolution).
+	prefetch bool
+}
+
+// downloadAll fetches jobs concurrently, at most limit at a time; a limit
+// of 1 fetches them one after another. The first failure of a job that is
+// not a prefetch cancels the remaining transfers and is returned. Nothing
+// is printed, so progress output stays in a fixed order.
+func downloadAll(ctx context.Context, jobs []downloadJob, limit int) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(limit, 1))
+	for _, job := range jobs {
+		g.Go(func() error {
+			err := downloadAssetContext(ctx, job.asset, job.path)
+			if err != nil && job.prefetch {
+				_ = os.Remove(job.path)
+				return nil
+			}
+			return err
+		})
+	}
+	return g.Wait()
+}
+
+// supplementalDownloads lists the release files the assessed workflow will
+// read besides the asset itself: checksum files, signatures and any key
+// shipped as a release asset. Files are named tmpDir/<asset name>, matching
+// where the workflow and key resolution look for them, so prefetched files
+// are not downloaded again.
+func supplementalDownloads(assessment *VerificationAssessment, assets []Asset, keys signatureKeyOptions, skipSig, skipChecksum bool, tmpDir string) []downloadJob {
+	var names []string
+	switch assessment.Workflow {
+	case workflowA:
+		names = append(names, assessment.ChecksumFileForSig, assessment.SignatureFile)
+		if !skipSig {
+			for _, check := range assessment.SignatureChecks {
+				names = append(names, check.File)
+			}
+		}
+	case workflowB:
+		names = append(names, assessment.SignatureFile)
+		if assessment.ChecksumAvailable && !skipChecksum {
+			names = append(names, assessment.ChecksumFile)
+		}
+	case workflowC:
+		names = append(names, assessment.ChecksumFile)
+	}
+
+	var jobs []downloadJob
+	seen := map[string]bool{}
+	add := func(asset *Asset, prefetch bool) {
+		if asset == nil || seen[asset.Name] {
+			return
+		}
+		seen[asset.Name] = true
+		jobs = append(jobs, downloadJob{asset: asset, path: filepath.Join(tmpDir, asset.Name), prefetch: prefetch})
+	}
+	for _, name := range names {
+		if name != "" {
+			add(findAssetByName(assets, name), false)
+		}
+	}
+
+	if skipSig || (assessment.Workflow != workflowA && assessment.Workflow != workflowB) {
+		return jobs
+	}
+	// Keys are only prefetched: a key that fails to download is a key
+	// resolution failure, which --on-missing-key may tolerate.
+	// A key the key cache still holds is not fetched at all.
+	addKey := func(asset *Asset) {
+		if asset != nil && keys.keyCache.holds(asset.BrowserDownloadUrl, listedSHA256(asset)) {
+			return
+		}
+		add(asset, true)
+	}
+	addKey(keys.releaseKeyAsset(assessment.SignatureFormat, assets))
+	for _, check := range assessment.SignatureChecks {
+		addKey(keys.releaseKeyAsset(check.Format, assets))
+	}
+	return jobs
+}
+
+// releaseKeyAsset returns the release asset key resolution will use for
+// format, or nil when the key comes from a file, URL or keyserver.
+func (k signatureKeyOptions) releaseKeyAsset(format string, assets []Asset) *Asset {
+	switch format {
+	case sigFormatMinisign:
+		if k.minisignPubKey != "" || k.minisignKeyURL != "" {
+			return nil
+		}
+		if k.minisignKeyAsset != "" {
+			return findAssetByName(assets, k.minisignKeyAsset)
+		}
+		return autoDetectMinisignKeyAsset(assets)
+	case sigFormatPGP:
+		if k.pgpKeyFile != "" || k.pgpKeyURL != "" || k.pgpKeyFPR != "" {
+			return nil
+		}
+		if k.pgpKeyAsset != "" {
+			return findAssetByName(assets, k.pgpKeyAsset)
+		}
+		return autoDetectKeyAsset(assets)
+	}
+	return nil
+}
+
+// errReleaseChanged marks a downloaded release file that does not match the
+// release listing it was assessed from: the release was edited between the
+// metadata and download requests, or a proxy served stale bytes.
+var errReleaseChanged = errors.New("release changed during fetch")
+
+// checkListedAsset compares the file downloaded to path with the size and,
+// when GitHub provided one, the SHA-256 digest the release listing gave for
+// asset. Synthetic assets without a size or digest are not checked.
+func checkListedAsset(asset *Asset, path string) error {
+	if asset.Size <= 0 && asset.Digest == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if asset.Size > 0 && info.Size() != asset.Size {
+		return fmt.Errorf("%w: %s is %d bytes but the release listed %d; re-run to fetch the current release", errReleaseChanged, asset.Name, info.Size(), asset.Size)
+	}
+	want := listedSHA256(asset)
+	if want == "" {
+		return nil
+	}
+	got, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("%w: %s has sha256 %s but the release listed %s; re-run to fetch the current release", errReleaseChanged, asset.Name, got, want)
+	}
+	return nil
+}
+
+// listedSHA256 returns the lower-case hex SHA-256 digest the release API
+// lists for asset ("sha256:<hex>"), or "" when it lists none, another
+// algorithm, or a malformed value.
+func listedSHA256(asset *Asset) string {
+	if asset == nil {
+		return ""
+	}
+	algo, digest, ok := strings.Cut(asset.Digest, ":")
+	if !ok || !strings.EqualFold(algo, "sha256") || len(digest) != sha256.Size*2 {
+		return ""
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return ""
+	}
+	return strings.ToLower(digest)
+}
+
+// downloadIfMissing fetches asset into path unless an earlier prefetch
+// already placed it there.
+func downloadIfMissing(ctx context.Context, asset *Asset, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return downloadAssetContext(ctx, asset, path)
+}
+
+// servedFile records how a downloaded file was served, for the provenance
+// record: which --mirror answered, and the response's Date and Age headers
+// so audits can spot bytes served from a stale cache.
+type servedFile struct {
+	mirror string // --mirror URL that served it; "" for the primary host
+	date   string // Date header as RFC 3339 UTC; "" when absent or invalid
+	age    *int64 // Age header in seconds; nil when absent or invalid
+	// sizeNote describes a tolerated difference between the declared and
+	// received size; "" when they agreed.
+	sizeNote string
+}
+
+var (
+	servedMu    sync.Mutex
+	servedFiles = map[string]servedFile{}
+)
+
+// resetServedFiles forgets files recorded by an earlier run.
+func resetServedFiles() {
+	servedMu.Lock()
+	defer servedMu.Unlock()
+	servedFiles = map[string]servedFile{}
+}
+
+// recordServedFile notes that name was served by mirror ("" for the primary
+// host) with response headers h.
+func recordServedFile(name, mirror string, h http.Header) {
+	served := servedFile{mirror: mirror}
+	if t, err := http.ParseTime(h.Get("Date")); err == nil {
+		served.date = t.UTC().Format(time.RFC3339)
+	}
+	if v := strings.TrimSpace(h.Get("Age")); v != "" {
+		if age, err := strconv.ParseInt(v, 10, 64); err == nil && age >= 0 {
+			served.age = &age
+		}
+	}
+	servedMu.Lock()
+	defer servedMu.Unlock()
+	servedFiles[name] = served
+}
+
+// recordServedSize attaches a size note from writeResponseBody to the file
+// recordServedFile noted for name.
+func recordServedSize(name, note string) {
+	if note == "" {
+		return
+	}
+	servedMu.Lock()
+	defer servedMu.Unlock()
+	served := servedFiles[name]
+	served.sizeNote = note
+	servedFiles[name] = served
+}
+
+// servedFileInfo returns what recordServedFile noted for name.
+func servedFileInfo(name string) servedFile {
+	servedMu.Lock()
+	defer servedMu.Unlock()
+	return servedFiles[name]
+}
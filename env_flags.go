@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Where a setting came from, as logged at debug level and recorded in
+// provenance flags.sources.
+const (
+	settingFromFlag    = "flag"
+	settingFromEnv     = "env"
+	settingFromDefault = "default"
+)
+
+// envFlag is a flag that falls back to an SFETCH_<NAME> environment
+// variable when it is not given on the command line.
+type envFlag struct {
+	name string
+	// security marks verification and policy settings, whose source is
+	// logged and recorded in provenance.
+	security bool
+	// overriddenBy lists flags that, given explicitly, make the variable
+	// moot; SFETCH_DEST_DIR must not collide with an explicit --install.
+	overriddenBy []string
+}
+
+// envFlags are the flags CI can set through the environment. Explicit flags
+// always win.
+var envFlags = []envFlag{
+	{name: "minisign-key", security: true},
+	{name: "minisign-key-url", security: true},
+	{name: "minisign-key-asset", security: true},
+	{name: "minisign-comment-regex", security: true},
+	{name: "pin-minisign-key", security: true},
+	{name: "pgp-key-file", security: true},
+	{name: "pgp-key-url", security: true},
+	{name: "pgp-key-asset", security: true},
+	{name: "pgp-key-fingerprint", security: true},
+	{name: "pin-pgp-fingerprint", security: true},
+	{name: "keyserver", security: true},
+	{name: "gpg-bin", security: true},
+	{name: "use-gpg-binary", security: true},
+	{name: "reject-expired-keys", security: true},
+	{name: "key", security: true},
+	{name: "prefer-per-asset", security: true},
+	{name: "require-minisign", security: true},
+	{name: "require-signature", security: true},
+	{name: "strict-keys", security: true},
+	{name: "on-missing-key", security: true},
+	{name: "verify-all-signatures", security: true},
+	{name: "strict-checksum-choice", security: true},
+	{name: "checksum-only", security: true},
+	{name: "skip-sig", security: true},
+	{name: "skip-checksum", security: true},
+	{name: "insecure", security: true},
+	{name: "allow-http", security: true},
+	{name: "trust-minimum", security: true},
+	{name: "trust-policy", security: true},
+	{name: "skip-tools-check"},
+	{name: "dest-dir", overriddenBy: []string{"install", "output", "self-update", "extract-all"}},
+	{name: "cache-dir"},
+	{name: "max-size"},
+	{name: "max-extract-size"},
+}
+
+// envFlagName is the environment variable backing flag name:
+// trust-minimum -> SFETCH_TRUST_MINIMUM.
+func envFlagName(name string) string {
+	return "SFETCH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// parseEnvBool accepts 1/true/yes and 0/false/no, case-insensitively.
+func parseEnvBool(raw string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "1", "true", "yes":
+		return true, nil
+	case "0", "false", "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean %q (use 1/true/yes or 0/false/no)", raw)
+	}
+}
+
+// applyEnvFlags sets every envFlags flag that was not given on the command
+// line from its environment variable, if that is non-empty. It returns the
+// source of each flag in envFlags.
+func applyEnvFlags(fs *flag.FlagSet, getenv func(string) string) (map[string]string, error) {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	sources := make(map[string]string, len(envFlags))
+	for _, ef := range envFlags {
+		if explicit[ef.name] {
+			sources[ef.name] = settingFromFlag
+			continue
+		}
+		sources[ef.name] = settingFromDefault
+		raw := getenv(envFlagName(ef.name))
+		if raw == "" || overridden(ef, explicit) {
+			continue
+		}
+		f := fs.Lookup(ef.name)
+		if f == nil {
+			continue
+		}
+		value := raw
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			v, err := parseEnvBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", envFlagName(ef.name), err)
+			}
+			value = fmt.Sprint(v)
+		}
+		if err := fs.Set(ef.name, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", envFlagName(ef.name), err)
+		}
+		sources[ef.name] = settingFromEnv
+	}
+	return sources, nil
+}
+
+func overridden(ef envFlag, explicit map[string]bool) bool {
+	for _, name := range ef.overriddenBy {
+		if explicit[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// logSettingSources writes the value and source of each security-relevant
+// setting to the debug log.
+func logSettingSources(fs *flag.FlagSet, sources map[string]string) {
+	for _, ef := range envFlags {
+		if !ef.security {
+			continue
+		}
+		f := fs.Lookup(ef.name)
+		if f == nil {
+			continue
+		}
+		from := sources[ef.name]
+		if from == settingFromEnv {
+			from += " " + envFlagName(ef.name)
+		}
+		debugf("setting --%s=%q (%s)", ef.name, f.Value.String(), from)
+	}
+}
+
+// provenanceSources is the provenance flags.sources map: each
+// security-relevant setting given by flag or environment. Settings left at
+// their default are omitted.
+func provenanceSources(sources map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, ef := range envFlags {
+		if from := sources[ef.name]; ef.security && from != "" && from != settingFromDefault {
+			out[ef.name] = from
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// envDisabledVerification lists the verification bypasses switched on by
+// environment variables rather than flags, so they can be called out.
+func envDisabledVerification(fs *flag.FlagSet, sources map[string]string) []string {
+	var names []string
+	for _, name := range []string{"insecure", "skip-sig", "skip-checksum", "allow-http"} {
+		if sources[name] == settingFromEnv && fs.Lookup(name).Value.String() == "true" {
+			names = append(names, envFlagName(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
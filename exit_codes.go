@@ -0,0 +1,40 @@
+package main
+
+// Exit codes returned by run. CI wrappers can branch on these instead of
+// parsing stderr; exitGeneric covers anything not listed.
+const (
+	exitOK        = 0
+	exitGeneric   = 1
+	exitUsage     = 2 // invalid flags or flag combinations
+	exitNetwork   = 3 // GitHub API or download failure
+	exitSelection = 4 // no asset, a tie, or the binary missing from the archive
+	exitChecksum  = 5 // checksum missing for the asset or mismatched
+	exitSignature = 6 // signature or key could not be verified
+	exitTrust     = 7 // --trust-minimum, --require-minisign, key-pin policy or --assert-version
+	exitInstall   = 8 // filesystem errors: temp dirs, extraction, cache, install
+)
+
+// exitCodeName returns the stable name reported as "exitReason" in --json
+// output.
+func exitCodeName(code int) string {
+	switch code {
+	case exitOK:
+		return "ok"
+	case exitUsage:
+		return "usage"
+	case exitNetwork:
+		return "network"
+	case exitSelection:
+		return "selection"
+	case exitChecksum:
+		return "checksum"
+	case exitSignature:
+		return "signature"
+	case exitTrust:
+		return "trust"
+	case exitInstall:
+		return "install"
+	default:
+		return "error"
+	}
+}
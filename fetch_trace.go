@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	gh "github.com/3leaps/sfetch/internal/host/github"
+)
+
+// FetchRecord is one HTTP request made during a run. --trace-provenance
+// reports them under "fetchLog" in the provenance record. Redirect hops are
+// recorded separately, so the log shows every URL bytes came from.
+type FetchRecord struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Bytes  int64  `json:"bytes"`
+	Error  string `json:"error,omitempty"`
+}
+
+// fetchTrace is an http.RoundTripper that records each request and counts
+// the response body bytes read by the caller.
+type fetchTrace struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	records []FetchRecord
+}
+
+func newFetchTrace(next http.RoundTripper) *fetchTrace {
+	return &fetchTrace{next: next}
+}
+
+func (t *fetchTrace) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	record := FetchRecord{URL: req.URL.String()}
+	if err != nil {
+		record.Error = err.Error()
+		t.records = append(t.records, record)
+		return resp, err
+	}
+	record.Status = resp.StatusCode
+	t.records = append(t.records, record)
+	resp.Body = &tracedBody{ReadCloser: resp.Body, trace: t, index: len(t.records) - 1}
+	return resp, nil
+}
+
+// snapshot returns a copy of the records so far, in request order.
+func (t *fetchTrace) snapshot() []FetchRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]FetchRecord(nil), t.records...)
+}
+
+type tracedBody struct {
+	io.ReadCloser
+	trace *fetchTrace
+	index int
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.trace.mu.Lock()
+		b.trace.records[b.index].Bytes += int64(n)
+		b.trace.mu.Unlock()
+	}
+	return n, err
+}
+
+var (
+	activeFetchMu    sync.Mutex
+	activeFetchTrace *fetchTrace
+	activeTransport  http.RoundTripper
+)
+
+// setFetchTransport routes every outbound request through rt; nil restores
+// http.DefaultTransport. trace is the --trace-provenance recorder in rt's
+// chain, or nil when tracing is off.
+func setFetchTransport(rt http.RoundTripper, trace *fetchTrace) {
+	activeFetchMu.Lock()
+	defer activeFetchMu.Unlock()
+	activeTransport = rt
+	activeFetchTrace = trace
+	gh.SetTransport(rt)
+}
+
+// fetchTransport returns the RoundTripper for HTTP clients built outside the
+// GitHub client, or nil for http.DefaultTransport.
+func fetchTransport() http.RoundTripper {
+	activeFetchMu.Lock()
+	defer activeFetchMu.Unlock()
+	return activeTransport
+}
+
+// newHTTPClient returns a client on the run's transport chain, so requests
+// made outside the GitHub client get the same proxy, TLS, timeout, logging
+// and trace handling as release API and asset requests.
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: fetchTransport()}
+}
+
+// attachFetchLog adds the trace so far to record. It is a no-op unless
+// --trace-provenance is set.
+func attachFetchLog(record *ProvenanceRecord) {
+	activeFetchMu.Lock()
+	t := activeFetchTrace
+	activeFetchMu.Unlock()
+	if t == nil {
+		return
+	}
+	record.FetchLog = t.snapshot()
+	if record.FetchLog == nil {
+		record.FetchLog = []FetchRecord{}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 
 	gh "github.com/3leaps/sfetch/internal/host/github"
@@ -29,13 +30,37 @@ func setTokenEnvOverride(name string) {
 	gh.SetResolver(gh.EnvVarResolver{Name: name})
 }
 
+// setGhAuthFallback installs the --use-gh-auth resolver: the default env
+// chain, then the token in the gh CLI's hosts.yml.
+func setGhAuthFallback() {
+	gh.SetResolver(gh.GhHostsResolver{})
+}
+
+// setMaxRedirects applies --max-redirects to the GitHub client.
+func setMaxRedirects(n int) {
+	gh.SetMaxRedirects(n)
+}
+
+// checkRedirectHops is the shared redirect-loop and limit check.
+func checkRedirectHops(req *http.Request, via []*http.Request, limit int) error {
+	return gh.CheckRedirectHops(req, via, limit)
+}
+
 func httpGetWithAuth(url string) (*http.Response, error) {
-	return gh.Get(url, gh.UserAgent(version))
+	return httpGetWithAuthContext(context.Background(), url)
+}
+
+func httpGetWithAuthContext(ctx context.Context, url string) (*http.Response, error) {
+	return gh.GetContext(ctx, url, gh.UserAgent(version))
 }
 
 // httpGetAssetAPI fetches a release asset via the API endpoint. Sets
 // `Accept: application/octet-stream` so the API returns a 302 to the signed
 // download URL rather than JSON metadata.
 func httpGetAssetAPI(url string) (*http.Response, error) {
-	return gh.GetAsset(url, gh.UserAgent(version))
+	return httpGetAssetAPIContext(context.Background(), url)
+}
+
+func httpGetAssetAPIContext(ctx context.Context, url string) (*http.Response, error) {
+	return gh.GetAssetContext(ctx, url, gh.UserAgent(version))
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// hintKind names a common failure that has a canned next step.
+type hintKind string
+
+const (
+	hintNoDestination   hintKind = "no-destination"
+	hintNoExecMount     hintKind = "noexec-mount"
+	hintSelectionTie    hintKind = "selection-tie"
+	hintMissingKey      hintKind = "missing-key"
+	hintWritePermission hintKind = "write-permission"
+	hintNotOnPath       hintKind = "not-on-path"
+	hintChecksumChoice  hintKind = "checksum-choice"
+	hintPackageManaged  hintKind = "package-managed"
+)
+
+// hintRegistry maps each hint kind to its message. Messages are format
+// strings; the arguments are supplied where the hint is emitted.
+var hintRegistry = map[hintKind]string{
+	hintNoDestination:   "use --install to install to %s",
+	hintNoExecMount:     "choose a different --dest-dir/--output location; noexec cannot be fixed with chmod",
+	hintSelectionTie:    "pick one with --asset-match %q (or another candidate's name), rerun with --interactive on a terminal, or take the latest upload with --prefer-newer-asset",
+	hintMissingKey:      "get the maintainer's %s public key (README, website or keyserver) and pass it with %s",
+	hintWritePermission: "choose a writable --dest-dir/--output location, or use --install to install to %s",
+	hintNotOnPath:       "add %s to PATH for %s: %s",
+	hintChecksumChoice:  "pick one with --checksum-file %q (or another manifest's name)",
+	hintPackageManaged:  "pass --self-update-force to replace it anyway, or --self-update-dir to install elsewhere",
+}
+
+// Hint is one emitted next step. --json reports them under "hints".
+type Hint struct {
+	Kind    hintKind `json:"kind"`
+	Message string   `json:"message"`
+}
+
+// hintSink prints hints as "  hint: ..." lines and keeps them for --json.
+type hintSink struct {
+	w     io.Writer
+	hints []Hint
+}
+
+func newHintSink(w io.Writer) *hintSink {
+	return &hintSink{w: w}
+}
+
+// emit prints the registered hint for kind, formatted with args.
+func (h *hintSink) emit(kind hintKind, args ...any) {
+	msg := fmt.Sprintf(hintRegistry[kind], args...)
+	h.hints = append(h.hints, Hint{Kind: kind, Message: msg})
+	_, _ = fmt.Fprintf(h.w, "  hint: %s\n", msg) //nolint:errcheck
+}
+
+// emitForError emits the hint for a failure sfetch recognizes in err's
+// chain. Unrecognized errors emit nothing.
+func (h *hintSink) emitForError(err error) {
+	var tie *assetTieError
+	var missing *missingKeyError
+	switch {
+	case errors.As(err, &tie) && len(tie.Candidates) > 0:
+		h.emit(hintSelectionTie, tie.Candidates[0].Name)
+	case errors.As(err, &missing):
+		h.emit(hintMissingKey, signatureFormatLabel(missing.format), missing.flag())
+	case errors.Is(err, fs.ErrPermission):
+		h.emit(hintWritePermission, userBinDirDisplay())
+	}
+}
+
+// runResult is the --json summary written for an install, or for a run that
+// failed or emitted hints.
+type runResult struct {
+	ExitCode   int               `json:"exitCode"`
+	ExitReason string            `json:"exitReason"`
+	Hints      []Hint            `json:"hints"`
+	Install    *InstallResult    `json:"install,omitempty"`
+	Assessment *AssessmentRecord `json:"assessment,omitempty"`
+}
+
+// writeJSON writes the runResult for code to w. install is the summary of a
+// successful install and assessment the --dry-run plan; either may be nil.
+// Other successful runs without hints write nothing, so --json output of
+// other modes is unaffected.
+func (h *hintSink) writeJSON(w io.Writer, code int, install *InstallResult, assessment *AssessmentRecord) {
+	if code == exitOK && len(h.hints) == 0 && install == nil && assessment == nil {
+		return
+	}
+	result := runResult{ExitCode: code, ExitReason: exitCodeName(code), Hints: h.hints, Install: install, Assessment: assessment}
+	if result.Hints == nil {
+		result.Hints = []Hint{}
+	}
+	data, _ := json.MarshalIndent(result, "", "  ")
+	_, _ = fmt.Fprintln(w, string(data)) //nolint:errcheck
+}
+
+// missingKeyError reports a signature that cannot be checked because no
+// public key was supplied or found in the release.
+type missingKeyError struct {
+	format string
+}
+
+func (e *missingKeyError) Error() string {
+	if e.format == sigFormatPGP {
+		return "error: provide --pgp-key-file, --pgp-key-url, or --pgp-key-asset to verify .asc signatures"
+	}
+	return "error: provide --minisign-key, --minisign-key-url, or --minisign-key-asset to verify minisign signatures"
+}
+
+// flag returns the local-file key flag for the signature format.
+func (e *missingKeyError) flag() string {
+	if e.format == sigFormatPGP {
+		return "--pgp-key-file"
+	}
+	return "--minisign-key"
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// userInferenceRulesFile is read from $XDG_CONFIG_HOME/sfetch (or
+// ~/.config/sfetch) and merged over the embedded defaults.
+const userInferenceRulesFile = "inference-rules.json"
+
+//go:embed schemas/inference-rules.schema.json
+var inferenceRulesSchemaJSON []byte
+
+// userInferenceRules is the on-disk override format: any subset of
+// InferenceRules plus the sections that should replace, not extend, the
+// embedded defaults.
+//
+// Merge policy:
+//   - platformTokens, archTokens, libcTokens, armVariantTokens and
+//     platformExclusions extend the embedded map per key; new keys are added.
+//   - archiveExtensions extends the embedded list.
+//   - formatPreference is an ordering, so a user list replaces it.
+//   - Sections named in "replace" take the user value as-is.
+type userInferenceRules struct {
+	InferenceRules
+	Replace []string `json:"replace"`
+}
+
+// userInferenceRulesPath returns the override file location, or "" when no
+// config directory can be determined.
+func userInferenceRulesPath() string {
+	return userConfigPath(userInferenceRulesFile)
+}
+
+// loadUserInferenceRules reads and validates the override file at path. A
+// missing file returns (nil, nil).
+func loadUserInferenceRules(path string) (*userInferenceRules, error) {
+	// #nosec G304 -- path is the user's own config file
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := validateUserInferenceRules(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var user userInferenceRules
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &user, nil
+}
+
+// validateUserInferenceRules checks an override document against
+// schemas/inference-rules.schema.json. The override is laid over the embedded
+// document first so a partial file still satisfies the schema's required
+// sections, while every section it does set is type-checked.
+func validateUserInferenceRules(data []byte) error {
+	var user map[string]interface{}
+	if err := json.Unmarshal(data, &user); err != nil {
+		return fmt.Errorf("parse inference rules override: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(defaultInferenceRulesJSON, &doc); err != nil {
+		return fmt.Errorf("parse embedded inference rules: %w", err)
+	}
+	for k, v := range user {
+		doc[k] = v
+	}
+
+	schema, err := compileEmbeddedSchema("inference-rules.schema.json", inferenceRulesSchemaJSON)
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("invalid inference rules override: %w", err)
+	}
+	return nil
+}
+
+// mergeInferenceRules returns base with user applied according to the merge
+// policy documented on userInferenceRules. base is not modified.
+func mergeInferenceRules(base *InferenceRules, user *userInferenceRules) *InferenceRules {
+	replace := map[string]bool{}
+	for _, name := range user.Replace {
+		replace[name] = true
+	}
+	merged := *base
+	merged.PlatformExclusions = mergeTokenMap(base.PlatformExclusions, user.PlatformExclusions, replace["platformExclusions"])
+	merged.PlatformTokens = mergeTokenMap(base.PlatformTokens, user.PlatformTokens, replace["platformTokens"])
+	merged.ArchTokens = mergeTokenMap(base.ArchTokens, user.ArchTokens, replace["archTokens"])
+	merged.LibcTokens = mergeTokenMap(base.LibcTokens, user.LibcTokens, replace["libcTokens"])
+	merged.ARMVariantTokens = mergeTokenMap(base.ARMVariantTokens, user.ARMVariantTokens, replace["armVariantTokens"])
+	if user.FormatPreference != nil {
+		merged.FormatPreference = append([]string(nil), user.FormatPreference...)
+	}
+	if user.ArchiveExtensions != nil {
+		if replace["archiveExtensions"] {
+			merged.ArchiveExtensions = append([]string(nil), user.ArchiveExtensions...)
+		} else {
+			merged.ArchiveExtensions = appendUniqueTokens(base.ArchiveExtensions, user.ArchiveExtensions)
+		}
+	}
+	return &merged
+}
+
+func mergeTokenMap(base, user map[string][]string, replace bool) map[string][]string {
+	if user == nil {
+		return base
+	}
+	out := make(map[string][]string, len(base)+len(user))
+	if !replace {
+		for k, v := range base {
+			out[k] = v
+		}
+	}
+	for k, v := range user {
+		key := strings.ToLower(k)
+		out[key] = appendUniqueTokens(out[key], v)
+	}
+	return out
+}
+
+// appendUniqueTokens returns base followed by the extra tokens it does not
+// already contain (case-insensitively).
+func appendUniqueTokens(base, extra []string) []string {
+	out := append([]string(nil), base...)
+	seen := make(map[string]bool, len(base)+len(extra))
+	for _, t := range base {
+		seen[strings.ToLower(t)] = true
+	}
+	for _, t := range extra {
+		if !seen[strings.ToLower(t)] {
+			seen[strings.ToLower(t)] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package main
+
+// InstallResult is the --json summary of a successful install, reported under
+// "install" in the run result. Verification and trust reuse the provenance
+// types; see schemas/provenance.schema.json for their fields.
+type InstallResult struct {
+	Source       string            `json:"source"` // github (release) or url (--url, --github-raw)
+	Repository   string            `json:"repository,omitempty"`
+	Tag          string            `json:"tag,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Asset        string            `json:"asset"`
+	Checksum     *ProvenanceHash   `json:"checksum,omitempty"`
+	Workflow     string            `json:"workflow,omitempty"`
+	Trust        *TrustScore       `json:"trust,omitempty"`
+	Verification *ProvenanceVerify `json:"verification,omitempty"`
+	// Offline is set when the asset came from the cache (--offline); the
+	// workflow is the one recorded when it was cached.
+	Offline   bool            `json:"offline,omitempty"`
+	Installed []InstalledFile `json:"installed"`
+	// Timings is the per-step duration breakdown (--timings).
+	Timings *Timings `json:"timings,omitempty"`
+}
+
+// InstalledFile is one file written by the install.
+type InstalledFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// newInstallResult summarizes an install from its provenance record.
+func newInstallResult(record *ProvenanceRecord, installed []InstalledFile) *InstallResult {
+	result := &InstallResult{
+		Source:       record.Source.Type,
+		Repository:   record.Source.Repository,
+		URL:          record.Source.URL,
+		Asset:        record.Asset.Name,
+		Checksum:     record.Asset.ComputedChecksum,
+		Workflow:     record.Verification.Workflow,
+		Trust:        &record.Trust,
+		Verification: &record.Verification,
+		Installed:    installed,
+		Timings:      record.Timings,
+	}
+	if record.Source.Release != nil {
+		result.Tag = record.Source.Release.Tag
+	}
+	return result
+}
+
+// installedFiles converts installReleaseAsset's result for InstallResult.
+func installedFiles(installed []installedBinary) []InstalledFile {
+	files := make([]InstalledFile, 0, len(installed))
+	for _, b := range installed {
+		files = append(files, InstalledFile{Name: b.name, Path: b.path})
+	}
+	return files
+}
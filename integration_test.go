@@ -1,7 +1,14 @@
 package main
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,6 +16,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -22,6 +34,33 @@ func TestIntegrationPGPSignature(t *testing.T) {
 	if err != nil {
 		t.Skip("gpg not found in PATH")
 	}
+	runIntegrationPGP(t, pgpKeyFileArgs, "--gpg-bin", gpgPath, "--use-gpg-binary")
+}
+
+// The built-in verifier must handle the fixture with no gpg available.
+func TestIntegrationPGPSignatureWithoutGPG(t *testing.T) {
+	runIntegrationPGP(t, pgpKeyFileArgs, "--gpg-bin", filepath.Join(t.TempDir(), "no-such-gpg"))
+}
+
+// The key is fetched from a mock VKS keyserver by fingerprint.
+func TestIntegrationPGPKeyFingerprint(t *testing.T) {
+	runIntegrationPGP(t, func(serverURL string) []string {
+		return []string{"--pgp-key-fingerprint", testPGPFingerprint, "--keyserver", serverURL, "--allow-http"}
+	}, "--gpg-bin", filepath.Join(t.TempDir(), "no-such-gpg"))
+}
+
+// testPGPFingerprint is the fingerprint of testdata/keys/test-pgp-pub.asc.
+const testPGPFingerprint = "267E31B6115F69D865C3F41040F449D203A26393"
+
+func pgpKeyFileArgs(string) []string {
+	return []string{"--pgp-key-file", "testdata/keys/test-pgp-pub.asc"}
+}
+
+// runIntegrationPGP installs a PGP-signed fixture. keyArgs returns the key
+// flags given the mock server URL, which also serves
+// /vks/v1/by-fingerprint/<testPGPFingerprint>.
+func runIntegrationPGP(t *testing.T, keyArgs func(serverURL string) []string, extraArgs ...string) {
+	t.Helper()
 
 	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
 	if err != nil {
@@ -35,9 +74,15 @@ func TestIntegrationPGPSignature(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read sig: %v", err)
 	}
+	keyBytes, err := os.ReadFile("testdata/keys/test-pgp-pub.asc")
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
+		case "/vks/v1/by-fingerprint/" + testPGPFingerprint:
+			_, _ = w.Write(keyBytes)
 		case "/repos/test/example/releases/latest":
 			base := fmt.Sprintf("http://%s", r.Host)
 			rel := fakeRelease{
@@ -72,7 +117,9 @@ func TestIntegrationPGPSignature(t *testing.T) {
 
 	destDir := t.TempDir()
 	cacheDir := filepath.Join(destDir, "cache")
-	cmd := exec.Command("go", "run", ".", "--repo", "test/example", "--latest", "--dest-dir", destDir, "--pgp-key-file", "testdata/keys/test-pgp-pub.asc", "--gpg-bin", gpgPath, "--cache-dir", cacheDir, "--binary-name", "sfetch")
+	args := []string{"run", ".", "--repo", "test/example", "--latest", "--dest-dir", destDir, "--cache-dir", cacheDir, "--binary-name", "sfetch"}
+	args = append(args, keyArgs(ts.URL)...)
+	cmd := exec.Command("go", append(args, extraArgs...)...)
 	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
 	var output bytes.Buffer
 	cmd.Stdout = &output
@@ -171,6 +218,535 @@ func TestIntegrationMinisignChecksumLevel(t *testing.T) {
 	}
 }
 
+// newWorkflowAServer serves a release of repo signed at the checksum level
+// with minisign (Workflow A). It returns the server and the bodies served at
+// each asset path.
+func newWorkflowAServer(t *testing.T, repo string) (*httptest.Server, map[string][]byte) {
+	t.Helper()
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata/integration", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return data
+	}
+	files := map[string][]byte{
+		"/assets/bin":         read("sfetch_test_darwin_arm64.tar.gz"),
+		"/assets/sha":         read("SHA256SUMS"),
+		"/assets/sha-minisig": read("SHA256SUMS.minisig"),
+		"/assets/pubkey":      read("test-minisign.pub"),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/"+repo+"/releases/latest" {
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.2.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+					{Name: "SHA256SUMS.minisig", BrowserDownloadUrl: base + "/assets/sha-minisig"},
+					{Name: "test-minisign.pub", BrowserDownloadUrl: base + "/assets/pubkey"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Errorf("encode release: %v", err)
+			}
+			return
+		}
+		data, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, files
+}
+
+func TestIntegrationVerifyOnly(t *testing.T) {
+	ts, files := newWorkflowAServer(t, "test/verify-only")
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	asset := files["/assets/bin"]
+	good := writeFile("sfetch_test_darwin_arm64.tar.gz", asset)
+	renamed := writeFile("download.tar.gz", asset)
+	tampered := filepath.Join(dir, "tampered", "sfetch_test_darwin_arm64.tar.gz")
+	if err := os.MkdirAll(filepath.Dir(tampered), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tampered, append(append([]byte(nil), asset...), 0), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	otherKey := writeFile("other.pub", []byte("untrusted comment: minisign public key\nRWTAoUJ007VE3h8tbHlBCyk2+y0nn7kyA4QP34LTzdtk8M6A2sryQtZC\n"))
+	unlisted := writeFile("unlisted.tar.gz", asset)
+
+	tests := []struct {
+		name       string
+		file       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "verified", file: good, args: []string{"--minisign-key-asset", "test-minisign.pub"}, wantCode: exitOK, wantStderr: "Verified " + good + " as sfetch_test_darwin_arm64.tar.gz from test/verify-only v0.2.0 (workflow A)"},
+		{name: "asset-match for another file name", file: renamed, args: []string{"--minisign-key-asset", "test-minisign.pub", "--asset-match", "sfetch_test_darwin_arm64.tar.gz"}, wantCode: exitOK, wantStderr: "Minisign checksum signature verified OK"},
+		{name: "checksum mismatch", file: tampered, args: []string{"--minisign-key-asset", "test-minisign.pub"}, wantCode: exitChecksum, wantStderr: "checksum mismatch"},
+		{name: "signature from another key", file: good, args: []string{"--minisign-key", otherKey}, wantCode: exitSignature},
+		{name: "not a release asset", file: unlisted, wantCode: exitSelection},
+		{name: "expect-sha256 mismatch", file: good, args: []string{"--minisign-key-asset", "test-minisign.pub", "--expect-sha256", strings.Repeat("0", 64)}, wantCode: exitChecksum, wantStderr: "sha256 mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			args := append([]string{"--repo", "test/verify-only", "--latest", "--verify-only", tt.file, "--cache-dir", cacheDir, "--provenance-file", provPath, "--skip-tools-check"}, tt.args...)
+			var stdout, stderr bytes.Buffer
+			code := run(args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Fatalf("stderr missing %q:\n%s", tt.wantStderr, stderr.String())
+			}
+			if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+				t.Errorf("--verify-only wrote to the cache: %v", entries)
+			}
+			if tt.wantCode != exitOK {
+				return
+			}
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var record ProvenanceRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			sum := sha256.Sum256(asset)
+			if record.Asset.ComputedChecksum == nil || record.Asset.ComputedChecksum.Value != hex.EncodeToString(sum[:]) {
+				t.Errorf("computed checksum = %+v, want %x", record.Asset.ComputedChecksum, sum)
+			}
+			if record.Verification.Workflow != workflowA {
+				t.Errorf("workflow = %q, want A", record.Verification.Workflow)
+			}
+		})
+	}
+}
+
+func TestIntegrationDryRunProvenanceLocalFile(t *testing.T) {
+	ts, files := newWorkflowAServer(t, "test/verify-only")
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	asset := files["/assets/bin"]
+	local := filepath.Join(t.TempDir(), "sfetch_test_darwin_arm64.tar.gz")
+	if err := os.WriteFile(local, asset, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(asset)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantHash string
+	}{
+		{name: "local file supplied", args: []string{"--verify-file", local}, wantHash: hex.EncodeToString(sum[:])},
+		{name: "no local file", args: []string{"--asset-match", "sfetch_test_darwin_arm64.tar.gz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			args := append([]string{"--repo", "test/verify-only", "--latest", "--dry-run", "--provenance-file", provPath, "--skip-tools-check"}, tt.args...)
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != exitOK {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitOK, stderr.String())
+			}
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var record ProvenanceRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			if !record.Flags.DryRun {
+				t.Errorf("flags.dryRun = false, want true")
+			}
+			switch {
+			case tt.wantHash == "" && record.Asset.ComputedChecksum != nil:
+				t.Errorf("computed checksum = %+v, want none", record.Asset.ComputedChecksum)
+			case tt.wantHash != "" && (record.Asset.ComputedChecksum == nil || record.Asset.ComputedChecksum.Value != tt.wantHash):
+				t.Errorf("computed checksum = %+v, want %s", record.Asset.ComputedChecksum, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestIntegrationVerifyOnlyExplicitURLs(t *testing.T) {
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata/integration", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return data
+	}
+	asset := read("sfetch_test_darwin_arm64.tar.gz")
+	sums := read("SHA256SUMS")
+	sumsSig := read("SHA256SUMS.minisig")
+	// Served under names no release config would guess.
+	files := map[string][]byte{
+		"/dl/sums.txt":         sums,
+		"/dl/sums.txt.minisig": sumsSig,
+		"/dl/sums-sig":         sumsSig,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(ts.Close)
+	// No release lookup may happen.
+	t.Setenv("SFETCH_API_BASE", "http://127.0.0.1:1")
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "sfetch_test_darwin_arm64.tar.gz")
+	if err := os.WriteFile(good, asset, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tampered := filepath.Join(dir, "tampered", "sfetch_test_darwin_arm64.tar.gz")
+	if err := os.MkdirAll(filepath.Dir(tampered), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tampered, append(append([]byte(nil), asset...), 0), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pubKey := filepath.Join("testdata/integration", "test-minisign.pub")
+	sumsURL := ts.URL + "/dl/sums.txt"
+	sigURL := ts.URL + "/dl/sums.txt.minisig"
+
+	tests := []struct {
+		name       string
+		file       string
+		flag       string // default --verify-only
+		strictHTTP bool   // omit --allow-http
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "checksum and signature", file: good, args: []string{"--checksum-url", sumsURL, "--sig-url", sigURL, "--minisign-key", pubKey}, wantCode: exitOK, wantStderr: "Verified " + good + " against " + sumsURL + " and " + sigURL + " (workflow A)"},
+		{name: "verify-file alias", file: good, flag: "--verify-file", args: []string{"--checksum-url", sumsURL}, wantCode: exitOK, wantStderr: "(workflow C)"},
+		{name: "checksum mismatch", file: tampered, args: []string{"--checksum-url", sumsURL, "--sig-url", sigURL, "--minisign-key", pubKey}, wantCode: exitChecksum, wantStderr: "checksum mismatch"},
+		{name: "signature needs a key", file: good, args: []string{"--checksum-url", sumsURL, "--sig-url", sigURL}, wantCode: exitSignature},
+		{name: "http needs allow-http", file: good, strictHTTP: true, args: []string{"--checksum-url", "http://example.com/sums.txt"}, wantCode: exitUsage, wantStderr: "pass --allow-http"},
+		{name: "sig-url without signature extension", file: good, args: []string{"--checksum-url", sumsURL, "--sig-url", ts.URL + "/dl/sums-sig"}, wantCode: exitUsage, wantStderr: "must end in .minisig, .asc, .sig"},
+		{name: "with repo", file: good, args: []string{"--repo", "o/r", "--checksum-url", sumsURL}, wantCode: exitUsage, wantStderr: "replace the release lookup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			flag := tt.flag
+			if flag == "" {
+				flag = "--verify-only"
+			}
+			args := append([]string{flag, tt.file, "--cache-dir", cacheDir, "--skip-tools-check"}, tt.args...)
+			if !tt.strictHTTP {
+				args = append(args, "--allow-http")
+			}
+			var stdout, stderr bytes.Buffer
+			code := run(args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Fatalf("stderr missing %q:\n%s", tt.wantStderr, stderr.String())
+			}
+			if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+				t.Errorf("--verify-only wrote to the cache: %v", entries)
+			}
+		})
+	}
+}
+
+func TestIntegrationTraceProvenance(t *testing.T) {
+	// Workflow A install with --trace-provenance: the fetch log must cover
+	// every artifact the verification relied on.
+	ts, files := newWorkflowAServer(t, "test/trace-example")
+
+	destDir := t.TempDir()
+	provenancePath := filepath.Join(t.TempDir(), "provenance.json")
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/trace-example",
+		"--latest",
+		"--dest-dir", destDir,
+		"--minisign-key-asset", "test-minisign.pub",
+		"--cache-dir", filepath.Join(destDir, "cache"),
+		"--binary-name", "sfetch",
+		"--provenance-file", provenancePath,
+		"--trace-provenance",
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+	}
+
+	data, err := os.ReadFile(provenancePath)
+	if err != nil {
+		t.Fatalf("read provenance: %v", err)
+	}
+	var record ProvenanceRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("parse provenance: %v", err)
+	}
+	if record.Verification.Workflow != "A" {
+		t.Fatalf("workflow = %q, want A", record.Verification.Workflow)
+	}
+	if members := record.Asset.ArchiveMembers; len(members) != 1 || members[0] != "sfetch" {
+		t.Fatalf("archiveMembers = %v, want [sfetch]", members)
+	}
+	installedBytes, err := os.ReadFile(filepath.Join(destDir, "sfetch"))
+	if err != nil {
+		t.Fatalf("read installed binary: %v", err)
+	}
+	installedSum := sha256.Sum256(installedBytes)
+	wantExtracted := []ProvenanceExtractedBinary{{
+		Path:   filepath.Join(destDir, "sfetch"),
+		Member: "sfetch",
+		Size:   int64(len(installedBytes)),
+		SHA256: hex.EncodeToString(installedSum[:]),
+	}}
+	if !reflect.DeepEqual(record.ExtractedBinaries, wantExtracted) {
+		t.Fatalf("extractedBinaries = %+v, want %+v", record.ExtractedBinaries, wantExtracted)
+	}
+	if record.ExtractedBinaries[0].SHA256 == record.Asset.ComputedChecksum.Value {
+		t.Fatalf("extracted binary digest equals the archive digest")
+	}
+	fetched := map[string]FetchRecord{}
+	for _, f := range record.FetchLog {
+		fetched[strings.TrimPrefix(f.URL, ts.URL)] = f
+	}
+	for path, body := range files {
+		f, ok := fetched[path]
+		if !ok {
+			t.Errorf("fetch log is missing %s: %+v", path, record.FetchLog)
+			continue
+		}
+		if f.Status != http.StatusOK || f.Bytes != int64(len(body)) {
+			t.Errorf("%s: status %d, %d bytes; want 200, %d bytes", path, f.Status, f.Bytes, len(body))
+		}
+	}
+	if _, ok := fetched["/repos/test/trace-example/releases/latest"]; !ok {
+		t.Errorf("fetch log is missing the release lookup: %+v", record.FetchLog)
+	}
+}
+
+func TestIntegrationChannelPrerelease(t *testing.T) {
+	// A mixed /releases list: --channel prerelease must pick the highest
+	// tag, rc included, and skip the draft.
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata/integration", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return data
+	}
+	files := map[string][]byte{
+		"/assets/bin": read("sfetch_test_darwin_arm64.tar.gz"),
+		"/assets/sha": read("SHA256SUMS"),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/test/channel/releases" {
+			base := fmt.Sprintf("http://%s", r.Host)
+			assets := []Asset{
+				{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"tag_name": "v0.3.0", "draft": true, "assets": assets},
+				{"tag_name": "v0.2.0-rc.1", "prerelease": true, "assets": assets},
+				{"tag_name": "v0.2.0-rc.2", "prerelease": true, "assets": assets},
+				{"tag_name": "v0.1.0", "assets": assets},
+			})
+			return
+		}
+		data, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	destDir := t.TempDir()
+	provenancePath := filepath.Join(t.TempDir(), "provenance.json")
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/channel",
+		"--channel", "prerelease",
+		"--dest-dir", destDir,
+		"--cache-dir", filepath.Join(destDir, "cache"),
+		"--binary-name", "sfetch",
+		"--provenance-file", provenancePath,
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+	}
+
+	data, err := os.ReadFile(provenancePath)
+	if err != nil {
+		t.Fatalf("read provenance: %v", err)
+	}
+	var record ProvenanceRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("parse provenance: %v", err)
+	}
+	if rel := record.Source.Release; rel == nil || rel.Tag != "v0.2.0-rc.2" || !rel.Prerelease {
+		t.Fatalf("provenance release = %+v, want v0.2.0-rc.2 marked prerelease", rel)
+	}
+}
+
+func TestIntegrationJSONInstallResult(t *testing.T) {
+	ts, _ := newWorkflowAServer(t, "test/json-example")
+
+	destDir := t.TempDir()
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/json-example",
+		"--latest",
+		"--dest-dir", destDir,
+		"--minisign-key-asset", "test-minisign.pub",
+		"--cache-dir", filepath.Join(destDir, "cache"),
+		"--binary-name", "sfetch",
+		"--json",
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	var result runResult
+	dec := json.NewDecoder(&stdout)
+	if err := dec.Decode(&result); err != nil {
+		t.Fatalf("parse --json output: %v\nstdout:\n%s", err, stdout.String())
+	}
+	if dec.More() {
+		t.Fatalf("stdout holds more than one JSON value:\n%s", stdout.String())
+	}
+	install := result.Install
+	if result.ExitCode != exitOK || install == nil {
+		t.Fatalf("result = %+v, want a successful install", result)
+	}
+	wantPath := filepath.Join(destDir, "sfetch")
+	switch {
+	case install.Source != "github" || install.Repository != "test/json-example" || install.Tag != "v0.2.0":
+		t.Errorf("source = %s %s@%s", install.Source, install.Repository, install.Tag)
+	case install.Asset != "sfetch_test_darwin_arm64.tar.gz" || install.Checksum == nil || install.Checksum.Algorithm != "sha256":
+		t.Errorf("asset = %s, checksum %+v", install.Asset, install.Checksum)
+	case install.Workflow != "A" || install.Trust == nil || install.Trust.Score == 0:
+		t.Errorf("workflow = %s, trust %+v", install.Workflow, install.Trust)
+	case install.Verification == nil || !install.Verification.Signature.Verified || !install.Verification.Checksum.Verified:
+		t.Errorf("verification = %+v", install.Verification)
+	case len(install.Installed) != 1 || install.Installed[0].Path != wantPath:
+		t.Errorf("installed = %+v, want %s", install.Installed, wantPath)
+	}
+	for _, line := range []string{"Release:", "Installed ", "Checksum verified OK"} {
+		if strings.Contains(stderr.String(), line) {
+			t.Errorf("--json left %q on stderr:\n%s", line, stderr.String())
+		}
+	}
+}
+
+func TestIntegrationVerbosity(t *testing.T) {
+	ts, _ := newWorkflowAServer(t, "test/verbosity-example")
+
+	tests := []struct {
+		name      string
+		flags     []string
+		want      []string
+		notWant   []string
+		wantEmpty bool
+	}{
+		{
+			name:    "default hides routine confirmations",
+			want:    []string{"Release: v0.2.0", "Installed sfetch to "},
+			notWant: []string{"Checksum verified OK", "Cached to ", "GET "},
+		},
+		{
+			name:  "verbose shows URLs and key resolution",
+			flags: []string{"-v"},
+			want: []string{"Checksum verified OK", "Cached to ", "Minisign key source: asset",
+				"GET " + ts.URL + "/repos/test/verbosity-example/releases/latest", "GET " + ts.URL + "/assets/bin"},
+			notWant: []string{"200 OK"},
+		},
+		{
+			name:  "-vv adds HTTP outcomes",
+			flags: []string{"-vv"},
+			want:  []string{"GET " + ts.URL + "/assets/sha", "200 OK"},
+		},
+		{
+			name:      "quiet prints nothing on success",
+			flags:     []string{"--quiet"},
+			wantEmpty: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			args := append([]string{"run", ".",
+				"--repo", "test/verbosity-example",
+				"--latest",
+				"--dest-dir", destDir,
+				"--minisign-key-asset", "test-minisign.pub",
+				"--cache-dir", filepath.Join(destDir, "cache"),
+				"--binary-name", "sfetch",
+				"--no-path-check",
+			}, tt.flags...)
+			cmd := exec.Command("go", args...)
+			cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("sfetch failed: %v\nstderr:\n%s", err, stderr.String())
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(stderr.String(), line) {
+					t.Errorf("stderr is missing %q:\n%s", line, stderr.String())
+				}
+			}
+			for _, line := range tt.notWant {
+				if strings.Contains(stderr.String(), line) {
+					t.Errorf("stderr should not contain %q:\n%s", line, stderr.String())
+				}
+			}
+			if tt.wantEmpty && stderr.Len() != 0 {
+				t.Errorf("--quiet wrote to stderr:\n%s", stderr.String())
+			}
+		})
+	}
+}
+
 func TestIntegrationMinisignAutoDetect(t *testing.T) {
 	// Test auto-detection of minisign public key from release assets
 	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
@@ -252,124 +828,1386 @@ func TestIntegrationMinisignAutoDetect(t *testing.T) {
 	}
 }
 
-func TestIntegrationInsecureStillInstalls(t *testing.T) {
-	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
-	if err != nil {
-		t.Fatalf("read asset: %v", err)
-	}
-	shaBytes, err := os.ReadFile("testdata/integration/SHA256SUMS")
-	if err != nil {
-		t.Fatalf("read checksum: %v", err)
-	}
-	minisigBytes, err := os.ReadFile("testdata/integration/SHA256SUMS.minisig")
-	if err != nil {
-		t.Fatalf("read minisig: %v", err)
+func TestIntegrationPinMinisignKey(t *testing.T) {
+	const releaseKey = "RWQofujyCgZE45KW4wjPCDP6M/KdG9WDzwSWU6TjnCb3DpsEMOrUt4KX"
+	const otherKey = "RWTAoUJ007VE3h8tbHlBCyk2+y0nn7kyA4QP34LTzdtk8M6A2sryQtZC"
+
+	tests := []struct {
+		name          string
+		args          []string
+		wantErr       string
+		wantKeySource string
+	}{
+		{
+			name:          "asset key matches pin",
+			args:          []string{"--pin-minisign-key", releaseKey},
+			wantKeySource: "auto-detect",
+		},
+		{
+			name:    "asset key does not match pin",
+			args:    []string{"--pin-minisign-key", otherKey},
+			wantErr: "minisign public key does not match pinned key",
+		},
+		{
+			name:          "local key file matches pin",
+			args:          []string{"--minisign-key", "testdata/integration/test-minisign.pub", "--pin-minisign-key", releaseKey},
+			wantKeySource: "flag",
+		},
+		{
+			name:    "local key file does not match pin",
+			args:    []string{"--minisign-key", "testdata/integration/test-minisign.pub", "--pin-minisign-key", otherKey},
+			wantErr: "minisign public key does not match pinned key",
+		},
 	}
-	pubKeyBytes, err := os.ReadFile("testdata/integration/test-minisign.pub")
-	if err != nil {
-		t.Fatalf("read pubkey: %v", err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, provenance, err := runIntegrationPinnedMinisign(t, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("expected failure\noutput:\n%s", output)
+				}
+				if !bytes.Contains([]byte(output), []byte(tt.wantErr)) {
+					t.Fatalf("expected %q in output:\n%s", tt.wantErr, output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output)
+			}
+			var record ProvenanceRecord
+			if err := json.Unmarshal(provenance, &record); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			sig := record.Verification.Signature
+			if sig.KeySource != tt.wantKeySource {
+				t.Errorf("keySource = %q, want %q", sig.KeySource, tt.wantKeySource)
+			}
+			if sig.KeyPin == nil || sig.KeyPin.Type != "minisign-key" || sig.KeyPin.Value != releaseKey || !sig.KeyPin.Matched {
+				t.Errorf("keyPin = %+v, want matched minisign-key pin", sig.KeyPin)
+			}
+		})
 	}
+}
 
+// runIntegrationPinnedMinisign serves a Workflow A release that ships its own
+// minisign key and runs sfetch with extraArgs.
+func runIntegrationPinnedMinisign(t *testing.T, extraArgs ...string) (string, []byte, error) {
+	t.Helper()
+
+	files := map[string]string{
+		"/assets/bin":         "testdata/integration/sfetch_test_darwin_arm64.tar.gz",
+		"/assets/sha":         "testdata/integration/SHA256SUMS",
+		"/assets/sha-minisig": "testdata/integration/SHA256SUMS.minisig",
+		"/assets/release-key": "testdata/integration/test-minisign.pub",
+	}
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/repos/test/insecure/releases/latest":
+		if r.URL.Path == "/repos/test/pinned-example/releases/latest" {
 			base := fmt.Sprintf("http://%s", r.Host)
 			rel := fakeRelease{
-				TagName: "v0.1.0",
+				TagName: "v0.5.0",
 				Assets: []Asset{
 					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
 					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
 					{Name: "SHA256SUMS.minisig", BrowserDownloadUrl: base + "/assets/sha-minisig"},
-					{Name: "test-minisign.pub", BrowserDownloadUrl: base + "/assets/pubkey"},
+					{Name: "release-minisign.pub", BrowserDownloadUrl: base + "/assets/release-key"},
 				},
 			}
 			w.Header().Set("Content-Type", "application/json")
 			if err := json.NewEncoder(w).Encode(&rel); err != nil {
-				t.Fatalf("encode release: %v", err)
+				t.Errorf("encode release: %v", err)
 			}
-		case "/assets/bin":
-			_, _ = w.Write(assetBytes)
-		case "/assets/sha":
-			_, _ = w.Write(shaBytes)
-		case "/assets/sha-minisig":
-			_, _ = w.Write(minisigBytes)
-		case "/assets/pubkey":
-			_, _ = w.Write(pubKeyBytes)
-		default:
+			return
+		}
+		path, ok := files[r.URL.Path]
+		if !ok {
 			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("read %s: %v", path, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
+		_, _ = w.Write(data)
 	}))
 	defer ts.Close()
 
 	destDir := t.TempDir()
-	cacheDir := filepath.Join(destDir, "cache")
-	cmd := exec.Command("go", "run", ".",
-		"--repo", "test/insecure",
+	provenancePath := filepath.Join(t.TempDir(), "provenance.json")
+
+	args := []string{"run", ".",
+		"--repo", "test/pinned-example",
 		"--latest",
 		"--dest-dir", destDir,
-		"--cache-dir", cacheDir,
+		"--cache-dir", filepath.Join(destDir, "cache"),
 		"--binary-name", "sfetch",
-		"--insecure",
-	)
+		"--provenance-file", provenancePath,
+	}
+	cmd := exec.Command("go", append(args, extraArgs...)...)
 	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
 	var output bytes.Buffer
 	cmd.Stdout = &output
 	cmd.Stderr = &output
-	if err := cmd.Run(); err != nil {
-		t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
-	}
-
-	installed := filepath.Join(destDir, "sfetch")
-	if _, err := os.Stat(installed); err != nil {
-		t.Fatalf("expected installed binary at %s: %v", installed, err)
-	}
+	runErr := cmd.Run()
 
-	if !bytes.Contains(output.Bytes(), []byte("WARNING: verification bypass enabled (--insecure)")) {
-		t.Fatalf("expected insecure warning in output:\n%s", output.String())
-	}
+	provenance, _ := os.ReadFile(provenancePath)
+	return output.String(), provenance, runErr
 }
 
-func TestIntegrationTrustMinimumBlocksUnsigned(t *testing.T) {
+func TestIntegrationSigOverDigest(t *testing.T) {
 	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
 	if err != nil {
 		t.Fatalf("read asset: %v", err)
 	}
+	sum := sha256.Sum256(assetBytes)
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	pubHex := hex.EncodeToString(priv.Public().(ed25519.PublicKey))
+	// The signature covers the hex digest string, not the asset bytes.
+	sig := ed25519.Sign(priv, []byte(hex.EncodeToString(sum[:])))
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
-		case "/repos/test/unsigned/releases/latest":
+		case "/repos/test/digest-signed/releases/latest":
 			base := fmt.Sprintf("http://%s", r.Host)
 			rel := fakeRelease{
-				TagName: "v0.1.0",
+				TagName: "v0.6.0",
 				Assets: []Asset{
-					// No signature or checksum assets.
 					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "sfetch_test_darwin_arm64.tar.gz.sig", BrowserDownloadUrl: base + "/assets/sig"},
 				},
 			}
 			w.Header().Set("Content-Type", "application/json")
 			if err := json.NewEncoder(w).Encode(&rel); err != nil {
-				t.Fatalf("encode release: %v", err)
+				t.Errorf("encode release: %v", err)
 			}
 		case "/assets/bin":
 			_, _ = w.Write(assetBytes)
+		case "/assets/sig":
+			_, _ = w.Write(sig)
 		default:
 			w.WriteHeader(http.StatusNotFound)
 		}
 	}))
 	defer ts.Close()
 
-	destDir := t.TempDir()
-	cacheDir := filepath.Join(destDir, "cache")
-	cmd := exec.Command("go", "run", ".",
-		"--repo", "test/unsigned",
-		"--latest",
-		"--dest-dir", destDir,
-		"--cache-dir", cacheDir,
-		"--trust-minimum", "30",
+	run := func(extraArgs ...string) (string, []byte, error) {
+		destDir := t.TempDir()
+		provenancePath := filepath.Join(t.TempDir(), "provenance.json")
+		args := []string{"run", ".",
+			"--repo", "test/digest-signed",
+			"--latest",
+			"--dest-dir", destDir,
+			"--cache-dir", filepath.Join(destDir, "cache"),
+			"--binary-name", "sfetch",
+			"--key", pubHex,
+			"--provenance-file", provenancePath,
+		}
+		cmd := exec.Command("go", append(args, extraArgs...)...)
+		cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		runErr := cmd.Run()
+		provenance, _ := os.ReadFile(provenancePath)
+		return output.String(), provenance, runErr
+	}
+
+	t.Run("rejected without flag", func(t *testing.T) {
+		output, _, err := run()
+		if err == nil {
+			t.Fatalf("expected failure without --sig-over-digest\noutput:\n%s", output)
+		}
+		if !bytes.Contains([]byte(output), []byte("signature verification failed")) {
+			t.Errorf("expected verification failure in output:\n%s", output)
+		}
+	})
+
+	t.Run("accepted with flag", func(t *testing.T) {
+		output, provenance, err := run("--sig-over-digest")
+		if err != nil {
+			t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output)
+		}
+		for _, want := range []string{
+			"Signature verified OK (over the asset's SHA-256 digest)",
+			"warning: the signature covers the asset's SHA-256 digest string",
+		} {
+			if !bytes.Contains([]byte(output), []byte(want)) {
+				t.Errorf("expected %q in output:\n%s", want, output)
+			}
+		}
+		var record ProvenanceRecord
+		if err := json.Unmarshal(provenance, &record); err != nil {
+			t.Fatalf("parse provenance: %v", err)
+		}
+		if !record.Verification.Signature.OverDigest {
+			t.Errorf("provenance signature.overDigest not set: %+v", record.Verification.Signature)
+		}
+	})
+}
+
+func TestIntegrationVerifyAllSignatures(t *testing.T) {
+	validASC, err := os.ReadFile("testdata/integration/SHA256SUMS.asc")
+	if err != nil {
+		t.Fatalf("read asc: %v", err)
+	}
+	// A valid signature over different content stands in for a tampered one.
+	wrongASC, err := os.ReadFile("testdata/pgp/payload.txt.rsa.asc")
+	if err != nil {
+		t.Fatalf("read wrong asc: %v", err)
+	}
+
+	t.Run("both pass", func(t *testing.T) {
+		output, provenance, err := runIntegrationVerifyAll(t, validASC, "testdata/integration/test-pgp-checksum-pub.asc")
+		if err != nil {
+			t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output)
+		}
+		for _, want := range []string{
+			"Minisign checksum signature verified OK (SHA256SUMS.minisig)",
+			"PGP checksum signature verified OK (SHA256SUMS.asc)",
+		} {
+			if !bytes.Contains([]byte(output), []byte(want)) {
+				t.Errorf("expected %q in output:\n%s", want, output)
+			}
+		}
+
+		var record ProvenanceRecord
+		if err := json.Unmarshal(provenance, &record); err != nil {
+			t.Fatalf("parse provenance: %v", err)
+		}
+		sigs := record.Verification.Signatures
+		if len(sigs) != 2 {
+			t.Fatalf("provenance signatures = %d, want 2", len(sigs))
+		}
+		for _, sig := range sigs {
+			if !sig.Verified {
+				t.Errorf("signature %s not recorded as verified: %+v", sig.File, sig)
+			}
+		}
+		if record.Trust.Factors.Signature.IndependentFormats != 2 {
+			t.Errorf("independent formats = %d, want 2", record.Trust.Factors.Signature.IndependentFormats)
+		}
+	})
+
+	t.Run("one pass one fail", func(t *testing.T) {
+		output, _, err := runIntegrationVerifyAll(t, wrongASC, "testdata/pgp/rsa-pub.asc")
+		if err == nil {
+			t.Fatalf("expected failure when one signature is invalid\noutput:\n%s", output)
+		}
+		for _, want := range []string{
+			"Minisign checksum signature verified OK (SHA256SUMS.minisig)",
+			"PGP checksum signature SHA256SUMS.asc FAILED",
+			"1 of 2 checksum signatures failed verification",
+		} {
+			if !bytes.Contains([]byte(output), []byte(want)) {
+				t.Errorf("expected %q in output:\n%s", want, output)
+			}
+		}
+	})
+}
+
+// runIntegrationVerifyAll serves a release carrying both SHA256SUMS.minisig
+// and SHA256SUMS.asc and runs sfetch with --verify-all-signatures.
+func runIntegrationVerifyAll(t *testing.T, ascBytes []byte, pgpKeyFile string) (string, []byte, error) {
+	t.Helper()
+
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	shaBytes, err := os.ReadFile("testdata/integration/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("read checksum: %v", err)
+	}
+	minisigBytes, err := os.ReadFile("testdata/integration/SHA256SUMS.minisig")
+	if err != nil {
+		t.Fatalf("read minisig: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/multisig-example/releases/latest":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.4.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+					{Name: "SHA256SUMS.minisig", BrowserDownloadUrl: base + "/assets/sha-minisig"},
+					{Name: "SHA256SUMS.asc", BrowserDownloadUrl: base + "/assets/sha-asc"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		case "/assets/sha-minisig":
+			_, _ = w.Write(minisigBytes)
+		case "/assets/sha-asc":
+			_, _ = w.Write(ascBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	destDir := t.TempDir()
+	provenancePath := filepath.Join(t.TempDir(), "provenance.json")
+
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/multisig-example",
+		"--latest",
+		"--dest-dir", destDir,
+		"--cache-dir", filepath.Join(destDir, "cache"),
+		"--binary-name", "sfetch",
+		"--verify-all-signatures",
+		"--minisign-key", "testdata/integration/test-minisign.pub",
+		"--pgp-key-file", pgpKeyFile,
+		"--gpg-bin", filepath.Join(t.TempDir(), "no-such-gpg"),
+		"--provenance-file", provenancePath,
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	runErr := cmd.Run()
+
+	provenance, _ := os.ReadFile(provenancePath)
+	return output.String(), provenance, runErr
+}
+
+func TestIntegrationOfflineFromCache(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	shaBytes, err := os.ReadFile("testdata/integration/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("read checksum: %v", err)
+	}
+	minisigBytes, err := os.ReadFile("testdata/integration/SHA256SUMS.minisig")
+	if err != nil {
+		t.Fatalf("read minisig: %v", err)
+	}
+
+	var assetRequests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/cache-example/releases/tags/v0.5.0":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.5.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin", Size: int64(len(assetBytes))},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+					{Name: "SHA256SUMS.minisig", BrowserDownloadUrl: base + "/assets/sha-minisig"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			assetRequests.Add(1)
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		case "/assets/sha-minisig":
+			_, _ = w.Write(minisigBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	runSfetch := func(extraArgs ...string) (string, string, error) {
+		destDir := t.TempDir()
+		args := append([]string{"run", ".",
+			"--repo", "test/cache-example",
+			"--tag", "v0.5.0",
+			"--dest-dir", destDir,
+			"--cache-dir", cacheDir,
+			"--binary-name", "sfetch",
+			"--minisign-key", "testdata/integration/test-minisign.pub",
+		}, extraArgs...)
+		cmd := exec.Command("go", args...)
+		cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		err := cmd.Run()
+		return output.String(), destDir, err
+	}
+
+	// Cold run downloads, verifies and records the asset in the cache index.
+	if out, _, err := runSfetch(); err != nil {
+		t.Fatalf("cold run failed: %v\noutput:\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "index.json")); err != nil {
+		t.Fatalf("expected cache index: %v", err)
+	}
+
+	// Warm run re-verifies signatures but reuses the cached asset.
+	out, _, err := runSfetch()
+	if err != nil {
+		t.Fatalf("warm run failed: %v\noutput:\n%s", err, out)
+	}
+	if !bytes.Contains([]byte(out), []byte("Using cached sfetch_test_darwin_arm64.tar.gz")) {
+		t.Errorf("expected cache reuse in output:\n%s", out)
+	}
+	if n := assetRequests.Load(); n != 1 {
+		t.Errorf("asset downloaded %d times, want 1", n)
+	}
+
+	// With the server gone, --offline must still install from the cache.
+	ts.Close()
+	out, destDir, err := runSfetch("--offline")
+	if err != nil {
+		t.Fatalf("offline run failed: %v\noutput:\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "sfetch")); err != nil {
+		t.Fatalf("expected installed binary after offline run: %v\noutput:\n%s", err, out)
+	}
+
+	// An uncached tag fails offline instead of reaching for the network.
+	cmd := exec.Command("go", "run", ".", "--repo", "test/cache-example", "--tag", "v9.9.9", "--offline", "--cache-dir", cacheDir, "--dest-dir", t.TempDir())
+	if output, err := cmd.CombinedOutput(); err == nil || !bytes.Contains(output, []byte("no cached assets")) {
+		t.Fatalf("expected offline miss to fail, err=%v output:\n%s", err, output)
+	}
+}
+
+func TestIntegrationInsecureStillInstalls(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	shaBytes, err := os.ReadFile("testdata/integration/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("read checksum: %v", err)
+	}
+	minisigBytes, err := os.ReadFile("testdata/integration/SHA256SUMS.minisig")
+	if err != nil {
+		t.Fatalf("read minisig: %v", err)
+	}
+	pubKeyBytes, err := os.ReadFile("testdata/integration/test-minisign.pub")
+	if err != nil {
+		t.Fatalf("read pubkey: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/insecure/releases/latest":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+					{Name: "SHA256SUMS.minisig", BrowserDownloadUrl: base + "/assets/sha-minisig"},
+					{Name: "test-minisign.pub", BrowserDownloadUrl: base + "/assets/pubkey"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		case "/assets/sha-minisig":
+			_, _ = w.Write(minisigBytes)
+		case "/assets/pubkey":
+			_, _ = w.Write(pubKeyBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	destDir := t.TempDir()
+	cacheDir := filepath.Join(destDir, "cache")
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/insecure",
+		"--latest",
+		"--dest-dir", destDir,
+		"--cache-dir", cacheDir,
+		"--binary-name", "sfetch",
+		"--insecure",
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+	}
+
+	installed := filepath.Join(destDir, "sfetch")
+	if _, err := os.Stat(installed); err != nil {
+		t.Fatalf("expected installed binary at %s: %v", installed, err)
+	}
+
+	if !bytes.Contains(output.Bytes(), []byte("WARNING: verification bypass enabled (--insecure)")) {
+		t.Fatalf("expected insecure warning in output:\n%s", output.String())
+	}
+}
+
+// An existing directory passed to --output is treated like --dest-dir.
+func TestIntegrationSourceArchive(t *testing.T) {
+	tarball := []byte("source tarball bytes\n")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := fmt.Sprintf("http://%s", r.Host)
+		switch r.URL.Path {
+		case "/repos/test/srcpkg/releases/tags/v1.2.0":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"tag_name":    "v1.2.0",
+				"tarball_url": base + "/repos/test/srcpkg/tarball/v1.2.0",
+				"zipball_url": base + "/repos/test/srcpkg/zipball/v1.2.0",
+				// Release checksums never cover the generated archive.
+				"assets": []Asset{{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"}},
+			})
+		case "/repos/test/srcpkg/tarball/v1.2.0":
+			_, _ = w.Write(tarball)
+		case "/assets/sha":
+			_, _ = w.Write([]byte(strings.Repeat("0", 64) + "  srcpkg_linux_amd64.tar.gz\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	destDir := t.TempDir()
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/srcpkg",
+		"--tag", "v1.2.0",
+		"--source-archive", "tar",
+		"--dest-dir", destDir,
+		"--cache-dir", t.TempDir(),
+		"--json",
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	got, err := os.ReadFile(filepath.Join(destDir, "srcpkg-v1.2.0.tar.gz"))
+	if err != nil {
+		t.Fatalf("expected the source archive installed as-is: %v", err)
+	}
+	if !bytes.Equal(got, tarball) {
+		t.Fatalf("installed %q, want %q", got, tarball)
+	}
+	var result runResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("parse --json output: %v\nstdout:\n%s", err, stdout.String())
+	}
+	if result.Install == nil || result.Install.Workflow != workflowNone || result.Install.Trust == nil || result.Install.Trust.Score > 25 {
+		t.Fatalf("want an unverified, low-trust install; got %s", stdout.String())
+	}
+}
+
+func TestIntegrationOutputDirectory(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	shaBytes, err := os.ReadFile("testdata/integration/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("read checksum: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/outdir/releases/latest":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	outDir := t.TempDir()
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/outdir",
+		"--latest",
+		"--output", outDir,
+		"--cache-dir", t.TempDir(),
+		"--binary-name", "sfetch",
+		"--skip-sig",
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+	}
+
+	installed := filepath.Join(outDir, "sfetch")
+	info, err := os.Stat(installed)
+	if err != nil {
+		t.Fatalf("expected installed binary at %s: %v\noutput:\n%s", installed, err, output.String())
+	}
+	if !info.Mode().IsRegular() {
+		t.Fatalf("%s is not a regular file", installed)
+	}
+}
+
+// A SHA256SUMS file with base64 digests verifies only with --checksum-base64.
+func TestIntegrationBase64Checksum(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+	shaBytes := []byte(base64.StdEncoding.EncodeToString(sum[:]) + "  sfetch_test_darwin_arm64.tar.gz\n")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/b64/releases/latest":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "hex only by default", wantCode: exitChecksum},
+		{name: "checksum-base64", args: []string{"--checksum-base64"}, wantCode: exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			args := append([]string{"run", ".",
+				"--repo", "test/b64",
+				"--latest",
+				"--dest-dir", destDir,
+				"--cache-dir", t.TempDir(),
+				"--binary-name", "sfetch",
+				"--skip-sig",
+				"--json",
+			}, tt.args...)
+			cmd := exec.Command("go", args...)
+			cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if tt.wantCode != exitOK {
+				assertJSONExitCode(t, stdout.Bytes(), tt.wantCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("sfetch failed: %v\nstderr:\n%s", err, stderr.String())
+			}
+			var result runResult
+			if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+				t.Fatalf("parse --json output: %v\nstdout:\n%s", err, stdout.String())
+			}
+			if result.Install == nil || result.Install.Verification == nil || !result.Install.Verification.Checksum.Verified {
+				t.Fatalf("expected a verified checksum in the --json result:\n%s", stdout.String())
+			}
+			if _, err := os.Stat(filepath.Join(destDir, "sfetch")); err != nil {
+				t.Fatalf("expected installed binary: %v", err)
+			}
+		})
+	}
+}
+
+func TestIntegrationLockfile(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+	shaBytes := []byte(hex.EncodeToString(sum[:]) + "  sfetch_test_darwin_arm64.tar.gz\n")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/lock/releases/latest", "/repos/test/lock/releases/tags/v0.1.0":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	runSfetch := func(t *testing.T, destDir string, extra ...string) (string, string, error) {
+		t.Helper()
+		args := append([]string{"run", ".",
+			"--dest-dir", destDir,
+			"--cache-dir", t.TempDir(),
+			"--binary-name", "sfetch",
+			"--skip-sig",
+			"--json",
+		}, extra...)
+		cmd := exec.Command("go", args...)
+		cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+
+	lockPath := filepath.Join(t.TempDir(), "sfetch.lock")
+	if _, stderr, err := runSfetch(t, t.TempDir(), "--repo", "test/lock", "--latest", "--lockfile", lockPath); err != nil {
+		t.Fatalf("install with --lockfile failed: %v\nstderr:\n%s", err, stderr)
+	}
+	lock, err := loadLockfile(lockPath)
+	if err != nil {
+		t.Fatalf("load written lockfile: %v", err)
+	}
+	want := LockEntry{
+		Repo:     "test/lock",
+		Platform: lockPlatform(runtime.GOOS, runtime.GOARCH),
+		Tag:      "v0.1.0",
+		Asset:    "sfetch_test_darwin_arm64.tar.gz",
+		SHA256:   hex.EncodeToString(sum[:]),
+	}
+	got, ok := lock.find("test/lock", want.Platform)
+	if !ok {
+		t.Fatalf("lockfile has no entry for test/lock: %+v", lock)
+	}
+	got.TrustScore, got.Workflow = 0, ""
+	if got != want {
+		t.Fatalf("lock entry = %+v, want %+v", got, want)
+	}
+
+	t.Run("from-lockfile installs pinned asset", func(t *testing.T) {
+		destDir := t.TempDir()
+		if _, stderr, err := runSfetch(t, destDir, "--from-lockfile", lockPath); err != nil {
+			t.Fatalf("--from-lockfile failed: %v\nstderr:\n%s", err, stderr)
+		}
+		if _, err := os.Stat(filepath.Join(destDir, "sfetch")); err != nil {
+			t.Fatalf("expected installed binary: %v", err)
+		}
+	})
+
+	t.Run("from-lockfile rejects a different digest", func(t *testing.T) {
+		tampered := *lock
+		tampered.Tools = []LockEntry{want}
+		tampered.Tools[0].SHA256 = strings.Repeat("0", 64)
+		tamperedPath := filepath.Join(t.TempDir(), "sfetch.lock")
+		if err := writeLockfile(tamperedPath, &tampered); err != nil {
+			t.Fatalf("write lockfile: %v", err)
+		}
+		destDir := t.TempDir()
+		stdout, _, _ := runSfetch(t, destDir, "--repo", "test/lock", "--from-lockfile", tamperedPath)
+		assertJSONExitCode(t, []byte(stdout), exitChecksum)
+		if _, err := os.Stat(filepath.Join(destDir, "sfetch")); !os.IsNotExist(err) {
+			t.Fatalf("binary installed despite digest mismatch: %v", err)
+		}
+	})
+}
+
+func TestIntegrationUserRepoConfig(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	shaBytes, err := os.ReadFile("testdata/integration/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("read checksum: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/renamed-tool/releases/latest":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	// The archive holds "sfetch", not the inferred "renamed-tool"; only the
+	// user's repos.json makes the install succeed.
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "sfetch"), 0o755); err != nil {
+		t.Fatalf("mkdir config: %v", err)
+	}
+	repos := `{
+  "Test/Renamed-Tool": {"binaryName": "sfetch"},
+  "test/broken": {"hashAlgo": "md5"}
+}`
+	if err := os.WriteFile(filepath.Join(configHome, "sfetch", "repos.json"), []byte(repos), 0o644); err != nil {
+		t.Fatalf("write repos.json: %v", err)
+	}
+
+	destDir := t.TempDir()
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/renamed-tool",
+		"--latest",
+		"--dest-dir", destDir,
+		"--cache-dir", filepath.Join(destDir, "cache"),
+		"--skip-sig",
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL, "XDG_CONFIG_HOME="+configHome)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(destDir, "sfetch")); err != nil {
+		t.Fatalf("expected binary named by repos.json: %v\noutput:\n%s", err, output.String())
+	}
+	if !bytes.Contains(output.Bytes(), []byte("skipping test/broken")) {
+		t.Fatalf("expected warning for invalid entry:\n%s", output.String())
+	}
+}
+
+func TestIntegrationNextStepHints(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	shaBytes, err := os.ReadFile("testdata/integration/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("read checksum: %v", err)
+	}
+	minisigBytes, err := os.ReadFile("testdata/integration/SHA256SUMS.minisig")
+	if err != nil {
+		t.Fatalf("read minisig: %v", err)
+	}
+
+	tieA := fmt.Sprintf("tool-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	tieB := fmt.Sprintf("tool-%s-%s-debug.tar.gz", runtime.GOOS, runtime.GOARCH)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := fmt.Sprintf("http://%s", r.Host)
+		var rel fakeRelease
+		switch r.URL.Path {
+		case "/repos/test/tie/releases/latest":
+			rel = fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					{Name: tieA, BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: tieB, BrowserDownloadUrl: base + "/assets/bin"},
+				},
+			}
+		case "/repos/test/nokey/releases/latest":
+			rel = fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+					{Name: "SHA256SUMS.minisig", BrowserDownloadUrl: base + "/assets/sha-minisig"},
+				},
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+			return
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+			return
+		case "/assets/sha-minisig":
+			_, _ = w.Write(minisigBytes)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(&rel); err != nil {
+			t.Fatalf("encode release: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		repo     string
+		wantKind hintKind
+		wantText string
+		wantCode int
+	}{
+		{name: "selection tie", repo: "test/tie", wantKind: hintSelectionTie, wantText: fmt.Sprintf("--asset-match %q", tieA), wantCode: exitSelection},
+		{name: "missing key", repo: "test/nokey", wantKind: hintMissingKey, wantText: "pass it with --minisign-key", wantCode: exitSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			cmd := exec.Command("go", "run", ".",
+				"--repo", tt.repo,
+				"--latest",
+				"--dest-dir", destDir,
+				"--cache-dir", filepath.Join(destDir, "cache"),
+				"--binary-name", "sfetch",
+				"--json",
+			)
+			cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err == nil {
+				t.Fatalf("expected failure\nstderr:\n%s", stderr.String())
+			}
+
+			if !strings.Contains(stderr.String(), "  hint: ") || !strings.Contains(stderr.String(), tt.wantText) {
+				t.Fatalf("expected hint containing %q in stderr:\n%s", tt.wantText, stderr.String())
+			}
+			var doc runResult
+			if err := json.Unmarshal(stdout.Bytes(), &doc); err != nil {
+				t.Fatalf("parse --json output: %v\nstdout:\n%s", err, stdout.String())
+			}
+			if len(doc.Hints) != 1 || doc.Hints[0].Kind != tt.wantKind || !strings.Contains(doc.Hints[0].Message, tt.wantText) {
+				t.Fatalf("hints = %+v, want one %s hint containing %q", doc.Hints, tt.wantKind, tt.wantText)
+			}
+			if doc.ExitCode != tt.wantCode {
+				t.Fatalf("exitCode = %d, want %d", doc.ExitCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestIntegrationMultipleBinaries(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		mode int64
+	}{
+		{"tool", 0o755},
+		{"tool-helper", 0o755},
+		{"README.md", 0o644},
+		{"libexec/tool-extra", 0o755},
+	} {
+		body := []byte("#!/bin/sh\necho " + f.name + "\n")
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: f.mode, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("tar header: %v", err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			t.Fatalf("tar write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	assetBytes := archive.Bytes()
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	sum := sha256.Sum256(assetBytes)
+	shaBytes := []byte(hex.EncodeToString(sum[:]) + "  " + assetName + "\n")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/tool/releases/latest":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v1.0.0",
+				Assets: []Asset{
+					{Name: assetName, BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		case "/assets/sha":
+			_, _ = w.Write(shaBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "sfetch"), 0o755); err != nil {
+		t.Fatalf("mkdir config: %v", err)
+	}
+	reposJSON := []byte(`{"test/tool": {"binaries": ["tool", "tool-helper"]}}`)
+	if err := os.WriteFile(filepath.Join(configHome, "sfetch", "repos.json"), reposJSON, 0o644); err != nil {
+		t.Fatalf("write repos.json: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     []string
+		want    []string
+		wantErr string
+	}{
+		{name: "single binary default", args: nil, want: []string{"tool"}},
+		{name: "binary-name list", args: []string{"--binary-name", "tool,tool-helper"}, want: []string{"tool", "tool-helper"}},
+		{name: "binaries flag", args: []string{"--binaries", "tool,tool-helper"}, want: []string{"tool", "tool-helper"}},
+		{name: "repo config binaries", env: []string{"XDG_CONFIG_HOME=" + configHome}, want: []string{"tool", "tool-helper"}},
+		{name: "binary-name overrides repo config", args: []string{"--binary-name", "tool"}, env: []string{"XDG_CONFIG_HOME=" + configHome}, want: []string{"tool"}},
+		{name: "missing listed binary installs nothing", args: []string{"--binaries", "tool,tool-missing"}, wantErr: "tool-missing"},
+		{name: "all binaries", args: []string{"--all-binaries"}, want: []string{"tool", "tool-extra", "tool-helper"}},
+		{name: "nested binary by name", args: []string{"--binary-name", "tool-extra"}, want: []string{"tool-extra"}},
+		{name: "nested binary by glob", args: []string{"--binary-name", "libexec/*"}, want: []string{"tool-extra"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			args := append([]string{"run", ".",
+				"--repo", "test/tool",
+				"--latest",
+				"--dest-dir", destDir,
+				"--cache-dir", t.TempDir(),
+				"--skip-sig",
+			}, tt.args...)
+			cmd := exec.Command("go", args...)
+			cmd.Env = append(append(os.Environ(), "SFETCH_API_BASE="+ts.URL), tt.env...)
+			var output bytes.Buffer
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			err := cmd.Run()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(output.String(), tt.wantErr) {
+					t.Fatalf("want failure mentioning %q, got err=%v\noutput:\n%s", tt.wantErr, err, output.String())
+				}
+			} else if err != nil {
+				t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+			}
+
+			entries, err := os.ReadDir(destDir)
+			if err != nil {
+				t.Fatalf("read dest: %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("installed %v, want %v\noutput:\n%s", got, tt.want, output.String())
+			}
+			for _, name := range tt.want {
+				line := fmt.Sprintf("Installed %s to %s", name, filepath.Join(destDir, name))
+				if !strings.Contains(output.String(), line) {
+					t.Errorf("expected %q in output:\n%s", line, output.String())
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationChecksumFileChoice(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+	// Two unsigned manifests: the per-asset one (picked first by default)
+	// is stale, SHA256SUMS is correct.
+	manifests := map[string][]byte{
+		assetName + ".sha256": []byte(strings.Repeat("0", 64) + "  " + assetName + "\n"),
+		"SHA256SUMS":          []byte(hex.EncodeToString(sum[:]) + "  " + assetName + "\n"),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := fmt.Sprintf("http://%s", r.Host)
+		switch r.URL.Path {
+		case "/repos/test/tool/releases/latest":
+			rel := fakeRelease{
+				TagName: "v1.0.0",
+				Assets: []Asset{
+					{Name: assetName, BrowserDownloadUrl: base + "/assets/bin"},
+					{Name: assetName + ".sha256", BrowserDownloadUrl: base + "/assets/" + assetName + ".sha256"},
+					{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/SHA256SUMS"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		default:
+			if data, ok := manifests[strings.TrimPrefix(r.URL.Path, "/assets/")]; ok {
+				_, _ = w.Write(data)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     string
+	}{
+		{name: "strict refuses to auto-pick", args: []string{"--strict-checksum-choice"}, wantCode: exitSelection, want: "2 unsigned checksum manifests match"},
+		{name: "checksum-file override", args: []string{"--strict-checksum-choice", "--checksum-file", "SHA256SUMS"}, wantCode: exitOK, want: "Installed tool"},
+		{name: "default pick uses the stale manifest", wantCode: exitChecksum, want: "checksum mismatch"},
+		{name: "unknown checksum-file", args: []string{"--checksum-file", "CHECKSUMS.txt"}, wantCode: exitChecksum, want: "--checksum-file CHECKSUMS.txt is not an asset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			args := append([]string{"run", ".",
+				"--repo", "test/tool",
+				"--latest",
+				"--dest-dir", destDir,
+				"--cache-dir", t.TempDir(),
+				"--skip-tools-check",
+			}, tt.args...)
+			cmd := exec.Command("go", args...)
+			cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+			var output bytes.Buffer
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			err := cmd.Run()
+			// go run reports the program's exit code as "exit status N".
+			if tt.wantCode == exitOK {
+				if err != nil {
+					t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+				}
+			} else if wantStatus := fmt.Sprintf("exit status %d", tt.wantCode); !strings.Contains(output.String(), wantStatus) {
+				t.Fatalf("expected %q in output:\n%s", wantStatus, output.String())
+			}
+			if !strings.Contains(output.String(), tt.want) {
+				t.Fatalf("expected %q in output:\n%s", tt.want, output.String())
+			}
+		})
+	}
+}
+
+func TestIntegrationMirrorFallback(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+	files := map[string][]byte{
+		assetName:    assetBytes,
+		"SHA256SUMS": []byte(hex.EncodeToString(sum[:]) + "  " + assetName + "\n"),
+	}
+
+	// primaryStatus is what the primary host answers for release files.
+	var primaryStatus atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/test/tool/releases/latest" {
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{TagName: "v1.0.0"}
+			for name := range files {
+				rel.Assets = append(rel.Assets, Asset{Name: name, BrowserDownloadUrl: base + "/test/tool/releases/download/v1.0.0/" + name})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+			return
+		}
+		w.WriteHeader(int(primaryStatus.Load()))
+	}))
+	defer primary.Close()
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/gh/test/tool/releases/download/v1.0.0/")
+		if data, ok := files[name]; ok {
+			_, _ = w.Write(data)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mirror.Close()
+
+	tests := []struct {
+		name          string
+		primaryStatus int
+		mirrors       []string
+		wantCode      int
+		want          string
+	}{
+		{name: "mirror serves after 503", primaryStatus: http.StatusServiceUnavailable, mirrors: []string{mirror.URL + "/gh"}, wantCode: exitOK, want: "Installed tool"},
+		{name: "mirrors tried in order", primaryStatus: http.StatusBadGateway, mirrors: []string{mirror.URL + "/missing", mirror.URL + "/gh"}, wantCode: exitOK, want: "Installed tool"},
+		{name: "all mirrors fail", primaryStatus: http.StatusServiceUnavailable, mirrors: []string{mirror.URL + "/missing"}, wantCode: exitNetwork, want: "status 404 from " + mirror.URL + "/missing/"},
+		{name: "404 is not retried", primaryStatus: http.StatusNotFound, mirrors: []string{mirror.URL + "/gh"}, wantCode: exitNetwork, want: "status 404 downloading"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryStatus.Store(int32(tt.primaryStatus))
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			args := []string{"run", ".",
+				"--repo", "test/tool",
+				"--latest",
+				"--dest-dir", t.TempDir(),
+				"--cache-dir", t.TempDir(),
+				"--skip-tools-check",
+				"--allow-http",
+				"--provenance-file", provPath,
+			}
+			for _, m := range tt.mirrors {
+				args = append(args, "--mirror", m)
+			}
+			cmd := exec.Command("go", args...)
+			cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+primary.URL)
+			var output bytes.Buffer
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			err := cmd.Run()
+			if tt.wantCode != exitOK {
+				if wantStatus := fmt.Sprintf("exit status %d", tt.wantCode); !strings.Contains(output.String(), wantStatus) {
+					t.Fatalf("expected %q in output:\n%s", wantStatus, output.String())
+				}
+				if !strings.Contains(output.String(), tt.want) {
+					t.Fatalf("expected %q in output:\n%s", tt.want, output.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+			}
+			if !strings.Contains(output.String(), tt.want) {
+				t.Fatalf("expected %q in output:\n%s", tt.want, output.String())
+			}
+
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var record ProvenanceRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			wantAsset := mirror.URL + "/gh/test/tool/releases/download/v1.0.0/" + assetName
+			if record.Asset.ServedFrom != wantAsset {
+				t.Fatalf("asset servedFrom = %q, want %q", record.Asset.ServedFrom, wantAsset)
+			}
+			if want := mirror.URL + "/gh/test/tool/releases/download/v1.0.0/SHA256SUMS"; record.Verification.Checksum.ServedFrom != want {
+				t.Fatalf("checksum servedFrom = %q, want %q", record.Verification.Checksum.ServedFrom, want)
+			}
+		})
+	}
+}
+
+func TestIntegrationTrustMinimumBlocksUnsigned(t *testing.T) {
+	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/test/unsigned/releases/latest":
+			base := fmt.Sprintf("http://%s", r.Host)
+			rel := fakeRelease{
+				TagName: "v0.1.0",
+				Assets: []Asset{
+					// No signature or checksum assets.
+					{Name: "sfetch_test_darwin_arm64.tar.gz", BrowserDownloadUrl: base + "/assets/bin"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(&rel); err != nil {
+				t.Fatalf("encode release: %v", err)
+			}
+		case "/assets/bin":
+			_, _ = w.Write(assetBytes)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	destDir := t.TempDir()
+	cacheDir := filepath.Join(destDir, "cache")
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/unsigned",
+		"--latest",
+		"--dest-dir", destDir,
+		"--cache-dir", cacheDir,
+		"--trust-minimum", "30",
+		"--json",
 	)
 	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
-	var output bytes.Buffer
-	cmd.Stdout = &output
+	var stdout, output bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &output
 	err = cmd.Run()
 	if err == nil {
@@ -378,11 +2216,126 @@ func TestIntegrationTrustMinimumBlocksUnsigned(t *testing.T) {
 	if !bytes.Contains(output.Bytes(), []byte("below --trust-minimum 30")) {
 		t.Fatalf("expected trust-minimum error in output:\n%s", output.String())
 	}
+	assertJSONExitCode(t, stdout.Bytes(), exitTrust)
 	if _, err := os.Stat(filepath.Join(destDir, "sfetch")); err == nil {
 		t.Fatalf("did not expect binary to be installed when trust-minimum blocks")
 	}
 }
 
+func TestIntegrationURLSidecars(t *testing.T) {
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata/integration", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return data
+	}
+	tool := []byte("#!/bin/sh\necho tool\n")
+	toolSum := sha256.Sum256(tool)
+	const releaseKey = "RWQofujyCgZE45KW4wjPCDP6M/KdG9WDzwSWU6TjnCb3DpsEMOrUt4KX"
+	const otherKey = "RWTAoUJ007VE3h8tbHlBCyk2+y0nn7kyA4QP34LTzdtk8M6A2sryQtZC"
+
+	// Each directory publishes the same file with a different set of
+	// sidecars. /signed serves SHA256SUMS, which the test minisign key
+	// signed.
+	files := map[string][]byte{
+		"/bare/tool":                 tool,
+		"/sum/tool":                  tool,
+		"/sum/tool.sha256":           []byte(hex.EncodeToString(toolSum[:]) + "  tool\n"),
+		"/badsum/tool":               tool,
+		"/badsum/tool.sha256":        []byte(strings.Repeat("0", 64) + "  tool\n"),
+		"/signed/SHA256SUMS":         read("SHA256SUMS"),
+		"/signed/SHA256SUMS.minisig": read("SHA256SUMS.minisig"),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		args         []string
+		wantCode     int
+		want         []string
+		wantWorkflow string
+	}{
+		{name: "no sidecars", path: "/bare/tool", want: []string{"proceeding without verification artifacts"}, wantWorkflow: workflowNone},
+		{name: "checksum sidecar", path: "/sum/tool", args: []string{"-v"}, want: []string{"Checksum verified OK"}, wantWorkflow: workflowC},
+		{name: "checksum sidecar mismatch", path: "/badsum/tool", wantCode: exitChecksum, want: []string{"checksum mismatch"}},
+		{name: "skip-checksum ignores sidecar", path: "/badsum/tool", args: []string{"--skip-checksum"}, wantWorkflow: workflowNone},
+		{name: "minisign sidecar", path: "/signed/SHA256SUMS", args: []string{"--minisign-key", "testdata/integration/test-minisign.pub"}, want: []string{"Minisign signature verified OK"}, wantWorkflow: workflowB},
+		{name: "minisign sidecar without key", path: "/signed/SHA256SUMS", want: []string{"no verification key configured"}, wantWorkflow: workflowNone},
+		{name: "require-minisign without sidecar", path: "/sum/tool", args: []string{"--require-minisign"}, wantCode: exitTrust, want: []string{"no .minisig sidecar found"}},
+		{name: "require-signature with key", path: "/signed/SHA256SUMS", args: []string{"--require-signature", "--minisign-key", "testdata/integration/test-minisign.pub"}, want: []string{"Minisign signature verified OK"}, wantWorkflow: workflowB},
+		{name: "require-signature without key", path: "/signed/SHA256SUMS", args: []string{"--require-signature"}, wantCode: exitTrust, want: []string{"--require-signature specified but no verification key for minisign signature SHA256SUMS.minisig"}},
+		{name: "require-signature with checksum only", path: "/sum/tool", args: []string{"--require-signature"}, wantCode: exitTrust, want: []string{"--require-signature specified but no signature found"}},
+		{name: "strict-keys without key", path: "/signed/SHA256SUMS", args: []string{"--strict-keys"}, wantCode: exitTrust, want: []string{"--strict-keys: Minisign signature SHA256SUMS.minisig is published but no key is available", "hint: get the maintainer's Minisign public key"}},
+		{name: "strict-keys with checksum only", path: "/sum/tool", args: []string{"--strict-keys"}, wantWorkflow: workflowC},
+		{name: "expect-sha256 matches", path: "/bare/tool", args: []string{"--expect-sha256", hex.EncodeToString(toolSum[:])}, wantWorkflow: workflowNone},
+		{name: "expect-sha256 mismatch", path: "/bare/tool", args: []string{"--expect-sha256", strings.Repeat("0", 64)}, wantCode: exitChecksum, want: []string{"sha256 mismatch: expected " + strings.Repeat("0", 64)}},
+		{name: "pinned key matches", path: "/signed/SHA256SUMS", args: []string{"--minisign-key", "testdata/integration/test-minisign.pub", "--pin-minisign-key", releaseKey}, want: []string{"Minisign signature verified OK"}, wantWorkflow: workflowB},
+		{name: "pinned key mismatch", path: "/signed/SHA256SUMS", args: []string{"--minisign-key", "testdata/integration/test-minisign.pub", "--pin-minisign-key", otherKey}, wantCode: exitSignature, want: []string{"minisign public key does not match pinned key"}},
+		{name: "pin without sidecar", path: "/bare/tool", args: []string{"--pin-minisign-key", releaseKey, "--pin-pgp-fingerprint", testPGPFingerprint}, wantCode: exitTrust, want: []string{"a signing key is pinned but the release has no signature to verify"}},
+		{name: "pin with sidecar but no key", path: "/signed/SHA256SUMS", args: []string{"--pin-minisign-key", releaseKey}, wantCode: exitTrust, want: []string{"a signing key is pinned but the release has no signature to verify"}},
+		{name: "pin covers another format", path: "/signed/SHA256SUMS", args: []string{"--minisign-key", "testdata/integration/test-minisign.pub", "--pin-pgp-fingerprint", testPGPFingerprint}, wantCode: exitTrust, want: []string{"which no pin covers"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			args := append([]string{"run", ".",
+				"--url", ts.URL + tt.path,
+				"--allow-http",
+				"--dest-dir", t.TempDir(),
+				"--skip-tools-check",
+				"--no-path-check",
+				"--provenance-file", provPath,
+			}, tt.args...)
+			cmd := exec.Command("go", args...)
+			var output bytes.Buffer
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			err := cmd.Run()
+			if tt.wantCode == exitOK {
+				if err != nil {
+					t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+				}
+			} else if wantStatus := fmt.Sprintf("exit status %d", tt.wantCode); !strings.Contains(output.String(), wantStatus) {
+				t.Fatalf("expected %q in output:\n%s", wantStatus, output.String())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output.String(), want) {
+					t.Fatalf("expected %q in output:\n%s", want, output.String())
+				}
+			}
+			if tt.wantCode != exitOK {
+				return
+			}
+
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var record ProvenanceRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			if record.Source.Type != "url" {
+				t.Errorf("source.type = %q, want url", record.Source.Type)
+			}
+			if record.Verification.Workflow != tt.wantWorkflow {
+				t.Errorf("workflow = %q, want %q", record.Verification.Workflow, tt.wantWorkflow)
+			}
+		})
+	}
+}
+
 func TestIntegrationRequireMinisign(t *testing.T) {
 	// Test --require-minisign fails when no minisign sig present
 	assetBytes, err := os.ReadFile("testdata/integration/sfetch_test_darwin_arm64.tar.gz")
@@ -437,15 +2390,17 @@ func TestIntegrationRequireMinisign(t *testing.T) {
 		"--dest-dir", destDir,
 		"--require-minisign",
 		"--cache-dir", cacheDir,
+		"--json",
 	)
 	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
-	var output bytes.Buffer
-	cmd.Stdout = &output
+	var stdout, output bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &output
 	err = cmd.Run()
 	if err == nil {
 		t.Fatalf("expected sfetch to fail with --require-minisign but no minisig present")
 	}
+	assertJSONExitCode(t, stdout.Bytes(), exitTrust)
 
 	// Verify error message
 	if !bytes.Contains(output.Bytes(), []byte("--require-minisign")) {
@@ -453,6 +2408,412 @@ func TestIntegrationRequireMinisign(t *testing.T) {
 	}
 }
 
+func TestIntegrationRequireSignature(t *testing.T) {
+	// A signed release passes --require-signature in any format: here a
+	// minisign signature over SHA256SUMS (Workflow A).
+	ts, _ := newWorkflowAServer(t, "test/require-signature")
+
+	destDir := t.TempDir()
+	cmd := exec.Command("go", "run", ".",
+		"--repo", "test/require-signature",
+		"--latest",
+		"--dest-dir", destDir,
+		"--minisign-key-asset", "test-minisign.pub",
+		"--cache-dir", filepath.Join(destDir, "cache"),
+		"--binary-name", "sfetch",
+		"--require-signature",
+	)
+	cmd.Env = append(os.Environ(), "SFETCH_API_BASE="+ts.URL)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sfetch failed: %v\noutput:\n%s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "checksum signature verified OK") {
+		t.Errorf("expected signature verification in output:\n%s", output.String())
+	}
+}
+
+// minisignTestKey signs fixtures in the legacy (non-prehashed) minisign
+// format with a deterministic key.
+type minisignTestKey struct {
+	priv  ed25519.PrivateKey
+	keyID [8]byte
+}
+
+func newMinisignTestKey() minisignTestKey {
+	return minisignTestKey{
+		priv:  ed25519.NewKeyFromSeed(bytes.Repeat([]byte{9}, ed25519.SeedSize)),
+		keyID: [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+	}
+}
+
+func (k minisignTestKey) publicKey() []byte {
+	raw := append(append([]byte("Ed"), k.keyID[:]...), k.priv.Public().(ed25519.PublicKey)...)
+	return []byte("untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n")
+}
+
+func (k minisignTestKey) sign(data []byte) []byte {
+	const trusted = "timestamp:0"
+	sig := ed25519.Sign(k.priv, data)
+	global := ed25519.Sign(k.priv, append(append([]byte(nil), sig...), trusted...))
+	raw := append(append([]byte("Ed"), k.keyID[:]...), sig...)
+	return []byte("untrusted comment: signature\n" + base64.StdEncoding.EncodeToString(raw) +
+		"\ntrusted comment: " + trusted + "\n" + base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestIntegrationSHA512OnlyRelease(t *testing.T) {
+	// Releases that publish only SHA-512 checksums verify through every
+	// workflow, whatever the repo's hashAlgo: the checksum file decides.
+	assetName := "sfetch_test_darwin_arm64.tar.gz"
+	assetBytes, err := os.ReadFile("testdata/integration/" + assetName)
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	sum := sha512.Sum512(assetBytes)
+	digest := hex.EncodeToString(sum[:])
+	sums := []byte(digest + "  " + assetName + "\n")
+	key := newMinisignTestKey()
+
+	tests := []struct {
+		name         string
+		files        map[string][]byte
+		wantWorkflow string
+		wantCode     int
+	}{
+		{
+			name: "workflow A SHA512SUMS.minisig",
+			files: map[string][]byte{
+				"SHA512SUMS":         sums,
+				"SHA512SUMS.minisig": key.sign(sums),
+				"minisign.pub":       key.publicKey(),
+			},
+			wantWorkflow: workflowA,
+		},
+		{
+			name:         "workflow B per-asset .sha512",
+			files:        map[string][]byte{assetName + ".sha512": []byte(digest + "\n"), assetName + ".minisig": key.sign(assetBytes), "minisign.pub": key.publicKey()},
+			wantWorkflow: workflowB,
+		},
+		{
+			name:         "workflow C SHA512SUMS",
+			files:        map[string][]byte{"SHA512SUMS": sums},
+			wantWorkflow: workflowC,
+		},
+		{
+			name:         "workflow C sha512 digests in checksums.txt",
+			files:        map[string][]byte{"checksums.txt": sums},
+			wantWorkflow: workflowC,
+		},
+		{
+			name:     "workflow C tampered SHA512SUMS",
+			files:    map[string][]byte{"SHA512SUMS": []byte(strings.Repeat("0", 128) + "  " + assetName + "\n")},
+			wantCode: exitChecksum,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/repos/test/sha512/releases/latest" {
+					base := fmt.Sprintf("http://%s", r.Host)
+					rel := fakeRelease{TagName: "v0.5.0", Assets: []Asset{{Name: assetName, BrowserDownloadUrl: base + "/assets/" + assetName}}}
+					for name := range tc.files {
+						rel.Assets = append(rel.Assets, Asset{Name: name, BrowserDownloadUrl: base + "/assets/" + name})
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(&rel)
+					return
+				}
+				name := strings.TrimPrefix(r.URL.Path, "/assets/")
+				if name == assetName {
+					_, _ = w.Write(assetBytes)
+					return
+				}
+				data, ok := tc.files[name]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write(data)
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+
+			destDir := t.TempDir()
+			provenancePath := filepath.Join(t.TempDir(), "provenance.json")
+			args := []string{"--repo", "test/sha512", "--latest", "--dest-dir", destDir, "--cache-dir", t.TempDir(), "--binary-name", "sfetch", "--json", "--provenance-file", provenancePath}
+			readProvenance := func() ProvenanceRecord {
+				var record ProvenanceRecord
+				data, err := os.ReadFile(provenancePath)
+				if err == nil {
+					err = json.Unmarshal(data, &record)
+				}
+				if err != nil {
+					t.Fatalf("read provenance: %v", err)
+				}
+				return record
+			}
+			if _, ok := tc.files["minisign.pub"]; ok {
+				args = append(args, "--minisign-key-asset", "minisign.pub")
+			}
+			var stdout, stderr bytes.Buffer
+			code := run(args, &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tc.wantCode, stderr.String())
+			}
+			if tc.wantCode != exitOK {
+				return
+			}
+			var result runResult
+			if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+				t.Fatalf("parse --json output: %v\nstdout:\n%s", err, stdout.String())
+			}
+			install := result.Install
+			if install == nil || install.Verification == nil {
+				t.Fatalf("no install result:\n%s", stdout.String())
+			}
+			if install.Workflow != tc.wantWorkflow {
+				t.Errorf("workflow = %q, want %q", install.Workflow, tc.wantWorkflow)
+			}
+			if cs := install.Verification.Checksum; !cs.Verified || cs.Algorithm != "sha512" {
+				t.Errorf("checksum = %+v, want verified sha512", cs)
+			}
+			// The asset's own checksum is always recorded as SHA-256.
+			assetSHA256 := sha256.Sum256(assetBytes)
+			for label, got := range map[string]*ProvenanceHash{"install": install.Checksum, "provenance": readProvenance().Asset.ComputedChecksum} {
+				if got == nil || got.Algorithm != "sha256" || got.Value != hex.EncodeToString(assetSHA256[:]) {
+					t.Errorf("%s asset checksum = %+v, want sha256 of the asset", label, got)
+				}
+			}
+			if name := install.Trust.Factors.Algorithm.Name; name != "sha512" {
+				t.Errorf("trust algorithm = %q, want sha512", name)
+			}
+		})
+	}
+}
+
+func TestIntegrationOnMissingKey(t *testing.T) {
+	// The release publishes minisign.pub, so the signature looks verifiable,
+	// but the key download 404s.
+	assetName := "sfetch_test_darwin_arm64.tar.gz"
+	assetBytes, err := os.ReadFile("testdata/integration/" + assetName)
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+	digest := hex.EncodeToString(sum[:])
+	sums := []byte(digest + "  " + assetName + "\n")
+	key := newMinisignTestKey()
+	workflowAFiles := map[string][]byte{"SHA256SUMS": sums, "SHA256SUMS.minisig": key.sign(sums)}
+
+	tests := []struct {
+		name         string
+		files        map[string][]byte
+		args         []string
+		pinned       bool // repo config pins the minisign key
+		wantCode     int
+		wantWorkflow string
+		wantStderr   string
+	}{
+		{
+			name:       "workflow A fails closed by default",
+			files:      workflowAFiles,
+			wantCode:   exitSignature,
+			wantStderr: "Minisign signature verification blocked: no usable key: status 404",
+		},
+		{
+			name:       "workflow A explicit fail",
+			files:      workflowAFiles,
+			args:       []string{"--on-missing-key", "fail"},
+			wantCode:   exitSignature,
+			wantStderr: "signature verification blocked",
+		},
+		{
+			name:         "workflow A falls back to checksum",
+			files:        workflowAFiles,
+			args:         []string{"--on-missing-key", "fallback-checksum"},
+			wantWorkflow: workflowC,
+			wantStderr:   "WARNING: --on-missing-key fallback-checksum: Minisign signature SHA256SUMS.minisig was not verified",
+		},
+		{
+			name:         "workflow B falls back to checksum",
+			files:        map[string][]byte{assetName + ".sha256": []byte(digest + "\n"), assetName + ".minisig": key.sign(assetBytes)},
+			args:         []string{"--on-missing-key", "fallback-checksum"},
+			wantWorkflow: workflowC,
+			wantStderr:   "fell back to checksum-only verification (workflow C)",
+		},
+		{
+			name:       "workflow B without checksum stays closed",
+			files:      map[string][]byte{assetName + ".minisig": key.sign(assetBytes)},
+			args:       []string{"--on-missing-key", "fallback-checksum"},
+			wantCode:   exitSignature,
+			wantStderr: "signature verification blocked",
+		},
+		{
+			name:       "fallback rescored below trust minimum",
+			files:      workflowAFiles,
+			args:       []string{"--on-missing-key", "fallback-checksum", "--trust-minimum", "80"},
+			wantCode:   exitTrust,
+			wantStderr: "after falling back to checksum-only is below --trust-minimum 80",
+		},
+		{
+			name:       "repo config key pin stays closed",
+			files:      workflowAFiles,
+			args:       []string{"--on-missing-key", "fallback-checksum"},
+			pinned:     true,
+			wantCode:   exitSignature,
+			wantStderr: "does not apply while a key pin is configured",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.pinned {
+				pin := strings.Fields(string(key.publicKey()))
+				repoConfigs["test/missing-key"] = RepoConfig{PinnedMinisignKey: pin[len(pin)-1]}
+				t.Cleanup(func() { delete(repoConfigs, "test/missing-key") })
+			}
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/repos/test/missing-key/releases/latest" {
+					base := fmt.Sprintf("http://%s", r.Host)
+					rel := fakeRelease{TagName: "v0.5.0", Assets: []Asset{
+						{Name: assetName, BrowserDownloadUrl: base + "/assets/" + assetName},
+						{Name: "minisign.pub", BrowserDownloadUrl: base + "/assets/minisign.pub"},
+					}}
+					for name := range tc.files {
+						rel.Assets = append(rel.Assets, Asset{Name: name, BrowserDownloadUrl: base + "/assets/" + name})
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(&rel)
+					return
+				}
+				name := strings.TrimPrefix(r.URL.Path, "/assets/")
+				if name == assetName {
+					_, _ = w.Write(assetBytes)
+					return
+				}
+				data, ok := tc.files[name]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write(data)
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+
+			provenancePath := filepath.Join(t.TempDir(), "provenance.json")
+			args := append([]string{"--repo", "test/missing-key", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--binary-name", "sfetch", "--provenance-file", provenancePath}, tc.args...)
+			var stdout, stderr bytes.Buffer
+			code := run(args, &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tc.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.wantStderr) {
+				t.Errorf("stderr missing %q:\n%s", tc.wantStderr, stderr.String())
+			}
+			if tc.wantCode != exitOK {
+				return
+			}
+
+			var record ProvenanceRecord
+			data, err := os.ReadFile(provenancePath)
+			if err == nil {
+				err = json.Unmarshal(data, &record)
+			}
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			if record.Verification.Workflow != tc.wantWorkflow {
+				t.Errorf("workflow = %q, want %q", record.Verification.Workflow, tc.wantWorkflow)
+			}
+			if record.Flags.OnMissingKey != onMissingKeyFallbackChecksum {
+				t.Errorf("flags.onMissingKey = %q", record.Flags.OnMissingKey)
+			}
+			if !slices.ContainsFunc(record.Warnings, func(w string) bool { return strings.Contains(w, "fell back to checksum-only") }) {
+				t.Errorf("warnings = %q, want the fallback recorded", record.Warnings)
+			}
+			if f := record.Trust.Factors.Signature; f.Validated || f.Points != 0 {
+				t.Errorf("signature factor = %+v, want no credit", f)
+			}
+			if !record.Trust.Factors.Checksum.Validated {
+				t.Errorf("checksum factor = %+v, want validated", record.Trust.Factors.Checksum)
+			}
+			if v := record.Verification; !v.Checksum.Verified || v.Signature.Verified || v.Signature.Reason != "no verification key available" {
+				t.Errorf("verification = %+v, want checksum only", v)
+			}
+		})
+	}
+}
+
+func TestIntegrationAssertVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake binaries are shell scripts")
+	}
+	assetName := fmt.Sprintf("tool_%s_%s", runtime.GOOS, runtime.GOARCH)
+
+	tests := []struct {
+		name       string
+		script     string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "matching --version", script: `echo "tool version v1.2.3 (abc123)"`, wantStderr: "tool_" + runtime.GOOS + "_" + runtime.GOARCH + " reports version 1.2.3, matching v1.2.3"},
+		{name: "version subcommand", script: `[ "$1" = version ] || { echo "unknown flag $1" >&2; exit 2; }; echo 1.2.3`},
+		{name: "mismatched version", script: `echo "tool 2.0.0"`, wantCode: exitTrust, wantStderr: "reports version 2.0.0, but the release tag is v1.2.3"},
+		{name: "no version", script: `echo hello`, wantCode: exitTrust, wantStderr: "printed no version (tried --version, version)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			binary := []byte("#!/bin/sh\n" + tc.script + "\n")
+			sum := sha256.Sum256(binary)
+			sums := []byte(hex.EncodeToString(sum[:]) + "  " + assetName + "\n")
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/test/versioned/releases/latest":
+					base := fmt.Sprintf("http://%s", r.Host)
+					rel := fakeRelease{TagName: "v1.2.3", Assets: []Asset{
+						{Name: assetName, BrowserDownloadUrl: base + "/assets/bin"},
+						{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/assets/sha"},
+					}}
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(&rel)
+				case "/assets/bin":
+					_, _ = w.Write(binary)
+				case "/assets/sha":
+					_, _ = w.Write(sums)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"--repo", "test/versioned", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--assert-version", "--no-path-check", "-v"}, &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tc.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.wantStderr) {
+				t.Errorf("stderr missing %q:\n%s", tc.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+// assertJSONExitCode checks the --json run result. go run always exits 1 for
+// a failing program, so the specific code is read from the JSON instead.
+func assertJSONExitCode(t *testing.T, stdout []byte, want int) {
+	t.Helper()
+	var result runResult
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		t.Fatalf("parse --json output: %v\nstdout:\n%s", err, stdout)
+	}
+	if result.ExitCode != want || result.ExitReason != exitCodeName(want) {
+		t.Fatalf("exit = %d (%s), want %d (%s)", result.ExitCode, result.ExitReason, want, exitCodeName(want))
+	}
+}
+
 func TestIntegrationSelfUpdate(t *testing.T) {
 	// Skip if network access not desired (this test hits mock server but could be extended)
 	if os.Getenv("SFETCH_SKIP_NETWORK_TESTS") != "" {
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxPromptAttempts bounds how many invalid answers promptAssetChoice accepts
+// before giving up, so a stuck or scripted terminal cannot loop forever.
+const maxPromptAttempts = 3
+
+// Interactive prompts read from promptInput and only run when
+// promptIsTerminal reports a TTY. Tests replace both.
+var (
+	promptInput      io.Reader = os.Stdin
+	promptIsTerminal           = func() bool { return isTerminal(os.Stdin) }
+)
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// promptAssetChoice lists the tied candidates on out and reads a 1-based
+// choice from in. It returns an error when input ends or too many answers
+// are invalid.
+func promptAssetChoice(candidates []Asset, in io.Reader, out io.Writer) (*Asset, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no assets to choose from")
+	}
+
+	_, _ = fmt.Fprintln(out, "Multiple assets match this platform:") //nolint:errcheck
+	for i, a := range candidates {
+		if a.Size > 0 {
+			_, _ = fmt.Fprintf(out, "  %d) %s (%s)\n", i+1, a.Name, formatSize(a.Size)) //nolint:errcheck
+		} else {
+			_, _ = fmt.Fprintf(out, "  %d) %s\n", i+1, a.Name) //nolint:errcheck
+		}
+	}
+
+	reader := bufio.NewReader(in)
+	for attempt := 0; attempt < maxPromptAttempts; attempt++ {
+		_, _ = fmt.Fprintf(out, "Select asset [1-%d]: ", len(candidates)) //nolint:errcheck
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer != "" {
+			n, convErr := strconv.Atoi(answer)
+			if convErr == nil && n >= 1 && n <= len(candidates) {
+				return &candidates[n-1], nil
+			}
+			_, _ = fmt.Fprintf(out, "invalid choice %q\n", answer) //nolint:errcheck
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("asset selection cancelled")
+			}
+			return nil, fmt.Errorf("read selection: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("asset selection failed after %d invalid choices", maxPromptAttempts)
+}
+
+// errConfirmationRequired reports a yes/no prompt that could not be asked:
+// there is no terminal and --assume-yes was not given.
+var errConfirmationRequired = errors.New("confirmation required")
+
+// confirmer asks yes/no questions before consequential actions such as a
+// self-update or a downgrade. With --assume-yes (-y, or --yes) every
+// question is answered yes without reading input, so automation never
+// blocks; otherwise it prompts on a terminal and fails elsewhere.
+type confirmer struct {
+	assumeYes bool
+	in        io.Reader
+	out       io.Writer
+}
+
+// confirm asks question on out and reports whether the answer from in was
+// yes. Anything but y/yes, including end of input, declines. Without a
+// terminal it returns errConfirmationRequired unless assumeYes is set.
+func (c confirmer) confirm(question string) (bool, error) {
+	if c.assumeYes {
+		return true, nil
+	}
+	if !promptIsTerminal() {
+		return false, errConfirmationRequired
+	}
+	_, _ = fmt.Fprintf(c.out, "%s [y/N]: ", question) //nolint:errcheck
+	line, err := bufio.NewReader(c.in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("read answer: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IndexFile is the name of the cache index inside the cache directory.
+const IndexFile = "index.json"
+
+const indexVersion = 1
+
+// Entry records a verified asset stored in the cache.
+type Entry struct {
+	Repo       string `json:"repo"`
+	Tag        string `json:"tag"`
+	Asset      string `json:"asset"`
+	Size       int64  `json:"size,omitempty"`
+	SHA256     string `json:"sha256"`
+	Path       string `json:"path"` // relative to the cache directory
+	Workflow   string `json:"workflow,omitempty"`
+	VerifiedAt string `json:"verifiedAt"`
+}
+
+// Index maps repo@tag/asset to the cached file that was verified for it.
+type Index struct {
+	Version int              `json:"version"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Key returns the index key for an asset of a release.
+func Key(repo, tag, asset string) string {
+	return repo + "@" + tag + "/" + asset
+}
+
+// Load reads the index from dir. A missing index yields an empty one.
+func Load(dir string) (*Index, error) {
+	idx := &Index{Version: indexVersion, Entries: map[string]Entry{}}
+	// #nosec G304 -- cache dir is CLI-controlled
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cache index: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("parse cache index: %w", err)
+	}
+	if idx.Entries == nil {
+		idx.Entries = map[string]Entry{}
+	}
+	return idx, nil
+}
+
+// Save writes the index to dir, replacing any previous index atomically.
+func (idx *Index) Save(dir string) error {
+	idx.Version = indexVersion
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal cache index: %w", err)
+	}
+	// #nosec G301 -- SDR-002: cache directory
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("mkdir cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, IndexFile+".*")
+	if err != nil {
+		return fmt.Errorf("write cache index: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write cache index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cache index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, IndexFile)); err != nil {
+		return fmt.Errorf("write cache index: %w", err)
+	}
+	return nil
+}
+
+// Put records e, replacing any entry for the same repo, tag and asset.
+func (idx *Index) Put(e Entry) {
+	idx.Entries[Key(e.Repo, e.Tag, e.Asset)] = e
+}
+
+// Get returns the entry for an asset of a release.
+func (idx *Index) Get(repo, tag, asset string) (Entry, bool) {
+	e, ok := idx.Entries[Key(repo, tag, asset)]
+	return e, ok
+}
+
+// FindSHA256 returns an entry for asset whose content hash is sha256Hex,
+// regardless of the release it was recorded for.
+func (idx *Index) FindSHA256(sha256Hex, asset string) (Entry, bool) {
+	want := strings.ToLower(sha256Hex)
+	for _, key := range idx.sortedKeys() {
+		e := idx.Entries[key]
+		if e.Asset == asset && e.SHA256 == want {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Release returns the entries recorded for repo at tag, sorted by asset name.
+func (idx *Index) Release(repo, tag string) []Entry {
+	var out []Entry
+	for _, key := range idx.sortedKeys() {
+		e := idx.Entries[key]
+		if e.Repo == repo && e.Tag == tag {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Repos returns the repos with cached assets, those with the most cached
+// releases first; ties are sorted by name.
+func (idx *Index) Repos() []string {
+	tags := map[string]map[string]bool{}
+	for _, e := range idx.Entries {
+		if tags[e.Repo] == nil {
+			tags[e.Repo] = map[string]bool{}
+		}
+		tags[e.Repo][e.Tag] = true
+	}
+	repos := make([]string, 0, len(tags))
+	for repo := range tags {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if ni, nj := len(tags[repos[i]]), len(tags[repos[j]]); ni != nj {
+			return ni > nj
+		}
+		return repos[i] < repos[j]
+	})
+	return repos
+}
+
+func (idx *Index) sortedKeys() []string {
+	keys := make([]string, 0, len(idx.Entries))
+	for k := range idx.Entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Resolve returns the absolute path of e's cached file after checking that
+// its content still hashes to e.SHA256.
+func Resolve(dir string, e Entry) (string, error) {
+	if e.Path == "" || filepath.IsAbs(e.Path) || strings.HasPrefix(filepath.Clean(e.Path), "..") {
+		return "", fmt.Errorf("cache entry for %s has invalid path %q", e.Asset, e.Path)
+	}
+	path := filepath.Join(dir, e.Path)
+	got, err := FileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	if got != strings.ToLower(e.SHA256) {
+		return "", fmt.Errorf("cached %s does not match recorded sha256 (expected %s, got %s)", e.Asset, e.SHA256, got)
+	}
+	return path, nil
+}
+
+// FileSHA256 returns the hex SHA-256 of the file at path.
+func FileSHA256(path string) (string, error) {
+	// #nosec G304 -- cache path is CLI-controlled
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open cached file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash cached file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
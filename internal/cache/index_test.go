@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	idx, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() on empty dir: %v", err)
+	}
+	if len(idx.Entries) != 0 {
+		t.Fatalf("expected empty index, got %d entries", len(idx.Entries))
+	}
+
+	idx.Put(Entry{Repo: "owner/tool", Tag: "v1.0.0", Asset: "tool_linux_amd64.tar.gz", SHA256: "aa", Path: "aa/tool_linux_amd64.tar.gz"})
+	idx.Put(Entry{Repo: "owner/tool", Tag: "v1.0.0", Asset: "tool_darwin_arm64.tar.gz", SHA256: "bb", Path: "bb/tool_darwin_arm64.tar.gz"})
+	idx.Put(Entry{Repo: "owner/tool", Tag: "v0.9.0", Asset: "tool_linux_amd64.tar.gz", SHA256: "cc", Path: "cc/tool_linux_amd64.tar.gz"})
+	if err := idx.Save(dir); err != nil {
+		t.Fatalf("Save(): %v", err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load(): %v", err)
+	}
+	if e, ok := loaded.Get("owner/tool", "v1.0.0", "tool_linux_amd64.tar.gz"); !ok || e.SHA256 != "aa" {
+		t.Fatalf("Get() = %+v, %v", e, ok)
+	}
+	if e, ok := loaded.FindSHA256("CC", "tool_linux_amd64.tar.gz"); !ok || e.Tag != "v0.9.0" {
+		t.Fatalf("FindSHA256() = %+v, %v", e, ok)
+	}
+	rel := loaded.Release("owner/tool", "v1.0.0")
+	if len(rel) != 2 || rel[0].Asset != "tool_darwin_arm64.tar.gz" {
+		t.Fatalf("Release() = %+v", rel)
+	}
+}
+
+func TestIndexRepos(t *testing.T) {
+	t.Parallel()
+
+	idx := &Index{Entries: map[string]Entry{}}
+	if repos := idx.Repos(); len(repos) != 0 {
+		t.Fatalf("Repos() on empty index = %v", repos)
+	}
+	idx.Put(Entry{Repo: "b/one", Tag: "v1", Asset: "x"})
+	idx.Put(Entry{Repo: "b/one", Tag: "v1", Asset: "y"})
+	idx.Put(Entry{Repo: "a/two", Tag: "v1", Asset: "x"})
+	idx.Put(Entry{Repo: "z/many", Tag: "v1", Asset: "x"})
+	idx.Put(Entry{Repo: "z/many", Tag: "v2", Asset: "x"})
+	got := strings.Join(idx.Repos(), " ")
+	if want := "z/many a/two b/one"; got != want {
+		t.Fatalf("Repos() = %q, want %q", got, want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := []byte("cached asset\n")
+	path := filepath.Join(dir, "x", "asset.bin")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := FileSHA256(path)
+	if err != nil {
+		t.Fatalf("FileSHA256(): %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		entry   Entry
+		wantErr string
+	}{
+		{name: "match", entry: Entry{Asset: "asset.bin", SHA256: actual, Path: "x/asset.bin"}},
+		{name: "tampered", entry: Entry{Asset: "asset.bin", SHA256: strings.Repeat("0", 64), Path: "x/asset.bin"}, wantErr: "does not match"},
+		{name: "missing", entry: Entry{Asset: "gone.bin", SHA256: actual, Path: "x/gone.bin"}, wantErr: "open cached file"},
+		{name: "escapes cache dir", entry: Entry{Asset: "asset.bin", SHA256: actual, Path: "../asset.bin"}, wantErr: "invalid path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Resolve(dir, tt.entry)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != path {
+				t.Fatalf("Resolve() = %s, want %s", got, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeysDir holds verification keys inside the cache directory: one key file
+// and one record per key source.
+const KeysDir = "keys"
+
+// KeyRecord describes a cached verification key.
+type KeyRecord struct {
+	Source     string `json:"source"` // URL the key was fetched from
+	File       string `json:"file"`   // key file name inside KeysDir
+	SHA256     string `json:"sha256"`
+	VerifiedAt string `json:"verifiedAt"`
+}
+
+// keyID names the cache files for source.
+func keyID(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:16])
+}
+
+func keyRecordPath(dir, source string) string {
+	return filepath.Join(dir, KeysDir, keyID(source)+".json")
+}
+
+// LookupKey returns the cached key file for source after checking that its
+// content still hashes to the recorded sha256 and, when wantSHA256 is set,
+// to wantSHA256. ok is false when there is no usable entry.
+func LookupKey(dir, source, wantSHA256 string) (path string, ok bool) {
+	// #nosec G304 -- cache dir is CLI-controlled
+	data, err := os.ReadFile(keyRecordPath(dir, source))
+	if err != nil {
+		return "", false
+	}
+	var rec KeyRecord
+	if err := json.Unmarshal(data, &rec); err != nil || rec.Source != source {
+		return "", false
+	}
+	if rec.File == "" || rec.File != filepath.Base(rec.File) {
+		return "", false
+	}
+	path = filepath.Join(dir, KeysDir, rec.File)
+	got, err := FileSHA256(path)
+	if err != nil || got != strings.ToLower(rec.SHA256) {
+		return "", false
+	}
+	if wantSHA256 != "" && got != strings.ToLower(wantSHA256) {
+		return "", false
+	}
+	return path, true
+}
+
+// PutKey copies the key file at path into the cache as the key for source,
+// replacing any earlier key for it.
+func PutKey(dir, source, path string, verifiedAt time.Time) error {
+	// #nosec G304 -- key path from this run's temp dir
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read key: %w", err)
+	}
+	keysDir := filepath.Join(dir, KeysDir)
+	// #nosec G301 -- SDR-002: cache directory
+	if err := os.MkdirAll(keysDir, 0o755); err != nil {
+		return fmt.Errorf("mkdir key cache: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	rec := KeyRecord{
+		Source:     source,
+		File:       keyID(source) + filepath.Ext(path),
+		SHA256:     hex.EncodeToString(sum[:]),
+		VerifiedAt: verifiedAt.UTC().Format(time.RFC3339),
+	}
+	// #nosec G306 -- public key material
+	if err := os.WriteFile(filepath.Join(keysDir, rec.File), data, 0o644); err != nil {
+		return fmt.Errorf("write cached key: %w", err)
+	}
+	recData, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal key record: %w", err)
+	}
+	// #nosec G306 -- cache metadata
+	if err := os.WriteFile(keyRecordPath(dir, source), append(recData, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write key record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyCache(t *testing.T) {
+	t.Parallel()
+
+	const source = "https://example.com/keys/minisign.pub"
+	keyData := []byte("untrusted comment: test key\nRWTestKey\n")
+	sum := sha256.Sum256(keyData)
+	keySHA := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name       string
+		tamper     bool
+		lookupFrom string
+		wantSHA256 string
+		wantHit    bool
+	}{
+		{name: "hit", lookupFrom: source, wantHit: true},
+		{name: "hit with matching digest", lookupFrom: source, wantSHA256: keySHA, wantHit: true},
+		{name: "digest mismatch", lookupFrom: source, wantSHA256: "00" + keySHA[2:]},
+		{name: "other source", lookupFrom: "https://example.com/keys/other.pub"},
+		{name: "tampered key file", lookupFrom: source, tamper: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			keyPath := filepath.Join(t.TempDir(), "minisign-key-1.pub")
+			if err := os.WriteFile(keyPath, keyData, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := PutKey(dir, source, keyPath, time.Now()); err != nil {
+				t.Fatalf("PutKey: %v", err)
+			}
+			if tc.tamper {
+				cached, ok := LookupKey(dir, source, "")
+				if !ok {
+					t.Fatal("LookupKey missed a fresh entry")
+				}
+				if err := os.WriteFile(cached, []byte("RWOther\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path, ok := LookupKey(dir, tc.lookupFrom, tc.wantSHA256)
+			if ok != tc.wantHit {
+				t.Fatalf("LookupKey hit = %v, want %v", ok, tc.wantHit)
+			}
+			if !ok {
+				return
+			}
+			if filepath.Ext(path) != ".pub" {
+				t.Errorf("cached key %s lost its extension", path)
+			}
+			if got, _ := os.ReadFile(path); string(got) != string(keyData) {
+				t.Errorf("cached key = %q", got)
+			}
+		})
+	}
+}
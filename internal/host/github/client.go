@@ -1,13 +1,13 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
 	"sync"
-	"time"
 )
 
 // TokenSource reports where a resolved token came from. Empty when no token
@@ -100,6 +100,71 @@ func SetResolver(r TokenResolver) {
 	resolver = r
 }
 
+var transport http.RoundTripper
+
+// SetTransport installs the RoundTripper used for every request. Pass nil to
+// restore http.DefaultTransport. Like SetResolver, call it before any HTTP
+// request.
+func SetTransport(rt http.RoundTripper) {
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	transport = rt
+}
+
+func currentTransport() http.RoundTripper {
+	resolverMu.RLock()
+	defer resolverMu.RUnlock()
+	return transport
+}
+
+// DefaultMaxRedirects is the per-request redirect limit used until
+// SetMaxRedirects is called.
+const DefaultMaxRedirects = 10
+
+var maxRedirects = DefaultMaxRedirects
+
+// SetMaxRedirects sets how many redirects one request may follow. A
+// negative n restores DefaultMaxRedirects.
+func SetMaxRedirects(n int) {
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	if n < 0 {
+		n = DefaultMaxRedirects
+	}
+	maxRedirects = n
+}
+
+func currentMaxRedirects() int {
+	resolverMu.RLock()
+	defer resolverMu.RUnlock()
+	return maxRedirects
+}
+
+// CheckRedirectHops is the redirect policy shared by sfetch's HTTP clients.
+// It fails when req revisits a URL already in via, so a loop reports itself
+// instead of running into the limit, and when following req would exceed
+// limit redirects. Query strings are left out of the messages because
+// signed download URLs carry credentials there.
+func CheckRedirectHops(req *http.Request, via []*http.Request, limit int) error {
+	next := req.URL.String()
+	for _, prev := range via {
+		if prev.URL.String() == next {
+			return fmt.Errorf("redirect loop: %s redirects back to %s", redirectDisplay(via[len(via)-1].URL), redirectDisplay(req.URL))
+		}
+	}
+	if len(via) > limit {
+		return fmt.Errorf("too many redirects: %s exceeded the limit of %d", redirectDisplay(via[0].URL), limit)
+	}
+	return nil
+}
+
+func redirectDisplay(u *url.URL) string {
+	c := *u
+	c.RawQuery = ""
+	c.Fragment = ""
+	return c.Redacted()
+}
+
 // currentResolver returns the active resolver under read lock.
 func currentResolver() TokenResolver {
 	resolverMu.RLock()
@@ -158,7 +223,13 @@ func ResolveToken() (string, TokenSource, error) {
 // (e.g., to pre-signed S3) rely on Go's stdlib Authorization-stripping
 // behavior (since 1.17) to avoid leaking credentials.
 func Get(url, userAgent string) (*http.Response, error) {
-	return doGet(url, userAgent, "")
+	return doGet(context.Background(), url, userAgent, "")
+}
+
+// GetContext is Get bound to ctx; cancelling ctx aborts the request and any
+// in-progress body read.
+func GetContext(ctx context.Context, url, userAgent string) (*http.Response, error) {
+	return doGet(ctx, url, userAgent, "")
 }
 
 // GetAsset fetches a release asset via the GitHub API asset endpoint
@@ -166,15 +237,21 @@ func Get(url, userAgent string) (*http.Response, error) {
 // Accept: application/octet-stream so the API returns a 302 to a signed
 // download URL rather than the JSON metadata.
 func GetAsset(url, userAgent string) (*http.Response, error) {
-	return doGet(url, userAgent, "application/octet-stream")
+	return doGet(context.Background(), url, userAgent, "application/octet-stream")
+}
+
+// GetAssetContext is GetAsset bound to ctx.
+func GetAssetContext(ctx context.Context, url, userAgent string) (*http.Response, error) {
+	return doGet(ctx, url, userAgent, "application/octet-stream")
 }
 
-func doGet(url, userAgent, accept string) (*http.Response, error) {
+func doGet(ctx context.Context, url, userAgent, accept string) (*http.Response, error) {
+	// Timeouts come from ctx and the installed transport (see SetTransport).
 	client := &http.Client{
-		Timeout:       30 * time.Second,
 		CheckRedirect: stripAuthOnUntrustedRedirect,
+		Transport:     currentTransport(),
 	}
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -200,8 +277,8 @@ func doGet(url, userAgent, accept string) (*http.Response, error) {
 // the same domain (or two ports on the same IP) would otherwise inherit
 // the credential. Defense-in-depth for the github→S3 hop.
 func stripAuthOnUntrustedRedirect(req *http.Request, via []*http.Request) error {
-	if len(via) >= 10 {
-		return http.ErrUseLastResponse
+	if err := CheckRedirectHops(req, via, currentMaxRedirects()); err != nil {
+		return err
 	}
 	if !shouldAttachAuth(req.URL.String()) {
 		req.Header.Del("Authorization")
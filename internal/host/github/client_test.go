@@ -1,6 +1,10 @@
 package github
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -212,3 +216,70 @@ func TestShouldAttachAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckRedirectHops(t *testing.T) {
+	mustReq := func(u string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatalf("NewRequest(%q): %v", u, err)
+		}
+		return req
+	}
+	tests := []struct {
+		name    string
+		next    string
+		via     []string
+		limit   int
+		wantErr string
+	}{
+		{"first hop", "https://b.example/x", []string{"https://a.example/x"}, 5, ""},
+		{"at the limit", "https://c.example/x", []string{"https://a.example/x", "https://b.example/x"}, 2, ""},
+		{"over the limit", "https://d.example/x", []string{"https://a.example/x", "https://b.example/x", "https://c.example/x"}, 2, "too many redirects"},
+		{"zero limit", "https://b.example/x", []string{"https://a.example/x"}, 0, "too many redirects"},
+		{"loop back to origin", "https://a.example/x", []string{"https://a.example/x", "https://b.example/x"}, 5, "redirect loop"},
+		{"query differs", "https://a.example/x?sig=2", []string{"https://a.example/x?sig=1"}, 5, ""},
+		{"loop message hides signed query", "https://a.example/x?sig=secret", []string{"https://a.example/x?sig=secret"}, 5, "redirect loop"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var via []*http.Request
+			for _, u := range tc.via {
+				via = append(via, mustReq(u))
+			}
+			err := CheckRedirectHops(mustReq(tc.next), via, tc.limit)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tc.wantErr)
+			}
+			if strings.Contains(err.Error(), "secret") {
+				t.Fatalf("error leaks the query string: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetRedirectLoop(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		default:
+			http.Redirect(w, r, "/a", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	resp, err := GetContext(context.Background(), ts.URL+"/a", UserAgent("test"))
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("expected a redirect loop error")
+	}
+	if !strings.Contains(err.Error(), "redirect loop") {
+		t.Fatalf("error = %v, want a redirect loop error", err)
+	}
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// SourceGhHosts is the token source for a token read from the gh CLI's
+// hosts.yml (--use-gh-auth).
+const SourceGhHosts TokenSource = "gh hosts.yml"
+
+// GhHostsResolver walks the default env chain, then falls back to the
+// oauth_token the gh CLI stored for github.com in its hosts.yml. gh 2.40+
+// keeps tokens in the system keyring by default; such a hosts.yml has no
+// token and the resolver proceeds unauthenticated.
+type GhHostsResolver struct {
+	// Path is the hosts.yml to read; empty means GhHostsPath().
+	Path string
+}
+
+func (r GhHostsResolver) Resolve() (string, TokenSource, error) {
+	if tok, source, err := (defaultResolver{}).Resolve(); tok != "" || err != nil {
+		return tok, source, err
+	}
+	p := r.Path
+	if p == "" {
+		p = GhHostsPath()
+	}
+	if p == "" {
+		return "", SourceNone, nil
+	}
+	// #nosec G304 -- the gh CLI's own config file, read at the user's request
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", SourceNone, nil
+	}
+	if err != nil {
+		return "", SourceGhHosts, fmt.Errorf("--use-gh-auth: read %s: %w", p, err)
+	}
+	tok := ghHostsToken(data, "github.com")
+	if tok == "" {
+		return "", SourceNone, nil
+	}
+	return tok, SourceGhHosts, nil
+}
+
+// GhHostsPath returns where the gh CLI keeps hosts.yml: $GH_CONFIG_DIR,
+// then $XDG_CONFIG_HOME/gh, then %AppData%\GitHub CLI on Windows, then
+// ~/.config/gh. It returns "" when no home directory is known.
+func GhHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI", "hosts.yml")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// ghHostsToken returns the oauth_token stored for host in a gh hosts.yml.
+// It reads only the YAML gh writes there: top-level host keys holding
+// indented scalar fields. Deeper blocks (the per-account "users:" map) are
+// skipped.
+//
+//	github.com:
+//	    user: octocat
+//	    oauth_token: gho_xxx
+//	    git_protocol: https
+func ghHostsToken(data []byte, host string) string {
+	inHost := false
+	fieldIndent := -1
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == 0 {
+			key, _, _ := strings.Cut(trimmed, ":")
+			inHost = yamlScalar(key) == host
+			fieldIndent = -1
+			continue
+		}
+		if !inHost {
+			continue
+		}
+		if fieldIndent < 0 {
+			fieldIndent = indent
+		}
+		if indent != fieldIndent {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if ok && strings.TrimSpace(key) == "oauth_token" {
+			return yamlScalar(value)
+		}
+	}
+	return ""
+}
+
+// yamlScalar unquotes a plain or quoted YAML scalar and drops a trailing
+// comment from a plain one.
+func yamlScalar(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGhHostsToken(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "token in file",
+			data: "github.com:\n    user: octocat\n    oauth_token: gho_abc\n    git_protocol: https\n",
+			want: "gho_abc",
+		},
+		{
+			name: "quoted token and comment",
+			data: "# written by gh\n\"github.com\":\n  oauth_token: 'gho_quoted'\n",
+			want: "gho_quoted",
+		},
+		{
+			name: "trailing comment",
+			data: "github.com:\n    oauth_token: gho_plain # keep\n",
+			want: "gho_plain",
+		},
+		{
+			name: "keyring storage leaves only nested users",
+			data: "github.com:\n    users:\n        octocat:\n            oauth_token: gho_nested\n    user: octocat\n    git_protocol: https\n",
+		},
+		{
+			name: "other host only",
+			data: "ghe.example.com:\n    oauth_token: ghe_abc\n",
+		},
+		{
+			name: "CRLF line endings",
+			data: "github.com:\r\n    oauth_token: gho_crlf\r\n",
+			want: "gho_crlf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ghHostsToken([]byte(tt.data), "github.com"); got != tt.want {
+				t.Fatalf("token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGhHostsResolver(t *testing.T) {
+	dir := t.TempDir()
+	hosts := filepath.Join(dir, "hosts.yml")
+	if err := os.WriteFile(hosts, []byte("github.com:\n    oauth_token: gho_file\n"), 0o600); err != nil {
+		t.Fatalf("write hosts.yml: %v", err)
+	}
+
+	t.Run("env chain wins", func(t *testing.T) {
+		clearChain(t)
+		t.Setenv("GH_TOKEN", "env-tok")
+		tok, src, err := GhHostsResolver{Path: hosts}.Resolve()
+		if err != nil || tok != "env-tok" || src != SourceGhToken {
+			t.Fatalf("got (%q, %q, %v)", tok, src, err)
+		}
+	})
+	t.Run("falls back to hosts.yml", func(t *testing.T) {
+		clearChain(t)
+		tok, src, err := GhHostsResolver{Path: hosts}.Resolve()
+		if err != nil || tok != "gho_file" || src != SourceGhHosts {
+			t.Fatalf("got (%q, %q, %v)", tok, src, err)
+		}
+	})
+	t.Run("GH_CONFIG_DIR locates hosts.yml", func(t *testing.T) {
+		clearChain(t)
+		t.Setenv("GH_CONFIG_DIR", dir)
+		tok, src, err := GhHostsResolver{}.Resolve()
+		if err != nil || tok != "gho_file" || src != SourceGhHosts {
+			t.Fatalf("got (%q, %q, %v)", tok, src, err)
+		}
+	})
+	t.Run("missing hosts.yml is unauthenticated", func(t *testing.T) {
+		clearChain(t)
+		tok, src, err := GhHostsResolver{Path: filepath.Join(dir, "absent.yml")}.Resolve()
+		if err != nil || tok != "" || src != SourceNone {
+			t.Fatalf("got (%q, %q, %v)", tok, src, err)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package hostenv
+
+import "os"
+
+// DetectARMVariant reports the 32-bit ARM version ("5", "6", "7") of the
+// host, or "" when unknown. GOARM takes precedence over /proc/cpuinfo.
+func DetectARMVariant() string {
+	if v := parseGOARM(os.Getenv("GOARM")); v != "" {
+		return v
+	}
+	data, err := os.ReadFile("/proc/cpuinfo") // #nosec G304 -- fixed procfs path
+	if err != nil {
+		return ""
+	}
+	return parseCPUInfoARMVariant(string(data))
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package hostenv
+
+import "os"
+
+func DetectARMVariant() string {
+	return parseGOARM(os.Getenv("GOARM"))
+}
@@ -0,0 +1,67 @@
+package hostenv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseGOARM normalizes a GOARM value ("7", "6,softfloat") to its version.
+func parseGOARM(v string) string {
+	v, _, _ = strings.Cut(strings.TrimSpace(v), ",")
+	switch v {
+	case "5", "6", "7":
+		return v
+	default:
+		return ""
+	}
+}
+
+// parseCPUInfoARMVariant maps /proc/cpuinfo on 32-bit ARM to a GOARM
+// version. GOARM=7 needs VFPv3 and GOARM=6 needs VFP, mirroring the Go
+// toolchain's own requirements.
+func parseCPUInfoARMVariant(content string) string {
+	arch := 0
+	var features []string
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "CPU architecture":
+			// Older kernels print e.g. "7" or "AArch64"; take leading digits.
+			v := strings.TrimSpace(value)
+			end := 0
+			for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+				end++
+			}
+			if n, err := strconv.Atoi(v[:end]); err == nil && arch == 0 {
+				arch = n
+			}
+		case "Features":
+			if features == nil {
+				features = strings.Fields(value)
+			}
+		}
+	}
+
+	has := func(want string) bool {
+		for _, f := range features {
+			if f == want {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case arch == 0:
+		return ""
+	case arch >= 7 && (has("vfpv3") || has("vfpv4") || has("vfpd32")):
+		return "7"
+	case arch >= 6 && has("vfp"):
+		return "6"
+	default:
+		return "5"
+	}
+}
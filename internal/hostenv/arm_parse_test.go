@@ -0,0 +1,58 @@
+package hostenv
+
+import "testing"
+
+func TestParseCPUInfoARMVariant(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "raspberry pi zero (armv6)",
+			content: `processor	: 0
+model name	: ARMv6-compatible processor rev 7 (v6l)
+Features	: half thumb fastmult vfp edsp java tls
+CPU architecture: 7
+`,
+			want: "6",
+		},
+		{
+			name: "raspberry pi 3 32-bit (armv7)",
+			content: `processor	: 0
+model name	: ARMv7 Processor rev 4 (v7l)
+Features	: half thumb fastmult vfp edsp neon vfpv3 tls vfpv4 idiva idivt vfpd32 lpae evtstrm crc32
+CPU architecture: 7
+`,
+			want: "7",
+		},
+		{
+			name: "armv5 without vfp",
+			content: `Processor	: Feroceon 88FR131 rev 1 (v5l)
+Features	: swp half thumb fastmult edsp
+CPU architecture: 5TE
+`,
+			want: "5",
+		},
+		{
+			name:    "not arm",
+			content: "processor\t: 0\nvendor_id\t: GenuineIntel\nflags\t\t: fpu vme\n",
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCPUInfoARMVariant(tt.content); got != tt.want {
+				t.Errorf("parseCPUInfoARMVariant() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGOARM(t *testing.T) {
+	for in, want := range map[string]string{"7": "7", "6,softfloat": "6", " 5 ": "5", "8": "", "": ""} {
+		if got := parseGOARM(in); got != want {
+			t.Errorf("parseGOARM(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
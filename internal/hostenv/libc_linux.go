@@ -0,0 +1,26 @@
+//go:build linux
+
+package hostenv
+
+import (
+	"os/exec"
+	"path/filepath"
+)
+
+// DetectLibc reports the host C library (LibcMusl or LibcGNU), or "" when it
+// cannot be determined.
+func DetectLibc() string {
+	// Best effort only: the musl dynamic loader is the cheapest signal.
+	for _, pattern := range []string{"/lib/ld-musl-*", "/usr/lib/ld-musl-*"} {
+		if matches, err := filepath.Glob(pattern); err == nil && len(matches) > 0 {
+			return LibcMusl
+		}
+	}
+
+	if _, err := exec.LookPath("ldd"); err != nil {
+		return ""
+	}
+	// Ignore the exit status: musl's ldd exits 1 after printing its banner.
+	out, _ := exec.Command("ldd", "--version").CombinedOutput() // #nosec G204 -- fixed command
+	return parseLddVersion(string(out))
+}
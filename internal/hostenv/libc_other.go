@@ -0,0 +1,7 @@
+//go:build !linux
+
+package hostenv
+
+func DetectLibc() string {
+	return ""
+}
@@ -0,0 +1,23 @@
+package hostenv
+
+import "strings"
+
+// C library flavours reported by DetectLibc.
+const (
+	LibcMusl = "musl"
+	LibcGNU  = "gnu"
+)
+
+// parseLddVersion classifies `ldd --version` output. musl's ldd prints its
+// banner to stderr and exits non-zero, so callers should pass combined output.
+func parseLddVersion(out string) string {
+	lower := strings.ToLower(out)
+	switch {
+	case strings.Contains(lower, "musl"):
+		return LibcMusl
+	case strings.Contains(lower, "glibc"), strings.Contains(lower, "gnu libc"), strings.Contains(lower, "gnu c library"):
+		return LibcGNU
+	default:
+		return ""
+	}
+}
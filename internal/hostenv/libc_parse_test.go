@@ -0,0 +1,24 @@
+package hostenv
+
+import "testing"
+
+func TestParseLddVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want string
+	}{
+		{"glibc", "ldd (Ubuntu GLIBC 2.35-0ubuntu3.8) 2.35\nCopyright (C) 2022 Free Software Foundation, Inc.\n", LibcGNU},
+		{"gnu libc", "ldd (GNU libc) 2.39\n", LibcGNU},
+		{"musl", "musl libc (x86_64)\nVersion 1.2.4\nDynamic Program Loader\n", LibcMusl},
+		{"empty", "", ""},
+		{"unknown", "ldd: unrecognized option\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLddVersion(tt.out); got != tt.want {
+				t.Errorf("parseLddVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
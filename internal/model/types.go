@@ -4,6 +4,21 @@ package model
 type Release struct {
 	TagName string  `json:"tag_name"`
 	Assets  []Asset `json:"assets"`
+	// Name is the release title and Body its Markdown notes, as typed on
+	// GitHub (--show-release-notes, self-update).
+	Name string `json:"name,omitempty"`
+	Body string `json:"body,omitempty"`
+	// AssetsURL is the paginated asset list endpoint, followed when Assets
+	// looks truncated.
+	AssetsURL string `json:"assets_url,omitempty"`
+	// TarballURL and ZipballURL are GitHub's auto-generated source
+	// archives for the tag (--source-archive).
+	TarballURL string `json:"tarball_url,omitempty"`
+	ZipballURL string `json:"zipball_url,omitempty"`
+	// Prerelease and Draft mirror GitHub's release flags; only the
+	// /releases list (--include-prerelease) returns drafts.
+	Prerelease bool `json:"prerelease,omitempty"`
+	Draft      bool `json:"draft,omitempty"`
 }
 
 // Asset is the subset of the GitHub release asset payload that sfetch uses.
@@ -23,6 +38,13 @@ type Asset struct {
 	ID                 int64  `json:"id"`
 	BrowserDownloadUrl string `json:"browser_download_url"`
 	Size               int64  `json:"size"`
+	// Digest is GitHub's content digest ("sha256:<hex>"), absent for
+	// assets uploaded before GitHub started computing them.
+	Digest string `json:"digest,omitempty"`
+	// CreatedAt and UpdatedAt are GitHub's RFC 3339 upload timestamps,
+	// kept as strings so a mirror that omits or blanks them still decodes.
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
 }
 
 // AssetType describes how an asset should be handled after download.
@@ -44,6 +66,15 @@ const (
 	ArchiveFormatTarBz2 ArchiveFormat = "tar.bz2"
 	ArchiveFormatTar    ArchiveFormat = "tar"
 	ArchiveFormatZip    ArchiveFormat = "zip"
+	// ArchiveFormatSevenZip archives are extracted with a 7-Zip sidecar
+	// (7z, 7za, 7zz or 7zr on PATH).
+	ArchiveFormatSevenZip ArchiveFormat = "7z"
+
+	// Single-file formats: one compressed binary (tool_linux_amd64.gz), not
+	// an archive. Assets in these formats classify as raw.
+	ArchiveFormatGz  ArchiveFormat = "gz"
+	ArchiveFormatXz  ArchiveFormat = "xz"
+	ArchiveFormatBz2 ArchiveFormat = "bz2"
 )
 
 // SignatureFormats maps file extensions to verification methods.
@@ -58,6 +89,7 @@ type SignatureFormats struct {
 // Schema: schemas/repo-config.schema.json
 type RepoConfig struct {
 	BinaryName            string           `json:"binaryName"`
+	Binaries              []string         `json:"binaries,omitempty"` // installed together from one archive into --dest-dir
 	HashAlgo              string           `json:"hashAlgo"`
 	ArchiveType           string           `json:"archiveType"` // deprecated; derived from AssetType/ArchiveFormat
 	ArchiveExtensions     []string         `json:"archiveExtensions"`
@@ -68,5 +100,8 @@ type RepoConfig struct {
 	ChecksumSigCandidates []string         `json:"checksumSigCandidates"` // Workflow A: sigs over checksum files
 	SignatureCandidates   []string         `json:"signatureCandidates"`   // Workflow B: per-asset sigs
 	SignatureFormats      SignatureFormats `json:"signatureFormats"`
-	PreferChecksumSig     *bool            `json:"preferChecksumSig,omitempty"` // prefer Workflow A over B; nil = use default (true)
+	PreferChecksumSig     *bool            `json:"preferChecksumSig,omitempty"`    // prefer Workflow A over B; nil = use default (true)
+	PinnedMinisignKey     string           `json:"pinnedMinisignKey,omitempty"`    // RW... key the resolved minisign key must equal
+	PinnedPGPFingerprint  string           `json:"pinnedPGPFingerprint,omitempty"` // fingerprint the PGP signing key must match
+	ChecksumBase64        bool             `json:"checksumBase64,omitempty"`       // checksum files may carry base64 digests
 }
@@ -0,0 +1,197 @@
+// Package receipt records what sfetch installed where, so an install can be
+// removed (--uninstall) or rolled back to the version before it (--rollback).
+package receipt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/3leaps/sfetch/internal/cache"
+)
+
+// Dir is the name of the receipts directory inside the cache directory.
+const Dir = "receipts"
+
+const receiptVersion = 1
+
+// MaxInstalls bounds the install history kept per repo; the oldest entries
+// are dropped first.
+const MaxInstalls = 10
+
+// File is one installed file.
+type File struct {
+	Path   string `json:"path"` // absolute
+	SHA256 string `json:"sha256"`
+	// Member is the file's path inside the release archive; empty for raw
+	// assets.
+	Member string `json:"member,omitempty"`
+}
+
+// Install records one successful install of a release asset.
+type Install struct {
+	Tag         string `json:"tag"`
+	Asset       string `json:"asset"`
+	AssetSHA256 string `json:"assetSha256"`
+	SelfUpdate  bool   `json:"selfUpdate,omitempty"`
+	// ExtractAll is the directory an --extract-all install put the whole
+	// archive tree under (absolute); empty for binary installs.
+	ExtractAll  string `json:"extractAll,omitempty"`
+	InstalledAt string `json:"installedAt"`
+	Files       []File `json:"files"`
+}
+
+// Receipt is the install history of one repo, oldest first.
+type Receipt struct {
+	Version  int       `json:"version"`
+	Repo     string    `json:"repo"`
+	Installs []Install `json:"installs"`
+}
+
+// path returns the receipt file of repo inside dir: owner/repo is stored as
+// owner__repo.json.
+func path(dir, repo string) string {
+	return filepath.Join(dir, strings.ReplaceAll(repo, "/", "__")+".json")
+}
+
+// Load reads the receipt of repo from dir. A repo without one yields an
+// empty receipt.
+func Load(dir, repo string) (*Receipt, error) {
+	r := &Receipt{Version: receiptVersion, Repo: repo}
+	// #nosec G304 -- receipts live in the CLI-controlled cache dir
+	data, err := os.ReadFile(path(dir, repo))
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read install receipt: %w", err)
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("parse install receipt for %s: %w", repo, err)
+	}
+	return r, nil
+}
+
+// Save writes r to dir, replacing any previous receipt atomically.
+func (r *Receipt) Save(dir string) error {
+	r.Version = receiptVersion
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal install receipt: %w", err)
+	}
+	// #nosec G301 -- SDR-002: cache directory
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("mkdir receipts: %w", err)
+	}
+	dst := path(dir, r.Repo)
+	tmp, err := os.CreateTemp(dir, filepath.Base(dst)+".*")
+	if err != nil {
+		return fmt.Errorf("write install receipt: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write install receipt: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write install receipt: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("write install receipt: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the receipt of repo from dir. A missing receipt is not an
+// error.
+func Delete(dir, repo string) error {
+	if err := os.Remove(path(dir, repo)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove install receipt: %w", err)
+	}
+	return nil
+}
+
+// Add appends in to the history. Reinstalling the asset the newest entry
+// already records replaces that entry instead of growing the history.
+func (r *Receipt) Add(in Install) {
+	if n := len(r.Installs); n > 0 {
+		last := r.Installs[n-1]
+		if last.Tag == in.Tag && last.AssetSHA256 == in.AssetSHA256 {
+			r.Installs = r.Installs[:n-1]
+		}
+	}
+	r.Installs = append(r.Installs, in)
+	if extra := len(r.Installs) - MaxInstalls; extra > 0 {
+		r.Installs = append([]Install(nil), r.Installs[extra:]...)
+	}
+}
+
+// Latest returns the newest install.
+func (r *Receipt) Latest() (Install, bool) {
+	if len(r.Installs) == 0 {
+		return Install{}, false
+	}
+	return r.Installs[len(r.Installs)-1], true
+}
+
+// Previous returns the newest install of a tag other than the latest one's:
+// the version a rollback goes back to.
+func (r *Receipt) Previous() (Install, bool) {
+	latest, ok := r.Latest()
+	if !ok {
+		return Install{}, false
+	}
+	for i := len(r.Installs) - 2; i >= 0; i-- {
+		if r.Installs[i].Tag != latest.Tag {
+			return r.Installs[i], true
+		}
+	}
+	return Install{}, false
+}
+
+// ModifiedError reports installed files whose content no longer matches the
+// receipt.
+type ModifiedError struct {
+	Paths []string
+}
+
+func (e *ModifiedError) Error() string {
+	return fmt.Sprintf("changed since sfetch installed them: %s", strings.Join(e.Paths, ", "))
+}
+
+// RemoveFiles deletes the files of in. Every file is hash-checked first; if
+// any was changed since install nothing is removed and a *ModifiedError is
+// returned, unless force is set. Files already gone are skipped and returned
+// in missing.
+func RemoveFiles(in Install, force bool) (removed, missing []string, err error) {
+	var modified []string
+	for _, f := range in.Files {
+		got, err := cache.FileSHA256(f.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if got != strings.ToLower(f.SHA256) {
+			modified = append(modified, f.Path)
+		}
+	}
+	if len(modified) > 0 && !force {
+		return nil, nil, &ModifiedError{Paths: modified}
+	}
+	for _, f := range in.Files {
+		if err := os.Remove(f.Path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				missing = append(missing, f.Path)
+				continue
+			}
+			return removed, missing, fmt.Errorf("remove %s: %w", f.Path, err)
+		}
+		removed = append(removed, f.Path)
+	}
+	return removed, missing, nil
+}
@@ -0,0 +1,138 @@
+package receipt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestReceiptRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	r, err := Load(dir, "o/tool")
+	if err != nil {
+		t.Fatalf("Load missing: %v", err)
+	}
+	if _, ok := r.Latest(); ok {
+		t.Fatal("empty receipt has a latest install")
+	}
+
+	r.Add(Install{Tag: "v1.0.0", Asset: "tool.tar.gz", AssetSHA256: "aa", Files: []File{{Path: "/bin/tool", SHA256: "11", Member: "tool"}}})
+	r.Add(Install{Tag: "v2.0.0", Asset: "tool.tar.gz", AssetSHA256: "bb", SelfUpdate: true, Files: []File{{Path: "/bin/tool", SHA256: "22", Member: "tool"}}})
+	// Reinstalling the same asset replaces the newest entry.
+	r.Add(Install{Tag: "v2.0.0", Asset: "tool.tar.gz", AssetSHA256: "bb", Files: []File{{Path: "/bin/tool", SHA256: "22", Member: "tool"}}})
+	if err := r.Save(dir); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "o__tool.json")); err != nil {
+		t.Fatalf("receipt file: %v", err)
+	}
+
+	got, err := Load(dir, "o/tool")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got.Installs) != 2 {
+		t.Fatalf("installs = %d, want 2: %+v", len(got.Installs), got.Installs)
+	}
+	if latest, _ := got.Latest(); latest.Tag != "v2.0.0" || latest.Files[0].SHA256 != "22" {
+		t.Fatalf("latest = %+v", latest)
+	}
+	if prev, ok := got.Previous(); !ok || prev.Tag != "v1.0.0" {
+		t.Fatalf("previous = %+v, %v", prev, ok)
+	}
+
+	if err := Delete(dir, "o/tool"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := Delete(dir, "o/tool"); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+}
+
+func TestReceiptHistory(t *testing.T) {
+	r := &Receipt{Repo: "o/tool"}
+	r.Add(Install{Tag: "v1.0.0", AssetSHA256: "a"})
+	if _, ok := r.Previous(); ok {
+		t.Fatal("one install has no previous version")
+	}
+	r.Add(Install{Tag: "v2.0.0", AssetSHA256: "b"})
+	// A rollback to v1.0.0 is recorded; the next rollback goes back to v2.0.0.
+	r.Add(Install{Tag: "v1.0.0", AssetSHA256: "a"})
+	if prev, _ := r.Previous(); prev.Tag != "v2.0.0" {
+		t.Fatalf("previous after rollback = %s, want v2.0.0", prev.Tag)
+	}
+
+	for i := 0; i < MaxInstalls+5; i++ {
+		r.Add(Install{Tag: fmt.Sprintf("v3.0.%d", i)})
+	}
+	if len(r.Installs) != MaxInstalls {
+		t.Fatalf("installs = %d, want %d", len(r.Installs), MaxInstalls)
+	}
+	if latest, _ := r.Latest(); latest.Tag != fmt.Sprintf("v3.0.%d", MaxInstalls+4) {
+		t.Fatalf("latest = %s", latest.Tag)
+	}
+}
+
+func TestRemoveFiles(t *testing.T) {
+	write := func(t *testing.T, path, content string) File {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return File{Path: path, SHA256: sha256Hex([]byte(content))}
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		dir := t.TempDir()
+		a := write(t, filepath.Join(dir, "a"), "a")
+		b := write(t, filepath.Join(dir, "b"), "b")
+		gone := File{Path: filepath.Join(dir, "gone"), SHA256: sha256Hex([]byte("gone"))}
+		removed, missing, err := RemoveFiles(Install{Files: []File{a, b, gone}}, false)
+		if err != nil {
+			t.Fatalf("RemoveFiles: %v", err)
+		}
+		if len(removed) != 2 || len(missing) != 1 || missing[0] != gone.Path {
+			t.Fatalf("removed = %v, missing = %v", removed, missing)
+		}
+		for _, f := range []File{a, b} {
+			if _, err := os.Stat(f.Path); !os.IsNotExist(err) {
+				t.Fatalf("%s not removed: %v", f.Path, err)
+			}
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		dir := t.TempDir()
+		a := write(t, filepath.Join(dir, "a"), "a")
+		b := write(t, filepath.Join(dir, "b"), "b")
+		if err := os.WriteFile(b.Path, []byte("edited"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := RemoveFiles(Install{Files: []File{a, b}}, false)
+		var modified *ModifiedError
+		if !errors.As(err, &modified) || len(modified.Paths) != 1 || modified.Paths[0] != b.Path {
+			t.Fatalf("RemoveFiles error = %v, want ModifiedError for %s", err, b.Path)
+		}
+		// Nothing is removed when any file was changed.
+		for _, f := range []File{a, b} {
+			if _, err := os.Stat(f.Path); err != nil {
+				t.Fatalf("%s removed despite the refusal: %v", f.Path, err)
+			}
+		}
+
+		removed, _, err := RemoveFiles(Install{Files: []File{a, b}}, true)
+		if err != nil || len(removed) != 2 {
+			t.Fatalf("forced RemoveFiles = %v, %v", removed, err)
+		}
+	})
+}
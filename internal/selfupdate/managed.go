@@ -0,0 +1,53 @@
+package selfupdate
+
+import "strings"
+
+// Install describes the binary a self-update would replace.
+type Install struct {
+	// Path is the resolved path of the binary.
+	Path string
+	// OwnerUID is the file owner's uid, or -1 when unknown (Windows).
+	OwnerUID int
+}
+
+// managedPrefixes maps install locations owned by a package manager to the
+// command that updates sfetch there. Paths are slash-separated and matched
+// as directory prefixes.
+var managedPrefixes = []struct {
+	prefix string
+	update string
+}{
+	{"/opt/homebrew/Cellar/", "brew upgrade sfetch"},
+	{"/usr/local/Cellar/", "brew upgrade sfetch"},
+	{"/home/linuxbrew/.linuxbrew/Cellar/", "brew upgrade sfetch"},
+	{"/nix/store/", "your Nix configuration"},
+	{"/snap/", "snap refresh sfetch"},
+	{"/usr/bin/", "your system package manager"},
+	{"/usr/sbin/", "your system package manager"},
+	{"/bin/", "your system package manager"},
+	{"/sbin/", "your system package manager"},
+	{"/usr/lib/", "your system package manager"},
+	{"/usr/libexec/", "your system package manager"},
+}
+
+// DetectPackageManaged reports whether in looks installed by a package
+// manager: under a system or package-manager prefix, or owned by root.
+// Replacing such a binary in place fights the package manager, which would
+// later overwrite it or report the file as modified. reason explains why and
+// names how to update instead.
+func DetectPackageManaged(in Install) (managed bool, reason string) {
+	// Normalize separators by hand: filepath.ToSlash is a no-op off Windows.
+	path := strings.ReplaceAll(in.Path, `\`, "/")
+	if strings.Contains(strings.ToLower(path), "/scoop/apps/") {
+		return true, in.Path + " is managed by Scoop; update with: scoop update sfetch"
+	}
+	for _, m := range managedPrefixes {
+		if strings.HasPrefix(path, m.prefix) {
+			return true, in.Path + " is under " + strings.TrimSuffix(m.prefix, "/") + "; update with " + m.update
+		}
+	}
+	if in.OwnerUID == 0 {
+		return true, in.Path + " is owned by root, which suggests a package manager installed it; update with your system package manager"
+	}
+	return false, ""
+}
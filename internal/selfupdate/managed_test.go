@@ -0,0 +1,42 @@
+package selfupdate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectPackageManaged(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		in          Install
+		wantManaged bool
+		wantReason  string
+	}{
+		{name: "distro package", in: Install{Path: "/usr/bin/sfetch", OwnerUID: 0}, wantManaged: true, wantReason: "under /usr/bin"},
+		{name: "system path owned by user", in: Install{Path: "/usr/bin/sfetch", OwnerUID: 1000}, wantManaged: true, wantReason: "system package manager"},
+		{name: "homebrew cellar", in: Install{Path: "/opt/homebrew/Cellar/sfetch/1.0.0/bin/sfetch", OwnerUID: 501}, wantManaged: true, wantReason: "brew upgrade sfetch"},
+		{name: "nix store", in: Install{Path: "/nix/store/abc-sfetch-1.0.0/bin/sfetch", OwnerUID: 0}, wantManaged: true, wantReason: "Nix"},
+		{name: "snap", in: Install{Path: "/snap/sfetch/12/bin/sfetch", OwnerUID: 0}, wantManaged: true, wantReason: "snap refresh"},
+		{name: "scoop", in: Install{Path: `C:\Users\me\scoop\apps\sfetch\current\sfetch.exe`, OwnerUID: -1}, wantManaged: true, wantReason: "scoop update"},
+		{name: "root-owned elsewhere", in: Install{Path: "/usr/local/bin/sfetch", OwnerUID: 0}, wantManaged: true, wantReason: "owned by root"},
+		{name: "user install", in: Install{Path: "/home/me/.local/bin/sfetch", OwnerUID: 1000}},
+		{name: "usr/local by user", in: Install{Path: "/usr/local/bin/sfetch", OwnerUID: 501}},
+		{name: "unknown owner", in: Install{Path: `C:\Tools\sfetch.exe`, OwnerUID: -1}},
+		{name: "prefix is a directory match", in: Install{Path: "/usr/binaries/sfetch", OwnerUID: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			managed, reason := DetectPackageManaged(tt.in)
+			if managed != tt.wantManaged {
+				t.Fatalf("managed = %v, want %v (reason %q)", managed, tt.wantManaged, reason)
+			}
+			if !strings.Contains(reason, tt.wantReason) {
+				t.Fatalf("reason = %q, want containing %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
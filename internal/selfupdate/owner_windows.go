@@ -0,0 +1,9 @@
+//go:build windows
+
+package selfupdate
+
+// FileOwner returns -1: Windows has no uid, and package-managed installs are
+// recognized by path instead.
+func FileOwner(path string) int {
+	return -1
+}
@@ -0,0 +1,59 @@
+package selfupdate
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// EmbeddedVersion returns the version the Go binary at path was built as:
+// the value "-X main.version=..." sets in its recorded -ldflags (how sfetch
+// releases are built), otherwise the main module version "go install"
+// records. It returns "" when the binary records neither.
+func EmbeddedVersion(path string) (string, error) {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read build info from %s: %w", path, err)
+	}
+	return embeddedVersion(bi), nil
+}
+
+func embeddedVersion(bi *debug.BuildInfo) string {
+	for _, s := range bi.Settings {
+		if s.Key != "-ldflags" {
+			continue
+		}
+		if v := ldflagsVar(s.Value, "main.version"); v != "" {
+			return v
+		}
+	}
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	return ""
+}
+
+// ldflagsVar returns the value an -ldflags string sets name to with -X. As
+// with the linker, the last assignment wins.
+func ldflagsVar(ldflags, name string) string {
+	var value string
+	fields := strings.Fields(ldflags)
+	for i, f := range fields {
+		var assign string
+		switch {
+		case (f == "-X" || f == "--X") && i+1 < len(fields):
+			assign = fields[i+1]
+		case strings.HasPrefix(f, "-X="):
+			assign = strings.TrimPrefix(f, "-X=")
+		case strings.HasPrefix(f, "--X="):
+			assign = strings.TrimPrefix(f, "--X=")
+		default:
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.Trim(assign, `'"`), name+"="); ok {
+			value = v
+		}
+	}
+	return value
+}
@@ -0,0 +1,72 @@
+package selfupdate
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+)
+
+func TestEmbeddedVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		bi   debug.BuildInfo
+		want string
+	}{
+		{
+			name: "release ldflags",
+			bi: debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{
+				{Key: "-ldflags", Value: "-s -w -X main.version=0.4.7 -X main.buildTime=2025-12-09T14:30:00Z"},
+			}},
+			want: "0.4.7",
+		},
+		{
+			name: "-X= form and quotes",
+			bi: debug.BuildInfo{Settings: []debug.BuildSetting{
+				{Key: "-ldflags", Value: `-X='main.version=v1.2.3'`},
+			}},
+			want: "v1.2.3",
+		},
+		{
+			name: "last assignment wins",
+			bi: debug.BuildInfo{Settings: []debug.BuildSetting{
+				{Key: "-ldflags", Value: "-X main.version=1.0.0 -X main.version=1.0.1"},
+			}},
+			want: "1.0.1",
+		},
+		{
+			name: "other package variable ignored",
+			bi: debug.BuildInfo{Main: debug.Module{Version: "v2.0.0"}, Settings: []debug.BuildSetting{
+				{Key: "-ldflags", Value: "-X github.com/o/tool/main.version=9.9.9 -X main.versionSuffix=rc"},
+			}},
+			want: "v2.0.0",
+		},
+		{
+			name: "go install module version",
+			bi:   debug.BuildInfo{Main: debug.Module{Version: "v0.4.7"}},
+			want: "v0.4.7",
+		},
+		{
+			name: "devel build",
+			bi:   debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := embeddedVersion(&tt.bi); got != tt.want {
+				t.Errorf("embeddedVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedVersionNotGoBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho 1.0.0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EmbeddedVersion(path); err == nil {
+		t.Fatal("EmbeddedVersion on a shell script: want error")
+	}
+}
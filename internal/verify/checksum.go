@@ -2,19 +2,49 @@ package verify
 
 import (
 	"crypto/ed25519"
+	"crypto/md5"  // #nosec G501 -- verifies published MD5SUMS; scored as weak
+	"crypto/sha1" // #nosec G505 -- verifies published SHA1SUMS; scored as weak
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"path/filepath"
 	"strings"
+
+	"golang.org/x/crypto/blake2b"
 )
 
+// ErrChecksumNotListed is wrapped by the error ExtractChecksumWithOptions
+// returns when the file has no entry for the asset.
+var ErrChecksumNotListed = errors.New("not found")
+
+// ChecksumOptions relaxes what ExtractChecksumWithOptions accepts.
+type ChecksumOptions struct {
+	// AllowBase64 also accepts digests written in base64 (standard or URL
+	// alphabet, padded or not, optionally with an SRI prefix such as
+	// "sha256-"). Off by default: a base64 digest is only recognized by its
+	// length, so opting in is per repo or per run.
+	AllowBase64 bool
+}
+
 func ExtractChecksum(data []byte, algo, assetName string) (string, error) {
+	return ExtractChecksumWithOptions(data, algo, assetName, ChecksumOptions{})
+}
+
+// ExtractChecksumWithOptions finds assetName's digest in a checksum file and
+// returns it as lowercase hex, whatever encoding the file used.
+func ExtractChecksumWithOptions(data []byte, algo, assetName string, opts ChecksumOptions) (string, error) {
 	text := strings.TrimSpace(string(data))
 	if text == "" {
 		return "", fmt.Errorf("checksum file is empty")
 	}
 	digestLen := expectedDigestLength(algo)
-	if isHexDigest(text, digestLen) {
-		return strings.ToLower(text), nil
+	if digest, ok := parseDigest(text, algo, digestLen, opts); ok {
+		return digest, nil
 	}
 
 	lines := strings.Split(text, "\n")
@@ -23,21 +53,99 @@ func ExtractChecksum(data []byte, algo, assetName string) (string, error) {
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		if lineAlgo, name, digest, ok := parseBSDChecksumLine(line); ok {
+			if !strings.EqualFold(lineAlgo, algo) {
+				continue
+			}
+			digest, ok := parseDigest(digest, algo, digestLen, opts)
+			if ok && filepath.Base(name) == assetName {
+				return digest, nil
+			}
+			continue
+		}
 		fields := strings.Fields(line)
 		if len(fields) < 2 {
 			continue
 		}
-		digest := fields[0]
-		if !isHexDigest(digest, digestLen) {
+		digest, ok := parseDigest(fields[0], algo, digestLen, opts)
+		if !ok {
 			continue
 		}
 		candidate := filepath.Base(fields[len(fields)-1])
 		if candidate == assetName {
-			return strings.ToLower(digest), nil
+			return digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("checksum for %s %w", assetName, ErrChecksumNotListed)
+}
+
+// ChecksumAlgorithmFromContent reads the algorithm of assetName's entry in a
+// checksum file whose name carries no hint (checksums.txt): a BSD tag names
+// it outright, otherwise a digest of the other SHA-2 length switches between
+// sha256 and sha512. It returns algo when the entry already parses as algo or
+// cannot be found either way.
+func ChecksumAlgorithmFromContent(data []byte, algo, assetName string, opts ChecksumOptions) string {
+	if _, err := ExtractChecksumWithOptions(data, algo, assetName, opts); err == nil {
+		return algo
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if lineAlgo, name, _, ok := parseBSDChecksumLine(strings.TrimSpace(line)); ok && filepath.Base(name) == assetName {
+			// A weak algorithm only counts when the file's name says so,
+			// so that the assessment already warned about it.
+			if expectedDigestLength(lineAlgo) > 0 && !WeakAlgorithm(lineAlgo) {
+				return lineAlgo
+			}
 		}
 	}
+	other := "sha512"
+	if expectedDigestLength(algo) == 128 {
+		other = "sha256"
+	}
+	if _, err := ExtractChecksumWithOptions(data, other, assetName, opts); err == nil {
+		return other
+	}
+	return algo
+}
 
-	return "", fmt.Errorf("checksum for %s not found", assetName)
+// parseBSDChecksumLine parses BSD-style tagged lines such as
+// "SHA256 (tool-linux-amd64) = abcdef..." as written by `shasum --tag` and
+// `openssl dgst`. The algorithm is normalized to sfetch's names (sha256,
+// sha512, sha3-256, blake2b-512, ...); the filename may contain spaces and
+// parentheses.
+func parseBSDChecksumLine(line string) (algo, name, digest string, ok bool) {
+	open := strings.Index(line, " (")
+	closeEq := strings.LastIndex(line, ") = ")
+	if open <= 0 || closeEq < open+2 {
+		return "", "", "", false
+	}
+	algo = normalizeBSDAlgo(line[:open])
+	name = line[open+2 : closeEq]
+	digest = strings.TrimSpace(line[closeEq+len(") = "):])
+	if algo == "" || name == "" || digest == "" {
+		return "", "", "", false
+	}
+	return algo, name, digest, true
+}
+
+func normalizeBSDAlgo(tag string) string {
+	switch strings.ToLower(strings.TrimSpace(tag)) {
+	case "sha256", "sha2-256", "sha-256":
+		return "sha256"
+	case "sha512", "sha2-512", "sha-512":
+		return "sha512"
+	case "sha3-256", "sha3_256":
+		return "sha3-256"
+	case "sha3-512", "sha3_512":
+		return "sha3-512"
+	case "blake2b-256", "blake2b256":
+		return "blake2b-256"
+	// b2sum --tag writes "BLAKE2b" for its default 512-bit digest.
+	case "blake2b", "blake2b-512", "blake2b512":
+		return "blake2b-512"
+	default:
+		return strings.ToLower(strings.TrimSpace(tag))
+	}
 }
 
 func NormalizeHexKey(input string) (string, error) {
@@ -59,6 +167,47 @@ func NormalizeHexKey(input string) (string, error) {
 	return strings.ToLower(trimmed), nil
 }
 
+// parseDigest returns value as lowercase hex if it is a hex digest of
+// hexLen characters or, with opts.AllowBase64, the base64 encoding of the
+// same number of bytes.
+func parseDigest(value, algo string, hexLen int, opts ChecksumOptions) (string, bool) {
+	if isHexDigest(value, hexLen) {
+		return strings.ToLower(value), true
+	}
+	if !opts.AllowBase64 || hexLen == 0 {
+		return "", false
+	}
+	if prefix := strings.ToLower(algo) + "-"; len(value) > len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		value = value[len(prefix):]
+	}
+	raw, ok := decodeBase64Digest(value, hexLen/2)
+	if !ok {
+		return "", false
+	}
+	return hex.EncodeToString(raw), true
+}
+
+// decodeBase64Digest decodes value as base64 if its length and alphabet fit
+// an encoding of exactly size bytes.
+func decodeBase64Digest(value string, size int) ([]byte, bool) {
+	var encodings []*base64.Encoding
+	switch len(value) {
+	case base64.StdEncoding.EncodedLen(size):
+		encodings = []*base64.Encoding{base64.StdEncoding, base64.URLEncoding}
+	case base64.RawStdEncoding.EncodedLen(size):
+		encodings = []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding}
+	default:
+		return nil, false
+	}
+	for _, enc := range encodings {
+		raw, err := enc.DecodeString(value)
+		if err == nil && len(raw) == size {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
 func isHexDigest(value string, expectedLen int) bool {
 	if expectedLen > 0 && len(value) != expectedLen {
 		return false
@@ -76,11 +225,41 @@ func isHexDigest(value string, expectedLen int) bool {
 
 func expectedDigestLength(algo string) int {
 	switch strings.ToLower(algo) {
-	case "sha256":
+	case "sha256", "sha3-256", "blake2b-256":
 		return 64
-	case "sha512":
+	case "sha512", "sha3-512", "blake2b-512":
 		return 128
+	case "sha1":
+		return 40
+	case "md5":
+		return 32
 	default:
 		return 0
 	}
 }
+
+// NewHash returns a hash for one of the checksum algorithms sfetch
+// verifies: sha256, sha512, sha3-256, sha3-512, blake2b-256 or blake2b-512,
+// and the weak sha1 and md5 for releases that publish nothing better.
+func NewHash(algo string) (hash.Hash, error) {
+	switch strings.ToLower(algo) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "sha3-256":
+		return sha3.New256(), nil
+	case "sha3-512":
+		return sha3.New512(), nil
+	case "blake2b-256":
+		return blake2b.New256(nil)
+	case "blake2b-512":
+		return blake2b.New512(nil)
+	case "sha1":
+		return sha1.New(), nil // #nosec G401 -- weak algorithm, scored as such
+	case "md5":
+		return md5.New(), nil // #nosec G401 -- weak algorithm, scored as such
+	default:
+		return nil, fmt.Errorf("unknown hash algo %q", algo)
+	}
+}
@@ -0,0 +1,190 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// ErrPGPUnsupported reports a key or signature the built-in OpenPGP verifier
+// cannot handle. Callers fall back to the gpg binary when they see it.
+var ErrPGPUnsupported = errors.New("pgp: unsupported by built-in verifier")
+
+// VerifyPGPSignatureNative verifies an ASCII-armored detached signature over
+// assetPath using an ASCII-armored public key, without invoking gpg. The
+// returned warnings are informational (e.g. the signing key has expired).
+func VerifyPGPSignatureNative(assetPath, sigPath, pubKeyPath string) ([]string, error) {
+	check, err := verifyPGPNative(assetPath, sigPath, pubKeyPath)
+	return check.warnings, err
+}
+
+// verifyPGPNative checks the signature with go-crypto's OpenPGP
+// implementation. Reading the key ring verifies every self-signature and
+// subkey binding signature: a key with a forged or missing binding is
+// dropped, so it can never sign on behalf of the primary key. Revocations
+// fail the check; expiry comes from the verified self-signatures only.
+func verifyPGPNative(assetPath, sigPath, pubKeyPath string) (pgpCheck, error) {
+	// #nosec G304 -- key path is user-configured or downloaded to tmp
+	keyData, err := os.ReadFile(pubKeyPath)
+	if err != nil {
+		return pgpCheck{}, fmt.Errorf("read pgp key: %w", err)
+	}
+	// #nosec G304 -- sig path tmp controlled
+	sigData, err := os.ReadFile(sigPath)
+	if err != nil {
+		return pgpCheck{}, fmt.Errorf("read pgp signature: %w", err)
+	}
+
+	keyring, err := readPGPKeyRing(keyData)
+	if err != nil {
+		return pgpCheck{}, err
+	}
+	sigBlock, err := armor.Decode(bytes.NewReader(sigData))
+	if err != nil {
+		return pgpCheck{}, fmt.Errorf("pgp: no armored signature found: %w", err)
+	}
+	if sigBlock.Type != openpgp.SignatureType {
+		return pgpCheck{}, fmt.Errorf("pgp: expected %s, found %s", openpgp.SignatureType, sigBlock.Type)
+	}
+
+	// #nosec G304 -- asset path tmp controlled
+	f, err := os.Open(assetPath)
+	if err != nil {
+		return pgpCheck{}, fmt.Errorf("open signed file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+
+	now := time.Now()
+	sig, signer, err := openpgp.VerifyDetachedSignature(keyring, f, sigBlock.Body, &packet.Config{Time: func() time.Time { return now }})
+	var check pgpCheck
+	switch {
+	case err == nil:
+	case signer != nil && errors.Is(err, pgperrors.ErrKeyExpired):
+		// The signature is good; only the key's validity period is over.
+		check.keyExpired = true
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		return pgpCheck{}, fmt.Errorf("pgp: signature issued by key %s not found in public key", pgpIssuer(sig, sigData))
+	case errors.Is(err, pgperrors.ErrKeyRevoked):
+		return pgpCheck{}, fmt.Errorf("pgp: signing key has been revoked: %w", err)
+	default:
+		var unsupported pgperrors.UnsupportedError
+		if errors.As(err, &unsupported) {
+			return pgpCheck{}, fmt.Errorf("%w: %v", ErrPGPUnsupported, err)
+		}
+		return pgpCheck{}, fmt.Errorf("pgp: signature verification failed: %w", err)
+	}
+
+	key, selfSig := pgpSigningKey(signer, sig)
+	check.signers = []string{strings.ToUpper(fmt.Sprintf("%x", key.Fingerprint))}
+	if key != signer.PrimaryKey {
+		// Only bound subkeys reach this point (see readPGPKeyRing), so the
+		// primary fingerprint is a legitimate signer identity for pinning.
+		check.signers = append(check.signers, strings.ToUpper(fmt.Sprintf("%x", signer.PrimaryKey.Fingerprint)))
+	}
+	if check.keyExpired {
+		check.warnings = append(check.warnings, pgpExpiryWarning(signer, key, selfSig, now))
+	}
+	return check, nil
+}
+
+// readPGPKeyRing parses an ASCII-armored public key block. Entities whose
+// self-signatures or subkey bindings do not verify are rejected by the
+// parser.
+func readPGPKeyRing(keyData []byte) (openpgp.EntityList, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyData))
+	if err != nil {
+		var unsupported pgperrors.UnsupportedError
+		if errors.As(err, &unsupported) {
+			return nil, fmt.Errorf("%w: %v", ErrPGPUnsupported, err)
+		}
+		return nil, fmt.Errorf("pgp: read public key: %w", err)
+	}
+	if len(keyring) == 0 {
+		// The parser skips packets it does not implement (unknown key
+		// versions or algorithms) rather than failing on them.
+		return nil, fmt.Errorf("%w: no public key it can read", ErrPGPUnsupported)
+	}
+	return keyring, nil
+}
+
+// pgpSigningKey returns the key of signer that issued sig, with the verified
+// self-signature that governs its flags and expiry.
+func pgpSigningKey(signer *openpgp.Entity, sig *packet.Signature) (*packet.PublicKey, *packet.Signature) {
+	if sig != nil && sig.IssuerKeyId != nil {
+		for _, sub := range signer.Subkeys {
+			if sub.PublicKey.KeyId == *sig.IssuerKeyId {
+				return sub.PublicKey, sub.Sig
+			}
+		}
+	}
+	selfSig, _ := signer.PrimarySelfSignature()
+	return signer.PrimaryKey, selfSig
+}
+
+// pgpExpiryWarning names the expired key: the signing subkey when its own
+// binding has lapsed, otherwise the primary key.
+func pgpExpiryWarning(signer *openpgp.Entity, key *packet.PublicKey, selfSig *packet.Signature, now time.Time) string {
+	if key != signer.PrimaryKey {
+		primarySig, _ := signer.PrimarySelfSignature()
+		if primarySig != nil && signer.PrimaryKey.KeyExpired(primarySig, now) {
+			key, selfSig = signer.PrimaryKey, primarySig
+		}
+	}
+	keyID := strings.ToUpper(fmt.Sprintf("%016x", key.KeyId))
+	if selfSig == nil || selfSig.KeyLifetimeSecs == nil || *selfSig.KeyLifetimeSecs == 0 {
+		return fmt.Sprintf("PGP key %s has expired", keyID)
+	}
+	expires := key.CreationTime.Add(time.Duration(*selfSig.KeyLifetimeSecs) * time.Second)
+	return fmt.Sprintf("PGP key %s expired on %s", keyID, expires.UTC().Format("2006-01-02"))
+}
+
+// pgpIssuer describes the issuer of sig for error messages, re-reading the
+// signature when verification stopped before returning it.
+func pgpIssuer(sig *packet.Signature, sigData []byte) string {
+	if sig == nil {
+		block, err := armor.Decode(bytes.NewReader(sigData))
+		if err != nil {
+			return "(unknown)"
+		}
+		p, err := packet.Read(block.Body)
+		if err != nil {
+			return "(unknown)"
+		}
+		sig, _ = p.(*packet.Signature)
+	}
+	switch {
+	case sig == nil:
+		return "(unknown)"
+	case len(sig.IssuerFingerprint) > 0:
+		return strings.ToUpper(fmt.Sprintf("%x", sig.IssuerFingerprint))
+	case sig.IssuerKeyId != nil:
+		return strings.ToUpper(fmt.Sprintf("%016x", *sig.IssuerKeyId))
+	default:
+		return "(unknown)"
+	}
+}
+
+// PGPKeyFingerprints returns the uppercase hex fingerprints of every primary
+// key and bound subkey in an ASCII-armored public key block.
+func PGPKeyFingerprints(keyData []byte) ([]string, error) {
+	keyring, err := readPGPKeyRing(keyData)
+	if err != nil {
+		return nil, err
+	}
+	var fps []string
+	for _, e := range keyring {
+		fps = append(fps, strings.ToUpper(fmt.Sprintf("%x", e.PrimaryKey.Fingerprint)))
+		for _, sub := range e.Subkeys {
+			fps = append(fps, strings.ToUpper(fmt.Sprintf("%x", sub.PublicKey.Fingerprint)))
+		}
+	}
+	return fps, nil
+}
@@ -8,16 +8,42 @@ import (
 // DetectChecksumType determines if a checksum file is consolidated or per-asset.
 func DetectChecksumType(filename string) string {
 	lower := strings.ToLower(filename)
-	if strings.HasSuffix(lower, ".sha256") || strings.HasSuffix(lower, ".sha512") ||
-		strings.HasSuffix(lower, ".sha256.txt") || strings.HasSuffix(lower, ".sha512.txt") {
-		return "per-asset"
+	for _, ext := range []string{".sha256", ".sha512", ".sha1", ".md5"} {
+		if strings.HasSuffix(lower, ext) || strings.HasSuffix(lower, ext+".txt") {
+			return "per-asset"
+		}
 	}
 	return "consolidated"
 }
 
+// DetectChecksumAlgorithm infers the digest algorithm from a checksum file's
+// name (SHA256SUMS, B2SUMS, SHA3-512SUMS, tool.tar.gz.sha512, ...), returning
+// defaultAlgo when the name carries no hint. GNU b2sum writes BLAKE2b-512 by
+// default, so B2SUMS and BLAKE2SUMS mean blake2b-512. MD5SUMS, SHA1SUMS and
+// their per-asset forms are recognized too; see WeakAlgorithm.
 func DetectChecksumAlgorithm(filename, defaultAlgo string) string {
 	lower := strings.ToLower(filename)
 	switch {
+	case strings.Contains(lower, "sha3-512sums"),
+		strings.Contains(lower, "sha3_512sums"),
+		strings.HasSuffix(lower, ".sha3-512"):
+		return "sha3-512"
+	case strings.Contains(lower, "sha3-256sums"),
+		strings.Contains(lower, "sha3_256sums"),
+		strings.HasSuffix(lower, ".sha3-256"):
+		return "sha3-256"
+	case strings.Contains(lower, "b2-256sums"),
+		strings.Contains(lower, "blake2b-256sums"),
+		strings.HasSuffix(lower, ".blake2b-256"):
+		return "blake2b-256"
+	case strings.Contains(lower, "b2sums"),
+		strings.Contains(lower, "b2-512sums"),
+		strings.Contains(lower, "blake2sums"),
+		strings.Contains(lower, "blake2bsums"),
+		strings.Contains(lower, "blake2b-512sums"),
+		strings.HasSuffix(lower, ".b2"),
+		strings.HasSuffix(lower, ".blake2b"):
+		return "blake2b-512"
 	case strings.Contains(lower, "sha2-512sums"),
 		strings.Contains(lower, "sha512sums"),
 		strings.HasSuffix(lower, ".sha512"),
@@ -28,11 +54,30 @@ func DetectChecksumAlgorithm(filename, defaultAlgo string) string {
 		strings.HasSuffix(lower, ".sha256"),
 		strings.HasSuffix(lower, ".sha256.txt"):
 		return "sha256"
+	case strings.Contains(lower, "sha1sums"),
+		strings.HasSuffix(lower, ".sha1"),
+		strings.HasSuffix(lower, ".sha1.txt"):
+		return "sha1"
+	case strings.Contains(lower, "md5sums"),
+		strings.HasSuffix(lower, ".md5"),
+		strings.HasSuffix(lower, ".md5.txt"):
+		return "md5"
 	default:
 		return defaultAlgo
 	}
 }
 
+// WeakAlgorithm reports whether algo is a broken digest (md5, sha1). A
+// matching weak checksum still catches corruption, but not tampering.
+func WeakAlgorithm(algo string) bool {
+	switch strings.ToLower(algo) {
+	case "md5", "sha1":
+		return true
+	default:
+		return false
+	}
+}
+
 // FormatSize formats bytes as human-readable size.
 func FormatSize(bytes int64) string {
 	const unit = 1024
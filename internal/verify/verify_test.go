@@ -1,10 +1,26 @@
 package verify
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"golang.org/x/crypto/blake2b"
 
 	"github.com/3leaps/sfetch/internal/model"
 )
@@ -197,9 +213,12 @@ func TestDetectChecksumType(t *testing.T) {
 		{"tool.sha256.txt", "per-asset"},
 		{"tool.sha512.txt", "per-asset"},
 		{"TOOL.SHA256", "per-asset"},
+		{"tool.sha1", "per-asset"},
+		{"tool.md5.txt", "per-asset"},
 		// Consolidated formats
 		{"SHA256SUMS", "consolidated"},
 		{"SHA2-512SUMS", "consolidated"},
+		{"MD5SUMS", "consolidated"},
 		{"checksums.txt", "consolidated"},
 		{"sha256sums.txt", "consolidated"},
 		// Unknown defaults to consolidated
@@ -236,6 +255,20 @@ func TestDetectChecksumAlgorithm(t *testing.T) {
 		{"SHA256SUMS", "sha512", "sha256"},
 		{"tool.sha256", "sha512", "sha256"},
 		{"tool.sha256.txt", "sha512", "sha256"},
+		// SHA-3 and BLAKE2b patterns
+		{"SHA3-256SUMS", "sha256", "sha3-256"},
+		{"sha3-512sums.txt", "sha256", "sha3-512"},
+		{"tool.tar.gz.sha3-256", "sha256", "sha3-256"},
+		{"B2SUMS", "sha256", "blake2b-512"},
+		{"BLAKE2SUMS", "sha256", "blake2b-512"},
+		{"B2-256SUMS", "sha256", "blake2b-256"},
+		{"tool.tar.gz.b2", "sha256", "blake2b-512"},
+		// Weak algorithms
+		{"SHA1SUMS", "sha256", "sha1"},
+		{"tool.tar.gz.sha1", "sha256", "sha1"},
+		{"MD5SUMS", "sha256", "md5"},
+		{"md5sums.txt", "sha256", "md5"},
+		{"tool.tar.gz.md5", "sha256", "md5"},
 		// Falls back to default
 		{"checksums.txt", "sha256", "sha256"},
 		{"checksums.txt", "sha512", "sha512"},
@@ -253,6 +286,35 @@ func TestDetectChecksumAlgorithm(t *testing.T) {
 	}
 }
 
+func TestChecksumAlgorithmFromContent(t *testing.T) {
+	t.Parallel()
+
+	sha256Hex := strings.Repeat("a", 64)
+	sha512Hex := strings.Repeat("b", 128)
+	tests := []struct {
+		name string
+		data string
+		algo string
+		want string
+	}{
+		{"matches default", sha256Hex + "  tool.tar.gz\n", "sha256", "sha256"},
+		{"sha512 digest", sha512Hex + "  tool.tar.gz\n", "sha256", "sha512"},
+		{"sha256 digest under sha512 default", sha256Hex + "  tool.tar.gz\n", "sha512", "sha256"},
+		{"bare sha512 digest", sha512Hex + "\n", "sha256", "sha512"},
+		{"BSD tag", "SHA3-512 (tool.tar.gz) = " + sha512Hex + "\n", "sha256", "sha3-512"},
+		{"other asset only", sha512Hex + "  other.tar.gz\n", "sha256", "sha256"},
+		{"unknown length", strings.Repeat("c", 40) + "  tool.tar.gz\n", "sha256", "sha256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChecksumAlgorithmFromContent([]byte(tt.data), tt.algo, "tool.tar.gz", ChecksumOptions{})
+			if got != tt.want {
+				t.Errorf("ChecksumAlgorithmFromContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFormatSize(t *testing.T) {
 	t.Parallel()
 
@@ -386,6 +448,149 @@ func TestExtractChecksumEdgeCases(t *testing.T) {
 	}
 }
 
+func TestExtractChecksumBase64(t *testing.T) {
+	t.Parallel()
+
+	sum256 := sha256.Sum256([]byte("tool"))
+	sum512 := sha512.Sum512([]byte("tool"))
+	hex256 := hex.EncodeToString(sum256[:])
+	hex512 := hex.EncodeToString(sum512[:])
+	b64 := base64.StdEncoding.EncodeToString(sum256[:])
+
+	tests := []struct {
+		name        string
+		data        string
+		algo        string
+		allowBase64 bool
+		want        string
+		wantErr     string
+	}{
+		{name: "sha256sum style", data: b64 + "  tool.tar.gz\n", algo: "sha256", allowBase64: true, want: hex256},
+		{name: "unpadded", data: base64.RawStdEncoding.EncodeToString(sum256[:]) + "  tool.tar.gz", algo: "sha256", allowBase64: true, want: hex256},
+		{name: "url alphabet", data: base64.URLEncoding.EncodeToString(sum256[:]) + "  tool.tar.gz", algo: "sha256", allowBase64: true, want: hex256},
+		{name: "sri prefix", data: "sha256-" + b64 + "  tool.tar.gz", algo: "sha256", allowBase64: true, want: hex256},
+		{name: "bsd tagged", data: "SHA256 (tool.tar.gz) = " + b64, algo: "sha256", allowBase64: true, want: hex256},
+		{name: "bare digest", data: b64, algo: "sha256", allowBase64: true, want: hex256},
+		{name: "sha512", data: base64.StdEncoding.EncodeToString(sum512[:]) + "  tool.tar.gz", algo: "sha512", allowBase64: true, want: hex512},
+		{name: "hex still accepted", data: hex256 + "  tool.tar.gz", algo: "sha256", allowBase64: true, want: hex256},
+		{name: "not enabled", data: b64 + "  tool.tar.gz", algo: "sha256", wantErr: "not found"},
+		{name: "wrong length", data: base64.StdEncoding.EncodeToString(sum512[:]) + "  tool.tar.gz", algo: "sha256", allowBase64: true, wantErr: "not found"},
+		{name: "not base64", data: strings.Repeat("!", 44) + "  tool.tar.gz", algo: "sha256", allowBase64: true, wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ExtractChecksumWithOptions([]byte(tt.data), tt.algo, "tool.tar.gz", ChecksumOptions{AllowBase64: tt.allowBase64})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractChecksumBSDTagged(t *testing.T) {
+	t.Parallel()
+
+	sha256Digest := strings.Repeat("a", 64)
+	sha512Digest := strings.Repeat("b", 128)
+
+	tests := []struct {
+		name      string
+		data      string
+		algo      string
+		assetName string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "bsd sha256",
+			data:      "SHA256 (tool-linux-amd64) = " + sha256Digest,
+			algo:      "sha256",
+			assetName: "tool-linux-amd64",
+			want:      sha256Digest,
+		},
+		{
+			name:      "bsd sha512",
+			data:      "SHA512 (tool-linux-amd64) = " + sha512Digest,
+			algo:      "sha512",
+			assetName: "tool-linux-amd64",
+			want:      sha512Digest,
+		},
+		{
+			name:      "bsd sha2-256 tag and uppercase digest",
+			data:      "SHA2-256 (tool.tar.gz) = " + strings.ToUpper(sha256Digest),
+			algo:      "sha256",
+			assetName: "tool.tar.gz",
+			want:      sha256Digest,
+		},
+		{
+			name:      "bsd path stripped to basename",
+			data:      "SHA256 (dist/tool.tar.gz) = " + sha256Digest,
+			algo:      "sha256",
+			assetName: "tool.tar.gz",
+			want:      sha256Digest,
+		},
+		{
+			name:      "bsd filename with spaces and parens",
+			data:      "SHA256 (My Tool (beta).zip) = " + sha256Digest,
+			algo:      "sha256",
+			assetName: "My Tool (beta).zip",
+			want:      sha256Digest,
+		},
+		{
+			name: "mixed bsd and gnu lines",
+			data: "SHA512 (tool.tar.gz) = " + sha512Digest + "\n" +
+				strings.Repeat("c", 64) + "  other.tar.gz\n" +
+				"SHA256 (tool.tar.gz) = " + sha256Digest,
+			algo:      "sha256",
+			assetName: "tool.tar.gz",
+			want:      sha256Digest,
+		},
+		{
+			name:      "bsd algorithm mismatch rejected",
+			data:      "SHA512 (tool.tar.gz) = " + sha512Digest,
+			algo:      "sha256",
+			assetName: "tool.tar.gz",
+			wantErr:   "not found",
+		},
+		{
+			name:      "bsd digest length mismatch rejected",
+			data:      "SHA256 (tool.tar.gz) = " + sha512Digest,
+			algo:      "sha256",
+			assetName: "tool.tar.gz",
+			wantErr:   "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ExtractChecksum([]byte(tt.data), tt.algo, tt.assetName)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSignatureFormatFromExtensionEdgeCases(t *testing.T) {
 	t.Parallel()
 
@@ -475,7 +680,12 @@ func TestExpectedDigestLength(t *testing.T) {
 		{"SHA256", 64},
 		{"sha512", 128},
 		{"SHA512", 128},
-		{"md5", 0},
+		{"sha3-256", 64},
+		{"SHA3-512", 128},
+		{"blake2b-256", 64},
+		{"blake2b-512", 128},
+		{"sha1", 40},
+		{"MD5", 32},
 		{"unknown", 0},
 		{"", 0},
 	}
@@ -489,3 +699,688 @@ func TestExpectedDigestLength(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHash(t *testing.T) {
+	t.Parallel()
+
+	// Digests of the empty input.
+	tests := []struct {
+		algo    string
+		want    string
+		wantErr bool
+	}{
+		{algo: "sha256", want: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{algo: "sha3-256", want: "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{algo: "sha3-512", want: "a69f73cca23a9ac5c8b567dc185a756e97c982164fe25859e0d1dcc1475c80a615b2123af1f5f94c11e3e9402c3ac558f500199d95b6d3e301758586281dcd26"},
+		{algo: "blake2b-256", want: "0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"},
+		{algo: "BLAKE2b-512", want: "786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce"},
+		{algo: "sha1", want: "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{algo: "md5", want: "d41d8cd98f00b204e9800998ecf8427e"},
+		{algo: "crc32", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algo, func(t *testing.T) {
+			h, err := NewHash(tt.algo)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewHash(%q) succeeded, want error", tt.algo)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewHash(%q): %v", tt.algo, err)
+			}
+			if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+				t.Fatalf("NewHash(%q) digest = %s, want %s", tt.algo, got, tt.want)
+			}
+			if len(tt.want) != expectedDigestLength(tt.algo) {
+				t.Fatalf("expectedDigestLength(%q) = %d, want %d", tt.algo, expectedDigestLength(tt.algo), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestExtractChecksumSHA3AndBLAKE2(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte("sfetch checksum fixture\n")
+	digest := func(algo string) string {
+		h, err := NewHash(algo)
+		if err != nil {
+			t.Fatalf("NewHash(%q): %v", algo, err)
+		}
+		h.Write(payload)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	sha3256 := digest("sha3-256")
+	sha3512 := digest("sha3-512")
+	b2256 := digest("blake2b-256")
+	b2512 := digest("blake2b-512")
+
+	tests := []struct {
+		name     string
+		manifest string
+		data     string
+		want     string
+		wantErr  bool
+	}{
+		{name: "SHA3-256SUMS", manifest: "SHA3-256SUMS", data: sha3256 + "  tool.tar.gz\n" + strings.Repeat("0", 64) + "  other.zip\n", want: sha3256},
+		{name: "SHA3-512SUMS", manifest: "SHA3-512SUMS", data: sha3512 + "  tool.tar.gz\n", want: sha3512},
+		{name: "B2SUMS", manifest: "B2SUMS", data: b2512 + "  tool.tar.gz\n", want: b2512},
+		{name: "B2-256SUMS", manifest: "B2-256SUMS", data: b2256 + "  tool.tar.gz\n", want: b2256},
+		{name: "b2sum --tag", manifest: "B2SUMS", data: "BLAKE2b (tool.tar.gz) = " + b2512 + "\n", want: b2512},
+		{name: "b2sum -l 256 --tag", manifest: "checksums.txt", data: "BLAKE2b-256 (tool.tar.gz) = " + b2256 + "\n", want: b2256},
+		{name: "sha3 bsd tag", manifest: "SHA3-256SUMS", data: "SHA3-256 (tool.tar.gz) = " + sha3256 + "\n", want: sha3256},
+		// A 256-bit digest in a 512-bit manifest is rejected by length.
+		{name: "B2SUMS with 256-bit digest", manifest: "B2SUMS", data: b2256 + "  tool.tar.gz\n", wantErr: true},
+		{name: "SHA3-256SUMS with 512-bit digest", manifest: "SHA3-256SUMS", data: sha3512 + "  tool.tar.gz\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			algo := DetectChecksumAlgorithm(tt.manifest, "blake2b-256")
+			got, err := ExtractChecksum([]byte(tt.data), algo, "tool.tar.gz")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ExtractChecksum(%s) = %q, want error", algo, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractChecksum(%s): %v", algo, err)
+			}
+			if got != tt.want {
+				t.Fatalf("ExtractChecksum(%s) = %q, want %q", algo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyPGPSignatureNative(t *testing.T) {
+	t.Parallel()
+
+	integration := filepath.Join("..", "..", "testdata", "integration")
+	pgpDir := filepath.Join("..", "..", "testdata", "pgp")
+	tampered := filepath.Join(t.TempDir(), "payload.txt")
+	if err := os.WriteFile(tampered, []byte("sfetch pgp fixture payload (tampered)\n"), 0o600); err != nil {
+		t.Fatalf("write tampered payload: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		asset       string
+		sig         string
+		key         string
+		wantErr     string
+		wantWarning string
+	}{
+		{
+			name:  "ed25519 integration fixture",
+			asset: filepath.Join(integration, "sfetch_test_darwin_arm64.tar.gz"),
+			sig:   filepath.Join(integration, "sfetch_test_darwin_arm64.tar.gz.asc"),
+			key:   filepath.Join("..", "..", "testdata", "keys", "test-pgp-pub.asc"),
+		},
+		{
+			name:  "rsa",
+			asset: filepath.Join(pgpDir, "payload.txt"),
+			sig:   filepath.Join(pgpDir, "payload.txt.rsa.asc"),
+			key:   filepath.Join(pgpDir, "rsa-pub.asc"),
+		},
+		{
+			name:        "expired key warns",
+			asset:       filepath.Join(pgpDir, "payload.txt"),
+			sig:         filepath.Join(pgpDir, "payload.txt.expired.asc"),
+			key:         filepath.Join(pgpDir, "expired-pub.asc"),
+			wantWarning: "expired on 2020-01-02",
+		},
+		{
+			name:    "tampered content",
+			asset:   tampered,
+			sig:     filepath.Join(pgpDir, "payload.txt.rsa.asc"),
+			key:     filepath.Join(pgpDir, "rsa-pub.asc"),
+			wantErr: "verification failed",
+		},
+		{
+			name:    "wrong key",
+			asset:   filepath.Join(pgpDir, "payload.txt"),
+			sig:     filepath.Join(pgpDir, "payload.txt.rsa.asc"),
+			key:     filepath.Join(pgpDir, "expired-pub.asc"),
+			wantErr: "not found in public key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := VerifyPGPSignatureNative(tt.asset, tt.sig, tt.key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("VerifyPGPSignatureNative() error = %v, want %q", err, tt.wantErr)
+				}
+				if errors.Is(err, ErrPGPUnsupported) {
+					t.Fatalf("verification failure must not be reported as unsupported: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyPGPSignatureNative() error = %v", err)
+			}
+			if tt.wantWarning == "" {
+				if len(warnings) != 0 {
+					t.Fatalf("unexpected warnings: %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.wantWarning) {
+				t.Fatalf("warnings = %v, want one containing %q", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestParseGPGStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		status      string
+		wantExpired bool
+		wantWarning string
+	}{
+		{
+			name:   "good signature",
+			status: "[GNUPG:] NEWSIG\n[GNUPG:] GOODSIG 0123456789ABCDEF Test <t@example.com>\n[GNUPG:] VALIDSIG ABC\n",
+		},
+		{
+			name:        "expired key with epoch timestamp",
+			status:      "[GNUPG:] KEYEXPIRED 1577923200\n[GNUPG:] EXPKEYSIG 0123456789ABCDEF Test <t@example.com>\n",
+			wantExpired: true,
+			wantWarning: "PGP key 0123456789ABCDEF expired on 2020-01-02",
+		},
+		{
+			name:        "expired key with ISO timestamp",
+			status:      "[GNUPG:] KEYEXPIRED 20200102T000000\n",
+			wantExpired: true,
+			wantWarning: "PGP key expired on 2020-01-02",
+		},
+		{
+			name:        "expkeysig only",
+			status:      "[GNUPG:] EXPKEYSIG 0123456789ABCDEF Test\n",
+			wantExpired: true,
+			wantWarning: "PGP key 0123456789ABCDEF has expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			check := parseGPGStatus([]byte(tt.status))
+			if check.keyExpired != tt.wantExpired {
+				t.Fatalf("keyExpired = %v, want %v", check.keyExpired, tt.wantExpired)
+			}
+			if tt.wantWarning == "" {
+				if len(check.warnings) != 0 {
+					t.Fatalf("unexpected warnings: %v", check.warnings)
+				}
+				return
+			}
+			if len(check.warnings) != 1 || check.warnings[0] != tt.wantWarning {
+				t.Fatalf("warnings = %v, want [%q]", check.warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestVerifyPGPSignatureExpiredKey(t *testing.T) {
+	t.Parallel()
+
+	pgpDir := filepath.Join("..", "..", "testdata", "pgp")
+	asset := filepath.Join(pgpDir, "payload.txt")
+	sig := filepath.Join(pgpDir, "payload.txt.expired.asc")
+	key := filepath.Join(pgpDir, "expired-pub.asc")
+
+	tests := []struct {
+		name         string
+		useGPGBinary bool
+	}{
+		{name: "built-in"},
+		{name: "gpg", useGPGBinary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			opts := PGPOptions{GPGBin: "gpg", UseGPGBinary: tt.useGPGBinary}
+			if tt.useGPGBinary {
+				gpgPath, err := exec.LookPath("gpg")
+				if err != nil {
+					t.Skip("gpg not found in PATH")
+				}
+				opts.GPGBin = gpgPath
+			}
+
+			warnings, err := VerifyPGPSignature(asset, sig, key, opts)
+			if err != nil {
+				t.Fatalf("VerifyPGPSignature() error = %v", err)
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], "expired on 2020-01-02") {
+				t.Fatalf("warnings = %v, want expiry warning", warnings)
+			}
+
+			opts.RejectExpiredKeys = true
+			if _, err := VerifyPGPSignature(asset, sig, key, opts); !errors.Is(err, ErrPGPKeyExpired) {
+				t.Fatalf("VerifyPGPSignature() error = %v, want ErrPGPKeyExpired", err)
+			}
+		})
+	}
+}
+
+func TestVerifyPGPSignatureUnsupportedFallsBack(t *testing.T) {
+	t.Parallel()
+
+	// A v5 key packet is outside the built-in verifier; with no usable gpg
+	// binary the error must carry both causes.
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "v5.asc")
+	key := "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nxgYFAAAAAAA=\n-----END PGP PUBLIC KEY BLOCK-----\n"
+	if err := os.WriteFile(keyPath, []byte(key), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	pgpDir := filepath.Join("..", "..", "testdata", "pgp")
+	asset := filepath.Join(pgpDir, "payload.txt")
+	sig := filepath.Join(pgpDir, "payload.txt.rsa.asc")
+
+	if _, err := VerifyPGPSignatureNative(asset, sig, keyPath); !errors.Is(err, ErrPGPUnsupported) {
+		t.Fatalf("VerifyPGPSignatureNative() error = %v, want ErrPGPUnsupported", err)
+	}
+	_, err := VerifyPGPSignature(asset, sig, keyPath, PGPOptions{GPGBin: filepath.Join(dir, "no-such-gpg")})
+	if err == nil || !strings.Contains(err.Error(), "gpg fallback") {
+		t.Fatalf("VerifyPGPSignature() error = %v, want gpg fallback failure", err)
+	}
+}
+
+// forgedPGPSubkeyFixture appends an attacker's signing subkey to the public
+// key at keyPath, with the attacker's own binding signature when withBinding
+// is set, and signs asset with that subkey. It returns the key and signature
+// paths.
+func forgedPGPSubkeyFixture(t *testing.T, keyPath, asset string, withBinding bool) (string, string) {
+	t.Helper()
+	attacker, err := openpgp.NewEntity("attacker", "", "attacker@example.com", nil)
+	if err != nil {
+		t.Fatalf("generate attacker key: %v", err)
+	}
+	if err := attacker.AddSigningSubkey(nil); err != nil {
+		t.Fatalf("add signing subkey: %v", err)
+	}
+	sub := attacker.Subkeys[len(attacker.Subkeys)-1]
+
+	var raw bytes.Buffer
+	raw.Write(dearmorPGPFixture(t, keyPath))
+	if err := sub.PublicKey.Serialize(&raw); err != nil {
+		t.Fatalf("serialize subkey: %v", err)
+	}
+	if withBinding {
+		if err := sub.Sig.Serialize(&raw); err != nil {
+			t.Fatalf("serialize binding: %v", err)
+		}
+	}
+	dir := t.TempDir()
+	forgedKey := writeArmoredPGPFixture(t, filepath.Join(dir, "forged-pub.asc"), openpgp.PublicKeyType, raw.Bytes())
+
+	signer := &openpgp.Entity{PrimaryKey: sub.PublicKey, PrivateKey: sub.PrivateKey}
+	data, err := os.ReadFile(asset)
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	var sigBuf bytes.Buffer
+	sig := &packet.Signature{Version: 4, SigType: packet.SigTypeBinary, PubKeyAlgo: sub.PrivateKey.PubKeyAlgo, Hash: crypto.SHA256, CreationTime: time.Now(), IssuerKeyId: &sub.PublicKey.KeyId}
+	h, err := sig.PrepareSign(nil)
+	if err != nil {
+		t.Fatalf("prepare signature: %v", err)
+	}
+	h.Write(data)
+	if err := sig.Sign(h, signer.PrivateKey, nil); err != nil {
+		t.Fatalf("sign asset: %v", err)
+	}
+	if err := sig.Serialize(&sigBuf); err != nil {
+		t.Fatalf("serialize signature: %v", err)
+	}
+	forgedSig := writeArmoredPGPFixture(t, filepath.Join(dir, "forged.asc"), openpgp.SignatureType, sigBuf.Bytes())
+	return forgedKey, forgedSig
+}
+
+func dearmorPGPFixture(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+	block, err := armor.Decode(f)
+	if err != nil {
+		t.Fatalf("dearmor %s: %v", path, err)
+	}
+	raw, err := io.ReadAll(block.Body)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return raw
+}
+
+func writeArmoredPGPFixture(t *testing.T, path, blockType string, raw []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, blockType, nil)
+	if err != nil {
+		t.Fatalf("armor: %v", err)
+	}
+	_, _ = w.Write(raw)
+	if err := w.Close(); err != nil {
+		t.Fatalf("armor: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestVerifyPGPSignatureForgedKeyMaterial(t *testing.T) {
+	t.Parallel()
+
+	pgpDir := filepath.Join("..", "..", "testdata", "pgp")
+	asset := filepath.Join(pgpDir, "payload.txt")
+
+	t.Run("unbound subkey", func(t *testing.T) {
+		t.Parallel()
+		key, sig := forgedPGPSubkeyFixture(t, filepath.Join(pgpDir, "rsa-pub.asc"), asset, false)
+		_, err := VerifyPGPSignatureNative(asset, sig, key)
+		if err == nil || errors.Is(err, ErrPGPUnsupported) {
+			t.Fatalf("VerifyPGPSignatureNative() error = %v, want rejection", err)
+		}
+	})
+
+	t.Run("subkey bound by another primary", func(t *testing.T) {
+		t.Parallel()
+		key, sig := forgedPGPSubkeyFixture(t, filepath.Join(pgpDir, "rsa-pub.asc"), asset, true)
+		_, err := VerifyPGPSignatureNative(asset, sig, key)
+		if err == nil || errors.Is(err, ErrPGPUnsupported) {
+			t.Fatalf("VerifyPGPSignatureNative() error = %v, want rejection", err)
+		}
+	})
+
+	t.Run("forged self-signature without expiry", func(t *testing.T) {
+		t.Parallel()
+		// A newer certification that claims the expired key as issuer but
+		// is signed by someone else must not lift the expiry.
+		expiredKey := filepath.Join(pgpDir, "expired-pub.asc")
+		raw := dearmorPGPFixture(t, expiredKey)
+		var primary *packet.PublicKey
+		var uid *packet.UserId
+		packets := packet.NewReader(bytes.NewReader(raw))
+		for {
+			p, err := packets.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			switch pkt := p.(type) {
+			case *packet.PublicKey:
+				if primary == nil {
+					primary = pkt
+				}
+			case *packet.UserId:
+				uid = pkt
+			}
+		}
+		if primary == nil || uid == nil {
+			t.Fatal("fixture lacks a primary key or user ID")
+		}
+
+		attacker, err := openpgp.NewEntity("attacker", "", "attacker@example.com", &packet.Config{Algorithm: primary.PubKeyAlgo, Curve: packet.Curve25519})
+		if err != nil {
+			t.Fatalf("generate attacker key: %v", err)
+		}
+		forger := *attacker.PrivateKey
+		forger.KeyId = primary.KeyId
+		forger.Fingerprint = primary.Fingerprint
+		forged := &packet.Signature{
+			Version:      4,
+			SigType:      packet.SigTypePositiveCert,
+			PubKeyAlgo:   primary.PubKeyAlgo,
+			Hash:         crypto.SHA256,
+			CreationTime: time.Now(),
+			IssuerKeyId:  &primary.KeyId,
+			FlagsValid:   true,
+			FlagSign:     true,
+			FlagCertify:  true,
+		}
+		if err := forged.SignUserId(uid.Id, primary, &forger, nil); err != nil {
+			t.Fatalf("forge self-signature: %v", err)
+		}
+		var buf bytes.Buffer
+		buf.Write(raw)
+		if err := forged.Serialize(&buf); err != nil {
+			t.Fatalf("serialize forged signature: %v", err)
+		}
+		key := writeArmoredPGPFixture(t, filepath.Join(t.TempDir(), "expired-forged.asc"), openpgp.PublicKeyType, buf.Bytes())
+
+		_, err = VerifyPGPSignature(asset, filepath.Join(pgpDir, "payload.txt.expired.asc"), key, PGPOptions{GPGBin: "no-such-gpg", RejectExpiredKeys: true})
+		if err == nil {
+			t.Fatal("VerifyPGPSignature() accepted an expired key with a forged self-signature")
+		}
+		if errors.Is(err, ErrPGPUnsupported) {
+			t.Fatalf("VerifyPGPSignature() error = %v, want rejection by the built-in verifier", err)
+		}
+	})
+}
+
+func TestVerifyPGPSignaturePinnedFingerprint(t *testing.T) {
+	t.Parallel()
+
+	pgpDir := filepath.Join("..", "..", "testdata", "pgp")
+	asset := filepath.Join(pgpDir, "payload.txt")
+	sig := filepath.Join(pgpDir, "payload.txt.rsa.asc")
+	key := filepath.Join(pgpDir, "rsa-pub.asc")
+
+	tests := []struct {
+		name         string
+		useGPGBinary bool
+		pin          string
+		wantErr      error
+	}{
+		{name: "built-in match", pin: "b816 86fe e118 a91c e119  70ef e9d7 cd5d dc61 a7db"},
+		{name: "built-in mismatch", pin: "9F369A0527F24A7F7A0DC5834E903D33C1338F5E", wantErr: ErrPGPFingerprintMismatch},
+		{name: "gpg match", useGPGBinary: true, pin: "0xB81686FEE118A91CE11970EFE9D7CD5DDC61A7DB"},
+		{name: "gpg mismatch", useGPGBinary: true, pin: "9F369A0527F24A7F7A0DC5834E903D33C1338F5E", wantErr: ErrPGPFingerprintMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			opts := PGPOptions{GPGBin: "gpg", UseGPGBinary: tt.useGPGBinary, PinnedFingerprint: tt.pin}
+			if tt.useGPGBinary {
+				gpgPath, err := exec.LookPath("gpg")
+				if err != nil {
+					t.Skip("gpg not found in PATH")
+				}
+				opts.GPGBin = gpgPath
+			}
+			_, err := VerifyPGPSignature(asset, sig, key, opts)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("VerifyPGPSignature() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyPGPSignature() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// An attacker's subkey appended to the pinned primary must not inherit
+	// the primary's fingerprint, bound by the attacker or not.
+	for _, withBinding := range []bool{false, true} {
+		forgedKey, forgedSig := forgedPGPSubkeyFixture(t, key, asset, withBinding)
+		opts := PGPOptions{GPGBin: "no-such-gpg", PinnedFingerprint: "B81686FEE118A91CE11970EFE9D7CD5DDC61A7DB"}
+		if _, err := VerifyPGPSignature(asset, forgedSig, forgedKey, opts); err == nil {
+			t.Fatalf("VerifyPGPSignature() accepted a forged subkey under the pinned primary (binding=%v)", withBinding)
+		}
+	}
+}
+
+func TestParseGPGStatusSigners(t *testing.T) {
+	t.Parallel()
+
+	status := "[GNUPG:] VALIDSIG aaaa1111 2024-01-01 1704067200 0 4 0 22 10 00 BBBB2222\n"
+	check := parseGPGStatus([]byte(status))
+	if want := []string{"AAAA1111", "BBBB2222"}; strings.Join(check.signers, ",") != strings.Join(want, ",") {
+		t.Fatalf("signers = %v, want %v", check.signers, want)
+	}
+}
+
+func TestNormalizePGPFingerprint(t *testing.T) {
+	t.Parallel()
+
+	const want = "B81686FEE118A91CE11970EFE9D7CD5DDC61A7DB"
+	tests := []struct {
+		raw     string
+		wantErr bool
+	}{
+		{raw: want},
+		{raw: "b81686fee118a91ce11970efe9d7cd5ddc61a7db"},
+		{raw: "B816 86FE E118 A91C E119  70EF E9D7 CD5D DC61 A7DB"},
+		{raw: "0xB81686FEE118A91CE11970EFE9D7CD5DDC61A7DB"},
+		{raw: "E9D7CD5DDC61A7DB", wantErr: true},
+		{raw: "Z81686FEE118A91CE11970EFE9D7CD5DDC61A7DB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizePGPFingerprint(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NormalizePGPFingerprint(%q) expected error, got %q", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("NormalizePGPFingerprint(%q) = %q, %v; want %q", tt.raw, got, err, want)
+		}
+	}
+}
+
+func TestVerifyMinisignSignaturePinnedKey(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("..", "..", "testdata")
+	content, err := os.ReadFile(filepath.Join(dir, "minisign", "SHA256SUMS"))
+	if err != nil {
+		t.Fatalf("read checksum file: %v", err)
+	}
+	sig := filepath.Join(dir, "minisign", "SHA256SUMS.minisig")
+	key := filepath.Join(dir, "keys", "test-minisign.pub")
+
+	if _, err := VerifyMinisignSignature(content, sig, key, MinisignOptions{PinnedKey: "RWR8C7FtUIbC1dWt8RIwk7dEyv6V3E7K02laVqjrZKmylpwkYBVzX14d"}); err != nil {
+		t.Fatalf("VerifyMinisignSignature() with matching pin: %v", err)
+	}
+	_, err = VerifyMinisignSignature(content, sig, key, MinisignOptions{PinnedKey: "RWQofujyCgZE45KW4wjPCDP6M/KdG9WDzwSWU6TjnCb3DpsEMOrUt4KX"})
+	if !errors.Is(err, ErrMinisignKeyMismatch) {
+		t.Fatalf("VerifyMinisignSignature() error = %v, want ErrMinisignKeyMismatch", err)
+	}
+}
+
+// writeMinisignFixture signs content with a fresh key in minisign format and
+// returns the key and signature paths. algo is "ED" (prehashed) or "Ed"
+// (legacy).
+func writeMinisignFixture(t *testing.T, dir string, content []byte, algo string) (keyPath, sigPath string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	message := content
+	if algo == "ED" {
+		h, _ := blake2b.New512(nil)
+		h.Write(content)
+		message = h.Sum(nil)
+	}
+	sig := ed25519.Sign(priv, message)
+	const trusted = "timestamp:1700000000\tfile:tool"
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), trusted...))
+
+	keyPath = filepath.Join(dir, algo+".pub")
+	keyLine := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+	if err := os.WriteFile(keyPath, []byte("untrusted comment: test key\n"+keyLine+"\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	sigPath = filepath.Join(dir, algo+".minisig")
+	sigLine := base64.StdEncoding.EncodeToString(append(append([]byte(algo), keyID...), sig...))
+	body := "untrusted comment: test signature\n" + sigLine + "\ntrusted comment: " + trusted + "\n" + base64.StdEncoding.EncodeToString(global) + "\n"
+	if err := os.WriteFile(sigPath, []byte(body), 0o600); err != nil {
+		t.Fatalf("write signature: %v", err)
+	}
+	return keyPath, sigPath
+}
+
+func TestVerifyMinisignSignatureFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := []byte(strings.Repeat("release asset bytes\n", 4096))
+	assetPath := filepath.Join(dir, "tool.tar.gz")
+	if err := os.WriteFile(assetPath, content, 0o600); err != nil {
+		t.Fatalf("write asset: %v", err)
+	}
+	tamperedPath := filepath.Join(dir, "tampered.tar.gz")
+	if err := os.WriteFile(tamperedPath, append([]byte("X"), content[1:]...), 0o600); err != nil {
+		t.Fatalf("write tampered asset: %v", err)
+	}
+
+	for _, algo := range []string{"ED", "Ed"} {
+		keyPath, sigPath := writeMinisignFixture(t, dir, content, algo)
+		t.Run(algo, func(t *testing.T) {
+			want, err := VerifyMinisignSignature(content, sigPath, keyPath, MinisignOptions{})
+			if err != nil {
+				t.Fatalf("VerifyMinisignSignature() error: %v", err)
+			}
+			got, err := VerifyMinisignSignatureFile(assetPath, sigPath, keyPath, MinisignOptions{})
+			if err != nil {
+				t.Fatalf("VerifyMinisignSignatureFile() error: %v", err)
+			}
+			if got != want {
+				t.Fatalf("trusted comment = %q, want %q", got, want)
+			}
+			if _, err := VerifyMinisignSignatureFile(tamperedPath, sigPath, keyPath, MinisignOptions{}); err == nil {
+				t.Fatal("VerifyMinisignSignatureFile() accepted a tampered file")
+			}
+			if _, err := VerifyMinisignSignatureFile(filepath.Join(dir, "missing"), sigPath, keyPath, MinisignOptions{}); err == nil {
+				t.Fatal("VerifyMinisignSignatureFile() accepted a missing file")
+			}
+		})
+	}
+
+	// The checked-in fixture is a prehashed signature made by minisign itself.
+	fixtures := filepath.Join("..", "..", "testdata")
+	if _, err := VerifyMinisignSignatureFile(filepath.Join(fixtures, "minisign", "SHA256SUMS"), filepath.Join(fixtures, "minisign", "SHA256SUMS.minisig"), filepath.Join(fixtures, "keys", "test-minisign.pub"), MinisignOptions{}); err != nil {
+		t.Fatalf("VerifyMinisignSignatureFile() on fixture: %v", err)
+	}
+}
+
+func TestPGPKeyFingerprints(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "pgp", "rsa-pub.asc"))
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	got, err := PGPKeyFingerprints(data)
+	if err != nil {
+		t.Fatalf("PGPKeyFingerprints: %v", err)
+	}
+	if len(got) == 0 || got[0] != "B81686FEE118A91CE11970EFE9D7CD5DDC61A7DB" {
+		t.Fatalf("fingerprints = %v", got)
+	}
+
+	if _, err := PGPKeyFingerprints([]byte("not a key")); err == nil {
+		t.Fatal("expected error for non-key input")
+	}
+}
@@ -9,16 +9,49 @@ import (
 // FindChecksumSignature looks for a signature over the checksum file (Workflow A).
 // It searches for assets matching the ChecksumSigCandidates patterns.
 // Returns the signature asset and the corresponding checksum asset name, or nil if not found.
+// A signature is only paired when the manifest it covers is also in the release,
+// so the checksum algorithm always follows the signed manifest rather than
+// whichever manifest happens to be strongest.
 func FindChecksumSignature(assets []model.Asset, cfg *model.RepoConfig) (*model.Asset, string) {
+	sigs := FindChecksumSignatures(assets, cfg)
+	if len(sigs) == 0 {
+		return nil, ""
+	}
+	return sigs[0].Asset, sigs[0].ChecksumName
+}
+
+// ChecksumSignature pairs a checksum-level signature asset with the manifest it covers.
+type ChecksumSignature struct {
+	Asset        *model.Asset
+	ChecksumName string
+}
+
+// FindChecksumSignatures returns every checksum-level signature whose manifest
+// is present in the release, in ChecksumSigCandidates order. The first entry is
+// the one FindChecksumSignature picks.
+func FindChecksumSignatures(assets []model.Asset, cfg *model.RepoConfig) []ChecksumSignature {
+	var out []ChecksumSignature
 	for _, candidate := range cfg.ChecksumSigCandidates {
 		for i := range assets {
-			if assets[i].Name == candidate {
-				checksumName := strings.TrimSuffix(candidate, ".minisig")
-				checksumName = strings.TrimSuffix(checksumName, ".asc")
-				checksumName = strings.TrimSuffix(checksumName, ".sig")
-				return &assets[i], checksumName
+			if assets[i].Name != candidate {
+				continue
 			}
+			checksumName := strings.TrimSuffix(candidate, ".minisig")
+			checksumName = strings.TrimSuffix(checksumName, ".asc")
+			checksumName = strings.TrimSuffix(checksumName, ".sig")
+			if hasAsset(assets, checksumName) {
+				out = append(out, ChecksumSignature{Asset: &assets[i], ChecksumName: checksumName})
+			}
+		}
+	}
+	return out
+}
+
+func hasAsset(assets []model.Asset, name string) bool {
+	for i := range assets {
+		if assets[i].Name == name {
+			return true
 		}
 	}
-	return nil, ""
+	return false
 }
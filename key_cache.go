@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/3leaps/sfetch/internal/cache"
+)
+
+// keyCache reuses verification keys fetched from a URL or release asset by
+// earlier runs, so repeated installs from a repo skip the key download. A
+// key is stored only after it verified a signature, and is then trusted on
+// first use: a source that later serves a different key fails the run with
+// a *keyChangedError until --refresh-keys accepts the new key. A cached key
+// that fails to verify is fetched again to tell a rotated key from a bad
+// signature. A nil *keyCache fetches every time.
+type keyCache struct {
+	dir         string
+	refresh     bool // --refresh: fetch keys again, still storing them
+	refreshKeys bool // --refresh-keys: fetch keys again and accept changed ones
+	warn        io.Writer
+
+	mu      sync.Mutex
+	fetched map[string]string // key path -> source, downloaded this run
+	reused  map[string]string // key path -> source, served from the cache
+	retried map[string]bool   // sources whose cached key failed to verify
+}
+
+func newKeyCache(cacheDir string, refresh, refreshKeys bool, warn io.Writer) *keyCache {
+	return &keyCache{dir: cacheDir, refresh: refresh, refreshKeys: refreshKeys, warn: warn, fetched: map[string]string{}, reused: map[string]string{}, retried: map[string]bool{}}
+}
+
+// keyChangedError reports a key source that serves a different key than the
+// one cached after an earlier verified run. It is not a key resolution
+// failure, so --on-missing-key never tolerates it.
+type keyChangedError struct {
+	format string
+	source string
+}
+
+func (e *keyChangedError) Error() string {
+	return fmt.Sprintf("error: %s key from %s differs from the key cached after an earlier verified run; if the project rotated its key, confirm the new key and rerun with --refresh-keys",
+		signatureFormatLabel(e.format), e.source)
+}
+
+// holds reports whether a usable key for source is cached.
+func (c *keyCache) holds(source, wantSHA256 string) bool {
+	if c == nil || c.refresh || c.refreshKeys {
+		return false
+	}
+	_, ok := cache.LookupKey(c.dir, source, wantSHA256)
+	return ok
+}
+
+// fetch returns the cached key for source, or calls download for it. A
+// non-empty wantSHA256 (the release API digest of a key asset) must match
+// the cached key. A downloaded key that differs from the cached one is a
+// *keyChangedError unless --refresh-keys is set.
+func (c *keyCache) fetch(source, wantSHA256 string, download func() (string, error)) (string, error) {
+	if c == nil {
+		return download()
+	}
+	c.mu.Lock()
+	retried := c.retried[source]
+	c.mu.Unlock()
+	if !c.refresh && !c.refreshKeys && !retried {
+		if path, ok := cache.LookupKey(c.dir, source, wantSHA256); ok {
+			c.mu.Lock()
+			c.reused[path] = source
+			c.mu.Unlock()
+			return path, nil
+		}
+	}
+	path, err := download()
+	if err != nil {
+		return "", err
+	}
+	if cached, ok := cache.LookupKey(c.dir, source, ""); ok {
+		got, err := cache.FileSHA256(path)
+		if err != nil {
+			return "", err
+		}
+		want, err := cache.FileSHA256(cached)
+		if err != nil {
+			return "", err
+		}
+		if got != want {
+			if !c.refreshKeys {
+				return "", &keyChangedError{source: source}
+			}
+			_, _ = fmt.Fprintf(c.warn, "warning: key from %s changed since it was cached; --refresh-keys trusts the new key\n", source) //nolint:errcheck
+		}
+	}
+	c.mu.Lock()
+	c.fetched[path] = source
+	c.mu.Unlock()
+	return path, nil
+}
+
+// keep stores the key at path once it has verified a signature. Keys from
+// a local file, the keyserver or the cache itself are left alone.
+func (c *keyCache) keep(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	source, ok := c.fetched[path]
+	delete(c.fetched, path)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := cache.PutKey(c.dir, source, path, time.Now()); err != nil {
+		_, _ = fmt.Fprintf(c.warn, "warning: key cache: %v\n", err) //nolint:errcheck
+	}
+}
+
+// retry reports whether the key at path was served from the cache, and
+// marks its source to be downloaded on the next fetch: the caller then
+// resolves and verifies again. The cache entry stays, so a source that now
+// serves another key is caught as a changed key.
+func (c *keyCache) retry(path string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	source, ok := c.reused[path]
+	delete(c.reused, path)
+	if ok {
+		c.retried[source] = true
+	}
+	return ok
+}
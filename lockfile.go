@@ -0,0 +1,158 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// lockfileVersion is the sfetch.lock format version written by --lockfile.
+const lockfileVersion = 1
+
+//go:embed schemas/lockfile.schema.json
+var lockfileSchemaJSON []byte
+
+// Lockfile pins release assets so later runs install byte-identical
+// binaries. --lockfile writes it; --from-lockfile replays it.
+type Lockfile struct {
+	Version int         `json:"version"`
+	Tools   []LockEntry `json:"tools"`
+}
+
+// LockEntry records one installed asset. Entries are keyed by repo and
+// platform, so one lockfile can pin the same tool for several targets.
+type LockEntry struct {
+	Repo       string `json:"repo"`
+	Platform   string `json:"platform"`
+	Tag        string `json:"tag"`
+	Asset      string `json:"asset"`
+	SHA256     string `json:"sha256"`
+	TrustScore int    `json:"trustScore"`
+	Workflow   string `json:"workflow,omitempty"`
+}
+
+// lockPlatform returns the goos/goarch key used for lockfile entries.
+func lockPlatform(goos, goarch string) string {
+	return goos + "/" + goarch
+}
+
+// loadLockfile reads and validates the lockfile at path against
+// schemas/lockfile.schema.json. A missing file is returned as an error
+// wrapping os.ErrNotExist.
+func loadLockfile(path string) (*Lockfile, error) {
+	// #nosec G304 -- path is the lockfile named on the command line
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read lockfile: %w", err)
+	}
+	return parseLockfile(path, data)
+}
+
+func parseLockfile(path string, data []byte) (*Lockfile, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse lockfile %s: %w", path, err)
+	}
+	schema, err := compileEmbeddedSchema("lockfile.schema.json", lockfileSchemaJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(doc); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("parse lockfile %s: %w", path, err)
+	}
+	return &lock, nil
+}
+
+// find returns the entry for repo on platform. Repo names compare
+// case-insensitively, as on GitHub.
+func (l *Lockfile) find(repo, platform string) (LockEntry, bool) {
+	for _, e := range l.Tools {
+		if strings.EqualFold(e.Repo, repo) && e.Platform == platform {
+			return e, true
+		}
+	}
+	return LockEntry{}, false
+}
+
+// upsert replaces the entry for e's repo and platform, or adds it, and keeps
+// the entries sorted so rewrites produce stable diffs.
+func (l *Lockfile) upsert(e LockEntry) {
+	replaced := false
+	for i, existing := range l.Tools {
+		if strings.EqualFold(existing.Repo, e.Repo) && existing.Platform == e.Platform {
+			l.Tools[i] = e
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		l.Tools = append(l.Tools, e)
+	}
+	sort.SliceStable(l.Tools, func(i, j int) bool {
+		a, b := strings.ToLower(l.Tools[i].Repo), strings.ToLower(l.Tools[j].Repo)
+		if a != b {
+			return a < b
+		}
+		return l.Tools[i].Platform < l.Tools[j].Platform
+	})
+}
+
+// updateLockfile records e in the lockfile at path, creating the file if it
+// does not exist yet.
+func updateLockfile(path string, e LockEntry) error {
+	lock, err := loadLockfile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		lock, err = &Lockfile{Version: lockfileVersion}, nil
+	}
+	if err != nil {
+		return err
+	}
+	lock.upsert(e)
+	return writeLockfile(path, lock)
+}
+
+// writeLockfile writes lock to path through a temporary file and rename, so
+// an interrupted run never leaves a truncated lockfile behind.
+func writeLockfile(path string, lock *Lockfile) error {
+	if lock.Tools == nil {
+		lock.Tools = []LockEntry{}
+	}
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode lockfile: %w", err)
+	}
+	data = append(data, '\n')
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create lockfile: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write lockfile: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write lockfile: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil { // #nosec G302 -- lockfiles are meant to be committed and shared
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write lockfile: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil { // #nosec G703 -- lockfile path named on the command line
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write lockfile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verbosity levels: --quiet (--log-level error), --log-level warn, the
+// default (info), -v and -vv (--log-level debug).
+const (
+	verbosityQuiet   = -2
+	verbosityWarn    = -1
+	verbosityNormal  = 0
+	verbosityVerbose = 1
+	verbosityDebug   = 2
+)
+
+// logLevels maps --log-level values onto verbosity levels.
+var logLevels = map[string]int{
+	"error": verbosityQuiet,
+	"warn":  verbosityWarn,
+	"info":  verbosityNormal,
+	"debug": verbosityDebug,
+}
+
+func parseLogLevel(s string) (int, error) {
+	level, ok := logLevels[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("invalid --log-level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// outputLog routes run's human-readable stderr output by verbosity. Each
+// writer is stderr when its level is enabled and io.Discard otherwise, so
+// call sites keep using fmt.Fprintf.
+type outputLog struct {
+	// Error is always stderr; helpers that take an outputLog report
+	// failures there.
+	Error io.Writer
+	// Warn carries warnings and next-step hints; --quiet silences it.
+	Warn io.Writer
+	// Info carries progress and results; --quiet, --log-level warn and
+	// --json silence it.
+	Info io.Writer
+	// Verbose carries routine confirmations ("Checksum verified OK",
+	// "Cached to ..."), requested URLs and key resolution (-v).
+	Verbose io.Writer
+	// Debug carries per-request HTTP status, size and timing, and asset
+	// selection and checksum/signature discovery decisions (-vv).
+	Debug io.Writer
+}
+
+func newOutputLog(stderr io.Writer, verbosity int, jsonOut bool) outputLog {
+	at := func(level int) io.Writer {
+		if verbosity >= level {
+			return stderr
+		}
+		return io.Discard
+	}
+	log := outputLog{
+		Error:   stderr,
+		Warn:    at(verbosityWarn),
+		Info:    at(verbosityNormal),
+		Verbose: at(verbosityVerbose),
+		Debug:   at(verbosityDebug),
+	}
+	if jsonOut {
+		log.Info = io.Discard
+	}
+	return log
+}
+
+// jsonLog renders --log-format json: each message written to one of its
+// writers becomes a {"time","level","msg"} object on its own stderr line.
+// A message is one Write call, so multi-line output such as a selection
+// failure stays one record.
+type jsonLog struct {
+	mu  sync.Mutex
+	out io.Writer
+	now func() time.Time // nil: time.Now
+}
+
+// logPrefixes are the message prefixes run writes by hand; the JSON log
+// drops them from msg and, for raw stderr writes, takes the level from them.
+var logPrefixes = []struct{ prefix, level string }{
+	{"error: ", "error"},
+	{"warning: ", "warn"},
+	{"WARNING: ", "warn"},
+	{"note: ", "info"},
+}
+
+// jsonLogWriter writes messages at level; an empty level means raw stderr
+// output, classified by prefix and otherwise logged as info.
+type jsonLogWriter struct {
+	log   *jsonLog
+	level string
+}
+
+func (w jsonLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	if strings.TrimSpace(msg) == "" {
+		return len(p), nil
+	}
+	level := w.level
+	for _, lp := range logPrefixes {
+		if strings.HasPrefix(msg, lp.prefix) {
+			msg = strings.TrimPrefix(msg, lp.prefix)
+			if level == "" {
+				level = lp.level
+			}
+			break
+		}
+	}
+	if level == "" {
+		level = "info"
+	}
+	now := time.Now
+	if w.log.now != nil {
+		now = w.log.now
+	}
+	line, err := json.Marshal(struct {
+		Time  string `json:"time"`
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}{now().UTC().Format(time.RFC3339), level, msg})
+	if err != nil {
+		return 0, err
+	}
+	w.log.mu.Lock()
+	defer w.log.mu.Unlock()
+	if _, err := w.log.out.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writer returns a writer for level, or for raw stderr output when level
+// is empty.
+func (j *jsonLog) writer(level string) io.Writer {
+	return jsonLogWriter{log: j, level: level}
+}
+
+// wrap routes the enabled writers of l through j; disabled ones stay
+// io.Discard.
+func (j *jsonLog) wrap(l outputLog) outputLog {
+	at := func(w io.Writer, level string) io.Writer {
+		if w == io.Discard {
+			return w
+		}
+		return j.writer(level)
+	}
+	return outputLog{
+		Error:   at(l.Error, "error"),
+		Warn:    at(l.Warn, "warn"),
+		Info:    at(l.Info, "info"),
+		Verbose: at(l.Verbose, "debug"),
+		Debug:   at(l.Debug, "debug"),
+	}
+}
+
+var (
+	debugLogMu sync.Mutex
+	debugLog   io.Writer = io.Discard
+)
+
+// setDebugLog routes the decision traces of asset selection and
+// checksum/signature discovery, which run deep inside helpers that take
+// no outputLog. nil discards them.
+func setDebugLog(w io.Writer) {
+	debugLogMu.Lock()
+	defer debugLogMu.Unlock()
+	if w == nil {
+		w = io.Discard
+	}
+	debugLog = w
+}
+
+// debugf writes one decision trace line to the debug log.
+func debugf(format string, args ...any) {
+	debugLogMu.Lock()
+	defer debugLogMu.Unlock()
+	if debugLog == io.Discard {
+		return
+	}
+	_, _ = fmt.Fprintf(debugLog, format+"\n", args...) //nolint:errcheck
+}
+
+// verbosityFlag is a repeatable boolean flag: each -v raises the level by
+// step. It implements flag.Value with IsBoolFlag, so "-v -v" works.
+type verbosityFlag struct {
+	level *int
+	step  int
+}
+
+func (f verbosityFlag) String() string {
+	if f.level == nil || *f.level == 0 {
+		return ""
+	}
+	return strconv.Itoa(*f.level)
+}
+
+func (f verbosityFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if on {
+		*f.level += f.step
+	}
+	return nil
+}
+
+func (f verbosityFlag) IsBoolFlag() bool { return true }
+
+// logTransport writes each outbound request to log.Verbose and its outcome
+// to log.Debug. URLs are shown without their query, which carries the
+// credentials of signed download URLs.
+type logTransport struct {
+	next http.RoundTripper
+	log  outputLog
+}
+
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	display := *req.URL
+	display.RawQuery = ""
+	_, _ = fmt.Fprintf(t.log.Verbose, "%s %s\n", req.Method, display.Redacted()) //nolint:errcheck
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		_, _ = fmt.Fprintf(t.log.Debug, "  failed after %s: %v\n", elapsed, err) //nolint:errcheck
+		return resp, err
+	}
+	size := "unknown size"
+	if resp.ContentLength >= 0 {
+		size = fmt.Sprintf("%d bytes", resp.ContentLength)
+	}
+	_, _ = fmt.Fprintf(t.log.Debug, "  %s, %s, %s\n", resp.Status, size, elapsed) //nolint:errcheck
+	return resp, nil
+}
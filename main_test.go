@@ -1,24 +1,50 @@
 package main
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/ed25519"
+	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"hash"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
-
+	"time"
+	"unicode/utf8"
+
+	"github.com/3leaps/sfetch/internal/cache"
+	gh "github.com/3leaps/sfetch/internal/host/github"
+	"github.com/3leaps/sfetch/internal/receipt"
+	"github.com/3leaps/sfetch/internal/selfupdate"
+	"github.com/3leaps/sfetch/pkg/update"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
@@ -61,7 +87,7 @@ func TestInstallFileRawChmodOnRename(t *testing.T) {
 	}
 
 	cls := AssetClassification{Type: AssetTypeRaw, NeedsChmod: true}
-	installed, err := installFileWithRename(src, dst, cls, false, os.Rename)
+	installed, err := installFileWithRename(src, dst, cls, installOptions{}, os.Rename)
 	if err != nil {
 		t.Fatalf("installFileWithRename: %v", err)
 	}
@@ -93,7 +119,7 @@ func TestInstallFileRawChmodOnCopyFallback(t *testing.T) {
 
 	cls := AssetClassification{Type: AssetTypeRaw, NeedsChmod: true}
 	rename := func(oldPath, newPath string) error { return syscall.EXDEV }
-	installed, err := installFileWithRename(src, dst, cls, false, rename)
+	installed, err := installFileWithRename(src, dst, cls, installOptions{}, rename)
 	if err != nil {
 		t.Fatalf("installFileWithRename: %v", err)
 	}
@@ -125,7 +151,7 @@ func TestInstallFileCopyFallbackPreservesPermsWhenNoChmodNeeded(t *testing.T) {
 
 	cls := AssetClassification{Type: AssetTypeRaw, NeedsChmod: false}
 	rename := func(oldPath, newPath string) error { return syscall.EXDEV }
-	if _, err := installFileWithRename(src, dst, cls, false, rename); err != nil {
+	if _, err := installFileWithRename(src, dst, cls, installOptions{}, rename); err != nil {
 		t.Fatalf("installFileWithRename: %v", err)
 	}
 
@@ -138,6 +164,72 @@ func TestInstallFileCopyFallbackPreservesPermsWhenNoChmodNeeded(t *testing.T) {
 	}
 }
 
+func TestInstallIfChanged(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		existing      []byte // nil: no file at dst
+		force         bool
+		wantUnchanged bool
+	}{
+		{name: "identical content", existing: []byte("tool v1"), wantUnchanged: true},
+		{name: "differing content", existing: []byte("tool v0")},
+		{name: "same size differing content", existing: []byte("tool v2")},
+		{name: "missing destination"},
+		{name: "identical with --force-install", existing: []byte("tool v1"), force: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src")
+			dst := filepath.Join(dir, "tool")
+			if err := os.WriteFile(src, []byte("tool v1"), 0o755); err != nil {
+				t.Fatalf("write src: %v", err)
+			}
+			var before time.Time
+			if tt.existing != nil {
+				if err := os.WriteFile(dst, tt.existing, 0o755); err != nil {
+					t.Fatalf("write dst: %v", err)
+				}
+				before = time.Now().Add(-time.Hour)
+				if err := os.Chtimes(dst, before, before); err != nil {
+					t.Fatalf("chtimes: %v", err)
+				}
+			}
+
+			cls := AssetClassification{Type: AssetTypeRaw, NeedsChmod: true}
+			path, unchanged, err := installIfChanged(src, dst, cls, installOptions{keepBackup: true, force: tt.force})
+			if err != nil {
+				t.Fatalf("installIfChanged: %v", err)
+			}
+			if path != dst || unchanged != tt.wantUnchanged {
+				t.Fatalf("got (%q, %v), want (%q, %v)", path, unchanged, dst, tt.wantUnchanged)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil || string(got) != "tool v1" {
+				t.Fatalf("dst = %q, %v", got, err)
+			}
+			_, bakErr := os.Stat(dst + ".bak")
+			if tt.wantUnchanged {
+				info, err := os.Stat(dst)
+				if err != nil || !info.ModTime().Equal(before) {
+					t.Fatalf("identical dst was touched: %v", err)
+				}
+				if bakErr == nil {
+					t.Fatal("identical dst left a .bak")
+				}
+				if _, err := os.Stat(src); err != nil {
+					t.Fatalf("src consumed although nothing was installed: %v", err)
+				}
+			} else if tt.existing != nil && bakErr != nil {
+				t.Fatalf("replaced dst has no .bak: %v", bakErr)
+			}
+		})
+	}
+}
+
 func TestInstallFileArchivePreservesExecOnRename(t *testing.T) {
 	t.Parallel()
 
@@ -154,7 +246,7 @@ func TestInstallFileArchivePreservesExecOnRename(t *testing.T) {
 	}
 
 	cls := AssetClassification{Type: AssetTypeArchive}
-	installed, err := installFileWithRename(src, dst, cls, false, os.Rename)
+	installed, err := installFileWithRename(src, dst, cls, installOptions{}, os.Rename)
 	if err != nil {
 		t.Fatalf("installFileWithRename: %v", err)
 	}
@@ -186,7 +278,7 @@ func TestInstallFileArchivePreservesExecOnCopyFallback(t *testing.T) {
 
 	cls := AssetClassification{Type: AssetTypeArchive}
 	rename := func(oldPath, newPath string) error { return syscall.EXDEV }
-	installed, err := installFileWithRename(src, dst, cls, false, rename)
+	installed, err := installFileWithRename(src, dst, cls, installOptions{}, rename)
 	if err != nil {
 		t.Fatalf("installFileWithRename: %v", err)
 	}
@@ -222,7 +314,7 @@ func TestInstallFileWithRename_CrossDeviceNonEXDEV(t *testing.T) {
 	}
 
 	cls := AssetClassification{Type: AssetTypeRaw, NeedsChmod: true}
-	installed, err := installFileWithRename(src, dst, cls, false, rename)
+	installed, err := installFileWithRename(src, dst, cls, installOptions{}, rename)
 	if err != nil {
 		t.Fatalf("installFileWithRename: %v", err)
 	}
@@ -239,6 +331,247 @@ func TestInstallFileWithRename_CrossDeviceNonEXDEV(t *testing.T) {
 	}
 }
 
+func TestInstallFileBackup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		existing   bool
+		keepBackup bool
+		wantBackup bool
+	}{
+		{name: "fresh install", existing: false, keepBackup: true, wantBackup: false},
+		{name: "replace without --backup", existing: true, keepBackup: false, wantBackup: false},
+		{name: "replace with --backup", existing: true, keepBackup: true, wantBackup: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src")
+			dst := filepath.Join(dir, "tool")
+			if err := os.WriteFile(src, []byte("new"), 0o755); err != nil {
+				t.Fatalf("write src: %v", err)
+			}
+			if tt.existing {
+				if err := os.WriteFile(dst, []byte("old"), 0o755); err != nil {
+					t.Fatalf("write dst: %v", err)
+				}
+			}
+
+			cls := AssetClassification{Type: AssetTypeArchive}
+			if _, err := installFileWithRename(src, dst, cls, installOptions{keepBackup: tt.keepBackup}, os.Rename); err != nil {
+				t.Fatalf("installFileWithRename: %v", err)
+			}
+			if got, _ := os.ReadFile(dst); string(got) != "new" {
+				t.Fatalf("dst content: got %q want %q", got, "new")
+			}
+			if _, err := os.Stat(dst + ".new"); !os.IsNotExist(err) {
+				t.Fatalf("staging file left behind: %v", err)
+			}
+			backup, err := os.ReadFile(dst + ".bak")
+			if tt.wantBackup {
+				if err != nil || string(backup) != "old" {
+					t.Fatalf("backup: got %q, %v; want %q", backup, err, "old")
+				}
+			} else if !os.IsNotExist(err) {
+				t.Fatalf("expected no backup, stat err=%v", err)
+			}
+		})
+	}
+}
+
+func TestInstallFileLeavesDestinationOnFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "tool")
+	if err := os.WriteFile(src, []byte("new"), 0o755); err != nil {
+		t.Fatalf("write src: %v", err)
+	}
+	// A non-empty directory cannot be replaced by a rename.
+	if err := os.MkdirAll(filepath.Join(dst, "keep"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	cls := AssetClassification{Type: AssetTypeArchive}
+	if _, err := installFileWithRename(src, dst, cls, installOptions{}, os.Rename); err == nil {
+		t.Fatal("expected install over a directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "keep")); err != nil {
+		t.Fatalf("destination was disturbed: %v", err)
+	}
+	if _, err := os.Stat(dst + ".new"); !os.IsNotExist(err) {
+		t.Fatalf("staging file left behind: %v", err)
+	}
+}
+
+func TestReplaceInstalledRestoresBackup(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "tool")
+	if err := os.WriteFile(dst, []byte("old"), 0o755); err != nil {
+		t.Fatalf("write dst: %v", err)
+	}
+	hasBackup, err := backupInstalled(dst, dst+".bak")
+	if err != nil || !hasBackup {
+		t.Fatalf("backupInstalled = %v, %v", hasBackup, err)
+	}
+	// The staged file is missing, so both rename attempts fail.
+	if err := replaceInstalled(dst+".new", dst, dst+".bak", true); err == nil {
+		t.Fatal("expected replaceInstalled to fail")
+	}
+	if got, err := os.ReadFile(dst); err != nil || string(got) != "old" {
+		t.Fatalf("dst after failed replace: %q, %v; want %q", got, err, "old")
+	}
+}
+
+func TestRollbackInstall(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "tool")
+	if err := rollbackInstall(dst); err == nil || !strings.Contains(err.Error(), "no backup") {
+		t.Fatalf("rollback without backup: %v", err)
+	}
+
+	if err := os.WriteFile(dst, []byte("v2"), 0o755); err != nil {
+		t.Fatalf("write dst: %v", err)
+	}
+	if err := os.WriteFile(dst+".bak", []byte("v1"), 0o755); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	for _, want := range []string{"v1", "v2"} {
+		if err := rollbackInstall(dst); err != nil {
+			t.Fatalf("rollbackInstall: %v", err)
+		}
+		if got, _ := os.ReadFile(dst); string(got) != want {
+			t.Fatalf("dst after rollback: got %q want %q", got, want)
+		}
+	}
+	if _, err := os.Stat(dst + ".rollback"); !os.IsNotExist(err) {
+		t.Fatalf("rollback temp file left behind: %v", err)
+	}
+}
+
+func TestRunRollback(t *testing.T) {
+	t.Parallel()
+
+	destDir := t.TempDir()
+	dst := filepath.Join(destDir, "bar")
+	if err := os.WriteFile(dst, []byte("v2"), 0o755); err != nil {
+		t.Fatalf("write dst: %v", err)
+	}
+	if err := os.WriteFile(dst+".bak", []byte("v1"), 0o755); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"--rollback", "--repo", "foo/bar", "--dest-dir", destDir, "--skip-tools-check"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", code, exitOK, stderr.String())
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "v1" {
+		t.Fatalf("dst after rollback: got %q want %q", got, "v1")
+	}
+	if !strings.Contains(stderr.String(), "Rolled back "+dst) {
+		t.Fatalf("stderr: %q", stderr.String())
+	}
+}
+
+func TestRollbackPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		output  string
+		destDir string
+		bin     string
+		want    string
+		wantErr bool
+	}{
+		{name: "output file", output: filepath.Join(dir, "tool"), want: filepath.Join(dir, "tool")},
+		{name: "output directory", output: dir, bin: "tool", want: filepath.Join(dir, "tool")},
+		{name: "output directory without name", output: dir, wantErr: true},
+		{name: "dest-dir", destDir: dir, bin: "tool", want: filepath.Join(dir, "tool")},
+		{name: "dest-dir without name", destDir: dir, wantErr: true},
+		{name: "nothing", bin: "tool", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rollbackPath(tt.output, tt.destDir, tt.bin, "linux")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rollbackPath error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("rollbackPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockfileRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sfetch.lock")
+	sha := strings.Repeat("ab", 32)
+	entries := []LockEntry{
+		{Repo: "zeta/tool", Platform: "linux/amd64", Tag: "v1.0.0", Asset: "tool_linux_amd64.tar.gz", SHA256: sha, TrustScore: 80, Workflow: "A"},
+		{Repo: "alpha/tool", Platform: "linux/arm64", Tag: "v2.0.0", Asset: "tool_linux_arm64.tar.gz", SHA256: sha},
+		{Repo: "alpha/tool", Platform: "darwin/arm64", Tag: "v2.0.0", Asset: "tool_darwin_arm64.tar.gz", SHA256: sha},
+		// Same repo and platform as the first entry: replaces it.
+		{Repo: "Zeta/Tool", Platform: "linux/amd64", Tag: "v1.1.0", Asset: "tool_linux_amd64.tar.gz", SHA256: sha, TrustScore: 90, Workflow: "A"},
+	}
+	for _, e := range entries {
+		if err := updateLockfile(path, e); err != nil {
+			t.Fatalf("updateLockfile: %v", err)
+		}
+	}
+
+	lock, err := loadLockfile(path)
+	if err != nil {
+		t.Fatalf("loadLockfile: %v", err)
+	}
+	want := []LockEntry{entries[2], entries[1], entries[3]}
+	if !reflect.DeepEqual(lock.Tools, want) {
+		t.Fatalf("tools = %+v, want %+v", lock.Tools, want)
+	}
+	if lock.Version != lockfileVersion {
+		t.Fatalf("version = %d, want %d", lock.Version, lockfileVersion)
+	}
+	if e, ok := lock.find("zeta/tool", "linux/amd64"); !ok || e.Tag != "v1.1.0" {
+		t.Fatalf("find zeta/tool = %+v, %v", e, ok)
+	}
+	if _, ok := lock.find("zeta/tool", "darwin/arm64"); ok {
+		t.Fatal("find matched an entry for another platform")
+	}
+}
+
+func TestParseLockfileRejectsInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not json", data: "{"},
+		{name: "missing tools", data: `{"version":1}`},
+		{name: "unknown version", data: `{"version":2,"tools":[]}`},
+		{name: "short sha256", data: `{"version":1,"tools":[{"repo":"a/b","platform":"linux/amd64","tag":"v1","asset":"x","sha256":"abc"}]}`},
+		{name: "bad repo", data: `{"version":1,"tools":[{"repo":"ab","platform":"linux/amd64","tag":"v1","asset":"x","sha256":"` + strings.Repeat("0", 64) + `"}]}`},
+		{name: "unknown field", data: `{"version":1,"tools":[],"extra":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseLockfile("sfetch.lock", []byte(tt.data)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
 func TestMoveOrCopy_RenameFails(t *testing.T) {
 	t.Parallel()
 
@@ -491,6 +824,55 @@ func TestExtractZipRejectsSymlinks(t *testing.T) {
 	}
 }
 
+func TestExtractZipBackslashPaths(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		entry    string
+		wantErr  bool
+		wantPath string // relative to the extraction dir, slash-separated
+	}{
+		{name: "backslash traversal", entry: `..\evil`, wantErr: true},
+		{name: "nested backslash traversal", entry: `bin\..\..\evil`, wantErr: true},
+		{name: "mixed separators", entry: `bin/..\../evil`, wantErr: true},
+		{name: "rooted backslash", entry: `\evil`, wantErr: true},
+		{name: "drive letter", entry: `C:\evil`, wantErr: true},
+		{name: "drive-relative", entry: `c:evil`, wantErr: true},
+		{name: "windows directory separators", entry: `bin\tool`, wantPath: "bin/tool"},
+		{name: "dot segments inside", entry: `bin\.\sub\..\tool`, wantPath: "bin/tool"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tmp := t.TempDir()
+			zipPath := filepath.Join(tmp, "windows.zip")
+			extractDir := filepath.Join(tmp, "extract")
+			if err := os.Mkdir(extractDir, 0o755); err != nil {
+				t.Fatalf("mkdir extractDir: %v", err)
+			}
+			writeTestArchive(t, zipPath, ArchiveFormatZip, []testArchiveFile{{name: tc.entry, mode: 0o755}})
+
+			err := extractZip(zipPath, extractDir)
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "zip slip") {
+					t.Fatalf("extractZip(%q) error = %v, want zip slip", tc.entry, err)
+				}
+				if _, err := os.Stat(filepath.Join(tmp, "evil")); err == nil {
+					t.Fatalf("%q wrote outside the extraction dir", tc.entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractZip(%q): %v", tc.entry, err)
+			}
+			if _, err := os.Stat(filepath.Join(extractDir, filepath.FromSlash(tc.wantPath))); err != nil {
+				t.Fatalf("%q not extracted to %s: %v", tc.entry, tc.wantPath, err)
+			}
+		})
+	}
+}
+
 func TestExtractZipNestedPaths(t *testing.T) {
 	t.Parallel()
 
@@ -585,115 +967,650 @@ func TestResolveArchiveBinaryPathMissing(t *testing.T) {
 	}
 }
 
-func TestHelpExtendedAlias(t *testing.T) {
+func TestResolveArchiveBinaryPathNested(t *testing.T) {
 	t.Parallel()
 
-	tests := []string{"-helpextended", "-help-extended", "--help-extended"}
-	for _, arg := range tests {
-		t.Run(arg, func(t *testing.T) {
-			var stdout, stderr strings.Builder
-			code := run([]string{arg}, &stdout, &stderr)
-			if code != 0 {
-				t.Fatalf("exit code: got %d, stderr=%q", code, stderr.String())
+	tmp := t.TempDir()
+	for _, name := range []string{
+		"tool-1.2.3/bin/tool",
+		"tool-1.2.3/bin/toolctl",
+		"tool-1.2.3/share/tool/tool",
+		"tool-1.2.3/README.md",
+		"other/bin/helper",
+		"dup/a/dupe",
+		"dup/b/dupe",
+	} {
+		p := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("bin"), 0o755); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		binaryName string
+		want       string
+		wantErr    string
+	}{
+		{name: "shallowest match wins", binaryName: "tool", want: "tool-1.2.3/bin/tool"},
+		{name: "nested path", binaryName: "bin/toolctl", want: "tool-1.2.3/bin/toolctl"},
+		{name: "glob", binaryName: "bin/tool*", wantErr: "matches 2 files in archive: tool-1.2.3/bin/tool, tool-1.2.3/bin/toolctl"},
+		{name: "glob single match", binaryName: "other/bin/*", want: "other/bin/helper"},
+		{name: "tie at same depth", binaryName: "dupe", wantErr: "dup/a/dupe, dup/b/dupe"},
+		{name: "not found", binaryName: "missing", wantErr: "binary missing not found in archive"},
+		{name: "bad pattern", binaryName: "bin/[", wantErr: "invalid --binary-name pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveArchiveBinaryPath(tmp, tt.binaryName, "linux")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
 			}
-			// Help text goes to stderr (human-readable), stdout reserved for machine output
-			if !strings.Contains(stderr.String(), "sfetch quickstart") {
-				t.Fatalf("stderr missing quickstart header, got: %q", stderr.String())
+			if err != nil {
+				t.Fatalf("resolveArchiveBinaryPath: %v", err)
+			}
+			if want := filepath.Join(tmp, filepath.FromSlash(tt.want)); got != want {
+				t.Fatalf("path: got %q want %q", got, want)
 			}
 		})
 	}
 }
 
-func TestInstallFlagMutualExclusion(t *testing.T) {
-	t.Parallel()
+// testArchiveFile is one entry for writeTestArchive.
+type testArchiveFile struct {
+	name string
+	mode os.FileMode
+}
 
-	tests := []struct {
-		args []string
-		want string
-	}{
-		{args: []string{"--install", "--dest-dir", "/tmp", "--skip-tools-check"}, want: "mutually exclusive"},
-		{args: []string{"--install", "--output", "/tmp/tool", "--skip-tools-check"}, want: "mutually exclusive"},
-		{args: []string{"--install", "--self-update", "--skip-tools-check"}, want: "cannot be used with --self-update"},
+// writeTestArchive writes a tar.gz or zip archive holding files.
+func writeTestArchive(t *testing.T, archivePath string, format ArchiveFormat, files []testArchiveFile) {
+	t.Helper()
+	out, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("create archive: %v", err)
 	}
-	for _, tc := range tests {
-		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
-			var stdout, stderr strings.Builder
-			code := run(tc.args, &stdout, &stderr)
-			if code == 0 {
-				t.Fatalf("expected non-zero exit code; stdout=%q", stdout.String())
+	defer out.Close() //nolint:errcheck
+
+	switch format {
+	case ArchiveFormatZip:
+		zw := zip.NewWriter(out)
+		for _, f := range files {
+			hdr := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+			hdr.SetMode(f.mode)
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				t.Fatalf("zip header %s: %v", f.name, err)
 			}
-			if !strings.Contains(stderr.String(), tc.want) {
-				t.Fatalf("stderr mismatch: got %q want substring %q", stderr.String(), tc.want)
+			if _, err := w.Write([]byte(f.name)); err != nil {
+				t.Fatalf("zip write %s: %v", f.name, err)
 			}
-		})
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("close zip: %v", err)
+		}
+	default:
+		gz := gzip.NewWriter(out)
+		tw := tar.NewWriter(gz)
+		for _, f := range files {
+			if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: int64(f.mode), Size: int64(len(f.name)), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatalf("tar header %s: %v", f.name, err)
+			}
+			if _, err := tw.Write([]byte(f.name)); err != nil {
+				t.Fatalf("tar write %s: %v", f.name, err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("close tar: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("close gzip: %v", err)
+		}
 	}
 }
 
-// Pass 3: CLI flag validation tests
-func TestCLIFlagValidation(t *testing.T) {
+func TestResolveArchiveBinaryPathDiscovery(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
 		name       string
-		args       []string
-		wantCode   int
-		wantStderr string // substring to match in stderr
+		files      []testArchiveFile
+		binaryName string
+		want       string
+		wantErr    string
 	}{
 		{
-			name:       "insecure and require-minisign conflict",
-			args:       []string{"--repo", "foo/bar", "--insecure", "--require-minisign", "--skip-tools-check"},
-			wantCode:   1,
-			wantStderr: "mutually exclusive",
-		},
-		{
-			name:       "tag and latest conflict",
-			args:       []string{"--repo", "foo/bar", "--tag", "v1.0.0", "--latest", "--skip-tools-check"},
-			wantCode:   1,
-			wantStderr: "mutually exclusive",
+			name:       "exact name in versioned directory",
+			files:      []testArchiveFile{{"tool-1.2.3-linux-amd64/tool", 0o755}, {"tool-1.2.3-linux-amd64/LICENSE", 0o644}},
+			binaryName: "tool",
+			want:       "tool-1.2.3-linux-amd64/tool",
 		},
 		{
-			name:       "repo required",
-			args:       []string{"--skip-tools-check"},
-			wantCode:   1,
-			wantStderr: "--repo is required",
+			name:       "platform suffix",
+			files:      []testArchiveFile{{"tool_linux_amd64", 0o755}, {"README.md", 0o644}, {"LICENSE", 0o644}},
+			binaryName: "tool",
+			want:       "tool_linux_amd64",
 		},
 		{
-			name:       "github-raw bypasses repo requirement",
-			args:       []string{"--github-raw", "foo/bar@main:install.sh", "--dry-run", "--skip-tools-check"},
-			wantCode:   0,
-			wantStderr: "sfetch dry-run assessment",
+			name:       "platform suffix nested beside another executable",
+			files:      []testArchiveFile{{"dist/tool-x86_64-unknown-linux-musl", 0o755}, {"dist/install.sh", 0o755}},
+			binaryName: "tool",
+			want:       "dist/tool-x86_64-unknown-linux-musl",
 		},
 		{
-			name:       "url bypasses repo requirement",
-			args:       []string{"--url", "https://example.com/", "--dry-run", "--skip-tools-check"},
-			wantCode:   0,
-			wantStderr: "sfetch dry-run assessment",
+			name:       "single executable fallback",
+			files:      []testArchiveFile{{"release/td", 0o755}, {"release/._td", 0o755}, {"release/docs/td.1", 0o644}, {"release/COPYING", 0o644}},
+			binaryName: "tool",
+			want:       "release/td",
 		},
 		{
-			name:       "positional url",
-			args:       []string{"https://example.com/", "--dry-run", "--skip-tools-check"},
-			wantCode:   0,
-			wantStderr: "sfetch dry-run assessment",
+			name:       "extensionless file without execute bit",
+			files:      []testArchiveFile{{"pkg/tool-v2.0.1", 0o644}, {"pkg/notes.txt", 0o644}},
+			binaryName: "tool",
+			want:       "pkg/tool-v2.0.1",
 		},
 		{
-			name:       "url conflicts with repo",
-			args:       []string{"--url", "https://example.com/", "--repo", "foo/bar", "--dry-run", "--skip-tools-check"},
-			wantCode:   1,
-			wantStderr: "--url is mutually exclusive",
+			name:       "ambiguous candidates",
+			files:      []testArchiveFile{{"bin/alpha", 0o755}, {"bin/beta", 0o755}},
+			binaryName: "tool",
+			wantErr:    "binary tool not found in archive; candidates: bin/alpha, bin/beta; pass one of them with --binary-name",
 		},
 		{
-			name:       "unknown flag",
-			args:       []string{"--nonexistent-flag"},
-			wantCode:   2,
-			wantStderr: "flag provided but not defined",
+			name:       "ambiguous platform suffixes",
+			files:      []testArchiveFile{{"tool_linux_amd64", 0o755}, {"tool_linux_arm64", 0o755}},
+			binaryName: "tool",
+			wantErr:    "2 platform-specific candidates: tool_linux_amd64, tool_linux_arm64",
 		},
 		{
-			name:       "help flag returns success",
-			args:       []string{"--help"},
-			wantCode:   0,
-			wantStderr: "", // help goes to stdout
+			name:       "no candidates",
+			files:      []testArchiveFile{{"docs/guide.md", 0o644}},
+			binaryName: "tool",
+			wantErr:    "binary tool not found in archive",
 		},
-		{
+	}
+	for _, tt := range tests {
+		for _, format := range []ArchiveFormat{ArchiveFormatTarGz, ArchiveFormatZip} {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				t.Parallel()
+
+				tmp := t.TempDir()
+				archivePath := filepath.Join(tmp, "asset."+string(format))
+				writeTestArchive(t, archivePath, format, tt.files)
+				extractDir, err := extractReleaseArchive(archivePath, format, tmp)
+				if err != nil {
+					t.Fatalf("extractReleaseArchive: %v", err)
+				}
+
+				got, err := resolveArchiveBinaryPath(extractDir, tt.binaryName, "linux")
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("resolveArchiveBinaryPath: %v", err)
+				}
+				if member := archiveMember(extractDir, got); member != tt.want {
+					t.Fatalf("member = %q, want %q", member, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestSelectionBinaryName(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"tool":        "tool",
+		"bin/tool":    "tool",
+		"bin/*":       "",
+		"tool-[0-9]*": "",
+	} {
+		if got := selectionBinaryName(in); got != want {
+			t.Errorf("selectionBinaryName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "existing")
+	if err := os.WriteFile(file, []byte("old"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{output: dir, want: filepath.Join(dir, "tool")},
+		{output: file, want: file},
+		{output: filepath.Join(dir, "new-name"), want: filepath.Join(dir, "new-name")},
+	}
+	for _, tt := range tests {
+		if got := outputPath(tt.output, "tool"); got != tt.want {
+			t.Errorf("outputPath(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestHelpExtendedAlias(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{"-helpextended", "-help-extended", "--help-extended"}
+	for _, arg := range tests {
+		t.Run(arg, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			code := run([]string{arg}, &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("exit code: got %d, stderr=%q", code, stderr.String())
+			}
+			// Help text goes to stderr (human-readable), stdout reserved for machine output
+			if !strings.Contains(stderr.String(), "sfetch quickstart") {
+				t.Fatalf("stderr missing quickstart header, got: %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestInstallFlagMutualExclusion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--install", "--dest-dir", "/tmp", "--skip-tools-check"}, want: "mutually exclusive"},
+		{args: []string{"--install", "--output", "/tmp/tool", "--skip-tools-check"}, want: "mutually exclusive"},
+		{args: []string{"--install", "--self-update", "--skip-tools-check"}, want: "cannot be used with --self-update"},
+	}
+	for _, tc := range tests {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			code := run(tc.args, &stdout, &stderr)
+			if code == 0 {
+				t.Fatalf("expected non-zero exit code; stdout=%q", stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tc.want) {
+				t.Fatalf("stderr mismatch: got %q want substring %q", stderr.String(), tc.want)
+			}
+		})
+	}
+}
+
+// Pass 3: CLI flag validation tests
+func TestCLIFlagValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string // substring to match in stderr
+	}{
+		{
+			name:       "insecure and require-minisign conflict",
+			args:       []string{"--repo", "foo/bar", "--insecure", "--require-minisign", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "mutually exclusive",
+		},
+		{
+			name:       "verify-only with dest-dir",
+			args:       []string{"--repo", "foo/bar", "--verify-only", "main.go", "--dest-dir", "/tmp", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--verify-only never installs",
+		},
+		{
+			name:       "verify-only with url",
+			args:       []string{"--url", "https://example.com/tool", "--verify-only", "main.go", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--verify-only checks a local file against a --repo release",
+		},
+		{
+			name:       "verify-only missing file",
+			args:       []string{"--repo", "foo/bar", "--verify-only", "testdata/does-not-exist.tar.gz", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "error: --verify-only:",
+		},
+		{
+			name:       "verify-only directory",
+			args:       []string{"--repo", "foo/bar", "--verify-only", "testdata", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "is not a regular file",
+		},
+		{
+			name:       "checksum-url without verify-only",
+			args:       []string{"--repo", "foo/bar", "--checksum-url", "https://example.com/SHA256SUMS", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--checksum-url and --sig-url require --verify-only",
+		},
+		{
+			name:       "insecure and require-signature conflict",
+			args:       []string{"--repo", "foo/bar", "--insecure", "--require-signature", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--require-signature cannot be combined with --insecure or --skip-sig",
+		},
+		{
+			name:       "skip-sig and require-signature conflict",
+			args:       []string{"--repo", "foo/bar", "--skip-sig", "--require-signature", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--require-signature cannot be combined with --insecure or --skip-sig",
+		},
+		{
+			name:       "insecure and strict-keys conflict",
+			args:       []string{"--repo", "foo/bar", "--insecure", "--strict-keys", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--strict-keys cannot be combined with --insecure or --skip-sig",
+		},
+		{
+			name:       "skip-sig and strict-keys conflict",
+			args:       []string{"--repo", "foo/bar", "--skip-sig", "--strict-keys", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--strict-keys cannot be combined with --insecure or --skip-sig",
+		},
+		{
+			name:       "on-missing-key unknown policy",
+			args:       []string{"--repo", "foo/bar", "--on-missing-key", "skip", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--on-missing-key must be fail or fallback-checksum",
+		},
+		{
+			name:       "on-missing-key fallback with require-minisign",
+			args:       []string{"--repo", "foo/bar", "--on-missing-key", "fallback-checksum", "--require-minisign", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "cannot be combined with --require-minisign, --require-signature or --strict-keys",
+		},
+		{
+			name:       "on-missing-key fallback with key pin",
+			args:       []string{"--repo", "foo/bar", "--on-missing-key", "fallback-checksum", "--pin-pgp-fingerprint", "B81686FEE118A91CE11970EFE9D7CD5DDC61A7DB", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "cannot be combined with --pin-minisign-key or --pin-pgp-fingerprint",
+		},
+		{
+			name:       "offline with url",
+			args:       []string{"--url", "https://example.com/tool", "--offline", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--url and --github-raw always download",
+		},
+		{
+			name:       "offline with github-raw",
+			args:       []string{"--github-raw", "foo/bar@main:install.sh", "--offline", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--url and --github-raw always download",
+		},
+		{
+			name:       "malformed expect-sha256 with url",
+			args:       []string{"--url", "https://example.com/tool", "--expect-sha256", "zz", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--expect-sha256 must be 64 hex characters",
+		},
+		{
+			name:       "key pin with github-raw",
+			args:       []string{"--github-raw", "foo/bar@main:install.sh", "--pin-minisign-key", "RWQofujyCgZE45KW4wjPCDP6M/KdG9WDzwSWU6TjnCb3DpsEMOrUt4KX", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "cannot be combined with --github-raw: raw repository files are never signed",
+		},
+		{
+			name:       "key pin with raw url",
+			args:       []string{"--url", "https://raw.githubusercontent.com/foo/bar/main/install.sh", "--pin-pgp-fingerprint", "B81686FEE118A91CE11970EFE9D7CD5DDC61A7DB", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "is a raw repository file, which is never signed",
+		},
+		{
+			name:       "on-missing-key fallback with url",
+			args:       []string{"--url", "https://example.com/tool", "--on-missing-key", "fallback-checksum", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--on-missing-key applies to --repo releases",
+		},
+		{
+			name:       "provenance-next-to-binary with provenance-file",
+			args:       []string{"--repo", "foo/bar", "--provenance-next-to-binary", "--provenance-file", "prov.json", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--provenance-next-to-binary and --provenance-file are mutually exclusive",
+		},
+		{
+			name:       "intoto provenance for a dry-run",
+			args:       []string{"--repo", "foo/bar", "--dry-run", "--provenance", "--provenance-format", "intoto", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--provenance-format intoto needs the asset's digest",
+		},
+		{
+			name:       "slsa provenance for a url dry-run",
+			args:       []string{"--url", "https://example.com/tool", "--dry-run", "--provenance", "--provenance-format", "slsa", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--provenance-format slsa needs the asset's digest",
+		},
+		{
+			name:       "provenance-next-to-binary with dry-run",
+			args:       []string{"--repo", "foo/bar", "--provenance-next-to-binary", "--dry-run", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--provenance-next-to-binary needs an install",
+		},
+		{
+			name:       "assert-version with url",
+			args:       []string{"--url", "https://example.com/tool", "--assert-version", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--assert-version compares against a release tag",
+		},
+		{
+			name:       "assert-version with foreign arch",
+			args:       []string{"--repo", "foo/bar", "--arch", foreignArch(), "--assert-version", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--assert-version cannot run a " + foreignArch() + " binary",
+		},
+		{
+			name:       "tag and latest conflict",
+			args:       []string{"--repo", "foo/bar", "--tag", "v1.0.0", "--latest", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "mutually exclusive",
+		},
+		{
+			name:       "all-binaries with output",
+			args:       []string{"--repo", "foo/bar", "--latest", "--all-binaries", "--output", "/tmp/x", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--output names a single file",
+		},
+		{
+			name:       "mirror with url",
+			args:       []string{"--url", "https://example.com/tool.tar.gz", "--mirror", "https://mirror.example", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--mirror applies to release downloads",
+		},
+		{
+			name:       "plain http mirror",
+			args:       []string{"--repo", "foo/bar", "--latest", "--mirror", "http://mirror.example", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "pass --allow-http",
+		},
+		{
+			name:       "negative timeout",
+			args:       []string{"--repo", "foo/bar", "--latest", "--timeout", "-1s", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--timeout and --deadline must be >= 0",
+		},
+		{
+			name:       "negative deadline",
+			args:       []string{"--repo", "foo/bar", "--latest", "--deadline", "-5m", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--timeout and --deadline must be >= 0",
+		},
+		{
+			name:       "zero max concurrency",
+			args:       []string{"--repo", "foo/bar", "--latest", "--max-concurrency", "0", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--max-concurrency must be >= 1",
+		},
+		{
+			name:       "release file with offline",
+			args:       []string{"--repo", "foo/bar", "--tag", "v1.0.0", "--release-file", "release.json", "--offline", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--release-file replaces the release lookup",
+		},
+		{
+			name:       "binaries with output",
+			args:       []string{"--repo", "foo/bar", "--latest", "--binaries", "a", "--output", "/tmp/x", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--output names a single file",
+		},
+		{
+			name:       "binaries with binary-name",
+			args:       []string{"--repo", "foo/bar", "--latest", "--binaries", "a,b", "--binary-name", "a", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--binaries cannot be combined",
+		},
+		{
+			name:       "binary-name list with url",
+			args:       []string{"--url", "https://example.com/tool.tar.gz", "--binary-name", "a,b", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "only apply to --repo release archives",
+		},
+		{
+			name:       "rollback with binary-name list",
+			args:       []string{"--rollback", "--dest-dir", "/tmp/x", "--binary-name", "a,b", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--rollback restores one file",
+		},
+		{
+			name:       "rollback without destination",
+			args:       []string{"--rollback", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--rollback needs --output",
+		},
+		{
+			name:       "uninstall with rollback",
+			args:       []string{"--uninstall", "foo/bar", "--rollback", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--uninstall and --rollback are mutually exclusive",
+		},
+		{
+			name:       "extract-all with dest-dir",
+			args:       []string{"--repo", "foo/bar", "--extract-all", "/opt/bar", "--dest-dir", "/tmp/bin", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--extract-all names the destination; it cannot be combined with --output, --dest-dir or --install",
+		},
+		{
+			name:       "extract-all with all-binaries",
+			args:       []string{"--repo", "foo/bar", "--extract-all", "/opt/bar", "--all-binaries", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--extract-all installs every file",
+		},
+		{
+			name:       "extract-all with url",
+			args:       []string{"--url", "https://example.com/tool.tar.gz", "--extract-all", "/opt/tool", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--extract-all only applies to --repo release archives",
+		},
+		{
+			name:       "uninstall needs owner/repo",
+			args:       []string{"--uninstall", "bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: `"bar" is not owner/repo`,
+		},
+		{
+			name:       "quiet with verbose",
+			args:       []string{"--quiet", "-v", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--quiet and --verbose are mutually exclusive",
+		},
+		{
+			name:       "channel invalid",
+			args:       []string{"--channel", "beta", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "invalid --channel",
+		},
+		{
+			name:       "include-prerelease with tag",
+			args:       []string{"--include-prerelease", "--tag", "v1.0.0", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "cannot be combined with --tag",
+		},
+		{
+			name:       "channel stable with include-prerelease",
+			args:       []string{"--channel", "stable", "--include-prerelease", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "mutually exclusive",
+		},
+		{
+			name:       "source-archive invalid kind",
+			args:       []string{"--source-archive", "7z", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "invalid --source-archive",
+		},
+		{
+			name:       "source-archive with asset-match",
+			args:       []string{"--source-archive", "tar", "--asset-match", "linux", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--source-archive selects the asset itself",
+		},
+		{
+			name:       "from-lockfile with tag",
+			args:       []string{"--from-lockfile", "sfetch.lock", "--tag", "v1.0.0", "--repo", "foo/bar", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--from-lockfile pins the tag",
+		},
+		{
+			name:       "lockfile with url",
+			args:       []string{"--lockfile", "sfetch.lock", "--url", "https://example.com/tool.tar.gz", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "apply to --repo release installs",
+		},
+		{
+			name:       "from-lockfile missing file",
+			args:       []string{"--from-lockfile", "/nonexistent/sfetch.lock", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "read lockfile",
+		},
+		{
+			name:       "repo required",
+			args:       []string{"--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--repo is required",
+		},
+		{
+			name:       "github-raw bypasses repo requirement",
+			args:       []string{"--github-raw", "foo/bar@main:install.sh", "--dry-run", "--skip-tools-check"},
+			wantCode:   0,
+			wantStderr: "sfetch dry-run assessment",
+		},
+		{
+			name:       "url bypasses repo requirement",
+			args:       []string{"--url", "https://example.com/", "--dry-run", "--skip-tools-check"},
+			wantCode:   0,
+			wantStderr: "sfetch dry-run assessment",
+		},
+		{
+			name:       "positional url",
+			args:       []string{"https://example.com/", "--dry-run", "--skip-tools-check"},
+			wantCode:   0,
+			wantStderr: "sfetch dry-run assessment",
+		},
+		{
+			name:       "url conflicts with repo",
+			args:       []string{"--url", "https://example.com/", "--repo", "foo/bar", "--dry-run", "--skip-tools-check"},
+			wantCode:   exitUsage,
+			wantStderr: "--url is mutually exclusive",
+		},
+		{
+			name:       "unknown flag",
+			args:       []string{"--nonexistent-flag"},
+			wantCode:   exitUsage,
+			wantStderr: "flag provided but not defined",
+		},
+		{
+			name:       "help flag returns success",
+			args:       []string{"--help"},
+			wantCode:   0,
+			wantStderr: "", // help goes to stdout
+		},
+		{
 			name:       "version flag returns success",
 			args:       []string{"--version"},
 			wantCode:   0,
@@ -859,7 +1776,7 @@ func TestGetConfig(t *testing.T) {
 			if len(cfg.AssetPatterns) == 0 {
 				t.Fatalf("expected default asset patterns")
 			}
-			rendered := renderPattern(cfg.AssetPatterns[0], cfg, runtime.GOOS, runtime.GOARCH)
+			rendered := renderPattern(cfg.AssetPatterns[0], cfg, runtime.GOOS, runtime.GOARCH, platformHints{})
 			if !strings.Contains(rendered, strings.ToLower(runtime.GOOS)) {
 				t.Errorf("pattern %q does not reference GOOS", rendered)
 			}
@@ -925,7 +1842,7 @@ func TestResolvePGPKey(t *testing.T) {
 		if err := os.WriteFile(path, []byte("local"), 0o644); err != nil {
 			t.Fatalf("write file: %v", err)
 		}
-		got, err := resolvePGPKey(path, "", "", nil, tmp)
+		got, err := resolvePGPKey(context.Background(), path, "", "", nil, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -940,7 +1857,7 @@ func TestResolvePGPKey(t *testing.T) {
 		}))
 		defer server.Close()
 		tmp := t.TempDir()
-		got, err := resolvePGPKey("", server.URL+"/key.asc", "", nil, tmp)
+		got, err := resolvePGPKey(context.Background(), "", server.URL+"/key.asc", "", nil, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -960,7 +1877,7 @@ func TestResolvePGPKey(t *testing.T) {
 		defer server.Close()
 		tmp := t.TempDir()
 		assets := []Asset{{Name: "release-key.asc", BrowserDownloadUrl: server.URL + "/release-key.asc"}}
-		got, err := resolvePGPKey("", "", "release-key.asc", assets, tmp)
+		got, err := resolvePGPKey(context.Background(), "", "", "release-key.asc", assets, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -980,7 +1897,7 @@ func TestResolvePGPKey(t *testing.T) {
 		defer server.Close()
 		tmp := t.TempDir()
 		assets := []Asset{{Name: "fulmen-release-key.asc", BrowserDownloadUrl: server.URL + "/fulmen-release-key.asc"}}
-		got, err := resolvePGPKey("", "", "", assets, tmp)
+		got, err := resolvePGPKey(context.Background(), "", "", "", assets, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -994,7 +1911,7 @@ func TestResolvePGPKey(t *testing.T) {
 	})
 
 	t.Run("missing inputs", func(t *testing.T) {
-		_, err := resolvePGPKey("", "", "", nil, t.TempDir())
+		_, err := resolvePGPKey(context.Background(), "", "", "", nil, t.TempDir(), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -1014,7 +1931,7 @@ func TestVerifyMinisignSignature(t *testing.T) {
 	}
 
 	t.Run("valid signature", func(t *testing.T) {
-		err := verifyMinisignSignature(checksumBytes, sigFile, pubKeyFile)
+		_, err := verifyMinisignSignature(checksumBytes, sigFile, pubKeyFile, minisignOptions{})
 		if err != nil {
 			t.Fatalf("verification failed: %v", err)
 		}
@@ -1022,25 +1939,42 @@ func TestVerifyMinisignSignature(t *testing.T) {
 
 	t.Run("tampered content", func(t *testing.T) {
 		tampered := append(checksumBytes, []byte("tampered")...)
-		err := verifyMinisignSignature(tampered, sigFile, pubKeyFile)
+		_, err := verifyMinisignSignature(tampered, sigFile, pubKeyFile, minisignOptions{})
 		if err == nil {
 			t.Fatal("expected error for tampered content")
 		}
 	})
 
 	t.Run("missing pubkey", func(t *testing.T) {
-		err := verifyMinisignSignature(checksumBytes, sigFile, "nonexistent.pub")
+		_, err := verifyMinisignSignature(checksumBytes, sigFile, "nonexistent.pub", minisignOptions{})
 		if err == nil {
 			t.Fatal("expected error for missing pubkey")
 		}
 	})
 
 	t.Run("missing sigfile", func(t *testing.T) {
-		err := verifyMinisignSignature(checksumBytes, "nonexistent.minisig", pubKeyFile)
+		_, err := verifyMinisignSignature(checksumBytes, "nonexistent.minisig", pubKeyFile, minisignOptions{})
 		if err == nil {
 			t.Fatal("expected error for missing sigfile")
 		}
 	})
+
+	t.Run("trusted comment matches regex", func(t *testing.T) {
+		comment, err := verifyMinisignSignature(checksumBytes, sigFile, pubKeyFile, minisignOptions{CommentPattern: regexp.MustCompile(`^timestamp:`)})
+		if err != nil {
+			t.Fatalf("verification failed: %v", err)
+		}
+		if comment != "timestamp:$(date +%s)" {
+			t.Fatalf("trusted comment = %q", comment)
+		}
+	})
+
+	t.Run("trusted comment does not match regex", func(t *testing.T) {
+		_, err := verifyMinisignSignature(checksumBytes, sigFile, pubKeyFile, minisignOptions{CommentPattern: regexp.MustCompile(`v1\.2\.3`)})
+		if err == nil || !strings.Contains(err.Error(), "trusted comment does not match") {
+			t.Fatalf("expected trusted comment mismatch, got %v", err)
+		}
+	})
 }
 
 func TestFindChecksumSignature(t *testing.T) {
@@ -1187,7 +2121,7 @@ func TestSignatureFormatFromExtension(t *testing.T) {
 func TestApplyInferenceRulesPlatformExclusion(t *testing.T) {
 	rules := mustLoadInferenceRules(t)
 	assets := []Asset{{Name: "tool"}, {Name: "tool.exe"}}
-	out := applyInferenceRules(assets, rules, "darwin", "amd64", defaults.ArchiveExtensions)
+	out := applyInferenceRules(assets, rules, "darwin", "amd64", platformHints{}, defaults.ArchiveExtensions)
 	if len(out) != 1 || out[0].Name != "tool" {
 		t.Fatalf("expected only tool after darwin exclusions, got %v", out)
 	}
@@ -1196,27 +2130,182 @@ func TestApplyInferenceRulesPlatformExclusion(t *testing.T) {
 func TestApplyInferenceRulesPreferRawOverArchive(t *testing.T) {
 	rules := mustLoadInferenceRules(t)
 	assets := []Asset{{Name: "yt-dlp_macos"}, {Name: "yt-dlp_macos.zip"}}
-	out := applyInferenceRules(assets, rules, "darwin", "arm64", defaults.ArchiveExtensions)
+	out := applyInferenceRules(assets, rules, "darwin", "arm64", platformHints{}, defaults.ArchiveExtensions)
 	if len(out) != 1 || out[0].Name != "yt-dlp_macos" {
 		t.Fatalf("expected raw binary preferred, got %v", out)
 	}
 }
 
+func TestPreferRawOverArchiveDeterministicOrder(t *testing.T) {
+	assets := []Asset{
+		{Name: "zeta_linux.tar.gz"},
+		{Name: "alpha_linux"},
+		{Name: "mid_linux.zip"},
+		{Name: "alpha_linux.zip"},
+		{Name: "beta_linux.tar.gz"},
+		{Name: "beta_linux.zip"},
+	}
+	want := []string{"alpha_linux", "beta_linux.tar.gz", "beta_linux.zip", "mid_linux.zip", "zeta_linux.tar.gz"}
+
+	for i := 0; i < 50; i++ {
+		out := preferRawOverArchive(append([]Asset(nil), assets...), defaults.ArchiveExtensions)
+		got := make([]string, len(out))
+		for j, a := range out {
+			got[j] = a.Name
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("run %d: got %v want %v", i, got, want)
+		}
+	}
+}
+
 func TestApplyInferenceRulesPlatformTokensCaseInsensitive(t *testing.T) {
 	rules := mustLoadInferenceRules(t)
 	assets := []Asset{
 		{Name: "gh_2.0.0_macOS_arm64.zip"},
 		{Name: "gh_2.0.0_linux_amd64.tar.gz"},
 	}
-	out := applyInferenceRules(assets, rules, "darwin", "arm64", defaults.ArchiveExtensions)
+	out := applyInferenceRules(assets, rules, "darwin", "arm64", platformHints{}, defaults.ArchiveExtensions)
 	if len(out) != 1 || out[0].Name != "gh_2.0.0_macOS_arm64.zip" {
 		t.Fatalf("expected macOS asset selected, got %v", out)
 	}
 }
 
-func TestInferAssetClassification(t *testing.T) {
-	tests := []struct {
-		name           string
+func TestApplyInferenceRulesLibc(t *testing.T) {
+	rules := mustLoadInferenceRules(t)
+	assets := []Asset{
+		{Name: "rg-14.1.0-x86_64-unknown-linux-gnu.tar.gz"},
+		{Name: "rg-14.1.0-x86_64-unknown-linux-musl.tar.gz"},
+	}
+	tests := []struct {
+		libc string
+		want []string
+	}{
+		{"musl", []string{"rg-14.1.0-x86_64-unknown-linux-musl.tar.gz"}},
+		{"gnu", []string{"rg-14.1.0-x86_64-unknown-linux-gnu.tar.gz"}},
+		{"", []string{"rg-14.1.0-x86_64-unknown-linux-gnu.tar.gz", "rg-14.1.0-x86_64-unknown-linux-musl.tar.gz"}},
+	}
+	for _, tt := range tests {
+		out := applyInferenceRules(assets, rules, "linux", "amd64", platformHints{Libc: tt.libc}, defaults.ArchiveExtensions)
+		got := make([]string, len(out))
+		for i, a := range out {
+			got[i] = a.Name
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("libc=%q: got %v want %v", tt.libc, got, tt.want)
+		}
+	}
+}
+
+func TestSelectAssetLibcOrdering(t *testing.T) {
+	gnu := Asset{Name: "fd-v10.2.0-x86_64-unknown-linux-gnu.tar.gz"}
+	musl := Asset{Name: "fd-v10.2.0-x86_64-unknown-linux-musl.tar.gz"}
+	cfg := getConfig("sharkdp/fd")
+	tests := []struct {
+		name   string
+		assets []Asset
+		libc   string
+		want   string
+	}{
+		{"musl host, gnu first", []Asset{gnu, musl}, "musl", musl.Name},
+		{"musl host, musl first", []Asset{musl, gnu}, "musl", musl.Name},
+		{"gnu host, gnu first", []Asset{gnu, musl}, "gnu", gnu.Name},
+		{"gnu host, musl first", []Asset{musl, gnu}, "gnu", gnu.Name},
+		{"gnu host accepts static musl", []Asset{musl}, "gnu", musl.Name},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, pick := range []struct {
+				label string
+				fn    func() (*Asset, error)
+			}{
+				{"selectAsset", func() (*Asset, error) {
+					rel := &Release{TagName: "v10.2.0", Assets: tt.assets}
+					return selectAsset(rel, cfg, "linux", "amd64", platformHints{Libc: tt.libc}, "", "", false)
+				}},
+				{"pickByHeuristics", func() (*Asset, error) {
+					return pickByHeuristics(tt.assets, cfg, "linux", "amd64", platformHints{Libc: tt.libc})
+				}},
+			} {
+				got, err := pick.fn()
+				if err != nil {
+					t.Fatalf("%s: %v", pick.label, err)
+				}
+				if got.Name != tt.want {
+					t.Errorf("%s = %s, want %s", pick.label, got.Name, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveLibcOverride(t *testing.T) {
+	for _, mode := range []string{"musl", "gnu"} {
+		if got := resolveLibc(mode, "linux"); got != mode {
+			t.Errorf("resolveLibc(%q, linux) = %q, want override honored", mode, got)
+		}
+	}
+}
+
+func TestNormalizeLibc(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"", libcAuto, false},
+		{"auto", libcAuto, false},
+		{"MUSL", "musl", false},
+		{"gnu", "gnu", false},
+		{"glibc", "gnu", false},
+		{"uclibc", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeLibc(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("normalizeLibc(%q) err = %v, wantErr %v", tt.raw, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("normalizeLibc(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+	if got := resolveLibc("musl", "darwin"); got != "" {
+		t.Errorf("resolveLibc on darwin = %q, want empty", got)
+	}
+	if got := resolveLibc("gnu", "linux"); got != "gnu" {
+		t.Errorf("resolveLibc(gnu, linux) = %q, want gnu", got)
+	}
+}
+
+func TestParseArchOverride(t *testing.T) {
+	tests := []struct {
+		raw         string
+		wantArch    string
+		wantVariant string
+		wantErr     bool
+	}{
+		{"", "", "", false},
+		{"arm64", "arm64", "", false},
+		{"arm/v7", "arm", "7", false},
+		{"ARM/6", "arm", "6", false},
+		{"arm/v8", "", "", true},
+		{"amd64/v3", "", "", true},
+		{"/v7", "", "", true},
+	}
+	for _, tt := range tests {
+		arch, variant, err := parseArchOverride(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseArchOverride(%q) err = %v, wantErr %v", tt.raw, err, tt.wantErr)
+		}
+		if arch != tt.wantArch || variant != tt.wantVariant {
+			t.Errorf("parseArchOverride(%q) = (%q, %q), want (%q, %q)", tt.raw, arch, variant, tt.wantArch, tt.wantVariant)
+		}
+	}
+}
+
+func TestInferAssetClassification(t *testing.T) {
+	tests := []struct {
+		name           string
 		wantType       AssetType
 		wantFmt        ArchiveFormat
 		wantNeedsChmod bool
@@ -1227,6 +2316,11 @@ func TestInferAssetClassification(t *testing.T) {
 		{"tool.tar.bz2", AssetTypeArchive, ArchiveFormatTarBz2, false},
 		{"tool.tar", AssetTypeArchive, ArchiveFormatTar, false},
 		{"tool.zip", AssetTypeArchive, ArchiveFormatZip, false},
+		{"tool_windows_amd64.7z", AssetTypeArchive, ArchiveFormatSevenZip, false},
+		{"tool_linux_amd64.gz", AssetTypeRaw, ArchiveFormatGz, true},
+		{"tool_linux_amd64.xz", AssetTypeRaw, ArchiveFormatXz, true},
+		{"tool_linux_amd64.bz2", AssetTypeRaw, ArchiveFormatBz2, true},
+		{"TOOL.GZ", AssetTypeRaw, ArchiveFormatGz, true},
 		{"install.sh", AssetTypeRaw, "", true},
 		{"bootstrap.py", AssetTypeRaw, "", true},
 		{"kubectl", AssetTypeRaw, "", true},
@@ -1251,6 +2345,448 @@ func TestInferAssetClassification(t *testing.T) {
 	}
 }
 
+const sevenZipListing = `
+7-Zip [64] 16.02 : Copyright (c) 1999-2016 Igor Pavlov : 2016-05-21
+
+Listing archive: tool.7z
+
+--
+Path = tool.7z
+Type = 7z
+Physical Size = 1234
+
+----------
+Path = tool
+Size = 5
+Attributes = A_ -rwxr-xr-x
+
+Path = docs
+Size = 0
+Folder = +
+Attributes = D_ drwxr-xr-x
+
+Path = docs/README
+Size = 6
+Attributes = A_ -rw-r--r--
+`
+
+func TestParseSevenZipListing(t *testing.T) {
+	entries := parseSevenZipListing([]byte(sevenZipListing))
+	want := []sevenZipEntry{
+		{path: "tool", attributes: "A_ -rwxr-xr-x", size: 5},
+		{path: "docs", attributes: "D_ drwxr-xr-x", folder: true},
+		{path: "docs/README", attributes: "A_ -rw-r--r--", size: 6},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("entries = %+v, want %+v", entries, want)
+	}
+}
+
+func TestCheckSevenZipEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   sevenZipEntry
+		wantErr string
+	}{
+		{name: "file", entry: sevenZipEntry{path: "bin/tool", attributes: "A_ -rwxr-xr-x"}},
+		{name: "windows attributes only", entry: sevenZipEntry{path: "tool.exe", attributes: "A"}},
+		{name: "parent", entry: sevenZipEntry{path: "../tool"}, wantErr: "7z slip"},
+		{name: "nested parent", entry: sevenZipEntry{path: "bin/../../tool"}, wantErr: "7z slip"},
+		{name: "backslash parent", entry: sevenZipEntry{path: `..\tool`}, wantErr: "7z slip"},
+		{name: "absolute", entry: sevenZipEntry{path: "/usr/bin/tool"}, wantErr: "7z slip"},
+		{name: "symlink", entry: sevenZipEntry{path: "tool", attributes: "A_ lrwxrwxrwx"}, wantErr: "symlink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSevenZipEntries([]sevenZipEntry{tt.entry}, "tool.7z")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkSevenZipEntries: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("checkSevenZipEntries error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("over --max-extract-size", func(t *testing.T) {
+		setMaxExtractSize(10)
+		t.Cleanup(func() { setMaxExtractSize(defaultMaxExtractSize) })
+		entries := parseSevenZipListing([]byte(sevenZipListing))
+		if err := checkSevenZipEntries(entries, "tool.7z"); !errors.Is(err, errExtractTooLarge) {
+			t.Fatalf("checkSevenZipEntries error = %v, want errExtractTooLarge", err)
+		}
+	})
+}
+
+func TestExtractSevenZip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake 7z sidecar is a shell script")
+	}
+	// A stand-in 7z prints a canned listing and "extracts" by copying a tree,
+	// so the checks around the sidecar run without 7-Zip installed.
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"case \"$1\" in\n" +
+		"l) cat \"$FAKE7Z_LISTING\" ;;\n" +
+		"x) for a in \"$@\"; do case \"$a\" in -o*) out=\"${a#-o}\" ;; esac; done; cp -R \"$FAKE7Z_TREE\"/. \"$out\"/ ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "7z"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cpPath, err := exec.LookPath("cp")
+	if err != nil {
+		t.Skip("cp not available")
+	}
+	catPath, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("cat not available")
+	}
+	t.Setenv("PATH", strings.Join([]string{binDir, filepath.Dir(cpPath), filepath.Dir(catPath)}, string(os.PathListSeparator)))
+
+	writeListing := func(t *testing.T, listing string) {
+		t.Helper()
+		p := filepath.Join(t.TempDir(), "listing.txt")
+		if err := os.WriteFile(p, []byte(listing), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("FAKE7Z_LISTING", p)
+	}
+	writeTree := func(t *testing.T, symlink bool) {
+		t.Helper()
+		tree := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tree, "tool"), []byte("tool\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if symlink {
+			if err := os.Symlink("/etc/passwd", filepath.Join(tree, "link")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		t.Setenv("FAKE7Z_TREE", tree)
+	}
+	archive := filepath.Join(t.TempDir(), "tool.7z")
+	if err := os.WriteFile(archive, []byte("7z\xbc\xaf\x27\x1c"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("extracts", func(t *testing.T) {
+		writeListing(t, sevenZipListing)
+		writeTree(t, false)
+		dir := t.TempDir()
+		if err := extractSevenZip(archive, dir); err != nil {
+			t.Fatalf("extractSevenZip: %v", err)
+		}
+		if got, err := os.ReadFile(filepath.Join(dir, "tool")); err != nil || string(got) != "tool\n" {
+			t.Fatalf("extracted tool = %q, %v", got, err)
+		}
+	})
+	t.Run("listing escapes the directory", func(t *testing.T) {
+		writeListing(t, "----------\nPath = ../../tool\nAttributes = A_ -rwxr-xr-x\n")
+		writeTree(t, false)
+		dir := t.TempDir()
+		if err := extractSevenZip(archive, dir); err == nil || !strings.Contains(err.Error(), "7z slip") {
+			t.Fatalf("extractSevenZip error = %v, want 7z slip", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "tool")); !os.IsNotExist(err) {
+			t.Fatalf("files were extracted despite the bad listing: %v", err)
+		}
+	})
+	t.Run("symlink in the extracted tree", func(t *testing.T) {
+		writeListing(t, sevenZipListing)
+		writeTree(t, true)
+		if err := extractSevenZip(archive, t.TempDir()); err == nil || !strings.Contains(err.Error(), "symlink") {
+			t.Fatalf("extractSevenZip error = %v, want symlink refusal", err)
+		}
+	})
+	t.Run("tree over --max-extract-size", func(t *testing.T) {
+		// The listing claims no sizes; the extracted tree is measured too.
+		setMaxExtractSize(3)
+		t.Cleanup(func() { setMaxExtractSize(defaultMaxExtractSize) })
+		writeListing(t, "----------\nPath = tool\nAttributes = A_ -rwxr-xr-x\n")
+		writeTree(t, false)
+		if err := extractSevenZip(archive, t.TempDir()); !errors.Is(err, errExtractTooLarge) {
+			t.Fatalf("extractSevenZip error = %v, want errExtractTooLarge", err)
+		}
+	})
+	t.Run("no 7-Zip on PATH", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		if err := extractSevenZip(archive, t.TempDir()); !errors.Is(err, errNoSevenZip) {
+			t.Fatalf("extractSevenZip error = %v, want errNoSevenZip", err)
+		}
+	})
+}
+
+func TestExtractReleaseArchiveMaxExtractSize(t *testing.T) {
+	// Each member fits the limit on its own; together they do not.
+	files := []testArchiveFile{{"tool", 0o755}, {"LICENSE", 0o644}}
+	limit := int64(len("tool") + len("LICENSE") - 1)
+	tests := []struct {
+		name   string
+		format ArchiveFormat
+	}{
+		{"tool.zip", ArchiveFormatZip},
+		{"tool.tar.gz", ArchiveFormatTarGz},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			assetPath := filepath.Join(tmpDir, tt.name)
+			writeTestArchive(t, assetPath, tt.format, files)
+			if _, err := extractReleaseArchive(assetPath, tt.format, tmpDir); err != nil {
+				t.Fatalf("extractReleaseArchive under the default limit: %v", err)
+			}
+
+			setMaxExtractSize(limit)
+			t.Cleanup(func() { setMaxExtractSize(defaultMaxExtractSize) })
+			if err := os.RemoveAll(filepath.Join(tmpDir, "extract")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := extractReleaseArchive(assetPath, tt.format, tmpDir); !errors.Is(err, errExtractTooLarge) {
+				t.Fatalf("extractReleaseArchive error = %v, want errExtractTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestDecompressSingleFile(t *testing.T) {
+	payload := []byte("#!/bin/sh\necho tool\n")
+	compressCLI := func(tool string) func(t *testing.T) []byte {
+		return func(t *testing.T) []byte {
+			if _, err := exec.LookPath(tool); err != nil {
+				t.Skipf("%s not available", tool)
+			}
+			cmd := exec.Command(tool, "-c")
+			cmd.Stdin = bytes.NewReader(payload)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%s -c: %v", tool, err)
+			}
+			return out
+		}
+	}
+	tests := []struct {
+		name     string
+		format   ArchiveFormat
+		compress func(t *testing.T) []byte
+	}{
+		{
+			name:   "tool_linux_amd64.gz",
+			format: ArchiveFormatGz,
+			compress: func(t *testing.T) []byte {
+				var buf bytes.Buffer
+				zw := gzip.NewWriter(&buf)
+				_, _ = zw.Write(payload)
+				if err := zw.Close(); err != nil {
+					t.Fatal(err)
+				}
+				return buf.Bytes()
+			},
+		},
+		{name: "tool_linux_amd64.bz2", format: ArchiveFormatBz2, compress: compressCLI("bzip2")},
+		{name: "tool_linux_amd64.xz", format: ArchiveFormatXz, compress: compressCLI("xz")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" over --max-extract-size", func(t *testing.T) {
+			tmpDir := t.TempDir()
+			assetPath := filepath.Join(tmpDir, tt.name)
+			if err := os.WriteFile(assetPath, tt.compress(t), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			setMaxExtractSize(int64(len(payload)) - 1)
+			t.Cleanup(func() { setMaxExtractSize(defaultMaxExtractSize) })
+			if _, err := decompressSingleFile(assetPath, tt.name, tt.format, tmpDir); !errors.Is(err, errExtractTooLarge) {
+				t.Fatalf("decompressSingleFile error = %v, want errExtractTooLarge", err)
+			}
+		})
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			assetPath := filepath.Join(tmpDir, tt.name)
+			if err := os.WriteFile(assetPath, tt.compress(t), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := decompressSingleFile(assetPath, tt.name, tt.format, tmpDir)
+			if err != nil {
+				t.Fatalf("decompressSingleFile: %v", err)
+			}
+			if filepath.Base(got) != "tool_linux_amd64" {
+				t.Fatalf("decompressed to %s, want tool_linux_amd64", got)
+			}
+			data, err := os.ReadFile(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, payload) {
+				t.Fatalf("content = %q, want %q", data, payload)
+			}
+			if info, err := os.Stat(got); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm()&0o100 == 0) {
+				t.Fatalf("decompressed file not executable: %v", err)
+			}
+		})
+	}
+
+	t.Run("corrupt", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		assetPath := filepath.Join(tmpDir, "tool.gz")
+		if err := os.WriteFile(assetPath, []byte("not gzip"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decompressSingleFile(assetPath, "tool.gz", ArchiveFormatGz, tmpDir); err == nil {
+			t.Fatal("expected error for corrupt gzip data")
+		}
+	})
+}
+
+func TestRunSingleFileCompressedAsset(t *testing.T) {
+	installName := fmt.Sprintf("tool_%s_%s", runtime.GOOS, runtime.GOARCH)
+	assetName := installName + ".gz"
+	payload := []byte("#!/bin/sh\necho tool\n")
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(payload)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assetBytes := buf.Bytes()
+	sums := []byte(fmt.Sprintf("%x  %s\n", sha256.Sum256(assetBytes), assetName))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{ID: 101, Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName, Size: int64(len(assetBytes))},
+				{ID: 102, Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS", Size: int64(len(sums))},
+			}})
+		case "/dl/" + assetName:
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = w.Write(sums)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	destDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--latest", "--dest-dir", destDir, "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitOK, stderr.String())
+	}
+	data, err := os.ReadFile(filepath.Join(destDir, installName))
+	if err != nil {
+		t.Fatalf("decompressed binary not installed: %v", err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("installed content = %q, want %q", data, payload)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, assetName)); !os.IsNotExist(err) {
+		t.Fatalf("compressed asset should not be installed: %v", err)
+	}
+}
+
+func TestRunExtractAll(t *testing.T) {
+	files := []testArchiveFile{
+		{name: "tool-1.0.0/bin/tool", mode: 0o755},
+		{name: "tool-1.0.0/completions/tool.bash", mode: 0o644},
+		{name: "tool-1.0.0/share/man/man1/tool.1", mode: 0o644},
+		{name: "tool-1.0.0/LICENSE", mode: 0o644},
+	}
+	assets := map[string][]byte{}
+	for _, format := range []ArchiveFormat{ArchiveFormatTarGz, ArchiveFormatZip} {
+		name := fmt.Sprintf("tool_%s_%s.%s", runtime.GOOS, runtime.GOARCH, format)
+		path := filepath.Join(t.TempDir(), name)
+		writeTestArchive(t, path, format, files)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assets[name] = data
+	}
+	rawName := fmt.Sprintf("tool_%s_%s", runtime.GOOS, runtime.GOARCH)
+	assets[rawName] = []byte("#!/bin/sh\n")
+	var sums strings.Builder
+	for name, data := range assets {
+		_, _ = fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256(data), name)
+	}
+	assets["SHA256SUMS"] = []byte(sums.String())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		if name, ok := strings.CutPrefix(r.URL.Path, "/repos/o/"); ok {
+			// /repos/o/<asset>/releases/latest: a release holding that asset.
+			asset, _, _ := strings.Cut(name, "/")
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: asset, BrowserDownloadUrl: base + "/dl/" + asset},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+			return
+		}
+		data, ok := assets[strings.TrimPrefix(r.URL.Path, "/dl/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name       string
+		asset      string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "tar.gz", asset: fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH), wantCode: exitOK},
+		{name: "zip", asset: fmt.Sprintf("tool_%s_%s.zip", runtime.GOOS, runtime.GOARCH), wantCode: exitOK},
+		{name: "raw asset", asset: rawName, wantCode: exitUsage, wantStderr: "error: --extract-all needs an archive; " + rawName + " is a raw asset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "opt", "tool")
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"--repo", "o/" + tt.asset, "--latest", "--asset-match", tt.asset, "--extract-all", dest, "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check"}, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr missing %q:\n%s", tt.wantStderr, stderr.String())
+			}
+			if tt.wantCode != exitOK {
+				return
+			}
+			for _, f := range files {
+				path := filepath.Join(dest, filepath.FromSlash(f.name))
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Errorf("%s not installed: %v", f.name, err)
+					continue
+				}
+				if string(data) != f.name {
+					t.Errorf("%s content = %q", f.name, data)
+				}
+				if runtime.GOOS == "windows" {
+					continue
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := info.Mode().Perm()&0o111 != 0, f.mode&0o111 != 0; got != want {
+					t.Errorf("%s mode = %v, want executable %v", f.name, info.Mode().Perm(), want)
+				}
+			}
+		})
+	}
+}
+
 func TestClassifyAssetLegacyArchiveTypeDoesNotOverrideRaw(t *testing.T) {
 	// Regression test: legacy archiveType in config should not override
 	// correctly inferred raw scripts/packages
@@ -1270,6 +2806,8 @@ func TestClassifyAssetLegacyArchiveTypeDoesNotOverrideRaw(t *testing.T) {
 		// Archives should still work
 		{"tool.tar.gz", AssetTypeArchive},
 		{"binary.zip", AssetTypeArchive},
+		// Single compressed files are not archives
+		{"tool_linux_amd64.gz", AssetTypeRaw},
 	}
 
 	for _, tt := range tests {
@@ -1328,6 +2866,10 @@ func TestLooksLikeSupplemental(t *testing.T) {
 		{"SHA2-512SUMS", true},
 		{"checksums.txt", true},
 		{"CHECKSUMS", true},
+		{"MD5SUMS", true},
+		{"SHA1SUMS", true},
+		{"binary.tar.gz.md5", true},
+		{"binary.tar.gz.sha1", true},
 
 		// Public key files - should be supplemental
 		{"sfetch-minisign.pub", true},
@@ -1515,6 +3057,19 @@ func TestComputeTrustScoreOptionA(t *testing.T) {
 			},
 			want: TrustScore{Score: 55, Level: TrustLow, LevelName: "low"},
 		},
+		{
+			name: "two independent signature formats with --skip-checksum",
+			in: trustScoreInput{
+				SignatureVerifiable: true,
+				SignatureValidated:  true,
+				SignatureFormats:    2,
+				ChecksumVerifiable:  true,
+				ChecksumSkipped:     true,
+				ChecksumAlgorithm:   "sha256",
+				HTTPSUsed:           true,
+			},
+			want: TrustScore{Score: 60, Level: TrustMedium, LevelName: "medium"},
+		},
 		{
 			name: "--insecure with verification verifiable",
 			in: trustScoreInput{
@@ -1527,6 +3082,36 @@ func TestComputeTrustScoreOptionA(t *testing.T) {
 			},
 			want: TrustScore{Score: 0, Level: TrustBypassed, LevelName: "bypassed"},
 		},
+		{
+			name: "https-only with source digest",
+			in: trustScoreInput{
+				HTTPSUsed:             true,
+				SourceDigestAvailable: true,
+			},
+			want: TrustScore{Score: 35, Level: TrustLow, LevelName: "low"},
+		},
+		{
+			name: "checksum-only sha256 with source digest",
+			in: trustScoreInput{
+				ChecksumVerifiable:    true,
+				ChecksumValidated:     true,
+				ChecksumAlgorithm:     "sha256",
+				HTTPSUsed:             true,
+				SourceDigestAvailable: true,
+			},
+			want: TrustScore{Score: 55, Level: TrustLow, LevelName: "low"},
+		},
+		{
+			name: "--insecure with source digest",
+			in: trustScoreInput{
+				ChecksumVerifiable:    true,
+				ChecksumSkipped:       true,
+				HTTPSUsed:             true,
+				SourceDigestAvailable: true,
+				InsecureFlag:          true,
+			},
+			want: TrustScore{Score: 0, Level: TrustBypassed, LevelName: "bypassed"},
+		},
 	}
 
 	for _, tc := range tests {
@@ -1534,7 +3119,7 @@ func TestComputeTrustScoreOptionA(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := computeTrustScore(tc.in)
+			got := computeTrustScore(tc.in, defaultTrustPolicy)
 			if got.Score != tc.want.Score {
 				t.Fatalf("Score: got %d want %d", got.Score, tc.want.Score)
 			}
@@ -1565,6 +3150,14 @@ func TestComputeTrustScoreOptionA(t *testing.T) {
 					t.Fatalf("transport points: got %d want %d", got.Factors.Transport.Points, 0)
 				}
 
+			case "two independent signature formats with --skip-checksum":
+				if got.Factors.Signature.Points != 75 {
+					t.Fatalf("signature points: got %d want %d", got.Factors.Signature.Points, 75)
+				}
+				if got.Factors.Signature.IndependentFormats != 2 {
+					t.Fatalf("independent formats: got %d want %d", got.Factors.Signature.IndependentFormats, 2)
+				}
+
 			case "signature-only":
 				if got.Factors.Signature.Points != 70 {
 					t.Fatalf("signature points: got %d want %d", got.Factors.Signature.Points, 70)
@@ -1937,7 +3530,7 @@ func TestResolveMinisignKey(t *testing.T) {
 		if err := os.WriteFile(path, []byte("local minisign key"), 0o644); err != nil {
 			t.Fatalf("write file: %v", err)
 		}
-		got, err := resolveMinisignKey(path, "", "", nil, tmp)
+		got, err := resolveMinisignKey(context.Background(), path, "", "", nil, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -1952,7 +3545,7 @@ func TestResolveMinisignKey(t *testing.T) {
 		}))
 		defer server.Close()
 		tmp := t.TempDir()
-		got, err := resolveMinisignKey("", server.URL+"/key.pub", "", nil, tmp)
+		got, err := resolveMinisignKey(context.Background(), "", server.URL+"/key.pub", "", nil, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -1972,7 +3565,7 @@ func TestResolveMinisignKey(t *testing.T) {
 		defer server.Close()
 		tmp := t.TempDir()
 		assets := []Asset{{Name: "project-minisign.pub", BrowserDownloadUrl: server.URL + "/project-minisign.pub"}}
-		got, err := resolveMinisignKey("", "", "project-minisign.pub", assets, tmp)
+		got, err := resolveMinisignKey(context.Background(), "", "", "project-minisign.pub", assets, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -1992,7 +3585,7 @@ func TestResolveMinisignKey(t *testing.T) {
 		defer server.Close()
 		tmp := t.TempDir()
 		assets := []Asset{{Name: "sfetch-release-minisign.pub", BrowserDownloadUrl: server.URL + "/sfetch-release-minisign.pub"}}
-		got, err := resolveMinisignKey("", "", "", assets, tmp)
+		got, err := resolveMinisignKey(context.Background(), "", "", "", assets, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -2012,7 +3605,7 @@ func TestResolveMinisignKey(t *testing.T) {
 		defer server.Close()
 		tmp := t.TempDir()
 		// Pass URL in localPath position (first arg)
-		got, err := resolveMinisignKey(server.URL+"/key.pub", "", "", nil, tmp)
+		got, err := resolveMinisignKey(context.Background(), server.URL+"/key.pub", "", "", nil, tmp, nil)
 		if err != nil {
 			t.Fatalf("resolve: %v", err)
 		}
@@ -2028,7 +3621,7 @@ func TestResolveMinisignKey(t *testing.T) {
 	t.Run("missing asset", func(t *testing.T) {
 		tmp := t.TempDir()
 		assets := []Asset{{Name: "other-asset.txt"}}
-		_, err := resolveMinisignKey("", "", "nonexistent.pub", assets, tmp)
+		_, err := resolveMinisignKey(context.Background(), "", "", "nonexistent.pub", assets, tmp, nil)
 		if err == nil {
 			t.Fatal("expected error for missing asset")
 		}
@@ -2039,7 +3632,7 @@ func TestResolveMinisignKey(t *testing.T) {
 
 	t.Run("missing local file", func(t *testing.T) {
 		tmp := t.TempDir()
-		_, err := resolveMinisignKey("/nonexistent/path.pub", "", "", nil, tmp)
+		_, err := resolveMinisignKey(context.Background(), "/nonexistent/path.pub", "", "", nil, tmp, nil)
 		if err == nil {
 			t.Fatal("expected error for missing file")
 		}
@@ -2048,7 +3641,7 @@ func TestResolveMinisignKey(t *testing.T) {
 	t.Run("no inputs and no auto-detect match", func(t *testing.T) {
 		tmp := t.TempDir()
 		assets := []Asset{{Name: "binary.tar.gz"}}
-		_, err := resolveMinisignKey("", "", "", assets, tmp)
+		_, err := resolveMinisignKey(context.Background(), "", "", "", assets, tmp, nil)
 		if err == nil {
 			t.Fatal("expected error when no key sources available")
 		}
@@ -2090,24 +3683,212 @@ func TestInferenceRulesDocumentValidates(t *testing.T) {
 	}
 }
 
-// TestRepoConfigSchemaValidity validates that repo-config.schema.json is valid JSON Schema 2020-12.
-func TestRepoConfigSchemaValidity(t *testing.T) {
-	c := jsonschema.NewCompiler()
-	_, err := c.Compile("schemas/repo-config.schema.json")
-	if err != nil {
-		t.Fatalf("repo-config schema is not valid JSON Schema 2020-12: %v", err)
+func TestValidateUserInferenceRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr bool
+	}{
+		{"partial override", `{"version": "1.0", "archTokens": {"amd64": ["x64-linux"]}}`, false},
+		{"replace sections", `{"version": "1.0", "platformTokens": {"linux": ["lnx"]}, "replace": ["platformTokens"]}`, false},
+		{"wrong type", `{"version": "1.0", "archTokens": {"amd64": "x64"}}`, true},
+		{"unknown section", `{"version": "1.0", "osTokens": {}}`, true},
+		{"unknown replace target", `{"version": "1.0", "replace": ["formatPreference"]}`, true},
+		{"bad version", `{"version": "2.0"}`, true},
+		{"not json", `{`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateUserInferenceRules([]byte(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateUserInferenceRules() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
 	}
 }
 
-func TestUpdateTargetSchemaValidity(t *testing.T) {
-	c := jsonschema.NewCompiler()
-	repoSchemaBytes, err := os.ReadFile("schemas/repo-config.schema.json")
-	if err != nil {
-		t.Fatalf("read schemas/repo-config.schema.json: %v", err)
+func TestLoadUserInferenceRules(t *testing.T) {
+	dir := t.TempDir()
+	if user, err := loadUserInferenceRules(filepath.Join(dir, "missing.json")); user != nil || err != nil {
+		t.Fatalf("missing file: got %v, %v", user, err)
 	}
-	var repoSchemaDoc any
-	if err := json.Unmarshal(repoSchemaBytes, &repoSchemaDoc); err != nil {
-		t.Fatalf("unmarshal schemas/repo-config.schema.json: %v", err)
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"version": "1.0", "formatPreference": "raw"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadUserInferenceRules(invalid); err == nil || !strings.Contains(err.Error(), "invalid.json") {
+		t.Fatalf("expected validation error naming the file, got %v", err)
+	}
+
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"version": "1.0", "archTokens": {"amd64": ["x64-linux"]}, "replace": ["archTokens"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	user, err := loadUserInferenceRules(valid)
+	if err != nil {
+		t.Fatalf("loadUserInferenceRules() error = %v", err)
+	}
+	if !reflect.DeepEqual(user.Replace, []string{"archTokens"}) || len(user.ArchTokens["amd64"]) != 1 {
+		t.Fatalf("unexpected override: %+v", user)
+	}
+}
+
+func TestUserInferenceRulesPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got := userInferenceRulesPath(); got != filepath.Join("/xdg", "sfetch", "inference-rules.json") {
+		t.Fatalf("userInferenceRulesPath() = %s", got)
+	}
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/u")
+	if got := userInferenceRulesPath(); got != filepath.Join("/home/u", ".config", "sfetch", "inference-rules.json") {
+		t.Fatalf("userInferenceRulesPath() = %s", got)
+	}
+}
+
+func TestMergeInferenceRules(t *testing.T) {
+	base := &InferenceRules{
+		Version:            "1.0",
+		PlatformExclusions: map[string][]string{"linux": {".exe"}},
+		PlatformTokens:     map[string][]string{"linux": {"linux"}, "darwin": {"darwin", "macos"}},
+		ArchTokens:         map[string][]string{"amd64": {"amd64", "x86_64"}},
+		FormatPreference:   []string{"raw", "archive", "package"},
+		ArchiveExtensions:  []string{".tar.gz", ".zip"},
+	}
+
+	t.Run("extend", func(t *testing.T) {
+		got := mergeInferenceRules(base, &userInferenceRules{InferenceRules: InferenceRules{
+			PlatformTokens:    map[string][]string{"Linux": {"lnx", "LINUX"}, "freebsd": {"fbsd"}},
+			ArchiveExtensions: []string{".tar.zst", ".zip"},
+		}})
+		if want := []string{"linux", "lnx"}; !reflect.DeepEqual(got.PlatformTokens["linux"], want) {
+			t.Errorf("linux tokens = %v, want %v", got.PlatformTokens["linux"], want)
+		}
+		if !reflect.DeepEqual(got.PlatformTokens["darwin"], base.PlatformTokens["darwin"]) {
+			t.Errorf("darwin tokens should be untouched, got %v", got.PlatformTokens["darwin"])
+		}
+		if !reflect.DeepEqual(got.PlatformTokens["freebsd"], []string{"fbsd"}) {
+			t.Errorf("freebsd tokens = %v", got.PlatformTokens["freebsd"])
+		}
+		if want := []string{".tar.gz", ".zip", ".tar.zst"}; !reflect.DeepEqual(got.ArchiveExtensions, want) {
+			t.Errorf("archive extensions = %v, want %v", got.ArchiveExtensions, want)
+		}
+		if !reflect.DeepEqual(got.ArchTokens, base.ArchTokens) || !reflect.DeepEqual(got.FormatPreference, base.FormatPreference) {
+			t.Errorf("unset sections should keep defaults")
+		}
+		if len(base.PlatformTokens["linux"]) != 1 {
+			t.Errorf("base rules were modified: %v", base.PlatformTokens["linux"])
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		got := mergeInferenceRules(base, &userInferenceRules{
+			InferenceRules: InferenceRules{
+				ArchTokens:       map[string][]string{"amd64": {"x64"}},
+				FormatPreference: []string{"archive", "raw"},
+			},
+			Replace: []string{"archTokens"},
+		})
+		if want := map[string][]string{"amd64": {"x64"}}; !reflect.DeepEqual(got.ArchTokens, want) {
+			t.Errorf("arch tokens = %v, want %v", got.ArchTokens, want)
+		}
+		if want := []string{"archive", "raw"}; !reflect.DeepEqual(got.FormatPreference, want) {
+			t.Errorf("format preference = %v, want %v", got.FormatPreference, want)
+		}
+	})
+}
+
+func TestParseUserRepoConfigs(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantRepos    map[string]string // repo -> binaryName
+		wantProblems int
+		wantErr      bool
+	}{
+		{
+			name:      "map form",
+			data:      `{"Owner/Tool": {"binaryName": "tool-cli", "hashAlgo": "sha512"}}`,
+			wantRepos: map[string]string{"owner/tool": "tool-cli"},
+		},
+		{
+			name:      "array form",
+			data:      `[{"repo": "owner/a", "binaryName": "a"}, {"repo": "owner/b", "binaryName": "b"}]`,
+			wantRepos: map[string]string{"owner/a": "a", "owner/b": "b"},
+		},
+		{
+			name:         "invalid entries skipped",
+			data:         `{"owner/ok": {"binaryName": "ok"}, "owner/bad": {"hashAlgo": "md5"}, "noslash": {}}`,
+			wantRepos:    map[string]string{"owner/ok": "ok"},
+			wantProblems: 2,
+		},
+		{
+			name:         "array entry without repo",
+			data:         `[{"binaryName": "x"}]`,
+			wantRepos:    map[string]string{},
+			wantProblems: 1,
+		},
+		{
+			name:         "unknown field rejected",
+			data:         `{"owner/tool": {"binaryname": "typo"}}`,
+			wantRepos:    map[string]string{},
+			wantProblems: 1,
+		},
+		{name: "not json", data: `binaryName: x`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems, err := parseUserRepoConfigs([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(problems) != tt.wantProblems {
+				t.Fatalf("problems = %v, want %d", problems, tt.wantProblems)
+			}
+			if len(got) != len(tt.wantRepos) {
+				t.Fatalf("got %d repos, want %d: %+v", len(got), len(tt.wantRepos), got)
+			}
+			for repo, bin := range tt.wantRepos {
+				if got[repo].BinaryName != bin {
+					t.Fatalf("%s binaryName = %q, want %q", repo, got[repo].BinaryName, bin)
+				}
+			}
+		})
+	}
+}
+
+// TestRepoConfigSchemaValidity validates that repo-config.schema.json is valid JSON Schema 2020-12.
+func TestRepoConfigSchemaValidity(t *testing.T) {
+	c := jsonschema.NewCompiler()
+	_, err := c.Compile("schemas/repo-config.schema.json")
+	if err != nil {
+		t.Fatalf("repo-config schema is not valid JSON Schema 2020-12: %v", err)
+	}
+}
+
+func TestTrustPolicySchemaValidity(t *testing.T) {
+	c := jsonschema.NewCompiler()
+	if _, err := c.Compile("schemas/trust-policy.schema.json"); err != nil {
+		t.Fatalf("trust-policy.schema.json is not a valid JSON Schema: %v", err)
+	}
+}
+
+func TestUpdateTargetSchemaValidity(t *testing.T) {
+	c := jsonschema.NewCompiler()
+	repoSchemaBytes, err := os.ReadFile("schemas/repo-config.schema.json")
+	if err != nil {
+		t.Fatalf("read schemas/repo-config.schema.json: %v", err)
+	}
+	var repoSchemaDoc any
+	if err := json.Unmarshal(repoSchemaBytes, &repoSchemaDoc); err != nil {
+		t.Fatalf("unmarshal schemas/repo-config.schema.json: %v", err)
 	}
 	if err := c.AddResource("https://github.com/3leaps/sfetch/schemas/repo-config.schema.json", repoSchemaDoc); err != nil {
 		t.Fatalf("add repo-config schema resource: %v", err)
@@ -2202,9 +3983,12 @@ func TestProvenanceRecordValidation(t *testing.T) {
 					Release:    &ProvenanceRelease{Tag: "v2025.12.09", URL: "https://github.com/3leaps/sfetch/releases/tag/v2025.12.09"},
 				},
 				Asset: ProvenanceAsset{
-					Name: "sfetch_darwin_arm64.tar.gz",
-					Size: 2200000,
-					URL:  "https://github.com/3leaps/sfetch/releases/download/v2025.12.09/sfetch_darwin_arm64.tar.gz",
+					Name:         "sfetch_darwin_arm64.tar.gz",
+					Size:         2200000,
+					URL:          "https://github.com/3leaps/sfetch/releases/download/v2025.12.09/sfetch_darwin_arm64.tar.gz",
+					ServedFrom:   "https://mirror.example/gh/3leaps/sfetch/releases/download/v2025.12.09/sfetch_darwin_arm64.tar.gz",
+					ResponseDate: "2025-12-09T14:29:58Z",
+					ResponseAge:  func() *int64 { age := int64(3600); return &age }(),
 					ComputedChecksum: &ProvenanceHash{
 						Algorithm: "sha256",
 						Value:     "abc123def456",
@@ -2295,6 +4079,11 @@ func TestProvenanceRecordValidation(t *testing.T) {
 					},
 				},
 				Warnings: []string{"No signature available; authenticity cannot be proven"},
+				FetchLog: []FetchRecord{
+					{URL: "https://api.github.com/repos/BurntSushi/ripgrep/releases/latest", Status: 200, Bytes: 48213},
+					{URL: "https://github.com/BurntSushi/ripgrep/releases/download/15.1.0/ripgrep-15.1.0-aarch64-apple-darwin.tar.gz", Status: 302},
+					{URL: "https://example.invalid/sha256", Error: "dial tcp: no such host"},
+				},
 			},
 			wantErr: false,
 		},
@@ -2428,6 +4217,284 @@ func TestProvenanceRecordValidation(t *testing.T) {
 	}
 }
 
+// TestInTotoStatementValidation validates that the in-toto predicate produced
+// from a ProvenanceRecord conforms to the predicate schema.
+func TestInTotoStatementValidation(t *testing.T) {
+	// The predicate schema $refs provenance.schema.json by its $id; register
+	// the local copy so compilation resolves offline.
+	baseFile, err := os.Open("schemas/provenance.schema.json")
+	if err != nil {
+		t.Fatalf("open base schema: %v", err)
+	}
+	defer baseFile.Close() //nolint:errcheck // read-only test file
+	baseDoc, err := jsonschema.UnmarshalJSON(baseFile)
+	if err != nil {
+		t.Fatalf("parse base schema: %v", err)
+	}
+
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("https://github.com/3leaps/sfetch/schemas/provenance.schema.json", baseDoc); err != nil {
+		t.Fatalf("add base schema: %v", err)
+	}
+	schema, err := c.Compile("schemas/provenance-predicate.schema.json")
+	if err != nil {
+		t.Fatalf("compile schema: %v", err)
+	}
+
+	rel := &Release{TagName: "v1.2.3"}
+	asset := &Asset{
+		Name:               "tool_linux_amd64.tar.gz",
+		Size:               1234,
+		BrowserDownloadUrl: "https://github.com/owner/tool/releases/download/v1.2.3/tool_linux_amd64.tar.gz",
+	}
+	assessment := &VerificationAssessment{
+		SelectedAsset:     asset,
+		ChecksumAvailable: true,
+		ChecksumFile:      "SHA256SUMS",
+		ChecksumType:      "consolidated",
+		ChecksumAlgorithm: "sha256",
+		Workflow:          workflowC,
+		Warnings:          []string{"No signature available; authenticity cannot be proven"},
+	}
+	finalizeAssessmentTrust(assessment, rel, assessmentFlags{})
+
+	digest := strings.Repeat("a", 64)
+
+	tests := []struct {
+		name   string
+		dryRun bool
+	}{
+		{name: "installed asset"},
+		{name: "dry-run with --verify-only", dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := buildProvenanceRecord("owner/tool", rel, assessment, assessmentFlags{dryRun: tt.dryRun}, digest)
+			stmt := buildInTotoStatement(record)
+
+			if stmt.Type != inTotoStatementType {
+				t.Errorf("_type: got %q want %q", stmt.Type, inTotoStatementType)
+			}
+			if stmt.PredicateType != sfetchPredicateTypeURI {
+				t.Errorf("predicateType: got %q want %q", stmt.PredicateType, sfetchPredicateTypeURI)
+			}
+			if len(stmt.Subject) != 1 || stmt.Subject[0].Name != asset.Name {
+				t.Fatalf("subject: got %+v", stmt.Subject)
+			}
+			if got := stmt.Subject[0].Digest["sha256"]; got != digest {
+				t.Errorf("subject digest: got %q want %q", got, digest)
+			}
+
+			jsonBytes, err := json.Marshal(stmt.Predicate)
+			if err != nil {
+				t.Fatalf("marshal predicate: %v", err)
+			}
+			var doc interface{}
+			if err := json.Unmarshal(jsonBytes, &doc); err != nil {
+				t.Fatalf("unmarshal to interface: %v", err)
+			}
+			if err := schema.Validate(doc); err != nil {
+				t.Errorf("unexpected validation error: %v\nJSON: %s", err, jsonBytes)
+			}
+		})
+	}
+}
+
+func TestSLSAStatementValidation(t *testing.T) {
+	baseFile, err := os.Open("schemas/provenance.schema.json")
+	if err != nil {
+		t.Fatalf("open base schema: %v", err)
+	}
+	defer baseFile.Close() //nolint:errcheck // read-only test file
+	baseDoc, err := jsonschema.UnmarshalJSON(baseFile)
+	if err != nil {
+		t.Fatalf("parse base schema: %v", err)
+	}
+
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("https://github.com/3leaps/sfetch/schemas/provenance.schema.json", baseDoc); err != nil {
+		t.Fatalf("add base schema: %v", err)
+	}
+	schema, err := c.Compile("schemas/slsa-provenance.schema.json")
+	if err != nil {
+		t.Fatalf("compile schema: %v", err)
+	}
+
+	rel := &Release{TagName: "v1.2.3"}
+	asset := &Asset{
+		Name:               "tool_linux_amd64.tar.gz",
+		Size:               1234,
+		BrowserDownloadUrl: "https://github.com/owner/tool/releases/download/v1.2.3/tool_linux_amd64.tar.gz",
+	}
+	assessment := &VerificationAssessment{
+		SelectedAsset:       asset,
+		SignatureAvailable:  true,
+		SignatureFormat:     "minisign",
+		SignatureFile:       "SHA256SUMS.minisig",
+		SignatureIsChecksum: true,
+		ChecksumAvailable:   true,
+		ChecksumFile:        "SHA256SUMS",
+		ChecksumType:        "consolidated",
+		ChecksumAlgorithm:   "sha256",
+		Workflow:            workflowA,
+	}
+	finalizeAssessmentTrust(assessment, rel, assessmentFlags{})
+
+	digest := strings.Repeat("a", 64)
+
+	tests := []struct {
+		name   string
+		dryRun bool
+	}{
+		{name: "installed asset"},
+		{name: "dry-run with --verify-only", dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := buildProvenanceRecord("owner/tool", rel, assessment, assessmentFlags{dryRun: tt.dryRun}, digest)
+			stmt := buildSLSAStatement(record)
+
+			if stmt.PredicateType != slsaPredicateTypeURI {
+				t.Errorf("predicateType: got %q want %q", stmt.PredicateType, slsaPredicateTypeURI)
+			}
+			ext := stmt.Predicate.BuildDefinition.ExternalParameters
+			if ext.Source.Repository != "owner/tool" || ext.Source.Release == nil || ext.Source.Release.Tag != "v1.2.3" {
+				t.Errorf("externalParameters.source: got %+v", ext.Source)
+			}
+			deps := stmt.Predicate.BuildDefinition.ResolvedDependencies
+			var uris []string
+			for _, d := range deps {
+				uris = append(uris, d.URI)
+			}
+			wantURIs := []string{
+				asset.BrowserDownloadUrl,
+				"https://github.com/owner/tool/releases/download/v1.2.3/SHA256SUMS",
+				"https://github.com/owner/tool/releases/download/v1.2.3/SHA256SUMS.minisig",
+			}
+			if !reflect.DeepEqual(uris, wantURIs) {
+				t.Errorf("resolvedDependencies URIs: got %v want %v", uris, wantURIs)
+			}
+			if got := deps[0].Digest["sha256"]; got != digest {
+				t.Errorf("asset dependency digest: got %q want %q", got, digest)
+			}
+			if got := stmt.Subject[0].Digest["sha256"]; got != digest {
+				t.Errorf("subject digest: got %q want %q", got, digest)
+			}
+
+			jsonBytes, err := json.Marshal(stmt)
+			if err != nil {
+				t.Fatalf("marshal statement: %v", err)
+			}
+			var doc interface{}
+			if err := json.Unmarshal(jsonBytes, &doc); err != nil {
+				t.Fatalf("unmarshal to interface: %v", err)
+			}
+			if err := schema.Validate(doc); err != nil {
+				t.Errorf("unexpected validation error: %v\nJSON: %s", err, jsonBytes)
+			}
+		})
+	}
+}
+
+func TestRecordInstalled(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "tool")
+	if err := os.WriteFile(bin, []byte("tool binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("tool binary"))
+
+	tests := []struct {
+		name      string
+		installed []installedBinary
+		want      []ProvenanceExtractedBinary
+	}{
+		{
+			name:      "archive member",
+			installed: []installedBinary{{name: "tool", path: bin, member: "tool-1.0/bin/tool"}},
+			want:      []ProvenanceExtractedBinary{{Path: bin, Member: "tool-1.0/bin/tool", Size: 11, SHA256: hex.EncodeToString(sum[:])}},
+		},
+		{
+			name:      "raw asset",
+			installed: []installedBinary{{name: "tool", path: bin}},
+		},
+		{
+			name:      "unreadable file is left out",
+			installed: []installedBinary{{name: "gone", path: filepath.Join(dir, "gone"), member: "gone"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &ProvenanceRecord{}
+			recordInstalled(record, tt.installed)
+			if !reflect.DeepEqual(record.ExtractedBinaries, tt.want) {
+				t.Fatalf("extractedBinaries = %+v, want %+v", record.ExtractedBinaries, tt.want)
+			}
+			data, err := json.Marshal(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil && strings.Contains(string(data), "extractedBinaries") {
+				t.Fatalf("extractedBinaries not omitted: %s", data)
+			}
+		})
+	}
+}
+
+func TestOutputProvenanceInTotoFile(t *testing.T) {
+	record := &ProvenanceRecord{
+		Version: "1.0.0",
+		Asset: ProvenanceAsset{
+			Name:             "tool",
+			ComputedChecksum: &ProvenanceHash{Algorithm: "sha256", Value: "abc123"},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "statement.json")
+	if err := outputProvenance(record, path, provenanceFormatInToto); err != nil {
+		t.Fatalf("outputProvenance: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read statement: %v", err)
+	}
+	var stmt InTotoStatement
+	if err := json.Unmarshal(data, &stmt); err != nil {
+		t.Fatalf("unmarshal statement: %v", err)
+	}
+	if stmt.Type != inTotoStatementType || stmt.Subject[0].Digest["sha256"] != "abc123" {
+		t.Fatalf("unexpected statement: %s", data)
+	}
+}
+
+func TestNormalizeProvenanceFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: provenanceFormatSfetch},
+		{in: "sfetch", want: provenanceFormatSfetch},
+		{in: "intoto", want: provenanceFormatInToto},
+		{in: "In-Toto", want: provenanceFormatInToto},
+		{in: "SLSA", want: provenanceFormatSLSA},
+		{in: "spdx", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeProvenanceFormat(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeProvenanceFormat(%q): expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeProvenanceFormat(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
 // TestEmbeddedTrustAnchors validates the embedded trust anchor constants.
 func TestEmbeddedTrustAnchors(t *testing.T) {
 	t.Run("minisign pubkey has correct format", func(t *testing.T) {
@@ -2526,9 +4593,72 @@ func TestChecksumCommand(t *testing.T) {
 }
 
 // TestFetchExpectedHash tests the SHA256SUMS parsing logic.
+func TestFetchReleaseFollowsAssetsURL(t *testing.T) {
+	// 130 decoys plus the linux/amd64 build, which only the second page of
+	// the asset list carries.
+	var all []Asset
+	for i := 0; i < 130; i++ {
+		all = append(all, Asset{ID: int64(i + 1), Name: fmt.Sprintf("plugin-%03d.txt", i)})
+	}
+	target := Asset{ID: 1000, Name: "tool_linux_amd64.tar.gz"}
+	all = append(all[:120], append([]Asset{target}, all[120:]...)...)
+
+	var pages []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"tag_name":   "v1.0.0",
+				"assets":     all[:truncatedAssetCount],
+				"assets_url": "http://" + r.Host + "/repos/o/tool/releases/1/assets",
+			})
+		case "/repos/o/tool/releases/1/assets":
+			pages = append(pages, r.URL.RawQuery)
+			perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			start := min((page-1)*perPage, len(all))
+			end := min(start+perPage, len(all))
+			_ = json.NewEncoder(w).Encode(all[start:end])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	rel, err := fetchRelease(context.Background(), ts.URL+"/repos/o/tool/releases/latest")
+	if err != nil {
+		t.Fatalf("fetchRelease: %v", err)
+	}
+	if len(rel.Assets) != len(all) {
+		t.Fatalf("got %d assets, want %d", len(rel.Assets), len(all))
+	}
+	if want := []string{"page=1&per_page=100", "page=2&per_page=100"}; !reflect.DeepEqual(pages, want) {
+		t.Fatalf("asset pages requested = %v, want %v", pages, want)
+	}
+	selected, err := selectAsset(&rel, getConfig("o/tool"), "linux", "amd64", platformHints{}, "", "", false)
+	if err != nil {
+		t.Fatalf("selectAsset: %v", err)
+	}
+	if selected.Name != target.Name {
+		t.Fatalf("selected %s, want %s", selected.Name, target.Name)
+	}
+}
+
+func TestMergeAssets(t *testing.T) {
+	got := mergeAssets(
+		[]Asset{{ID: 1, Name: "a"}, {Name: "b"}},
+		[]Asset{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}, {ID: 3, Name: "c"}},
+	)
+	want := []Asset{{ID: 1, Name: "a"}, {Name: "b"}, {ID: 3, Name: "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergeAssets = %+v, want %+v", got, want)
+	}
+}
+
 func TestFetchExpectedHash(t *testing.T) {
 	t.Run("dev build returns error", func(t *testing.T) {
-		_, err := fetchExpectedHash("dev", "sfetch_darwin_arm64.tar.gz")
+		_, err := fetchExpectedHash(context.Background(), "dev", "sfetch_darwin_arm64.tar.gz")
 		if err == nil {
 			t.Error("expected error for dev build")
 		}
@@ -2724,32 +4854,278 @@ func TestAssessReleasePrefersSHA512ManifestSetsAlgo(t *testing.T) {
 	}
 }
 
-// TestSelfVerifyOutputJSON validates JSON output structure.
-func TestSelfVerifyOutputJSON(t *testing.T) {
-	// Test that the JSON struct marshals correctly
-	output := SelfVerifyOutput{
-		Version:     "0.2.1",
-		Platform:    "darwin/arm64",
-		BuildTime:   "2025-12-10T16:00:00Z",
-		GitCommit:   "abc123",
-		IsDev:       false,
-		Asset:       "sfetch_darwin_arm64.tar.gz",
-		ExpectedSHA: "b66cd9d99e70edec01980fe8f8587ce426f556f8bcb102f4a94c3d72b7690d0b",
-		URLs: &SelfVerifyURLs{
-			SHA256SUMS:        "https://github.com/3leaps/sfetch/releases/download/v0.2.1/SHA256SUMS",
-			SHA256SUMSMinisig: "https://github.com/3leaps/sfetch/releases/download/v0.2.1/SHA256SUMS.minisig",
-			SHA256SUMSAsc:     "https://github.com/3leaps/sfetch/releases/download/v0.2.1/SHA256SUMS.asc",
-		},
-		TrustAnchor: &TrustAnchorInfo{
-			MinisignPubkey: EmbeddedMinisignPubkey,
-			KeyID:          EmbeddedMinisignKeyID,
-		},
-		Commands: &VerifyCommands{
-			Checksum: "shasum -a 256 $(which sfetch)",
-			Minisign: "minisign -Vm /tmp/SHA256SUMS -P " + EmbeddedMinisignPubkey,
-		},
-		Warning: "A compromised binary could lie. Run verification commands yourself.",
-	}
+func TestAssessReleaseFindsSiblingChecksum(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		sibling  string
+		wantAlgo string
+	}{
+		{name: "uppercase extension", sibling: "tool_linux_amd64.tar.gz.SHA256", wantAlgo: "sha256"},
+		{name: "sha512 sibling", sibling: "tool_linux_amd64.tar.gz.sha512", wantAlgo: "sha512"},
+		{name: "mixed case txt", sibling: "TOOL_linux_amd64.tar.gz.Sha512.TXT", wantAlgo: "sha512"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaults
+			cfg.BinaryName = "tool"
+			rel := &Release{
+				TagName: "v1.0.0",
+				Assets: []Asset{
+					{Name: "tool_linux_amd64.tar.gz"},
+					{Name: "tool_darwin_arm64.tar.gz.sha256"},
+					{Name: tt.sibling},
+				},
+			}
+
+			assessment := assessRelease(rel, &cfg, &rel.Assets[0], assessmentFlags{})
+			if assessment.Workflow != workflowC {
+				t.Fatalf("workflow = %q, want %q", assessment.Workflow, workflowC)
+			}
+			if assessment.ChecksumFile != tt.sibling {
+				t.Fatalf("checksum file = %q, want %q", assessment.ChecksumFile, tt.sibling)
+			}
+			if assessment.ChecksumType != "per-asset" || assessment.ChecksumAlgorithm != tt.wantAlgo {
+				t.Fatalf("checksum type/algo = %s/%s, want per-asset/%s", assessment.ChecksumType, assessment.ChecksumAlgorithm, tt.wantAlgo)
+			}
+		})
+	}
+}
+
+func TestAssessReleaseDiscoversArtifacts(t *testing.T) {
+	t.Parallel()
+
+	const asset = "tool_linux_amd64.tar.gz"
+	tests := []struct {
+		name           string
+		asset          string // selected asset; asset when empty
+		others         []string
+		wantWorkflow   string
+		wantChecksum   string
+		wantType       string
+		wantAlgo       string
+		wantSig        string
+		wantDiscovered bool
+	}{
+		{
+			name:         "checksums_sha256.txt",
+			others:       []string{"tool_darwin_arm64.tar.gz", "checksums_sha256.txt"},
+			wantWorkflow: workflowC, wantChecksum: "checksums_sha256.txt", wantType: "consolidated", wantAlgo: "sha256",
+			wantDiscovered: true,
+		},
+		{
+			name:         "versioned sha256sum manifest",
+			asset:        "tool-v1.2.3-linux-amd64.tar.gz",
+			others:       []string{"tool-v1.2.3-darwin-arm64.tar.gz", "tool-v1.2.3.sha256sum"},
+			wantWorkflow: workflowC, wantChecksum: "tool-v1.2.3.sha256sum", wantType: "consolidated", wantAlgo: "sha256",
+			wantDiscovered: true,
+		},
+		{
+			name:         "goreleaser-style SHA512SUMS",
+			others:       []string{"tool_1.2.3_SHA512SUMS"},
+			wantWorkflow: workflowC, wantChecksum: "tool_1.2.3_SHA512SUMS", wantType: "consolidated", wantAlgo: "sha512",
+			wantDiscovered: true,
+		},
+		{
+			name:         "signed SUMS",
+			others:       []string{"SUMS", "SUMS.asc"},
+			wantWorkflow: workflowA, wantChecksum: "SUMS", wantType: "consolidated", wantAlgo: "sha256", wantSig: "SUMS.asc",
+			wantDiscovered: true,
+		},
+		{
+			name:         "asset-specific beats consolidated",
+			others:       []string{"checksums-sha512.txt", "tool_linux_amd64.tar.gz.sha256sum"},
+			wantWorkflow: workflowC, wantChecksum: "tool_linux_amd64.tar.gz.sha256sum", wantType: "per-asset", wantAlgo: "sha256",
+			wantDiscovered: true,
+		},
+		{
+			name:         "minisign over the base name",
+			others:       []string{"tool_linux_amd64.minisig", "checksums_sha256.txt"},
+			wantWorkflow: workflowB, wantChecksum: "checksums_sha256.txt", wantType: "consolidated", wantAlgo: "sha256", wantSig: "tool_linux_amd64.minisig",
+			wantDiscovered: true,
+		},
+		{
+			name:         "other platforms and look-alikes are ignored",
+			others:       []string{"tool_darwin_arm64.tar.gz", "tool_darwin_arm64.tar.gz.sha256sum", "tool_darwin_arm64.minisig", "summary.txt"},
+			wantWorkflow: workflowNone,
+		},
+		{
+			name:         "config names win",
+			others:       []string{"SHA256SUMS", "checksums_sha512.txt"},
+			wantWorkflow: workflowC, wantChecksum: "SHA256SUMS", wantType: "consolidated", wantAlgo: "sha256",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := defaults
+			cfg.BinaryName = "tool"
+			selected := tt.asset
+			if selected == "" {
+				selected = asset
+			}
+			rel := &Release{TagName: "v1.2.3", Assets: []Asset{{Name: selected}}}
+			for _, name := range tt.others {
+				rel.Assets = append(rel.Assets, Asset{Name: name})
+			}
+
+			assessment := assessRelease(rel, &cfg, &rel.Assets[0], assessmentFlags{})
+			if assessment.Workflow != tt.wantWorkflow {
+				t.Fatalf("workflow = %q, want %q", assessment.Workflow, tt.wantWorkflow)
+			}
+			if assessment.ChecksumFile != tt.wantChecksum || assessment.SignatureFile != tt.wantSig {
+				t.Fatalf("checksum/signature = %q/%q, want %q/%q", assessment.ChecksumFile, assessment.SignatureFile, tt.wantChecksum, tt.wantSig)
+			}
+			if tt.wantChecksum != "" && (assessment.ChecksumType != tt.wantType || assessment.ChecksumAlgorithm != tt.wantAlgo) {
+				t.Errorf("checksum type/algo = %s/%s, want %s/%s", assessment.ChecksumType, assessment.ChecksumAlgorithm, tt.wantType, tt.wantAlgo)
+			}
+			if tt.wantChecksum != "" && assessment.ChecksumDiscovered != tt.wantDiscovered {
+				t.Errorf("checksum discovered = %v, want %v", assessment.ChecksumDiscovered, tt.wantDiscovered)
+			}
+			if tt.wantSig != "" && assessment.SignatureDiscovered != tt.wantDiscovered {
+				t.Errorf("signature discovered = %v, want %v", assessment.SignatureDiscovered, tt.wantDiscovered)
+			}
+
+			out := formatDryRunOutput("o/tool", rel, assessment, nil)
+			if got := strings.Count(out, "discovered, not from config"); tt.wantDiscovered != (got > 0) {
+				t.Errorf("dry-run labels = %d, want discovered=%v:\n%s", got, tt.wantDiscovered, out)
+			}
+			record := newReleaseAssessmentRecord("o/tool", rel, assessment, nil)
+			if record.Checksum.Discovered != assessment.ChecksumDiscovered || record.Signature.Discovered != assessment.SignatureDiscovered {
+				t.Errorf("assessment record discovered = %v/%v", record.Checksum.Discovered, record.Signature.Discovered)
+			}
+		})
+	}
+}
+
+func TestAssessReleaseChecksumChoices(t *testing.T) {
+	t.Parallel()
+
+	assets := []Asset{
+		{Name: "tool_linux_amd64.tar.gz"},
+		{Name: "checksums.txt"},
+		{Name: "SHA256SUMS"},
+		{Name: "tool_linux_amd64.tar.gz.sha256"},
+	}
+	tests := []struct {
+		name        string
+		assets      []Asset
+		flags       assessmentFlags
+		wantFile    string
+		wantChoices []string
+	}{
+		{
+			name:        "auto-pick records every manifest",
+			assets:      assets,
+			wantFile:    "tool_linux_amd64.tar.gz.sha256",
+			wantChoices: []string{"tool_linux_amd64.tar.gz.sha256", "SHA256SUMS", "checksums.txt"},
+		},
+		{
+			name:     "checksum-file override",
+			assets:   assets,
+			flags:    assessmentFlags{checksumFile: "checksums.txt"},
+			wantFile: "checksums.txt",
+		},
+		{
+			name:        "single manifest",
+			assets:      assets[:2],
+			wantFile:    "checksums.txt",
+			wantChoices: []string{"checksums.txt"},
+		},
+		{
+			name: "signed manifest is not a choice",
+			assets: append(append([]Asset(nil), assets...),
+				Asset{Name: "checksums.txt.minisig"}),
+			wantFile: "checksums.txt",
+		},
+		{
+			name: "checksum-file selects the signed manifest it names",
+			assets: append(append([]Asset(nil), assets...),
+				Asset{Name: "SHA256SUMS.minisig"}, Asset{Name: "checksums.txt.minisig"}),
+			flags:    assessmentFlags{checksumFile: "checksums.txt"},
+			wantFile: "checksums.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaults
+			cfg.BinaryName = "tool"
+			rel := &Release{TagName: "v1.0.0", Assets: tt.assets}
+
+			assessment := assessRelease(rel, &cfg, &rel.Assets[0], tt.flags)
+			if assessment.ChecksumFile != tt.wantFile {
+				t.Fatalf("checksum file = %q, want %q", assessment.ChecksumFile, tt.wantFile)
+			}
+			if strings.Join(assessment.ChecksumChoices, ",") != strings.Join(tt.wantChoices, ",") {
+				t.Fatalf("checksum choices = %v, want %v", assessment.ChecksumChoices, tt.wantChoices)
+			}
+		})
+	}
+}
+
+func TestAssessReleaseUsesSignedManifestAlgo(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaults
+	cfg.HashAlgo = "sha512"
+	cfg.PreferChecksumSig = boolPtr(true)
+
+	// Only the sha256 manifest is signed; the stronger sha512 manifest must
+	// not be picked just because it exists. A dangling SHA2-512SUMS.minisig
+	// (its manifest is missing) must not be paired either.
+	rel := &Release{
+		TagName: "v0.2.4",
+		Assets: []Asset{
+			{Name: "sfetch_darwin_arm64.tar.gz"},
+			{Name: "SHA512SUMS"},
+			{Name: "SHA2-512SUMS.minisig"},
+			{Name: "SHA256SUMS"},
+			{Name: "SHA256SUMS.minisig"},
+		},
+	}
+
+	assessment := assessRelease(rel, &cfg, &rel.Assets[0], assessmentFlags{})
+	if assessment.Workflow != workflowA {
+		t.Fatalf("workflow = %q, want %q", assessment.Workflow, workflowA)
+	}
+	if assessment.SignatureFile != "SHA256SUMS.minisig" {
+		t.Fatalf("signature file = %q, want %q", assessment.SignatureFile, "SHA256SUMS.minisig")
+	}
+	if assessment.ChecksumFileForSig != "SHA256SUMS" || assessment.ChecksumFile != "SHA256SUMS" {
+		t.Fatalf("checksum file = %q (for sig %q), want SHA256SUMS", assessment.ChecksumFile, assessment.ChecksumFileForSig)
+	}
+	if assessment.ChecksumAlgorithm != "sha256" {
+		t.Fatalf("checksum algorithm = %q, want %q", assessment.ChecksumAlgorithm, "sha256")
+	}
+}
+
+// TestSelfVerifyOutputJSON validates JSON output structure.
+func TestSelfVerifyOutputJSON(t *testing.T) {
+	// Test that the JSON struct marshals correctly
+	output := SelfVerifyOutput{
+		Version:     "0.2.1",
+		Platform:    "darwin/arm64",
+		BuildTime:   "2025-12-10T16:00:00Z",
+		GitCommit:   "abc123",
+		IsDev:       false,
+		Asset:       "sfetch_darwin_arm64.tar.gz",
+		ExpectedSHA: "b66cd9d99e70edec01980fe8f8587ce426f556f8bcb102f4a94c3d72b7690d0b",
+		URLs: &SelfVerifyURLs{
+			SHA256SUMS:        "https://github.com/3leaps/sfetch/releases/download/v0.2.1/SHA256SUMS",
+			SHA256SUMSMinisig: "https://github.com/3leaps/sfetch/releases/download/v0.2.1/SHA256SUMS.minisig",
+			SHA256SUMSAsc:     "https://github.com/3leaps/sfetch/releases/download/v0.2.1/SHA256SUMS.asc",
+		},
+		TrustAnchor: &TrustAnchorInfo{
+			MinisignPubkey: EmbeddedMinisignPubkey,
+			KeyID:          EmbeddedMinisignKeyID,
+		},
+		Commands: &VerifyCommands{
+			Checksum: "shasum -a 256 $(which sfetch)",
+			Minisign: "minisign -Vm /tmp/SHA256SUMS -P " + EmbeddedMinisignPubkey,
+		},
+		Warning: "A compromised binary could lie. Run verification commands yourself.",
+	}
 
 	data, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
@@ -2812,6 +5188,279 @@ func TestSelfVerifyDevBuild(t *testing.T) {
 }
 
 // TestProvenanceSchemaRejectsInvalid ensures the schema properly rejects invalid records.
+func TestAssessmentSchemaValidity(t *testing.T) {
+	c := jsonschema.NewCompiler()
+	if _, err := c.Compile("schemas/assessment.schema.json"); err != nil {
+		t.Fatalf("assessment.schema.json is not a valid JSON Schema: %v", err)
+	}
+}
+
+func TestAssessmentRecordValidation(t *testing.T) {
+	schema, err := compileEmbeddedSchema("assessment.schema.json", assessmentSchemaJSON)
+	if err != nil {
+		t.Fatalf("compile embedded schema: %v", err)
+	}
+
+	workflowA := &VerificationAssessment{
+		SelectedAsset:       &Asset{Name: "tool_linux_amd64.tar.gz", Size: 2200000, BrowserDownloadUrl: "https://github.com/o/tool/releases/download/v1.0.0/tool_linux_amd64.tar.gz"},
+		SignatureAvailable:  true,
+		SignatureFormat:     sigFormatMinisign,
+		SignatureFile:       "SHA256SUMS.minisig",
+		SignatureIsChecksum: true,
+		SignatureChecks: []SignatureCheck{
+			{File: "SHA256SUMS.minisig", Format: sigFormatMinisign, Verifiable: true},
+			{File: "SHA256SUMS.asc", Format: sigFormatPGP},
+		},
+		ChecksumAvailable: true,
+		ChecksumFile:      "SHA256SUMS",
+		ChecksumAlgorithm: "sha256",
+		ChecksumType:      "consolidated",
+		Workflow:          workflowA,
+		Trust: computeTrustScore(trustScoreInput{
+			SignatureVerifiable: true, SignatureValidated: true,
+			ChecksumVerifiable: true, ChecksumValidated: true, ChecksumAlgorithm: "sha256",
+			HTTPSUsed: true,
+		}, defaultTrustPolicy),
+		Warnings: []string{},
+	}
+	none := &VerificationAssessment{
+		SelectedAsset: &Asset{Name: "install.sh"},
+		Workflow:      workflowNone,
+		Trust:         computeTrustScore(trustScoreInput{HTTPSUsed: true}, defaultTrustPolicy),
+		Warnings:      []string{"No verification artifacts"},
+	}
+	release := func() *AssessmentRecord {
+		return newReleaseAssessmentRecord("o/tool", &Release{TagName: "v1.0.0"}, workflowA, nil)
+	}
+
+	tests := []struct {
+		name    string
+		record  *AssessmentRecord
+		wantErr bool
+	}{
+		{name: "release workflow A", record: release()},
+		{
+			name: "self-update",
+			record: newReleaseAssessmentRecord("3leaps/sfetch", &Release{TagName: "v2.0.0"}, workflowA,
+				&SelfUpdateDryRunInfo{CurrentVersion: "v1.0.0", TargetVersion: "v2.0.0", Decision: update.DecisionProceed}),
+		},
+		{
+			name: "url with redirects",
+			record: newURLAssessmentRecord(urlSpec{URL: "https://example.com/dl/install.sh"},
+				urlFetchResult{finalURL: "https://cdn.example.com/install.sh", redirects: []string{"https://cdn.example.com/install.sh"}, contentType: "text/x-shellscript"}, none),
+		},
+		{
+			name:   "github raw",
+			record: newRawAssessmentRecord(githubRawSpec{Repo: "o/tool", Ref: "main", Path: "install.sh", URL: "https://raw.githubusercontent.com/o/tool/main/install.sh"}, none),
+		},
+		{
+			name:    "unknown workflow",
+			record:  func() *AssessmentRecord { r := release(); r.Workflow = "D"; return r }(),
+			wantErr: true,
+		},
+		{
+			name:    "unknown signature format",
+			record:  func() *AssessmentRecord { r := release(); r.Signature.Format = "sigstore"; return r }(),
+			wantErr: true,
+		},
+		{
+			name:    "trust score out of range",
+			record:  func() *AssessmentRecord { r := release(); r.Trust.Score = 101; return r }(),
+			wantErr: true,
+		},
+		{
+			name:    "bad repository",
+			record:  func() *AssessmentRecord { r := release(); r.Source.Repository = "tool"; return r }(),
+			wantErr: true,
+		},
+		{
+			name: "wrong schema id",
+			record: func() *AssessmentRecord {
+				r := release()
+				r.Schema = "https://github.com/3leaps/sfetch/schemas/provenance.schema.json"
+				return r
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.record)
+			if err != nil {
+				t.Fatalf("marshal record: %v", err)
+			}
+			doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("unmarshal record: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected validation error, got nil\nJSON: %s", data)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected validation error: %v\nJSON: %s", err, data)
+			}
+		})
+	}
+}
+
+func TestAssessmentSchemaRejectsInvalid(t *testing.T) {
+	schema, err := compileEmbeddedSchema("assessment.schema.json", assessmentSchemaJSON)
+	if err != nil {
+		t.Fatalf("compile embedded schema: %v", err)
+	}
+	trust := `{"score": 25, "level": 1, "levelName": "minimal", "factors": {
+		"signature": {"verifiable": false, "validated": false, "skipped": false, "points": 0},
+		"checksum": {"verifiable": false, "validated": false, "skipped": false, "points": 0},
+		"transport": {"https": true, "points": 25},
+		"algorithm": {"points": 0}}}`
+
+	tests := []struct {
+		name string
+		json string
+	}{
+		{name: "missing trust", json: `{"version": "1.0.0", "source": {"type": "github"}, "workflow": "none", "signature": {"available": false}, "checksum": {"available": false}, "warnings": []}`},
+		{name: "missing warnings", json: `{"version": "1.0.0", "source": {"type": "github"}, "workflow": "none", "signature": {"available": false}, "checksum": {"available": false}, "trust": ` + trust + `}`},
+		{name: "unknown source type", json: `{"version": "1.0.0", "source": {"type": "gitlab"}, "workflow": "none", "signature": {"available": false}, "checksum": {"available": false}, "trust": ` + trust + `, "warnings": []}`},
+		{name: "unknown top-level field", json: `{"version": "1.0.0", "source": {"type": "github"}, "workflow": "none", "signature": {"available": false}, "checksum": {"available": false}, "trust": ` + trust + `, "warnings": [], "verified": true}`},
+		{name: "bad version", json: `{"version": "1", "source": {"type": "github"}, "workflow": "none", "signature": {"available": false}, "checksum": {"available": false}, "trust": ` + trust + `, "warnings": []}`},
+		{name: "asset without name", json: `{"version": "1.0.0", "source": {"type": "github"}, "asset": {"size": 1}, "workflow": "none", "signature": {"available": false}, "checksum": {"available": false}, "trust": ` + trust + `, "warnings": []}`},
+		{name: "unknown checksum algorithm", json: `{"version": "1.0.0", "source": {"type": "github"}, "workflow": "C", "signature": {"available": false}, "checksum": {"available": true, "algorithm": "crc32"}, "trust": ` + trust + `, "warnings": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := jsonschema.UnmarshalJSON(strings.NewReader(tt.json))
+			if err != nil {
+				t.Fatalf("parse test JSON: %v", err)
+			}
+			if err := schema.Validate(doc); err == nil {
+				t.Fatal("expected validation error, got nil")
+			}
+		})
+	}
+}
+
+func TestRunUpdateAvailableNote(t *testing.T) {
+	origVersion := version
+	version = "v0.2.0"
+	defer func() { version = origVersion }()
+
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	var sfetchLookups atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/repos/3leaps/sfetch/releases/latest":
+			sfetchLookups.Add(1)
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v0.3.0"})
+		case "/repos/o/tool/releases/latest":
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, Size: 1024, BrowserDownloadUrl: "http://" + r.Host + "/dl/" + assetName},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	const note = "note: sfetch v0.3.0 is available (current v0.2.0)"
+	runDryRun := func(cacheDir string) string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"--repo", "o/tool", "--latest", "--dry-run", "--cache-dir", cacheDir, "--skip-tools-check"}, &stdout, &stderr); code != exitOK {
+			t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitOK, stderr.String())
+		}
+		return stderr.String()
+	}
+
+	t.Run("announced once per release", func(t *testing.T) {
+		sfetchLookups.Store(0)
+		cacheDir := t.TempDir()
+		if out := runDryRun(cacheDir); !strings.Contains(out, note) {
+			t.Fatalf("first run: stderr missing %q:\n%s", note, out)
+		}
+		if out := runDryRun(cacheDir); strings.Contains(out, note) {
+			t.Fatalf("second run repeated the note:\n%s", out)
+		}
+		if n := sfetchLookups.Load(); n != 1 {
+			t.Fatalf("latest sfetch release looked up %d times, want 1 within the interval", n)
+		}
+	})
+
+	t.Run("disabled by env", func(t *testing.T) {
+		sfetchLookups.Store(0)
+		t.Setenv(updateCheckEnv, "1")
+		if out := runDryRun(t.TempDir()); strings.Contains(out, "is available") {
+			t.Fatalf("note printed despite %s:\n%s", updateCheckEnv, out)
+		}
+		if n := sfetchLookups.Load(); n != 0 {
+			t.Fatalf("latest sfetch release looked up %d times, want 0", n)
+		}
+	})
+}
+
+func TestRunDryRunJSON(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/tool/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+			{Name: assetName, Size: 1024, BrowserDownloadUrl: base + "/dl/" + assetName},
+			{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+		}})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--latest", "--dry-run", "--json", "--skip-tools-check"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitOK, stderr.String())
+	}
+	if strings.Contains(stderr.String(), "sfetch dry-run assessment") {
+		t.Errorf("--json should replace the human-readable assessment:\n%s", stderr.String())
+	}
+
+	var result struct {
+		ExitCode   int             `json:"exitCode"`
+		Assessment json.RawMessage `json:"assessment"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("parse stdout: %v\n%s", err, stdout.String())
+	}
+	if len(result.Assessment) == 0 {
+		t.Fatalf("no assessment in result:\n%s", stdout.String())
+	}
+
+	schema, err := compileEmbeddedSchema("assessment.schema.json", assessmentSchemaJSON)
+	if err != nil {
+		t.Fatalf("compile embedded schema: %v", err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(result.Assessment))
+	if err != nil {
+		t.Fatalf("unmarshal assessment: %v", err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		t.Fatalf("assessment does not match schema: %v\n%s", err, result.Assessment)
+	}
+
+	var record AssessmentRecord
+	if err := json.Unmarshal(result.Assessment, &record); err != nil {
+		t.Fatalf("parse assessment: %v", err)
+	}
+	if record.Workflow != workflowC || record.Source.Tag != "v1.0.0" || record.Asset == nil || record.Asset.Name != assetName {
+		t.Errorf("assessment = %+v, want workflow C for %s at v1.0.0", record, assetName)
+	}
+	if record.Trust.Score != 45 {
+		t.Errorf("trust score = %d, want 45", record.Trust.Score)
+	}
+}
+
 func TestProvenanceSchemaRejectsInvalid(t *testing.T) {
 	c := jsonschema.NewCompiler()
 	schema, err := c.Compile("schemas/provenance.schema.json")
@@ -3503,7 +6152,7 @@ func TestMatchWithMatch(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := matchWithMatch(assets, tt.pattern, cfg, tt.goos, tt.goarch)
+			got, err := matchWithMatch(assets, tt.pattern, false, cfg, tt.goos, tt.goarch, platformHints{})
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("matchWithMatch() expected error, got nil")
@@ -3549,7 +6198,7 @@ func TestMatchWithRegex(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			re := regexp.MustCompile(tt.regex)
-			got, err := matchWithRegex(assets, re, cfg, tt.goos, tt.goarch)
+			got, err := matchWithRegex(assets, re, false, cfg, tt.goos, tt.goarch, platformHints{})
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("matchWithRegex() expected error, got nil")
@@ -3576,6 +6225,7 @@ func TestPickByHeuristics(t *testing.T) {
 		cfg       *RepoConfig
 		goos      string
 		goarch    string
+		hints     platformHints
 		wantAsset string
 		wantErr   bool
 	}{
@@ -3692,26 +6342,413 @@ func TestPickByHeuristics(t *testing.T) {
 			wantAsset: "goneat_v0.5.2_windows_amd64.zip",
 			wantErr:   false,
 		},
+		{
+			name: "musl host prefers musl asset",
+			assets: []Asset{
+				{Name: "tool-x86_64-unknown-linux-gnu.tar.gz"},
+				{Name: "tool-x86_64-unknown-linux-musl.tar.gz"},
+			},
+			cfg:       &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz"}},
+			goos:      "linux",
+			goarch:    "amd64",
+			hints:     platformHints{Libc: "musl"},
+			wantAsset: "tool-x86_64-unknown-linux-musl.tar.gz",
+			wantErr:   false,
+		},
+		{
+			name: "glibc host prefers gnu asset",
+			assets: []Asset{
+				{Name: "tool-x86_64-unknown-linux-gnu.tar.gz"},
+				{Name: "tool-x86_64-unknown-linux-musl.tar.gz"},
+			},
+			cfg:       &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz"}},
+			goos:      "linux",
+			goarch:    "amd64",
+			hints:     platformHints{Libc: "gnu"},
+			wantAsset: "tool-x86_64-unknown-linux-gnu.tar.gz",
+			wantErr:   false,
+		},
+		{
+			name: "glibc host avoids musl when untagged asset exists",
+			assets: []Asset{
+				{Name: "tool_linux_amd64.tar.gz"},
+				{Name: "tool_linux_amd64_musl.tar.gz"},
+			},
+			cfg:       &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz"}},
+			goos:      "linux",
+			goarch:    "amd64",
+			hints:     platformHints{Libc: "gnu"},
+			wantAsset: "tool_linux_amd64.tar.gz",
+			wantErr:   false,
+		},
+		{
+			name: "armv6 host skips armv7 build",
+			assets: []Asset{
+				{Name: "tool_linux_armv6.tar.gz"},
+				{Name: "tool_linux_armv7.tar.gz"},
+				{Name: "tool_linux_arm64.tar.gz"},
+			},
+			cfg:       &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz"}},
+			goos:      "linux",
+			goarch:    "arm",
+			hints:     platformHints{ARMVariant: "6"},
+			wantAsset: "tool_linux_armv6.tar.gz",
+			wantErr:   false,
+		},
+		{
+			name: "armv7 host prefers armhf over generic arm",
+			assets: []Asset{
+				{Name: "tool_linux_arm.tar.gz"},
+				{Name: "tool_linux_armhf.tar.gz"},
+			},
+			cfg:       &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz"}},
+			goos:      "linux",
+			goarch:    "arm",
+			hints:     platformHints{ARMVariant: "7"},
+			wantAsset: "tool_linux_armhf.tar.gz",
+			wantErr:   false,
+		},
+		{
+			name: "armv7 host falls back to armv6 build",
+			assets: []Asset{
+				{Name: "tool_linux_armv6.tar.gz"},
+				{Name: "tool_linux_arm64.tar.gz"},
+				{Name: "tool_linux_amd64.tar.gz"},
+			},
+			cfg:       &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz"}},
+			goos:      "linux",
+			goarch:    "arm",
+			hints:     platformHints{ARMVariant: "7"},
+			wantAsset: "tool_linux_armv6.tar.gz",
+			wantErr:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickByHeuristics(tt.assets, tt.cfg, tt.goos, tt.goarch, tt.hints)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("pickByHeuristics() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("pickByHeuristics() error = %v", err)
+				return
+			}
+			if got.Name != tt.wantAsset {
+				t.Errorf("pickByHeuristics() = %q, want %q", got.Name, tt.wantAsset)
+			}
+		})
+	}
+}
+
+func TestPickByHeuristicsTieCandidates(t *testing.T) {
+	t.Parallel()
+
+	assets := []Asset{
+		{Name: "tool-linux-amd64.tar.gz"},
+		{Name: "tool-linux-amd64-debug.tar.gz"},
+		{Name: "tool-darwin-arm64.tar.gz"},
+		{Name: "SHA256SUMS"},
+	}
+	cfg := &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz"}}
+
+	_, err := pickByHeuristics(assets, cfg, "linux", "amd64", platformHints{})
+	var tie *assetTieError
+	if !errors.As(err, &tie) {
+		t.Fatalf("pickByHeuristics() err = %v, want *assetTieError", err)
+	}
+	var names []string
+	for _, a := range tie.Candidates {
+		names = append(names, a.Name)
+	}
+	want := []string{"tool-linux-amd64.tar.gz", "tool-linux-amd64-debug.tar.gz"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("tie candidates = %v, want %v", names, want)
+	}
+	if !strings.Contains(err.Error(), "multiple assets tie for selection") {
+		t.Fatalf("unexpected tie message: %v", err)
+	}
+}
+
+func TestNewestAsset(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		assets []Asset
+		want   string // "" when the tie stands
+	}{
+		{
+			name: "later updated_at wins",
+			assets: []Asset{
+				{Name: "tool-linux-amd64.tar.gz", UpdatedAt: "2026-01-02T10:00:00Z"},
+				{Name: "tool-linux-amd64 (1).tar.gz", UpdatedAt: "2026-01-05T10:00:00Z"},
+			},
+			want: "tool-linux-amd64 (1).tar.gz",
+		},
+		{
+			name: "created_at when updated_at is missing",
+			assets: []Asset{
+				{Name: "a.tar.gz", CreatedAt: "2026-01-09T00:00:00Z"},
+				{Name: "b.tar.gz", CreatedAt: "2026-01-02T00:00:00Z", UpdatedAt: "2026-01-03T00:00:00Z"},
+			},
+			want: "a.tar.gz",
+		},
+		{
+			name: "equal timestamps",
+			assets: []Asset{
+				{Name: "a.tar.gz", UpdatedAt: "2026-01-02T10:00:00Z"},
+				{Name: "b.tar.gz", UpdatedAt: "2026-01-02T11:00:00+01:00"},
+			},
+		},
+		{
+			name: "missing timestamp",
+			assets: []Asset{
+				{Name: "a.tar.gz", UpdatedAt: "2026-01-02T10:00:00Z"},
+				{Name: "b.tar.gz"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, _ := newestAsset(tt.assets)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Fatalf("newestAsset() = %q, want none", got.Name)
+			case tt.want != "" && (got == nil || got.Name != tt.want):
+				t.Fatalf("newestAsset() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPreferNewerAsset(t *testing.T) {
+	old := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	reupload := fmt.Sprintf("tool_%s_%s-1.tar.gz", runtime.GOOS, runtime.GOARCH)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+			{Name: old, BrowserDownloadUrl: "http://" + r.Host + "/dl/old", CreatedAt: "2026-02-01T09:00:00Z", UpdatedAt: "2026-02-01T09:00:00Z"},
+			{Name: reupload, BrowserDownloadUrl: "http://" + r.Host + "/dl/new", CreatedAt: "2026-02-03T09:00:00Z", UpdatedAt: "2026-02-03T09:00:00Z"},
+		}})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+	args := []string{"--repo", "o/tool", "--tag", "v1.0.0", "--dry-run", "--skip-tools-check"}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != exitSelection {
+		t.Fatalf("without --prefer-newer-asset: exit = %d, want %d\nstderr:\n%s", code, exitSelection, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run(append(args, "--prefer-newer-asset"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+	}
+	for _, want := range []string{"Selected " + reupload + ", the newest of 2 tied assets (uploaded 2026-02-03T09:00:00Z)", "Asset:       " + reupload} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr missing %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"tool_linux_amd64", "tool_linux_amd64", 0},
+		{"tool_linux_x64", "tool_linux_amd64", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPickByHeuristicsSuggestsClosestAssets(t *testing.T) {
+	t.Parallel()
+
+	// Nothing scores: no binary, OS or arch token and no archive extension.
+	assets := []Asset{
+		{Name: "widget_netbsd_riscv64"},
+		{Name: "widget_freebsd_arm64"},
+		{Name: "widget_plan9_mips"},
+		{Name: "widget_freebsd_arm64.sha256"},
+		{Name: "README"},
+	}
+	cfg := &RepoConfig{BinaryName: "tool", ArchiveExtensions: []string{".tar.gz", ".zip"}}
+
+	_, err := pickByHeuristics(assets, cfg, "linux", "amd64", platformHints{})
+	if err == nil {
+		t.Fatal("expected selection failure")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"no asset matches GOOS/GOARCH heuristics",
+		"looked for: linux/amd64",
+		"arch tokens: amd64, x64, x86_64",
+		"available assets:\n    widget_netbsd_riscv64\n    widget_freebsd_arm64\n    widget_plan9_mips\n    README",
+		"closest matches:\n    widget_freebsd_arm64  (try --asset-match \"widget_freebsd_arm64\")",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, ".sha256") {
+		t.Errorf("supplemental assets should not be suggested:\n%s", msg)
+	}
+	if got := strings.Count(msg, "(try "); got != maxSelectionSuggestions {
+		t.Errorf("suggestions = %d, want %d:\n%s", got, maxSelectionSuggestions, msg)
+	}
+}
+
+func TestSelectionFlagFor(t *testing.T) {
+	t.Parallel()
+
+	if got := selectionFlagFor("tool_linux_amd64.tar.gz"); got != `--asset-match "tool_linux_amd64.tar.gz"` {
+		t.Errorf("plain name: got %s", got)
+	}
+	if got := selectionFlagFor("tool[linux].tar.gz"); got != `--asset-regex "^tool\\[linux\\]\\.tar\\.gz$"` {
+		t.Errorf("glob metacharacters: got %s", got)
+	}
+}
+
+func TestPromptAssetChoice(t *testing.T) {
+	t.Parallel()
+
+	candidates := []Asset{
+		{Name: "tool-linux-amd64.tar.gz", Size: 4 * 1024 * 1024},
+		{Name: "tool-linux-amd64.zip"},
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		wantAsset string
+		wantErr   string
+	}{
+		{name: "first choice", input: "1\n", wantAsset: "tool-linux-amd64.tar.gz"},
+		{name: "retry after invalid", input: "9\nzip\n2\n", wantAsset: "tool-linux-amd64.zip"},
+		{name: "answer without newline", input: "2", wantAsset: "tool-linux-amd64.zip"},
+		{name: "input closed", input: "", wantErr: "cancelled"},
+		{name: "too many invalid", input: "0\n0\n0\n1\n", wantErr: "invalid choices"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			got, err := promptAssetChoice(candidates, strings.NewReader(tt.input), &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("promptAssetChoice() err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("promptAssetChoice() error = %v", err)
+			}
+			if got.Name != tt.wantAsset {
+				t.Fatalf("promptAssetChoice() = %s, want %s", got.Name, tt.wantAsset)
+			}
+			if !strings.Contains(out.String(), "1) tool-linux-amd64.tar.gz ("+formatSize(4*1024*1024)+")") {
+				t.Fatalf("menu missing size:\n%s", out.String())
+			}
+		})
+	}
+}
+
+// stubPrompt makes prompts see a terminal (or not) that answers with input.
+func stubPrompt(t *testing.T, terminal bool, input string) {
+	t.Helper()
+	origInput, origTerminal := promptInput, promptIsTerminal
+	promptInput = strings.NewReader(input)
+	promptIsTerminal = func() bool { return terminal }
+	t.Cleanup(func() { promptInput, promptIsTerminal = origInput, origTerminal })
+}
+
+func TestConfirmer(t *testing.T) {
+	const question = "The signing key for o/tool changed. Accept the new key?"
+	tests := []struct {
+		name      string
+		assumeYes bool
+		terminal  bool
+		input     string
+		want      bool
+		wantErr   error
+		wantAsked bool
+	}{
+		{name: "assume-yes without terminal", assumeYes: true, input: "n\n", want: true},
+		{name: "assume-yes on terminal", assumeYes: true, terminal: true, input: "n\n", want: true},
+		{name: "no terminal", wantErr: errConfirmationRequired},
+		{name: "y", terminal: true, input: "y\n", want: true, wantAsked: true},
+		{name: "yes without newline", terminal: true, input: " YES ", want: true, wantAsked: true},
+		{name: "n", terminal: true, input: "n\n", wantAsked: true},
+		{name: "empty answer declines", terminal: true, input: "\n", wantAsked: true},
+		{name: "end of input declines", terminal: true, wantAsked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubPrompt(t, tt.terminal, "")
+			var out bytes.Buffer
+			c := confirmer{assumeYes: tt.assumeYes, in: strings.NewReader(tt.input), out: &out}
+			got, err := c.confirm(question)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("confirm() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("confirm() = %v, want %v", got, tt.want)
+			}
+			if asked := strings.Contains(out.String(), question+" [y/N]: "); asked != tt.wantAsked {
+				t.Fatalf("prompted = %v, want %v; output %q", asked, tt.wantAsked, out.String())
+			}
+		})
+	}
+}
+
+func TestMatchAgainstDownloadURL(t *testing.T) {
+	t.Parallel()
+
+	// Generic names: only the download URL encodes the platform.
+	assets := []Asset{
+		{Name: "tool.tar.gz", BrowserDownloadUrl: "https://example.com/dl/v1.0.0/linux-amd64/tool.tar.gz"},
+		{Name: "tool.tar.gz", BrowserDownloadUrl: "https://example.com/dl/v1.0.0/darwin-arm64/tool.tar.gz"},
+	}
+	cfg := &RepoConfig{BinaryName: "tool"}
+	want := assets[0].BrowserDownloadUrl
+
+	if _, err := matchWithMatch(assets, "linux-amd64", false, cfg, "linux", "amd64", platformHints{}); err == nil {
+		t.Fatalf("matchWithMatch() without --match-url should not match on URL")
+	}
+	for _, pattern := range []string{"linux-amd64", "*linux-amd64*"} {
+		got, err := matchWithMatch(assets, pattern, true, cfg, "linux", "amd64", platformHints{})
+		if err != nil {
+			t.Fatalf("matchWithMatch(%q) error = %v", pattern, err)
+		}
+		if got.BrowserDownloadUrl != want {
+			t.Errorf("matchWithMatch(%q) = %q, want %q", pattern, got.BrowserDownloadUrl, want)
+		}
+	}
+
+	got, err := matchWithRegex(assets, regexp.MustCompile(`/linux-amd64/`), true, cfg, "linux", "amd64", platformHints{})
+	if err != nil {
+		t.Fatalf("matchWithRegex() error = %v", err)
+	}
+	if got.BrowserDownloadUrl != want {
+		t.Errorf("matchWithRegex() = %q, want %q", got.BrowserDownloadUrl, want)
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := pickByHeuristics(tt.assets, tt.cfg, tt.goos, tt.goarch)
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("pickByHeuristics() expected error, got nil")
-				}
-				return
-			}
-			if err != nil {
-				t.Errorf("pickByHeuristics() error = %v", err)
-				return
-			}
-			if got.Name != tt.wantAsset {
-				t.Errorf("pickByHeuristics() = %q, want %q", got.Name, tt.wantAsset)
-			}
-		})
-	}
 }
 
 func TestSelectAsset(t *testing.T) {
@@ -3782,7 +6819,7 @@ func TestSelectAsset(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := selectAsset(release, tt.cfg, tt.goos, tt.goarch, tt.assetMatch, tt.assetRegex)
+			got, err := selectAsset(release, tt.cfg, tt.goos, tt.goarch, platformHints{}, tt.assetMatch, tt.assetRegex, false)
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("selectAsset() expected error, got nil")
@@ -3844,7 +6881,7 @@ func TestRenderPattern(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := renderPattern(tt.pattern, cfg, tt.goos, tt.goarch)
+			got := renderPattern(tt.pattern, cfg, tt.goos, tt.goarch, platformHints{})
 			// For simple patterns, check exact match
 			if !strings.Contains(tt.pattern, "Token") {
 				if got != tt.wantRe {
@@ -3916,6 +6953,34 @@ func TestComputeTrustScoreEdgeCases(t *testing.T) {
 			wantAlgoPoints: 5,
 			wantAlgoName:   "sha512",
 		},
+		{
+			name: "sha3-256 algorithm bonus",
+			in: trustScoreInput{
+				ChecksumVerifiable: true,
+				ChecksumValidated:  true,
+				ChecksumAlgorithm:  "sha3-256",
+				HTTPSUsed:          true,
+			},
+			wantScore:      45, // 40 checksum + 5 sha3-256
+			wantLevel:      TrustLow,
+			wantLevelName:  "low",
+			wantAlgoPoints: 5,
+			wantAlgoName:   "sha3-256",
+		},
+		{
+			name: "blake2b-512 algorithm bonus",
+			in: trustScoreInput{
+				ChecksumVerifiable: true,
+				ChecksumValidated:  true,
+				ChecksumAlgorithm:  "blake2b-512",
+				HTTPSUsed:          true,
+			},
+			wantScore:      45, // 40 checksum + 5 blake2b-512
+			wantLevel:      TrustLow,
+			wantLevelName:  "low",
+			wantAlgoPoints: 5,
+			wantAlgoName:   "blake2b-512",
+		},
 		{
 			name: "sha1 weak algorithm penalty",
 			in: trustScoreInput{
@@ -4037,7 +7102,7 @@ func TestComputeTrustScoreEdgeCases(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := computeTrustScore(tc.in)
+			got := computeTrustScore(tc.in, defaultTrustPolicy)
 			if got.Score != tc.wantScore {
 				t.Errorf("Score: got %d want %d", got.Score, tc.wantScore)
 			}
@@ -4091,49 +7156,667 @@ func TestTrustLevelFromScore(t *testing.T) {
 	}
 }
 
-func TestTrustLevelName(t *testing.T) {
+func TestParseTrustPolicy(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		level TrustLevel
-		want  string
+		name    string
+		json    string
+		want    func(p *TrustPolicy)
+		wantErr string
 	}{
-		{TrustBypassed, "bypassed"},
-		{TrustMinimal, "minimal"},
-		{TrustLow, "low"},
-		{TrustMedium, "medium"},
-		{TrustHigh, "high"},
-		{TrustLevel(99), "unknown"}, // invalid level
+		{name: "empty keeps defaults", json: `{}`},
+		{
+			name: "partial override",
+			json: `{"points": {"checksum": 10, "strongAlgorithm": 0}, "thresholds": {"high": 90}}`,
+			want: func(p *TrustPolicy) {
+				p.Points.Checksum = 10
+				p.Points.StrongAlgorithm = 0
+				p.Thresholds.High = 90
+			},
+		},
+		{name: "thresholds not increasing", json: `{"thresholds": {"low": 60, "medium": 60}}`, wantErr: "thresholds must increase"},
+		{name: "high below medium", json: `{"thresholds": {"high": 50}}`, wantErr: "thresholds must increase"},
+		{name: "award out of range", json: `{"points": {"signature": 150}}`, wantErr: "invalid trust policy"},
+		{name: "penalty must be negative", json: `{"points": {"signatureSkipped": 20}}`, wantErr: "invalid trust policy"},
+		{name: "threshold zero", json: `{"thresholds": {"low": 0}}`, wantErr: "invalid trust policy"},
+		{name: "unknown field", json: `{"points": {"gpg": 10}}`, wantErr: "invalid trust policy"},
+		{name: "not json", json: `points: 1`, wantErr: "parse trust policy"},
 	}
-
-	for _, tc := range tests {
-		tc := tc
-		t.Run(tc.want, func(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			got := tc.level.Name()
-			if got != tc.want {
-				t.Errorf("TrustLevel(%d).Name() = %q, want %q", tc.level, got, tc.want)
+			got, err := parseTrustPolicy("policy.json", []byte(tt.json))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTrustPolicy: %v", err)
+			}
+			want := defaultTrustPolicy
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			if got != want {
+				t.Fatalf("policy = %+v, want %+v", got, want)
 			}
 		})
 	}
 }
 
-func TestTrustScoreFactorsCopied(t *testing.T) {
+func TestComputeTrustScoreWithPolicy(t *testing.T) {
 	t.Parallel()
 
-	// Verify that input fields are properly copied to output factors
-	in := trustScoreInput{
-		SignatureVerifiable: true,
-		SignatureValidated:  true,
-		SignatureSkipped:    false,
-		ChecksumVerifiable:  true,
-		ChecksumValidated:   true,
-		ChecksumSkipped:     false,
-		ChecksumAlgorithm:   "sha256",
-		HTTPSUsed:           true,
+	strict := defaultTrustPolicy
+	strict.Points.Checksum = 10
+	strict.Points.StrongAlgorithm = 0
+	strict.Thresholds.High = 90
+
+	checksumOnly := trustScoreInput{ChecksumVerifiable: true, ChecksumValidated: true, ChecksumAlgorithm: "sha256", HTTPSUsed: true}
+	signatureOnly := trustScoreInput{SignatureVerifiable: true, SignatureValidated: true, HTTPSUsed: true}
+
+	tests := []struct {
+		name      string
+		in        trustScoreInput
+		policy    TrustPolicy
+		wantScore int
+		wantLevel TrustLevel
+	}{
+		{name: "checksum only, default", in: checksumOnly, policy: defaultTrustPolicy, wantScore: 45, wantLevel: TrustLow},
+		{name: "checksum only, strict", in: checksumOnly, policy: strict, wantScore: 10, wantLevel: TrustMinimal},
+		{name: "signature only, default", in: signatureOnly, policy: defaultTrustPolicy, wantScore: 70, wantLevel: TrustMedium},
+		{name: "signature and checksum, strict", in: trustScoreInput{SignatureVerifiable: true, SignatureValidated: true, ChecksumVerifiable: true, ChecksumValidated: true, ChecksumAlgorithm: "sha256"}, policy: strict, wantScore: 80, wantLevel: TrustMedium},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := computeTrustScore(tt.in, tt.policy)
+			if got.Score != tt.wantScore || got.Level != tt.wantLevel {
+				t.Fatalf("score = %d (%s), want %d (%s)", got.Score, got.LevelName, tt.wantScore, tt.wantLevel.Name())
+			}
+		})
+	}
+}
+
+func TestRunTrustPolicy(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/tool/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+			{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+			{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+		}})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	dir := t.TempDir()
+	strict := filepath.Join(dir, "strict.json")
+	if err := os.WriteFile(strict, []byte(`{"points": {"checksum": 10, "strongAlgorithm": 0}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"thresholds": {"low": 90}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "default policy", args: []string{"--dry-run"}, wantCode: exitOK, wantStderr: "45/100 (low)"},
+		{name: "strict policy", args: []string{"--dry-run", "--trust-policy", strict}, wantCode: exitOK, wantStderr: "10/100 (minimal)"},
+		{name: "strict policy fails trust-minimum", args: []string{"--trust-policy", strict, "--trust-minimum", "40"}, wantCode: exitTrust, wantStderr: "trust score 10/100 (minimal) is below --trust-minimum 40"},
+		{name: "invalid policy", args: []string{"--trust-policy", broken}, wantCode: exitUsage, wantStderr: "error: --trust-policy: invalid trust policy"},
+		{name: "missing policy", args: []string{"--trust-policy", filepath.Join(dir, "nope.json")}, wantCode: exitUsage, wantStderr: "read trust policy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--skip-tools-check"}, tt.args...)
+			code := run(args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Fatalf("stderr missing %q:\n%s", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunRequireSignatureUnsigned(t *testing.T) {
+	// A checksum-only release fails --require-signature before the asset
+	// is downloaded.
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	var downloads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/tool/releases/latest" {
+			downloads.Add(1)
+			http.NotFound(w, r)
+			return
+		}
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+			{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+			{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+		}})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--skip-tools-check", "--require-signature"}, &stdout, &stderr)
+	if code != exitTrust {
+		t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitTrust, stderr.String())
+	}
+	if want := "--require-signature specified but no signature found"; !strings.Contains(stderr.String(), want) {
+		t.Fatalf("stderr missing %q:\n%s", want, stderr.String())
+	}
+	if n := downloads.Load(); n != 0 {
+		t.Fatalf("%d download requests, want none", n)
+	}
+}
+
+func TestRunSignedChecksumMissingAsset(t *testing.T) {
+	// A good signature over a SHA256SUMS that has no line for the selected
+	// asset: the manifest does not vouch for the download.
+	const assetName = "sfetch_test_darwin_arm64.tar.gz"
+	assetBytes, err := os.ReadFile(filepath.Join("testdata/integration", assetName))
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+	key := newMinisignTestKey()
+	keyPath := filepath.Join(t.TempDir(), "minisign.pub")
+	if err := os.WriteFile(keyPath, key.publicKey(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		sums       string
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:       "asset listed",
+			sums:       fmt.Sprintf("%x  %s\n", sum, assetName),
+			wantCode:   exitOK,
+			wantStderr: "Minisign checksum signature verified OK",
+		},
+		{
+			name:       "asset missing from signed manifest",
+			sums:       fmt.Sprintf("%x  sfetch_test_linux_amd64.tar.gz\n", sum),
+			wantCode:   exitChecksum,
+			wantStderr: "error: signed checksum file SHA256SUMS does not cover " + assetName,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			files := map[string][]byte{
+				assetName:            assetBytes,
+				"SHA256SUMS":         []byte(tc.sums),
+				"SHA256SUMS.minisig": key.sign([]byte(tc.sums)),
+			}
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/repos/o/signed/releases/latest" {
+					base := "http://" + r.Host
+					rel := Release{TagName: "v1.0.0"}
+					for _, name := range []string{assetName, "SHA256SUMS", "SHA256SUMS.minisig"} {
+						rel.Assets = append(rel.Assets, Asset{Name: name, BrowserDownloadUrl: base + "/dl/" + name})
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(rel)
+					return
+				}
+				data, ok := files[strings.TrimPrefix(r.URL.Path, "/dl/")]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write(data)
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+
+			args := []string{"--repo", "o/signed", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--binary-name", "sfetch", "--skip-tools-check", "--minisign-key", keyPath}
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != tc.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tc.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.wantStderr) {
+				t.Errorf("stderr missing %q:\n%s", tc.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunWeakChecksumAlgorithm(t *testing.T) {
+	// Releases that only publish MD5SUMS or SHA1SUMS still verify, with a
+	// warning that the algorithm does not resist tampering.
+	const assetName = "sfetch_test_darwin_arm64.tar.gz"
+	assetBytes, err := os.ReadFile(filepath.Join("testdata/integration", assetName))
+	if err != nil {
+		t.Fatalf("read asset: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		sumsName   string
+		sums       string
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:       "md5sums",
+			sumsName:   "MD5SUMS",
+			sums:       fmt.Sprintf("%x  %s\n", md5.Sum(assetBytes), assetName),
+			wantCode:   exitOK,
+			wantStderr: "warning: Checksum file MD5SUMS uses md5, a cryptographically weak algorithm",
+		},
+		{
+			name:       "sha1 per-asset",
+			sumsName:   assetName + ".sha1",
+			sums:       fmt.Sprintf("%x  %s\n", sha1.Sum(assetBytes), assetName),
+			wantCode:   exitOK,
+			wantStderr: "uses sha1, a cryptographically weak algorithm",
+		},
+		{
+			name:       "md5 mismatch",
+			sumsName:   "MD5SUMS",
+			sums:       fmt.Sprintf("%x  %s\n", md5.Sum([]byte("tampered")), assetName),
+			wantCode:   exitChecksum,
+			wantStderr: "checksum mismatch",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			files := map[string][]byte{
+				assetName:   assetBytes,
+				tc.sumsName: []byte(tc.sums),
+			}
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/repos/o/weak/releases/latest" {
+					base := "http://" + r.Host
+					rel := Release{TagName: "v1.0.0"}
+					for _, name := range []string{assetName, tc.sumsName} {
+						rel.Assets = append(rel.Assets, Asset{Name: name, BrowserDownloadUrl: base + "/dl/" + name})
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(rel)
+					return
+				}
+				data, ok := files[strings.TrimPrefix(r.URL.Path, "/dl/")]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write(data)
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+
+			args := []string{"--repo", "o/weak", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--binary-name", "sfetch", "--skip-tools-check"}
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != tc.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tc.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.wantStderr) {
+				t.Errorf("stderr missing %q:\n%s", tc.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunStrictKeys(t *testing.T) {
+	// A release with a minisign signature over SHA256SUMS and no key to
+	// check it: --strict-keys fails before the asset is downloaded.
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata/integration", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return data
+	}
+	files := map[string][]byte{
+		"sfetch_test_darwin_arm64.tar.gz": read("sfetch_test_darwin_arm64.tar.gz"),
+		"SHA256SUMS":                      read("SHA256SUMS"),
+		"SHA256SUMS.minisig":              read("SHA256SUMS.minisig"),
+	}
+	var downloads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/o/signed/releases/latest" {
+			base := "http://" + r.Host
+			rel := Release{TagName: "v1.0.0"}
+			for _, name := range []string{"sfetch_test_darwin_arm64.tar.gz", "SHA256SUMS", "SHA256SUMS.minisig"} {
+				rel.Assets = append(rel.Assets, Asset{Name: name, BrowserDownloadUrl: base + "/dl/" + name})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(rel)
+			return
+		}
+		downloads.Add(1)
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/dl/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name          string
+		args          []string
+		wantCode      int
+		wantStderr    []string
+		wantDownloads bool
+	}{
+		{
+			name:       "strict-keys without key",
+			args:       []string{"--strict-keys"},
+			wantCode:   exitTrust,
+			wantStderr: []string{"error: --strict-keys: Minisign signature SHA256SUMS.minisig is published but no key is available to verify it", "hint: get the maintainer's Minisign public key"},
+		},
+		{
+			name:          "strict-keys with key",
+			args:          []string{"--strict-keys", "--minisign-key", "testdata/integration/test-minisign.pub"},
+			wantCode:      exitOK,
+			wantStderr:    []string{"Minisign checksum signature verified OK"},
+			wantDownloads: true,
+		},
+		{
+			name:          "default warns",
+			args:          nil,
+			wantCode:      exitSignature,
+			wantStderr:    []string{"warning: Signature file found but no verification key available"},
+			wantDownloads: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			downloads.Store(0)
+			args := append([]string{"--repo", "o/signed", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--binary-name", "sfetch", "--skip-tools-check"}, tc.args...)
+			var stdout, stderr bytes.Buffer
+			code := run(args, &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tc.wantCode, stderr.String())
+			}
+			for _, want := range tc.wantStderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("stderr missing %q:\n%s", want, stderr.String())
+				}
+			}
+			if n := downloads.Load(); (n > 0) != tc.wantDownloads {
+				t.Errorf("%d download requests, want downloads: %v", n, tc.wantDownloads)
+			}
+		})
+	}
+}
+
+func TestRunReusesCachedKey(t *testing.T) {
+	// A release with a minisign signature over SHA256SUMS whose key is
+	// fetched from a URL or a release asset: the second run with the same
+	// cache reuses the key that verified the first.
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata/integration", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return data
+	}
+	files := map[string][]byte{
+		"sfetch_test_darwin_arm64.tar.gz": read("sfetch_test_darwin_arm64.tar.gz"),
+		"SHA256SUMS":                      read("SHA256SUMS"),
+		"SHA256SUMS.minisig":              read("SHA256SUMS.minisig"),
+		"release-minisign.pub":            read("test-minisign.pub"),
+	}
+	var keyDownloads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/o/signed/releases/latest" {
+			base := "http://" + r.Host
+			rel := Release{TagName: "v1.0.0"}
+			for _, name := range []string{"sfetch_test_darwin_arm64.tar.gz", "SHA256SUMS", "SHA256SUMS.minisig", "release-minisign.pub"} {
+				rel.Assets = append(rel.Assets, Asset{Name: name, BrowserDownloadUrl: base + "/dl/" + name})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(rel)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/dl/")
+		if name == "release-minisign.pub" {
+			keyDownloads.Add(1)
+		}
+		data, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+	keyURL := ts.URL + "/dl/release-minisign.pub"
+
+	tests := []struct {
+		name       string
+		args       []string
+		staleKey   bool // seed the cache with another key for keyURL
+		wantFirst  int32
+		wantSecond int32
+	}{
+		{name: "minisign-key-url", args: []string{"--minisign-key-url", keyURL}, wantFirst: 1, wantSecond: 0},
+		{name: "minisign-key-asset", args: []string{"--minisign-key-asset", "release-minisign.pub"}, wantFirst: 1, wantSecond: 0},
+		{name: "refresh fetches again", args: []string{"--minisign-key-url", keyURL, "--refresh"}, wantFirst: 1, wantSecond: 1},
+		{name: "refresh-keys replaces a changed key", args: []string{"--minisign-key-url", keyURL, "--refresh-keys"}, staleKey: true, wantFirst: 1, wantSecond: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			if tc.staleKey {
+				if err := cache.PutKey(cacheDir, keyURL, "testdata/keys/test-minisign.pub", time.Now()); err != nil {
+					t.Fatalf("seed key cache: %v", err)
+				}
+			}
+			for i, want := range []int32{tc.wantFirst, tc.wantSecond} {
+				keyDownloads.Store(0)
+				args := append([]string{"--repo", "o/signed", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", cacheDir, "--binary-name", "sfetch", "--skip-tools-check"}, tc.args...)
+				var stdout, stderr bytes.Buffer
+				if code := run(args, &stdout, &stderr); code != exitOK {
+					t.Fatalf("run %d: exit = %d\nstderr:\n%s", i+1, code, stderr.String())
+				}
+				if !strings.Contains(stderr.String(), "Minisign checksum signature verified OK") {
+					t.Errorf("run %d: signature not verified:\n%s", i+1, stderr.String())
+				}
+				if got := keyDownloads.Load(); got != want {
+					t.Errorf("run %d: %d key downloads, want %d", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	// Without --refresh-keys, a source serving another key than the cached
+	// one fails the run, even where a missing key could fall back.
+	for _, extra := range [][]string{nil, {"--refresh"}, {"--on-missing-key", "fallback-checksum"}} {
+		t.Run(strings.TrimSpace("changed key fails "+strings.Join(extra, " ")), func(t *testing.T) {
+			cacheDir := t.TempDir()
+			if err := cache.PutKey(cacheDir, keyURL, "testdata/keys/test-minisign.pub", time.Now()); err != nil {
+				t.Fatalf("seed key cache: %v", err)
+			}
+			args := append([]string{"--repo", "o/signed", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", cacheDir, "--binary-name", "sfetch", "--skip-tools-check", "--minisign-key-url", keyURL}, extra...)
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != exitSignature {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitSignature, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), "differs from the key cached after an earlier verified run") {
+				t.Errorf("stderr missing changed-key error:\n%s", stderr.String())
+			}
+			path, ok := cache.LookupKey(cacheDir, keyURL, "")
+			if !ok {
+				t.Fatal("cached key dropped")
+			}
+			seeded, err := os.ReadFile("testdata/keys/test-minisign.pub")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, seeded) {
+				t.Error("cached key replaced without --refresh-keys")
+			}
+		})
+	}
+}
+
+func TestBuildProvenanceRecordSignatureReason(t *testing.T) {
+	t.Parallel()
+
+	rel := &Release{TagName: "v1.0.0", Assets: []Asset{{Name: "SHA256SUMS.minisig"}, {Name: "SHA256SUMS"}}}
+	tests := []struct {
+		name         string
+		sigAvailable bool
+		flags        assessmentFlags
+		wantReason   string
+		wantVerified bool
+	}{
+		{name: "no signature", wantReason: "no signature file found in release"},
+		{name: "no key", sigAvailable: true, wantReason: "no verification key available"},
+		{name: "key configured", sigAvailable: true, flags: assessmentFlags{minisignKeyConfigured: true}, wantVerified: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assessment := &VerificationAssessment{
+				ChecksumAvailable: true,
+				ChecksumFile:      "SHA256SUMS",
+				ChecksumAlgorithm: "sha256",
+				Workflow:          workflowC,
+			}
+			if tc.sigAvailable {
+				assessment.SignatureAvailable = true
+				assessment.SignatureFormat = sigFormatMinisign
+				assessment.SignatureFile = "SHA256SUMS.minisig"
+				assessment.SignatureIsChecksum = true
+				assessment.Workflow = workflowA
+			}
+			finalizeAssessmentTrust(assessment, rel, tc.flags)
+			sig := buildProvenanceRecord("o/tool", rel, assessment, tc.flags, "").Verification.Signature
+			if sig.Reason != tc.wantReason || sig.Verified != tc.wantVerified {
+				t.Errorf("signature = %+v, want reason %q verified %v", sig, tc.wantReason, tc.wantVerified)
+			}
+		})
+	}
+}
+
+// foreignArch returns an architecture --arch accepts that is not the host's.
+func foreignArch() string {
+	if runtime.GOARCH == "amd64" {
+		return "arm64"
+	}
+	return "amd64"
+}
+
+func TestFindVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"v1.2.3", "1.2.3", true},
+		{"tool-v1.2.3", "1.2.3", true},
+		{"tool version 1.2.3 (commit abc123, built 2025-01-02)", "1.2.3", true},
+		{"ripgrep 14.1.0\n\nfeatures:+pcre2", "14.1.0", true},
+		{"tool v2.0.0-rc.1+build.5", "2.0.0-rc.1+build.5", true},
+		{"go1.22 tool 0.9", "1.22", true},
+		{"release-2025", "", false},
+		{"hello", "", false},
+	}
+	for _, tc := range tests {
+		got, ok := findVersion(tc.in)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("findVersion(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestSameVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.3", "1.2.3", true},
+		{"1.2", "1.2.0", true},
+		{"1.2.3+abc", "1.2.3", true},
+		{"1.2.3-rc.1", "1.2.3-rc.1", true},
+		{"1.2.3-rc.1", "1.2.3", false},
+		{"1.2.4", "1.2.3", false},
+	}
+	for _, tc := range tests {
+		if got := sameVersion(tc.a, tc.b); got != tc.want {
+			t.Errorf("sameVersion(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestTrustLevelName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		level TrustLevel
+		want  string
+	}{
+		{TrustBypassed, "bypassed"},
+		{TrustMinimal, "minimal"},
+		{TrustLow, "low"},
+		{TrustMedium, "medium"},
+		{TrustHigh, "high"},
+		{TrustLevel(99), "unknown"}, // invalid level
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.want, func(t *testing.T) {
+			t.Parallel()
+			got := tc.level.Name()
+			if got != tc.want {
+				t.Errorf("TrustLevel(%d).Name() = %q, want %q", tc.level, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTrustScoreFactorsCopied(t *testing.T) {
+	t.Parallel()
+
+	// Verify that input fields are properly copied to output factors
+	in := trustScoreInput{
+		SignatureVerifiable: true,
+		SignatureValidated:  true,
+		SignatureSkipped:    false,
+		ChecksumVerifiable:  true,
+		ChecksumValidated:   true,
+		ChecksumSkipped:     false,
+		ChecksumAlgorithm:   "sha256",
+		HTTPSUsed:           true,
 	}
 
-	got := computeTrustScore(in)
+	got := computeTrustScore(in, defaultTrustPolicy)
 
 	// Signature factors
 	if got.Factors.Signature.Verifiable != in.SignatureVerifiable {
@@ -4239,30 +7922,1599 @@ func TestApplyProxyConfig(t *testing.T) {
 	}
 }
 
-func TestParseGitHubRawSpec(t *testing.T) {
+func TestNoProxyMatch(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		name    string
-		input   string
-		want    githubRawSpec
-		wantErr bool
+		name string
+		list string
+		host string
+		want bool
 	}{
-		{
-			name:  "valid",
-			input: "Homebrew/install@HEAD:install.sh",
-			want: githubRawSpec{
-				Repo:      "Homebrew/install",
-				Ref:       "HEAD",
-				Path:      "install.sh",
-				URL:       "https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh",
-				AssetName: "install.sh",
-			},
-		},
-		{
-			name:  "nested_path",
-			input: "helm/helm@main:scripts/get-helm-3",
-			want: githubRawSpec{
+		{"empty_list", "", "api.github.com", false},
+		{"wildcard", "*", "api.github.com", true},
+		{"exact", "api.github.com", "api.github.com:443", true},
+		{"domain_suffix", "github.com", "api.github.com", true},
+		{"leading_dot", ".github.com", "api.github.com", true},
+		{"not_a_suffix", "hub.com", "api.github.com", false},
+		{"port_match", "corp.local:8443", "git.corp.local:8443", true},
+		{"port_mismatch", "corp.local:8443", "git.corp.local:443", false},
+		{"cidr", "10.0.0.0/8", "10.1.2.3:443", true},
+		{"cidr_miss", "10.0.0.0/8", "192.168.1.1", false},
+		{"loopback_always", "", "127.0.0.1:8080", true},
+		{"localhost_always", "", "localhost", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := noProxyMatch(tc.list, tc.host); got != tc.want {
+				t.Fatalf("noProxyMatch(%q, %q) = %v, want %v", tc.list, tc.host, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunProxyFlagRoutesRequests(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		seen      []string
+		proxyAuth string
+	)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.String())
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		mu.Unlock()
+		http.Error(w, "blocked by test proxy", http.StatusForbidden)
+	}))
+	defer proxy.Close()
+
+	t.Setenv("SFETCH_API_BASE", "http://api.sfetch.invalid")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:secret@", 1)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--tag", "v1.0.0", "--dry-run", "--proxy", proxyURL, "--skip-tools-check"}, &stdout, &stderr)
+	if code == exitOK {
+		t.Fatalf("expected failure from the blocking proxy, got exit 0")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) == 0 {
+		t.Fatalf("proxy saw no requests\nstderr:\n%s", stderr.String())
+	}
+	if !strings.HasPrefix(seen[0], "http://api.sfetch.invalid/repos/o/tool/releases/") {
+		t.Fatalf("proxy request URI = %q, want an absolute URL for the API host", seen[0])
+	}
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	if proxyAuth != wantAuth {
+		t.Fatalf("Proxy-Authorization = %q, want %q", proxyAuth, wantAuth)
+	}
+}
+
+func TestRunProxyRefusedConnectNamesProxy(t *testing.T) {
+	var connects atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			connects.Add(1)
+		}
+		http.Error(w, "tunnel refused", http.StatusForbidden)
+	}))
+	defer proxy.Close()
+
+	t.Setenv("SFETCH_API_BASE", "https://api.sfetch.invalid")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:secret@", 1)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--tag", "v1.0.0", "--dry-run", "--proxy", proxyURL, "--skip-tools-check", "-vv"}, &stdout, &stderr)
+	if code == exitOK {
+		t.Fatalf("expected failure from the refusing proxy, got exit 0")
+	}
+	if connects.Load() == 0 {
+		t.Fatalf("proxy saw no CONNECT\nstderr:\n%s", stderr.String())
+	}
+	redacted := strings.Replace(proxy.URL, "http://", "http://user:xxxxx@", 1)
+	if !strings.Contains(stderr.String(), "via proxy "+redacted) {
+		t.Fatalf("error does not name the proxy %s\nstderr:\n%s", redacted, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "  proxy: "+redacted) {
+		t.Fatalf("-vv output does not log the proxy\nstderr:\n%s", stderr.String())
+	}
+	if strings.Contains(stderr.String(), "secret") {
+		t.Fatalf("proxy password leaked\nstderr:\n%s", stderr.String())
+	}
+}
+
+func TestRunProxyFlagConflicts(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--proxy", "http://proxy.local:3128", "--https-proxy", "http://other.local:3128"}, &stdout, &stderr)
+	if code != exitUsage {
+		t.Fatalf("exit = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr.String(), "--proxy cannot be combined") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestIdleTimeoutTransport(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name: "stall before headers",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			wantErr: "(--timeout)",
+		},
+		{
+			name: "stall mid body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("partial"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			},
+			wantErr: "(--timeout)",
+		},
+		{
+			name: "slow but steady body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				for i := 0; i < 6; i++ {
+					_, _ = w.Write([]byte("chunk"))
+					w.(http.Flusher).Flush()
+					time.Sleep(40 * time.Millisecond)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			defer ts.Close()
+
+			client := &http.Client{Transport: &idleTimeoutTransport{next: http.DefaultTransport, timeout: 150 * time.Millisecond}}
+			_, err := func() ([]byte, error) {
+				resp, err := client.Get(ts.URL)
+				if err != nil {
+					return nil, err
+				}
+				defer resp.Body.Close() //nolint:errcheck
+				return io.ReadAll(resp.Body)
+			}()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("err = %v, want it to wrap context.DeadlineExceeded", err)
+			}
+		})
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	ctx, cancel := withDeadline(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatalf("zero --deadline should leave the context unbounded")
+	}
+
+	ctx, cancel = withDeadline(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if cause := context.Cause(ctx); cause == nil || !strings.Contains(cause.Error(), "--deadline 10ms exceeded") {
+		t.Fatalf("cause = %v", cause)
+	}
+}
+
+func TestWriteResponseBodyRemovesPartialFile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1024")
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &idleTimeoutTransport{next: http.DefaultTransport, timeout: 100 * time.Millisecond}}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	dest := filepath.Join(t.TempDir(), "tool.tar.gz")
+	if _, err := writeResponseBody(resp, ts.URL, dest, 0); err == nil {
+		t.Fatalf("expected an error from the stalled body")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("partial file left behind: %v", err)
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "deadline", args: []string{"--deadline", "200ms"}, wantErr: "--deadline 200ms exceeded"},
+		{name: "timeout", args: []string{"--timeout", "200ms"}, wantErr: "(--timeout)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"--repo", "o/tool", "--tag", "v1.0.0", "--dry-run", "--skip-tools-check"}, tt.args...)
+			start := time.Now()
+			code := run(args, &stdout, &stderr)
+			if code == exitOK {
+				t.Fatalf("expected failure, got exit 0")
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Fatalf("run took %s, want it cut short", elapsed)
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr = %q, want containing %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClockSkewTransport(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		date     string
+		age      string
+		wantWarn string
+	}{
+		{name: "in sync", date: now.Add(-2 * time.Second).Format(http.TimeFormat)},
+		{name: "within threshold", date: now.Add(4 * time.Minute).Format(http.TimeFormat)},
+		{name: "clock behind", date: now.Add(3 * time.Hour).Format(http.TimeFormat), wantWarn: "system clock is 3h0m0s behind"},
+		{name: "clock ahead", date: now.Add(-48 * time.Hour).Format(http.TimeFormat), wantWarn: "system clock is 48h0m0s ahead of"},
+		{name: "cached response age", date: now.Add(-time.Hour).Format(http.TimeFormat), age: "3600"},
+		{name: "unparseable date", date: "yesterday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Date", tt.date)
+				if tt.age != "" {
+					w.Header().Set("Age", tt.age)
+				}
+			}))
+			defer ts.Close()
+
+			var warn bytes.Buffer
+			client := &http.Client{Transport: &clockSkewTransport{next: http.DefaultTransport, warn: &warn, now: func() time.Time { return now }}}
+			for i := 0; i < 2; i++ {
+				resp, err := client.Get(ts.URL)
+				if err != nil {
+					t.Fatalf("get: %v", err)
+				}
+				_ = resp.Body.Close()
+			}
+			if tt.wantWarn == "" {
+				if warn.Len() != 0 {
+					t.Fatalf("unexpected warning: %s", warn.String())
+				}
+				return
+			}
+			if !strings.Contains(warn.String(), tt.wantWarn) {
+				t.Fatalf("warning = %q, want %q", warn.String(), tt.wantWarn)
+			}
+			if n := strings.Count(warn.String(), "warning:"); n != 1 {
+				t.Fatalf("warned %d times, want once", n)
+			}
+		})
+	}
+}
+
+func TestRunWarnsOnClockSkew(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-26*time.Hour).UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0"})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	var stdout, stderr bytes.Buffer
+	_ = run([]string{"--repo", "o/tool", "--tag", "v1.0.0", "--dry-run", "--skip-tools-check"}, &stdout, &stderr)
+	if !strings.Contains(stderr.String(), "warning: system clock is 26h0m") || !strings.Contains(stderr.String(), "ahead of") {
+		t.Fatalf("stderr missing clock skew warning:\n%s", stderr.String())
+	}
+}
+
+func TestRecordServedFile(t *testing.T) {
+	resetServedFiles()
+	defer resetServedFiles()
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		wantDate string
+		wantAge  int64 // -1 for absent
+	}{
+		{name: "date and age", header: http.Header{"Date": {"Tue, 09 Dec 2025 14:30:00 GMT"}, "Age": {"86400"}}, wantDate: "2025-12-09T14:30:00Z", wantAge: 86400},
+		{name: "age zero is kept", header: http.Header{"Age": {"0"}}, wantAge: 0},
+		{name: "invalid headers dropped", header: http.Header{"Date": {"yesterday"}, "Age": {"-5"}}, wantAge: -1},
+		{name: "no headers", header: http.Header{}, wantAge: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordServedFile(tt.name, "", tt.header)
+			got := servedFileInfo(tt.name)
+			if got.date != tt.wantDate {
+				t.Errorf("date = %q, want %q", got.date, tt.wantDate)
+			}
+			switch {
+			case tt.wantAge < 0 && got.age != nil:
+				t.Errorf("age = %d, want absent", *got.age)
+			case tt.wantAge >= 0 && (got.age == nil || *got.age != tt.wantAge):
+				t.Errorf("age = %v, want %d", got.age, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestRunProvenanceRecordsResponseFreshness(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			// A cache that has held the asset for a day.
+			w.Header().Set("Date", "Tue, 09 Dec 2025 14:30:00 GMT")
+			w.Header().Set("Age", "86400")
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	provPath := filepath.Join(t.TempDir(), "provenance.json")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check", "--provenance-file", provPath}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+	}
+	data, err := os.ReadFile(provPath)
+	if err != nil {
+		t.Fatalf("read provenance: %v", err)
+	}
+	var record ProvenanceRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("parse provenance: %v", err)
+	}
+	if record.Asset.ResponseDate != "2025-12-09T14:30:00Z" {
+		t.Errorf("responseDate = %q", record.Asset.ResponseDate)
+	}
+	if record.Asset.ResponseAge == nil || *record.Asset.ResponseAge != 86400 {
+		t.Errorf("responseAge = %v, want 86400", record.Asset.ResponseAge)
+	}
+}
+
+func TestRunProvenanceOmitLegacyTrust(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name          string
+		args          []string
+		body          string // key holding the provenance fields, "" for the top level
+		wantLegacy    bool
+		wantLegacyVal string
+	}{
+		{name: "default emits both", wantLegacy: true, wantLegacyVal: trustLow},
+		{name: "flag omits legacy", args: []string{"--provenance-omit-legacy-trust"}},
+		{name: "intoto default emits both", args: []string{"--provenance-format", "intoto"}, body: "predicate", wantLegacy: true, wantLegacyVal: trustLow},
+		{name: "intoto flag omits legacy", args: []string{"--provenance-format", "intoto", "--provenance-omit-legacy-trust"}, body: "predicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			args := append([]string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check", "--provenance-file", provPath}, tt.args...)
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != exitOK {
+				t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+			}
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			if tt.body != "" {
+				if err := json.Unmarshal(fields[tt.body], &fields); err != nil {
+					t.Fatalf("parse %s: %v", tt.body, err)
+				}
+			}
+			if _, ok := fields["trust"]; !ok {
+				t.Errorf("trust missing:\n%s", data)
+			}
+			legacy, ok := fields["trustLevel"]
+			if ok != tt.wantLegacy {
+				t.Fatalf("trustLevel present = %v, want %v:\n%s", ok, tt.wantLegacy, data)
+			}
+			if tt.wantLegacy && string(legacy) != strconv.Quote(tt.wantLegacyVal) {
+				t.Errorf("trustLevel = %s, want %q", legacy, tt.wantLegacyVal)
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--repo", "o/tool", "--latest", "--provenance-omit-legacy-trust", "--skip-tools-check"}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("without a provenance output: exit = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRunChecksumOnly(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name        string
+		flag        string
+		wantWarning bool
+		wantReason  string
+		wantSkipSig bool
+		wantChkOnly bool
+	}{
+		{name: "default warns", wantWarning: true, wantReason: "no signature file found in release"},
+		{name: "skip-sig warns", flag: "--skip-sig", wantWarning: true, wantReason: "--skip-sig flag", wantSkipSig: true},
+		{name: "checksum-only is intentional", flag: "--checksum-only", wantReason: "--checksum-only flag", wantChkOnly: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			args := []string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check", "--provenance-file", provPath}
+			if tt.flag != "" {
+				args = append(args, tt.flag)
+			}
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != exitOK {
+				t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+			}
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var rec ProvenanceRecord
+			if err := json.Unmarshal(data, &rec); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			if rec.Verification.Workflow != workflowC {
+				t.Errorf("workflow = %q, want %q", rec.Verification.Workflow, workflowC)
+			}
+			const warning = "No signature available"
+			warned := strings.Contains(stderr.String(), warning)
+			for _, w := range rec.Warnings {
+				warned = warned || strings.Contains(w, warning)
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("signature warning = %v, want %v\nstderr:\n%s\nprovenance:\n%s", warned, tt.wantWarning, stderr.String(), data)
+			}
+			if got := rec.Verification.Signature.Reason; got != tt.wantReason {
+				t.Errorf("signature reason = %q, want %q", got, tt.wantReason)
+			}
+			if rec.Flags.SkipSig != tt.wantSkipSig || rec.Flags.ChecksumOnly != tt.wantChkOnly {
+				t.Errorf("flags skipSig=%v checksumOnly=%v, want %v/%v", rec.Flags.SkipSig, rec.Flags.ChecksumOnly, tt.wantSkipSig, tt.wantChkOnly)
+			}
+			if !rec.Verification.Checksum.Verified {
+				t.Errorf("checksum not verified:\n%s", data)
+			}
+		})
+	}
+	for _, extra := range [][]string{{"--skip-checksum"}, {"--insecure"}, {"--require-signature"}, {"--pin-minisign-key", "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"}} {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"--repo", "o/tool", "--latest", "--checksum-only", "--skip-tools-check"}, extra...)
+		if code := run(args, &stdout, &stderr); code != exitUsage {
+			t.Errorf("--checksum-only %v: exit = %d, want %d\nstderr:\n%s", extra, code, exitUsage, stderr.String())
+		}
+	}
+}
+
+func TestRunProvenanceNextToBinary(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	// Run from an empty directory so a record written to the CWD shows up.
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+	destDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--latest", "--dest-dir", destDir, "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check", "--provenance-next-to-binary"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+	}
+	data, err := os.ReadFile(filepath.Join(destDir, "tool.provenance.json"))
+	if err != nil {
+		t.Fatalf("read provenance: %v", err)
+	}
+	var record ProvenanceRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("parse provenance: %v", err)
+	}
+	if cs := record.Asset.ComputedChecksum; record.Asset.Name != assetName || cs == nil || cs.Value != fmt.Sprintf("%x", sum) {
+		t.Errorf("asset = %+v", record.Asset)
+	}
+	if entries, err := os.ReadDir(workDir); err != nil || len(entries) != 0 {
+		t.Errorf("working directory = %v (%v), want empty", entries, err)
+	}
+}
+
+func TestRunAlreadyUpToDate(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	destDir := t.TempDir()
+	target := filepath.Join(destDir, "tool")
+	install := func(extra ...string) string {
+		t.Helper()
+		args := append([]string{"--repo", "o/tool", "--latest", "--dest-dir", destDir, "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check", "--backup"}, extra...)
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != exitOK {
+			t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+		}
+		return stdout.String() + stderr.String()
+	}
+
+	if out := install(); !strings.Contains(out, "Installed tool to "+target) {
+		t.Fatalf("first install output:\n%s", out)
+	}
+	if out := install(); !strings.Contains(out, "Already up to date at "+target) || strings.Contains(out, "Installed tool") {
+		t.Fatalf("second install output:\n%s", out)
+	}
+	if _, err := os.Stat(target + ".bak"); err == nil {
+		t.Fatal("identical reinstall left a .bak")
+	}
+	if out := install("--force-install"); !strings.Contains(out, "Installed tool to "+target) {
+		t.Fatalf("--force-install output:\n%s", out)
+	}
+	if _, err := os.Stat(target + ".bak"); err != nil {
+		t.Fatalf("--force-install kept no .bak: %v", err)
+	}
+
+	if out := install("--diff"); !strings.Contains(out, "Diff: "+target+" is identical to the new binary") || !strings.Contains(out, "skipping install") {
+		t.Fatalf("--diff on identical install output:\n%s", out)
+	}
+	if err := os.WriteFile(target, []byte("locally patched"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if out := install("--diff"); !strings.Contains(out, "Diff: "+target+" differs from the new binary") || !strings.Contains(out, "Installed tool to "+target) {
+		t.Fatalf("--diff on changed install output:\n%s", out)
+	}
+}
+
+func TestRunMultipleDestDirs(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	var downloads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			downloads.Add(1)
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	dirs := []string{t.TempDir(), filepath.Join(t.TempDir(), "project", "bin"), t.TempDir()}
+	args := []string{"--repo", "o/tool", "--latest", "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check"}
+	for _, dir := range dirs {
+		args = append(args, "--dest-dir", dir)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("asset downloaded %d times, want 1", n)
+	}
+	first, err := os.ReadFile(filepath.Join(dirs[0], "tool"))
+	if err != nil {
+		t.Fatalf("read first install: %v", err)
+	}
+	for _, dir := range dirs {
+		target := filepath.Join(dir, "tool")
+		got, err := os.ReadFile(target)
+		if err != nil {
+			t.Fatalf("%s not installed: %v", target, err)
+		}
+		if !bytes.Equal(got, first) {
+			t.Errorf("%s differs from %s", target, dirs[0])
+		}
+		if info, err := os.Stat(target); err != nil || info.Mode().Perm()&0o100 == 0 {
+			t.Errorf("%s is not executable: %v", target, err)
+		}
+		if !strings.Contains(stderr.String(), "Installed tool to "+target) {
+			t.Errorf("stderr missing install of %s:\n%s", target, stderr.String())
+		}
+	}
+
+	stderr.Reset()
+	if code := run([]string{"--repo", "o/tool", "--latest", "--dest-dir", dirs[0], "--dest-dir", dirs[1], "--output", "x", "--skip-tools-check"}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("repeated --dest-dir with --output: exit = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRunUnauthenticatedNote(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		wantNote bool
+	}{
+		{name: "no token", wantNote: true},
+		{name: "SFETCH_GITHUB_TOKEN", env: map[string]string{"SFETCH_GITHUB_TOKEN": "t"}},
+		{name: "GH_TOKEN", env: map[string]string{"GH_TOKEN": "t"}},
+		{name: "GITHUB_TOKEN", env: map[string]string{"GITHUB_TOKEN": "t"}},
+		{name: "token-env", env: map[string]string{"CI_PAT": "t"}, args: []string{"--token-env", "CI_PAT"}},
+		{name: "quiet", args: []string{"--quiet"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookups atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lookups.Add(1)
+				http.NotFound(w, r)
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+			for _, name := range []string{"SFETCH_GITHUB_TOKEN", "GH_TOKEN", "GITHUB_TOKEN"} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			args := append([]string{"--repo", "o/tool", "--latest", "--cache-dir", t.TempDir(), "--skip-tools-check"}, tt.args...)
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != exitNetwork {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitNetwork, stderr.String())
+			}
+			if lookups.Load() == 0 {
+				t.Fatal("release lookup never reached the server")
+			}
+			out := stderr.String()
+			noteAt := strings.Index(out, "unauthenticated API requests are limited to 60 per hour")
+			if (noteAt >= 0) != tt.wantNote {
+				t.Fatalf("note printed = %v, want %v\nstderr:\n%s", noteAt >= 0, tt.wantNote, out)
+			}
+			if tt.wantNote && noteAt > strings.Index(out, "error:") {
+				t.Errorf("note printed after the failed lookup:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestParseEnvBool(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    bool
+		wantErr bool
+	}{
+		{raw: "1", want: true},
+		{raw: "true", want: true},
+		{raw: "TRUE", want: true},
+		{raw: "yes", want: true},
+		{raw: " Yes ", want: true},
+		{raw: "0"},
+		{raw: "false"},
+		{raw: "no"},
+		{raw: "on", wantErr: true},
+		{raw: "2", wantErr: true},
+		{raw: "y", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseEnvBool(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEnvBool(%q) err = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseEnvBool(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnvFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantKey     string
+		wantMin     int
+		wantReq     bool
+		wantDest    []string
+		wantSources map[string]string
+		wantErr     string
+	}{
+		{
+			name:        "defaults",
+			wantSources: map[string]string{"minisign-key": settingFromDefault, "trust-minimum": settingFromDefault, "require-minisign": settingFromDefault, "dest-dir": settingFromDefault},
+		},
+		{
+			name:        "env fills unset flags",
+			env:         map[string]string{"SFETCH_MINISIGN_KEY": "env.pub", "SFETCH_TRUST_MINIMUM": "70", "SFETCH_REQUIRE_MINISIGN": "yes", "SFETCH_DEST_DIR": "/env/bin"},
+			wantKey:     "env.pub",
+			wantMin:     70,
+			wantReq:     true,
+			wantDest:    []string{"/env/bin"},
+			wantSources: map[string]string{"minisign-key": settingFromEnv, "trust-minimum": settingFromEnv, "require-minisign": settingFromEnv, "dest-dir": settingFromEnv},
+		},
+		{
+			name:        "flags win over env",
+			args:        []string{"--minisign-key", "flag.pub", "--trust-minimum", "90", "--require-minisign=false", "--dest-dir", "/flag/bin"},
+			env:         map[string]string{"SFETCH_MINISIGN_KEY": "env.pub", "SFETCH_TRUST_MINIMUM": "70", "SFETCH_REQUIRE_MINISIGN": "1", "SFETCH_DEST_DIR": "/env/bin"},
+			wantKey:     "flag.pub",
+			wantMin:     90,
+			wantDest:    []string{"/flag/bin"},
+			wantSources: map[string]string{"minisign-key": settingFromFlag, "trust-minimum": settingFromFlag, "require-minisign": settingFromFlag, "dest-dir": settingFromFlag},
+		},
+		{
+			name:        "env false boolean",
+			env:         map[string]string{"SFETCH_REQUIRE_MINISIGN": "no"},
+			wantSources: map[string]string{"require-minisign": settingFromEnv},
+		},
+		{
+			name:        "install overrides env dest dir",
+			args:        []string{"--install"},
+			env:         map[string]string{"SFETCH_DEST_DIR": "/env/bin"},
+			wantSources: map[string]string{"dest-dir": settingFromDefault},
+		},
+		{
+			name:    "invalid boolean",
+			env:     map[string]string{"SFETCH_REQUIRE_MINISIGN": "maybe"},
+			wantErr: "SFETCH_REQUIRE_MINISIGN",
+		},
+		{
+			name:    "invalid integer",
+			env:     map[string]string{"SFETCH_TRUST_MINIMUM": "high"},
+			wantErr: "SFETCH_TRUST_MINIMUM",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			key := fs.String("minisign-key", "", "")
+			minimum := fs.Int("trust-minimum", 0, "")
+			require := fs.Bool("require-minisign", false, "")
+			fs.Bool("install", false, "")
+			var dest []string
+			fs.Var(destDirListFlag{dirs: &dest}, "dest-dir", "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			sources, err := applyEnvFlags(fs, func(name string) string { return tt.env[name] })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyEnvFlags: %v", err)
+			}
+			if *key != tt.wantKey || *minimum != tt.wantMin || *require != tt.wantReq || !slices.Equal(dest, tt.wantDest) {
+				t.Errorf("values = %q, %d, %v, %v; want %q, %d, %v, %v", *key, *minimum, *require, dest, tt.wantKey, tt.wantMin, tt.wantReq, tt.wantDest)
+			}
+			for name, want := range tt.wantSources {
+				if sources[name] != want {
+					t.Errorf("source of %s = %q, want %q", name, sources[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunEnvSettings(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	destDir := t.TempDir()
+	t.Setenv("SFETCH_DEST_DIR", destDir)
+	t.Setenv("SFETCH_PREFER_PER_ASSET", "true")
+	t.Setenv("SFETCH_SKIP_TOOLS_CHECK", "1")
+
+	provPath := filepath.Join(t.TempDir(), "provenance.json")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--repo", "o/tool", "--latest", "--cache-dir", t.TempDir(), "--no-path-check", "--trust-minimum", "0", "--provenance-file", provPath}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "tool")); err != nil {
+		t.Fatalf("not installed into SFETCH_DEST_DIR: %v", err)
+	}
+	data, err := os.ReadFile(provPath)
+	if err != nil {
+		t.Fatalf("read provenance: %v", err)
+	}
+	var record ProvenanceRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("parse provenance: %v", err)
+	}
+	if !record.Flags.PreferPerAsset {
+		t.Error("flags.preferPerAsset = false, want true from SFETCH_PREFER_PER_ASSET")
+	}
+	wantSources := map[string]string{"prefer-per-asset": settingFromEnv, "trust-minimum": settingFromFlag}
+	if !maps.Equal(record.Flags.Sources, wantSources) {
+		t.Errorf("flags.sources = %v, want %v", record.Flags.Sources, wantSources)
+	}
+
+	t.Setenv("SFETCH_SKIP_SIG", "sure")
+	stderr.Reset()
+	if code := run([]string{"--repo", "o/tool", "--latest"}, &stdout, &stderr); code != exitUsage {
+		t.Fatalf("invalid SFETCH_SKIP_SIG exit = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr.String(), "SFETCH_SKIP_SIG") {
+		t.Errorf("stderr does not name the variable:\n%s", stderr.String())
+	}
+}
+
+func TestRunTimings(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case "/dl/" + assetName:
+			_, _ = w.Write(assetBytes)
+		case "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	for _, timings := range []bool{true, false} {
+		t.Run(fmt.Sprintf("timings=%v", timings), func(t *testing.T) {
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			args := []string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check", "--json", "--provenance-file", provPath}
+			if timings {
+				args = append(args, "--timings")
+			}
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != exitOK {
+				t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+			}
+			var result runResult
+			if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+				t.Fatalf("parse --json output: %v\n%s", err, stdout.String())
+			}
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var record ProvenanceRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+
+			if !timings {
+				if result.Install.Timings != nil || record.Timings != nil {
+					t.Fatalf("timings reported without --timings: %+v, %+v", result.Install.Timings, record.Timings)
+				}
+				return
+			}
+			for where, got := range map[string]*Timings{"--json": result.Install.Timings, "provenance": record.Timings} {
+				if got == nil {
+					t.Fatalf("%s: no timings", where)
+				}
+				for step, ms := range map[string]float64{"download": got.DownloadMs, "hash": got.HashMs, "signature": got.SignatureMs, "extract": got.ExtractMs} {
+					if ms < 0 {
+						t.Errorf("%s: %s = %v ms, want >= 0", where, step, ms)
+					}
+				}
+			}
+			var raw struct {
+				Timings map[string]json.RawMessage `json:"timings"`
+			}
+			if err := json.Unmarshal(data, &raw); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"downloadMs", "hashMs", "signatureMs", "extractMs"} {
+				if _, ok := raw.Timings[key]; !ok {
+					t.Errorf("provenance timings missing %s: %s", key, data)
+				}
+			}
+		})
+	}
+}
+
+func TestReportDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	src := write("new", "v2")
+	same := write("same", "v2")
+	other := write("other", "v1")
+
+	tests := []struct {
+		name     string
+		dst      string
+		force    bool
+		wantSame bool
+		want     string
+	}{
+		{name: "nothing installed", dst: filepath.Join(dir, "absent"), want: "nothing installed at"},
+		{name: "identical", dst: same, wantSame: true, want: "is identical to the new binary (sha256 "},
+		{name: "identical with force", dst: same, force: true, wantSame: true, want: "reinstalling anyway"},
+		{name: "differs", dst: other, want: "differs from the new binary (installed sha256 "},
+		{name: "directory", dst: dir, want: "is not a regular file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := reportDiff(&out, src, tt.dst, tt.force)
+			if err != nil {
+				t.Fatalf("reportDiff: %v", err)
+			}
+			if got != tt.wantSame {
+				t.Errorf("identical = %v, want %v", got, tt.wantSame)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output %q missing %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUseGhAuth(t *testing.T) {
+	var gotAuth atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0"})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+	for _, env := range []string{"SFETCH_GITHUB_TOKEN", "GH_TOKEN", "GITHUB_TOKEN"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("GH_CONFIG_DIR", filepath.Join("testdata", "gh"))
+	gh.SetTrustedHostMatcher(func(string) bool { return true })
+	t.Cleanup(func() {
+		gh.SetTrustedHostMatcher(nil)
+		setTokenEnvOverride("")
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantAuth string
+	}{
+		{name: "without flag", wantAuth: ""},
+		{name: "with flag", args: []string{"--use-gh-auth"}, wantAuth: "Bearer gho_fixture_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAuth.Store("")
+			var stdout, stderr bytes.Buffer
+			// The release has no assets; only the lookup's headers matter.
+			run(append([]string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--skip-tools-check"}, tt.args...), &stdout, &stderr)
+			if got := gotAuth.Load().(string); got != tt.wantAuth {
+				t.Fatalf("Authorization = %q, want %q\nstderr:\n%s", got, tt.wantAuth, stderr.String())
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--repo", "o/tool", "--use-gh-auth", "--token-env", "MY_TOKEN", "--skip-tools-check"}, &stdout, &stderr); code != exitUsage {
+		t.Fatalf("--use-gh-auth with --token-env: exit = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRunSelfUpdatePackageManaged(t *testing.T) {
+	orig := selfUpdateInstall
+	selfUpdateInstall = func(path string) selfupdate.Install {
+		return selfupdate.Install{Path: "/usr/bin/sfetch", OwnerUID: 0}
+	}
+	defer func() { selfUpdateInstall = orig }()
+
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int // -1: any code, the check only warns
+		wantStderr string
+	}{
+		{name: "refused", args: []string{"--yes"}, wantCode: exitInstall, wantStderr: "error: refusing to self-update: /usr/bin/sfetch is under /usr/bin"},
+		{name: "dry-run warns", args: []string{"--dry-run"}, wantCode: -1, wantStderr: "warning: /usr/bin/sfetch is under /usr/bin; update with your system package manager (self-update will refuse without --self-update-force)"},
+		{name: "force warns", args: []string{"--yes", "--self-update-force"}, wantCode: -1, wantStderr: "(replacing it anyway: --self-update-force)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"--self-update", "--skip-tools-check"}, tt.args...), &stdout, &stderr)
+			if tt.wantCode >= 0 && code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Fatalf("stderr missing %q:\n%s", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+
+	t.Run("self-update-dir skips the check", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		run([]string{"--self-update", "--yes", "--self-update-dir", t.TempDir(), "--skip-tools-check"}, &stdout, &stderr)
+		if strings.Contains(stderr.String(), "/usr/bin") {
+			t.Fatalf("unexpected package-manager check:\n%s", stderr.String())
+		}
+	})
+}
+
+func TestFormatReleaseNotes(t *testing.T) {
+	const url = "https://github.com/o/tool/releases/tag/v1.0.0"
+	var long []string
+	for i := 1; i <= releaseNotesMaxLines+5; i++ {
+		long = append(long, fmt.Sprintf("- change %d", i))
+	}
+	tests := []struct {
+		name    string
+		rel     Release
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "name and markdown body",
+			rel:  Release{TagName: "v1.0.0", Name: "Spring release", Body: "## Changes\r\n\r\n- **faster** downloads\r\n"},
+			want: []string{"Release v1.0.0: Spring release\n", "  ## Changes\n", "  - **faster** downloads\n"},
+		},
+		{
+			name:    "name repeating the tag",
+			rel:     Release{TagName: "v1.0.0", Name: "v1.0.0", Body: "notes"},
+			want:    []string{"Release v1.0.0\n  notes\n"},
+			notWant: []string{"truncated"},
+		},
+		{
+			name: "no body",
+			rel:  Release{TagName: "v1.0.0", Name: "Spring release"},
+			want: []string{"(no release notes)"},
+		},
+		{
+			name:    "control characters dropped",
+			rel:     Release{TagName: "v1.0.0", Body: "safe\x1b]0;owned\x07 text"},
+			want:    []string{"  safe]0;owned text\n"},
+			notWant: []string{"\x1b", "\x07"},
+		},
+		{
+			name:    "too many lines",
+			rel:     Release{TagName: "v1.0.0", Body: strings.Join(long, "\n")},
+			want:    []string{fmt.Sprintf("- change %d\n", releaseNotesMaxLines), "… release notes truncated (5 more lines); full notes: " + url},
+			notWant: []string{fmt.Sprintf("- change %d\n", releaseNotesMaxLines+1)},
+		},
+		{
+			name: "one huge line",
+			rel:  Release{TagName: "v1.0.0", Body: strings.Repeat("é", releaseNotesMaxBytes)},
+			want: []string{"…\n", "release notes truncated; full notes: " + url},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatReleaseNotes(&tt.rel, url)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("missing %q in:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("unexpected %q in:\n%s", w, got)
+				}
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("output is not valid UTF-8")
+			}
+		})
+	}
+
+	if got := formatReleaseNotes(&Release{TagName: "v1.0.0"}, url); got != "" {
+		t.Errorf("release without name or notes: got %q, want empty", got)
+	}
+}
+
+func TestRunSelfUpdateReleaseNotes(t *testing.T) {
+	assetName := fmt.Sprintf("sfetch_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/3leaps/sfetch/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v9.0.0", Name: "Big one", Body: "- new **thing**", Assets: []Asset{
+			{Name: assetName, Size: 1024, BrowserDownloadUrl: base + "/dl/" + assetName},
+			{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+		}})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     string
+	}{
+		{name: "dry-run", args: []string{"--dry-run"}, wantCode: exitOK, want: "sfetch dry-run assessment"},
+		{name: "missing --yes", wantCode: exitUsage, want: "--self-update requires --yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubPrompt(t, false, "")
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"--self-update", "--self-update-dir", t.TempDir(), "--skip-tools-check"}, tt.args...)
+			if code := run(args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			for _, w := range []string{"Installing sfetch v9.0.0", "Release v9.0.0: Big one\n  - new **thing**\n", tt.want} {
+				if !strings.Contains(stderr.String(), w) {
+					t.Errorf("stderr missing %q:\n%s", w, stderr.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunSelfUpdateConfirmation(t *testing.T) {
+	origVersion := version
+	version = "v9.5.0"
+	defer func() { version = origVersion }()
+
+	assetName := fmt.Sprintf("sfetch_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/3leaps/sfetch/releases/tags/v9.0.0" {
+			http.NotFound(w, r)
+			return
+		}
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v9.0.0", Assets: []Asset{
+			{Name: assetName, Size: 1024, BrowserDownloadUrl: base + "/dl/" + assetName},
+			{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+		}})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	const declined = "Self-update cancelled."
+	const required = "--self-update requires --yes"
+	tests := []struct {
+		name      string
+		args      []string
+		terminal  bool
+		input     string
+		wantCode  int // -1: any code other than exitUsage; the download itself fails
+		want      []string
+		wantNotIn []string
+	}{
+		{name: "assume-yes confirms the downgrade", args: []string{"--assume-yes"}, wantCode: -1, want: []string{"Downgrading sfetch"}, wantNotIn: []string{"[y/N]", declined, required}},
+		{name: "-y", args: []string{"-y"}, terminal: true, wantCode: -1, wantNotIn: []string{"[y/N]", declined, required}},
+		{name: "--yes alias", args: []string{"--yes"}, wantCode: -1, wantNotIn: []string{declined, required}},
+		{name: "terminal accepts", terminal: true, input: "y\n", wantCode: -1, want: []string{"Downgrade sfetch to v9.0.0? [y/N]: "}, wantNotIn: []string{declined, required}},
+		{name: "terminal declines", terminal: true, input: "n\n", wantCode: exitOK, want: []string{"Downgrade sfetch to v9.0.0? [y/N]: ", declined}},
+		{name: "no terminal", wantCode: exitUsage, want: []string{required}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubPrompt(t, tt.terminal, tt.input)
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"--self-update", "--tag", "v9.0.0", "--self-update-dir", t.TempDir(), "--skip-tools-check"}, tt.args...)
+			code := run(args, &stdout, &stderr)
+			if (tt.wantCode >= 0 && code != tt.wantCode) || (tt.wantCode < 0 && code == exitUsage) {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(stderr.String(), w) {
+					t.Errorf("stderr missing %q:\n%s", w, stderr.String())
+				}
+			}
+			for _, w := range tt.wantNotIn {
+				if strings.Contains(stderr.String(), w) {
+					t.Errorf("stderr unexpectedly contains %q:\n%s", w, stderr.String())
+				}
+			}
+		})
+	}
+}
+
+// buildVersionedBinary builds a small Go program the way sfetch releases are
+// built, with -X main.version=ver, and returns its bytes.
+func buildVersionedBinary(t *testing.T, ver string) []byte {
+	t.Helper()
+	src := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module fake\n\ngo 1.21\n",
+		"main.go": "package main\n\nvar version = \"dev\"\n\nfunc main() { println(version) }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "sfetch")
+	cmd := exec.Command("go", "build", "-ldflags", "-X main.version="+ver, "-o", out, ".")
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, output)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRunSelfUpdateEmbeddedVersion(t *testing.T) {
+	binary := buildVersionedBinary(t, "1.0.0")
+	assetName := fmt.Sprintf("sfetch_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "sfetch", Mode: 0o755, Size: int64(len(binary)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(binary); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(archive.Bytes())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/3leaps/sfetch/releases/tags/"):
+			tag := strings.TrimPrefix(r.URL.Path, "/repos/3leaps/sfetch/releases/tags/")
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: tag, Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+			}})
+		case r.URL.Path == "/dl/"+assetName:
+			_, _ = w.Write(archive.Bytes())
+		case r.URL.Path == "/dl/SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name       string
+		tag        string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "matching version installs", tag: "v1.0.0", wantCode: exitOK},
+		{name: "mis-tagged release refused", tag: "v2.0.0", wantCode: exitTrust, wantStderr: "error: refusing to self-update: the new binary was built as version 1.0.0, but the release tag is v2.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"--self-update", "--tag", tt.tag, "--yes", "--self-update-force", "--self-update-dir", dir, "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check"}, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr missing %q:\n%s", tt.wantStderr, stderr.String())
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != exitOK {
+				if len(entries) != 0 {
+					t.Errorf("refused self-update wrote %d files to %s", len(entries), dir)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("%d files in %s, want the new binary", len(entries), dir)
+			}
+			if got, _ := os.ReadFile(filepath.Join(dir, entries[0].Name())); !bytes.Equal(got, binary) {
+				t.Errorf("installed %s is not the release binary", entries[0].Name())
+			}
+		})
+	}
+}
+
+func TestMirrorURL(t *testing.T) {
+	const asset = "https://github.com/o/tool/releases/download/v1.0.0/tool.tar.gz"
+	tests := []struct {
+		mirror string
+		want   string
+	}{
+		{"https://mirror.example", "https://mirror.example/o/tool/releases/download/v1.0.0/tool.tar.gz"},
+		{"https://mirror.example/", "https://mirror.example/o/tool/releases/download/v1.0.0/tool.tar.gz"},
+		{"https://mirror.example/gh/", "https://mirror.example/gh/o/tool/releases/download/v1.0.0/tool.tar.gz"},
+		{"http://10.0.0.5:8080/cache", "http://10.0.0.5:8080/cache/o/tool/releases/download/v1.0.0/tool.tar.gz"},
+	}
+	for _, tt := range tests {
+		got, err := mirrorURL(tt.mirror, asset)
+		if err != nil {
+			t.Fatalf("mirrorURL(%q): %v", tt.mirror, err)
+		}
+		if got != tt.want {
+			t.Errorf("mirrorURL(%q) = %q, want %q", tt.mirror, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMirrorURL(t *testing.T) {
+	tests := []struct {
+		mirror    string
+		allowHTTP bool
+		wantErr   string
+	}{
+		{mirror: "https://mirror.example/gh"},
+		{mirror: "http://mirror.example", allowHTTP: true},
+		{mirror: "http://mirror.example", wantErr: "--allow-http"},
+		{mirror: "ftp://mirror.example", wantErr: "scheme must be https"},
+		{mirror: "mirror.example", wantErr: "absolute URL"},
+		{mirror: "https://mirror.example/?x=1", wantErr: "query and fragment"},
+	}
+	for _, tt := range tests {
+		err := validateMirrorURL(tt.mirror, tt.allowHTTP)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("validateMirrorURL(%q) = %v", tt.mirror, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("validateMirrorURL(%q) = %v, want containing %q", tt.mirror, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseGitHubRawSpec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    githubRawSpec
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			input: "Homebrew/install@HEAD:install.sh",
+			want: githubRawSpec{
+				Repo:      "Homebrew/install",
+				Ref:       "HEAD",
+				Path:      "install.sh",
+				URL:       "https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh",
+				AssetName: "install.sh",
+			},
+		},
+		{
+			name:  "nested_path",
+			input: "helm/helm@main:scripts/get-helm-3",
+			want: githubRawSpec{
 				Repo:      "helm/helm",
 				Ref:       "main",
 				Path:      "scripts/get-helm-3",
@@ -4271,132 +9523,2212 @@ func TestParseGitHubRawSpec(t *testing.T) {
 			},
 		},
 		{
-			name:    "missing_colon",
-			input:   "owner/repo@ref",
-			wantErr: true,
+			name:    "missing_colon",
+			input:   "owner/repo@ref",
+			wantErr: true,
+		},
+		{
+			name:    "missing_at",
+			input:   "owner/repo:path",
+			wantErr: true,
+		},
+		{
+			name:    "missing_repo",
+			input:   "@ref:path",
+			wantErr: true,
+		},
+		{
+			name:    "path_escape",
+			input:   "owner/repo@ref:../secrets.txt",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseGitHubRawSpec(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("parseGitHubRawSpec(%q) = %+v, want %+v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatchWithPatterns_WinDarwinFalsePositive(t *testing.T) {
+	t.Parallel()
+
+	assets := []Asset{
+		{Name: "goneat_v0.5.2_darwin_amd64.tar.gz"},
+		{Name: "goneat_v0.5.2_darwin_arm64.tar.gz"},
+		{Name: "goneat_v0.5.2_linux_amd64.tar.gz"},
+		{Name: "goneat_v0.5.2_linux_arm64.tar.gz"},
+		{Name: "goneat_v0.5.2_windows_amd64.zip"},
+	}
+
+	cfg := &RepoConfig{
+		BinaryName:        "goneat",
+		ArchiveExtensions: []string{".tar.gz", ".zip"},
+		AssetPatterns: []string{
+			"(?i)^{{binary}}[_-]{{osToken}}[_-]{{archToken}}.*",
+			"(?i)^{{binary}}.*{{osToken}}.*{{archToken}}.*",
+		},
+	}
+
+	// windows/arm64 must NOT match darwin_arm64 via "win" substring in "darwin".
+	got := matchWithPatterns(assets, cfg, "windows", "arm64", platformHints{})
+	if got != nil {
+		t.Errorf("matchWithPatterns(windows, arm64) = %q, want nil (should fall through to heuristics)", got.Name)
+	}
+}
+
+func TestSelectAsset_WindowsArm64_EndToEnd(t *testing.T) {
+	t.Parallel()
+
+	release := &Release{
+		TagName: "v0.5.2",
+		Assets: []Asset{
+			{Name: "goneat_v0.5.2_darwin_amd64.tar.gz"},
+			{Name: "goneat_v0.5.2_darwin_arm64.tar.gz"},
+			{Name: "goneat_v0.5.2_linux_amd64.tar.gz"},
+			{Name: "goneat_v0.5.2_linux_arm64.tar.gz"},
+			{Name: "goneat_v0.5.2_windows_amd64.zip"},
+		},
+	}
+
+	cfg := &RepoConfig{
+		BinaryName:        "goneat",
+		ArchiveExtensions: []string{".tar.gz", ".zip"},
+		AssetPatterns: []string{
+			"(?i)^{{binary}}[_-]{{osToken}}[_-]{{archToken}}.*",
+			"(?i)^{{binary}}.*{{osToken}}.*{{archToken}}.*",
+		},
+	}
+
+	// No exact windows/arm64 asset exists; should fall through to heuristics
+	// and pick windows_amd64 as the best available.
+	got, err := selectAsset(release, cfg, "windows", "arm64", platformHints{}, "", "", false)
+	if err != nil {
+		t.Fatalf("selectAsset(windows, arm64) error = %v", err)
+	}
+	if got.Name != "goneat_v0.5.2_windows_amd64.zip" {
+		t.Errorf("selectAsset(windows, arm64) = %q, want %q", got.Name, "goneat_v0.5.2_windows_amd64.zip")
+	}
+}
+
+func TestResolveArchiveBinaryPathWindowsExe(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []string
+		binaryName string
+		want       string
+		wantErr    string
+	}{
+		{name: "exe at root", files: []string{"mytool.exe"}, binaryName: "mytool", want: "mytool.exe"},
+		{name: "exe preferred over wrapper at root", files: []string{"mytool", "mytool.exe"}, binaryName: "mytool", want: "mytool.exe"},
+		{name: "exe preferred over wrapper nested", files: []string{"mytool-1.0/bin/mytool", "mytool-1.0/bin/mytool.exe"}, binaryName: "mytool", want: "mytool-1.0/bin/mytool.exe"},
+		{name: "explicit exe name", files: []string{"mytool", "mytool.exe"}, binaryName: "mytool.exe", want: "mytool.exe"},
+		{name: "extensionless only", files: []string{"bin/mytool"}, binaryName: "mytool", want: "bin/mytool"},
+		{name: "not found names both", files: []string{"README.md"}, binaryName: "mytool", wantErr: "binary mytool not found in archive (looked for mytool.exe and mytool)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			for _, name := range tt.files {
+				p := filepath.Join(tmp, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				if err := os.WriteFile(p, []byte("bin"), 0o755); err != nil {
+					t.Fatalf("write %s: %v", name, err)
+				}
+			}
+
+			got, err := resolveArchiveBinaryPath(tmp, tt.binaryName, "windows")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveArchiveBinaryPath: %v", err)
+			}
+			if member := archiveMember(tmp, got); member != tt.want {
+				t.Fatalf("member = %q, want %q", member, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallReleaseAssetWindowsExeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		asset    string
+		files    []testArchiveFile
+		output   string // relative to the temp dir; empty installs to dest
+		wantName string
+	}{
+		{
+			name:     "archive exe keeps suffix",
+			asset:    "mytool_windows_amd64.zip",
+			files:    []testArchiveFile{{"mytool_windows_amd64/mytool.exe", 0o644}, {"mytool_windows_amd64/mytool", 0o755}},
+			wantName: "mytool.exe",
+		},
+		{
+			name:     "output directory keeps suffix",
+			asset:    "mytool_windows_amd64.zip",
+			files:    []testArchiveFile{{"mytool.exe", 0o644}},
+			output:   "dest",
+			wantName: "mytool.exe",
+		},
+		{
+			name:     "explicit output file wins",
+			asset:    "mytool_windows_amd64.zip",
+			files:    []testArchiveFile{{"mytool.exe", 0o644}},
+			output:   "dest/renamed.exe",
+			wantName: "renamed.exe",
+		},
+		{
+			name:     "raw exe asset",
+			asset:    "mytool_windows_amd64.exe",
+			wantName: "mytool_windows_amd64.exe",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			destDir := filepath.Join(tmp, "dest")
+			if err := os.MkdirAll(destDir, 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			cfg := &RepoConfig{BinaryName: "mytool"}
+			assetPath := filepath.Join(tmp, tt.asset)
+			if tt.files != nil {
+				writeTestArchive(t, assetPath, ArchiveFormatZip, tt.files)
+			} else if err := os.WriteFile(assetPath, []byte("MZ"), 0o644); err != nil {
+				t.Fatalf("write asset: %v", err)
+			}
+			cls, _, err := classifyAsset(tt.asset, cfg, "")
+			if err != nil {
+				t.Fatalf("classifyAsset: %v", err)
+			}
+
+			target := installTarget{destDir: destDir}
+			if tt.output != "" {
+				target = installTarget{output: filepath.Join(tmp, filepath.FromSlash(tt.output))}
+			}
+			var stderr bytes.Buffer
+			installed, err := installReleaseAsset(assetPath, &Asset{Name: tt.asset}, cls, cfg, "windows", t.TempDir(), target, &stderr, newHintSink(&stderr))
+			if err != nil {
+				t.Fatalf("installReleaseAsset: %v", err)
+			}
+			if len(installed) != 1 {
+				t.Fatalf("installed = %+v, want one binary", installed)
+			}
+			if want := filepath.Join(destDir, tt.wantName); installed[0].finalPath != want {
+				t.Fatalf("finalPath = %q, want %q", installed[0].finalPath, want)
+			}
+			if _, err := os.Stat(installed[0].path); err != nil {
+				t.Fatalf("installed file: %v", err)
+			}
+		})
+	}
+}
+
+func TestHintSinkEmitForError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind hintKind
+	}{
+		{name: "selection tie", err: &assetTieError{Candidates: []Asset{{Name: "a.tar.gz"}, {Name: "b.tar.gz"}}}, wantKind: hintSelectionTie},
+		{name: "missing pgp key", err: &missingKeyError{format: sigFormatPGP}, wantKind: hintMissingKey},
+		{name: "wrapped permission", err: fmt.Errorf("install to /opt/x: %w", &os.PathError{Op: "open", Path: "/opt/x", Err: os.ErrPermission}), wantKind: hintWritePermission},
+		{name: "unrecognized", err: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			sink := newHintSink(&out)
+			sink.emitForError(tt.err)
+			if tt.wantKind == "" {
+				if len(sink.hints) != 0 || out.Len() != 0 {
+					t.Fatalf("expected no hint, got %+v (%q)", sink.hints, out.String())
+				}
+				return
+			}
+			if len(sink.hints) != 1 || sink.hints[0].Kind != tt.wantKind {
+				t.Fatalf("hints = %+v, want one %s", sink.hints, tt.wantKind)
+			}
+			if out.String() != "  hint: "+sink.hints[0].Message+"\n" {
+				t.Fatalf("output = %q", out.String())
+			}
+		})
+	}
+}
+
+func TestDirOnPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		pathEnv string
+		goos    string
+		want    bool
+	}{
+		{name: "present", dir: "/home/u/.local/bin", pathEnv: "/usr/bin:/home/u/.local/bin", goos: "linux", want: true},
+		{name: "trailing slash", dir: "/home/u/.local/bin", pathEnv: "/usr/bin:/home/u/.local/bin/", goos: "linux", want: true},
+		{name: "absent", dir: "/home/u/.local/bin", pathEnv: "/usr/bin:/bin", goos: "linux"},
+		{name: "empty path", dir: "/home/u/.local/bin", goos: "linux"},
+		{name: "case differs on linux", dir: "/home/u/.local/bin", pathEnv: "/HOME/U/.local/bin", goos: "linux"},
+		{name: "case differs on windows", dir: `C:\Users\u\bin`, pathEnv: `C:\Windows;c:\users\u\bin`, goos: "windows", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dirOnPath(tt.dir, tt.pathEnv, tt.goos); got != tt.want {
+				t.Fatalf("dirOnPath(%q, %q) = %v, want %v", tt.dir, tt.pathEnv, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathGuidance(t *testing.T) {
+	tests := []struct {
+		shellEnv  string
+		goos      string
+		wantShell string
+		want      string
+	}{
+		{shellEnv: "/bin/bash", goos: "linux", wantShell: "bash", want: "~/.bashrc"},
+		{shellEnv: "/usr/bin/zsh", goos: "darwin", wantShell: "zsh", want: "~/.zshrc"},
+		{shellEnv: "/opt/homebrew/bin/fish", goos: "darwin", wantShell: "fish", want: "fish_add_path ~/.local/bin"},
+		{shellEnv: "/bin/ksh", goos: "linux", wantShell: "your shell", want: "~/.profile"},
+		{shellEnv: "", goos: "linux", wantShell: "your shell", want: "~/.profile"},
+		{shellEnv: "", goos: "windows", wantShell: "PowerShell", want: "SetEnvironmentVariable"},
+	}
+	for _, tt := range tests {
+		shell, command := pathGuidance(tt.shellEnv, tt.goos)
+		if shell != tt.wantShell || !strings.Contains(command, tt.want) {
+			t.Errorf("pathGuidance(%q, %q) = %q, %q; want %q and a command containing %q", tt.shellEnv, tt.goos, shell, command, tt.wantShell, tt.want)
+		}
+	}
+}
+
+func TestWarnIfNotOnPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SHELL", "/bin/zsh")
+
+	t.Setenv("PATH", dir)
+	var out bytes.Buffer
+	sink := newHintSink(&out)
+	warnIfNotOnPath(&out, sink, dir)
+	warnIfNotOnPath(&out, sink, "")
+	if out.Len() != 0 || len(sink.hints) != 0 {
+		t.Fatalf("unexpected guidance: %q", out.String())
+	}
+
+	t.Setenv("PATH", filepath.Join(dir, "other"))
+	warnIfNotOnPath(&out, sink, dir)
+	if len(sink.hints) != 1 || sink.hints[0].Kind != hintNotOnPath {
+		t.Fatalf("hints = %+v, want one %s", sink.hints, hintNotOnPath)
+	}
+	if !strings.Contains(out.String(), "is not on your PATH") || !strings.Contains(out.String(), "~/.zshrc") {
+		t.Fatalf("output = %q", out.String())
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	tests := []struct {
+		shell    string
+		wantCode int
+		want     []string
+	}{
+		{shell: "bash", want: []string{
+			"complete -o filenames -F _sfetch sfetch",
+			`-libc|--libc) COMPREPLY=($(compgen -W "auto musl gnu" -- "$cur")); return ;;`,
+			`-dest-dir|--dest-dir) COMPREPLY=($(compgen -d -- "$cur")); return ;;`,
+			"--verify-all-signatures",
+		}},
+		{shell: "zsh", want: []string{
+			"#compdef sfetch",
+			`'--provenance-format=[provenance output format (sfetch, intoto, slsa)]:provenance-format:(sfetch intoto slsa)' \`,
+			`'--skip-sig[skip signature verification (testing only)]' \`,
+			`'--allow-http[allow http\:// URLs (unsafe)]' \`,
+		}},
+		{shell: "fish", want: []string{
+			"complete -c sfetch -l completion -x -a 'bash zsh fish'",
+			"complete -c sfetch -l pgp-key-file -r -F",
+			"complete -c sfetch -l repo -x -d 'GitHub repo owner/repo'",
+			"complete -c sfetch -l insecure -d",
+		}},
+		{shell: "powershell", wantCode: exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"--completion", tt.shell, "--cache-dir", t.TempDir()}, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit code = %d, want %d (stderr=%q)", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("completion script missing %q:\n%s", want, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+// updateGolden rewrites golden files instead of comparing against them:
+// go test -run TestCompletionGolden -update-golden
+var updateGolden = flag.Bool("update-golden", false, "rewrite testdata golden files")
+
+// completionCacheDir returns a cache directory whose index lists a few repos
+// for --repo completion, plus one that must not reach a script.
+func completionCacheDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	idx := &cache.Index{Entries: map[string]cache.Entry{}}
+	for _, e := range []cache.Entry{
+		{Repo: "3leaps/sfetch", Tag: "v0.4.0", Asset: "a"},
+		{Repo: "3leaps/sfetch", Tag: "v0.5.0", Asset: "a"},
+		{Repo: "BurntSushi/ripgrep", Tag: "14.1.0", Asset: "a"},
+		{Repo: "evil/$(touch pwned)", Tag: "v1", Asset: "a"},
+	} {
+		idx.Put(e)
+	}
+	if err := idx.Save(dir); err != nil {
+		t.Fatalf("save cache index: %v", err)
+	}
+	return dir
+}
+
+func TestCompletionGolden(t *testing.T) {
+	cacheDir := completionCacheDir(t)
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run([]string{"--completion", shell, "--cache-dir", cacheDir}, &stdout, &stderr); code != exitOK {
+				t.Fatalf("exit = %d (stderr=%q)", code, stderr.String())
+			}
+			golden := filepath.Join("testdata", "completion", "sfetch."+shell)
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (regenerate with -update-golden): %v", err)
+			}
+			if stdout.String() != string(want) {
+				t.Errorf("%s completion differs from %s; if the flags changed on purpose, rerun with -update-golden", shell, golden)
+			}
+			if strings.Contains(stdout.String(), "pwned") {
+				t.Errorf("%s completion includes a cached repo that is not owner/repo", shell)
+			}
+		})
+	}
+}
+
+func TestCompletionBashSyntax(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--completion", "bash", "--cache-dir", completionCacheDir(t)}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d (stderr=%q)", code, stderr.String())
+	}
+	script := filepath.Join(t.TempDir(), "sfetch.bash")
+	if err := os.WriteFile(script, stdout.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(bash, "-n", script).CombinedOutput(); err != nil {
+		t.Fatalf("bash -n: %v\n%s", err, out)
+	}
+}
+
+func TestCompletionRepos(t *testing.T) {
+	if got := completionRepos(t.TempDir()); got != nil {
+		t.Errorf("empty cache: repos = %v, want none", got)
+	}
+	got := completionRepos(completionCacheDir(t))
+	if want := []string{"3leaps/sfetch", "BurntSushi/ripgrep"}; !slices.Equal(got, want) {
+		t.Errorf("repos = %v, want %v", got, want)
+	}
+}
+
+func TestSplitBinaryNames(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"tool", []string{"tool"}},
+		{"tool, tool-helper ,", []string{"tool", "tool-helper"}},
+	}
+	for _, tt := range tests {
+		if got := splitBinaryNames(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitBinaryNames(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFindArchiveExecutables(t *testing.T) {
+	write := func(t *testing.T, dir, name string, mode os.FileMode) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("executables only", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "tool", 0o755)
+		write(t, dir, "README.md", 0o644)
+		write(t, dir, "bin/helper", 0o755)
+		got, err := findArchiveExecutables(dir, "linux")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []string{filepath.Join(dir, "bin", "helper"), filepath.Join(dir, "tool")}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("windows uses exe suffix", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "tool.exe", 0o644)
+		write(t, dir, "tool.dll", 0o644)
+		got, err := findArchiveExecutables(dir, "windows")
+		if err != nil || len(got) != 1 || filepath.Base(got[0]) != "tool.exe" {
+			t.Fatalf("got %v, %v", got, err)
+		}
+	})
+
+	t.Run("duplicate names rejected", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "a/tool", 0o755)
+		write(t, dir, "b/tool", 0o755)
+		if _, err := findArchiveExecutables(dir, "linux"); err == nil || !strings.Contains(err.Error(), "more than one executable named tool") {
+			t.Fatalf("expected duplicate error, got %v", err)
+		}
+	})
+
+	t.Run("none found", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "README.md", 0o644)
+		if _, err := findArchiveExecutables(dir, "linux"); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestFetchPGPKeyByFingerprint(t *testing.T) {
+	keyBytes, err := os.ReadFile("testdata/pgp/rsa-pub.asc")
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	const fpr = "B81686FEE118A91CE11970EFE9D7CD5DDC61A7DB"
+	const other = "740A0E01C5395E407E3770A18D064FBC64CDD39C"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vks/v1/by-fingerprint/" + fpr, "/vks/v1/by-fingerprint/" + other:
+			// The second route returns the wrong key, as a hostile keyserver might.
+			_, _ = w.Write(keyBytes)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		fpr     string
+		wantErr string
+	}{
+		{name: "matching key", fpr: fpr},
+		{name: "substituted key", fpr: other, wantErr: "does not contain fingerprint " + other},
+		{name: "unknown fingerprint", fpr: "0000000000000000000000000000000000000000", wantErr: "status 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status bytes.Buffer
+			path, err := fetchPGPKeyByFingerprint(context.Background(), server.URL+"/", tt.fpr, t.TempDir(), &status)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, keyBytes) {
+				t.Fatal("fetched key differs from served key")
+			}
+			if !strings.Contains(status.String(), "Fetched PGP key "+tt.fpr) {
+				t.Fatalf("status = %q, want fetch note", status.String())
+			}
+		})
+	}
+}
+
+func TestPGPKeyFingerprintPinsSigner(t *testing.T) {
+	// The keyserver answers with the requested key plus the key that
+	// actually signed the release. Only the requested key may sign.
+	requested, err := os.ReadFile("testdata/pgp/rsa-pub.asc")
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	signer, err := os.ReadFile("testdata/keys/test-pgp-pub.asc")
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	const requestedFPR = "B81686FEE118A91CE11970EFE9D7CD5DDC61A7DB"
+	bundle := pgpKeyBundle(t, requested, signer)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bundle) //nolint:errcheck // test handler, error handled by http.ResponseWriter
+	}))
+	defer server.Close()
+
+	const asset = "testdata/integration/sfetch_test_darwin_arm64.tar.gz"
+	tests := []struct {
+		name    string
+		fpr     string
+		pin     string
+		wantErr bool
+	}{
+		{name: "other key in response signed", fpr: requestedFPR, wantErr: true},
+		{name: "requested key signed", fpr: testPGPFingerprint},
+		{name: "explicit pin takes precedence", fpr: requestedFPR, pin: testPGPFingerprint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := signatureKeyOptions{pgpKeyFPR: tt.fpr, pinPGPFingerprint: tt.pin, keyserver: server.URL, gpgBin: "no-such-gpg"}
+			path, err := keys.pgpKeyPath(context.Background(), nil, t.TempDir())
+			if err != nil {
+				t.Fatalf("pgpKeyPath: %v", err)
+			}
+			_, err = verifyPGPSignature(asset, asset+".asc", path, keys.pgpOptions())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "does not match pinned fingerprint") {
+					t.Fatalf("err = %v, want pinned fingerprint mismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// pgpKeyBundle joins armored public keys into one armored block, the way a
+// keyserver returns several keys.
+func pgpKeyBundle(t *testing.T, keys ...[]byte) []byte {
+	t.Helper()
+	var body bytes.Buffer
+	for _, k := range keys {
+		block, err := armor.Decode(bytes.NewReader(k))
+		if err != nil {
+			t.Fatalf("decode key: %v", err)
+		}
+		if _, err := io.Copy(&body, block.Body); err != nil {
+			t.Fatalf("read key: %v", err)
+		}
+	}
+	var out bytes.Buffer
+	w, err := armor.Encode(&out, "PGP PUBLIC KEY BLOCK", nil)
+	if err != nil {
+		t.Fatalf("encode bundle: %v", err)
+	}
+	if _, err := w.Write(body.Bytes()); err != nil {
+		t.Fatalf("encode bundle: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("encode bundle: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestValidateKeyserverURL(t *testing.T) {
+	tests := []struct {
+		raw       string
+		allowHTTP bool
+		wantErr   bool
+	}{
+		{raw: defaultKeyserver},
+		{raw: "http://keys.example", wantErr: true},
+		{raw: "http://keys.example", allowHTTP: true},
+		{raw: "ftp://keys.example", allowHTTP: true, wantErr: true},
+		{raw: "keys.openpgp.org", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateKeyserverURL(tt.raw, tt.allowHTTP); (err != nil) != tt.wantErr {
+			t.Errorf("validateKeyserverURL(%q, %v) = %v, wantErr %v", tt.raw, tt.allowHTTP, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDownloadAllConcurrent(t *testing.T) {
+	// The asset endpoint answers only after the checksum and signature have
+	// been served, so a sequential fetch would never finish.
+	var served sync.WaitGroup
+	served.Add(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool.tar.gz":
+			done := make(chan struct{})
+			go func() { served.Wait(); close(done) }()
+			select {
+			case <-done:
+				_, _ = w.Write([]byte("asset"))
+			case <-time.After(5 * time.Second):
+				http.Error(w, "supplemental files were not fetched while the asset was in flight", http.StatusGatewayTimeout)
+			}
+		case "/SHA256SUMS", "/SHA256SUMS.minisig":
+			_, _ = w.Write([]byte(r.URL.Path))
+			served.Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var jobs []downloadJob
+	for _, name := range []string{"tool.tar.gz", "SHA256SUMS", "SHA256SUMS.minisig"} {
+		jobs = append(jobs, downloadJob{
+			asset: &Asset{Name: name, BrowserDownloadUrl: server.URL + "/" + name},
+			path:  filepath.Join(dir, name),
+		})
+	}
+	if err := downloadAll(context.Background(), jobs, defaultMaxConcurrency); err != nil {
+		t.Fatalf("downloadAll: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "tool.tar.gz"))
+	if err != nil || string(got) != "asset" {
+		t.Fatalf("asset = %q, %v; want %q", got, err, "asset")
+	}
+}
+
+func TestDownloadAllLimit(t *testing.T) {
+	tests := []struct {
+		limit    int
+		wantPeak int32
+	}{
+		{limit: 1, wantPeak: 1},
+		{limit: 2, wantPeak: 2},
+		{limit: 0, wantPeak: 1}, // treated as 1
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit %d", tt.limit), func(t *testing.T) {
+			var inFlight, peak atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(50 * time.Millisecond)
+				_, _ = w.Write([]byte(r.URL.Path))
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			var jobs []downloadJob
+			for _, name := range []string{"tool.tar.gz", "SHA256SUMS", "SHA256SUMS.minisig", "tool.pub"} {
+				jobs = append(jobs, downloadJob{
+					asset: &Asset{Name: name, BrowserDownloadUrl: server.URL + "/" + name},
+					path:  filepath.Join(dir, name),
+				})
+			}
+			if err := downloadAll(context.Background(), jobs, tt.limit); err != nil {
+				t.Fatalf("downloadAll: %v", err)
+			}
+			if got := peak.Load(); got != tt.wantPeak {
+				t.Fatalf("peak concurrent downloads = %d, want %d", got, tt.wantPeak)
+			}
+			for _, job := range jobs {
+				got, err := os.ReadFile(job.path)
+				if err != nil || string(got) != "/"+job.asset.Name {
+					t.Errorf("%s = %q, %v", job.asset.Name, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadAllCancelsOnFailure(t *testing.T) {
+	slowCancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool.tar.gz":
+			select {
+			case <-r.Context().Done():
+				close(slowCancelled)
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	jobs := []downloadJob{
+		{asset: &Asset{Name: "tool.tar.gz", BrowserDownloadUrl: server.URL + "/tool.tar.gz"}, path: filepath.Join(dir, "tool.tar.gz")},
+		{asset: &Asset{Name: "SHA256SUMS", BrowserDownloadUrl: server.URL + "/SHA256SUMS"}, path: filepath.Join(dir, "SHA256SUMS")},
+	}
+	start := time.Now()
+	err := downloadAll(context.Background(), jobs, defaultMaxConcurrency)
+	if err == nil || !strings.Contains(err.Error(), "SHA256SUMS") {
+		t.Fatalf("downloadAll error = %v, want failure naming SHA256SUMS", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("downloadAll took %v; the slow asset was not cancelled", elapsed)
+	}
+	select {
+	case <-slowCancelled:
+	case <-time.After(3 * time.Second):
+		t.Fatal("slow asset request was not cancelled")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "1024", want: 1024},
+		{in: "500K", want: 500 << 10},
+		{in: "500M", want: 500 << 20},
+		{in: "2G", want: 2 << 30},
+		{in: "2GB", want: 2 << 30},
+		{in: "2gib", want: 2 << 30},
+		{in: "1T", want: 1 << 40},
+		{in: "", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "2X", wantErr: true},
+		{in: "G", wantErr: true},
+		{in: "99999999999T", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseByteSize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuardResponseSize(t *testing.T) {
+	defer setMaxAssetSize(defaultMaxAssetSize)
+	setMaxAssetSize(10 << 20)
+
+	tests := []struct {
+		name          string
+		contentLength int64
+		listed        int64
+		received      int64
+		wantErr       error
+		wantCheckErr  error
+		wantNote      string
+	}{
+		{name: "matching", contentLength: 100, listed: 100, received: 100},
+		{name: "no sizes known", contentLength: -1, received: 5 << 20},
+		{name: "content-length over max-size", contentLength: 11 << 20, wantErr: errAssetTooLarge},
+		{name: "content-length far from listed", contentLength: 3 << 20, listed: 100, wantErr: errAssetSizeMismatch},
+		{name: "content-length near listed", contentLength: 110, listed: 100, received: 100, wantNote: "Content-Length 110 differs from the listed size 100"},
+		{name: "body past declared size", contentLength: -1, listed: 100, received: 100 + assetSizeTolerance + 1, wantCheckErr: errAssetSizeMismatch},
+		{name: "body short of declared size", contentLength: -1, listed: 100, received: 90, wantNote: "received 90 bytes but 100 were declared"},
+		{name: "unsized body past max-size", contentLength: -1, received: 10<<20 + 1, wantCheckErr: errAssetTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := guardResponseSize(&http.Response{ContentLength: tt.contentLength}, "tool", tt.listed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("guardResponseSize error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := g.check(tt.received); !errors.Is(err, tt.wantCheckErr) {
+				t.Fatalf("check error = %v, want %v", err, tt.wantCheckErr)
+			}
+			if tt.wantNote == "" && g.note != "" && tt.wantCheckErr == nil {
+				t.Fatalf("unexpected note %q", g.note)
+			}
+			if !strings.Contains(g.note, tt.wantNote) {
+				t.Fatalf("note = %q, want %q", g.note, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestRunMaxSize(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s", runtime.GOOS, runtime.GOARCH)
+	payload := []byte("tool\n")
+	sums := fmt.Sprintf("%x  %s\n", sha256.Sum256(payload), assetName)
+
+	tests := []struct {
+		name      string
+		listed    int64
+		serve     func(w http.ResponseWriter)
+		args      []string
+		wantCode  int
+		wantError string
+		wantFetch bool
+	}{
+		{
+			name:      "listed over the default limit",
+			listed:    3 << 30,
+			wantCode:  exitNetwork,
+			wantError: "asset exceeds --max-size: " + assetName + " is listed at",
+		},
+		{
+			name:     "limit disabled",
+			listed:   int64(len(payload)),
+			args:     []string{"--max-size", "0"},
+			wantCode: exitOK, wantFetch: true,
+		},
+		{
+			name:   "content-length over --max-size",
+			listed: 0,
+			serve: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Length", strconv.Itoa(4<<10))
+				_, _ = w.Write(payload)
+			},
+			args:      []string{"--max-size", "1K"},
+			wantCode:  exitNetwork,
+			wantError: "per Content-Length (limit 1.0 KB",
+			wantFetch: true,
+		},
+		{
+			name:   "content-length contradicts the listing",
+			listed: int64(len(payload)),
+			serve: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Length", strconv.Itoa(8<<20))
+				_, _ = w.Write(payload)
+			},
+			wantCode:  exitNetwork,
+			wantError: "server declares 8388608 bytes but the release lists 5",
+			wantFetch: true,
+		},
+		{
+			name:   "unsized stream past the listing",
+			listed: int64(len(payload)),
+			serve: func(w http.ResponseWriter) {
+				chunk := bytes.Repeat([]byte("x"), 64<<10)
+				for range 32 {
+					if _, err := w.Write(chunk); err != nil {
+						return
+					}
+					w.(http.Flusher).Flush()
+				}
+			},
+			wantCode:  exitNetwork,
+			wantError: "transfer aborted",
+			wantFetch: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched atomic.Bool
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				base := "http://" + r.Host
+				switch r.URL.Path {
+				case "/repos/o/tool/releases/latest":
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+						{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName, Size: tt.listed},
+						{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+					}})
+				case "/dl/" + assetName:
+					fetched.Store(true)
+					if tt.serve != nil {
+						tt.serve(w)
+						return
+					}
+					_, _ = w.Write(payload)
+				case "/dl/SHA256SUMS":
+					_, _ = w.Write([]byte(sums))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+			defer setMaxAssetSize(defaultMaxAssetSize)
+
+			destDir := t.TempDir()
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"--repo", "o/tool", "--latest", "--dest-dir", destDir, "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check"}, tt.args...)
+			code := run(args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if tt.wantError != "" && !strings.Contains(stderr.String(), tt.wantError) {
+				t.Fatalf("stderr missing %q:\n%s", tt.wantError, stderr.String())
+			}
+			if fetched.Load() != tt.wantFetch {
+				t.Fatalf("asset fetched = %v, want %v", fetched.Load(), tt.wantFetch)
+			}
+			if _, err := os.Stat(filepath.Join(destDir, assetName)); (err == nil) != (tt.wantCode == exitOK) {
+				t.Fatalf("installed = %v, want %v", err == nil, tt.wantCode == exitOK)
+			}
+		})
+	}
+}
+
+func TestCheckListedAsset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "SHA256SUMS")
+	content := []byte("abc  tool.tar.gz\n")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		asset   Asset
+		wantErr string
+	}{
+		{name: "unlisted size and digest", asset: Asset{Name: "SHA256SUMS"}},
+		{name: "matching size", asset: Asset{Name: "SHA256SUMS", Size: int64(len(content))}},
+		{name: "matching size and digest", asset: Asset{Name: "SHA256SUMS", Size: int64(len(content)), Digest: digest}},
+		{name: "uppercase digest", asset: Asset{Name: "SHA256SUMS", Digest: strings.ToUpper(digest)}},
+		{name: "size changed", asset: Asset{Name: "SHA256SUMS", Size: 5}, wantErr: "SHA256SUMS is 17 bytes but the release listed 5"},
+		{name: "digest changed", asset: Asset{Name: "SHA256SUMS", Size: int64(len(content)), Digest: "sha256:" + strings.Repeat("0", 64)}, wantErr: "the release listed " + strings.Repeat("0", 64)},
+		{name: "other digest algorithm", asset: Asset{Name: "SHA256SUMS", Digest: "sha512:abcd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkListedAsset(&tt.asset, path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkListedAsset: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, errReleaseChanged) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want release changed error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool.tar.gz")
+	content := []byte(strings.Repeat("asset", 10000))
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	wantSHA256 := hex.EncodeToString(sum[:])
+
+	for _, algo := range []string{"sha256", "sha512", "blake2b-256"} {
+		t.Run(algo, func(t *testing.T) {
+			h, err := newChecksumHash(algo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.Write(content)
+			want := hex.EncodeToString(h.Sum(nil))
+
+			digest, sha256Hex, size, err := hashFile(path, algo)
+			if err != nil {
+				t.Fatalf("hashFile: %v", err)
+			}
+			if digest != want || sha256Hex != wantSHA256 || size != int64(len(content)) {
+				t.Fatalf("hashFile = %s, %s, %d; want %s, %s, %d", digest, sha256Hex, size, want, wantSHA256, len(content))
+			}
+		})
+	}
+
+	if _, _, _, err := hashFile(path, "crc32"); err == nil {
+		t.Fatal("hashFile accepted an unsupported algorithm")
+	}
+	if _, _, _, err := hashFile(filepath.Join(t.TempDir(), "missing"), "sha256"); err == nil {
+		t.Fatal("hashFile accepted a missing file")
+	}
+}
+
+func TestRunSourceDigest(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(assetBytes))
+
+	newServer := func(t *testing.T, digest string) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/o/tool/releases/latest":
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+					{Name: assetName, BrowserDownloadUrl: "http://" + r.Host + "/dl/" + assetName, Size: int64(len(assetBytes)), Digest: digest},
+				}})
+			case "/dl/" + assetName:
+				_, _ = w.Write(assetBytes)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(ts.Close)
+		t.Setenv("SFETCH_API_BASE", ts.URL)
+	}
+
+	t.Run("dry-run", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			digest string
+			want   []string
+		}{
+			{
+				name:   "digest listed",
+				digest: digest,
+				want:   []string{"Digest:     " + digest + " (release API, checked after download)", "Digest:     available=true matched=false points=10", "Trust:      35/100 (low)"},
+			},
+			{
+				name: "no digest",
+				want: []string{"Digest:     none", "Digest:     available=false matched=false points=0", "Trust:      25/100 (minimal)"},
+			},
+			{
+				name:   "other algorithm",
+				digest: "sha512:abcd",
+				want:   []string{"Digest:     none", "Trust:      25/100 (minimal)"},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				newServer(t, tt.digest)
+				var stdout, stderr bytes.Buffer
+				if code := run([]string{"--repo", "o/tool", "--latest", "--dry-run", "--skip-tools-check"}, &stdout, &stderr); code != exitOK {
+					t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+				}
+				for _, w := range tt.want {
+					if !strings.Contains(stdout.String()+stderr.String(), w) {
+						t.Errorf("dry-run output missing %q:\n%s%s", w, stdout.String(), stderr.String())
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("json assessment", func(t *testing.T) {
+		newServer(t, digest)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"--repo", "o/tool", "--latest", "--dry-run", "--json", "--skip-tools-check"}, &stdout, &stderr); code != exitOK {
+			t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+		}
+		var result runResult
+		if err := json.Unmarshal(stdout.Bytes(), &result); err != nil || result.Assessment == nil {
+			t.Fatalf("decode assessment: %v\n%s", err, stdout.String())
+		}
+		record := result.Assessment
+		if record.Asset == nil || record.Asset.Digest != digest {
+			t.Fatalf("asset = %+v, want digest %s", record.Asset, digest)
+		}
+		if f := record.Trust.Factors.SourceDigest; !f.Available || f.Points != 10 {
+			t.Fatalf("sourceDigest factor = %+v", f)
+		}
+	})
+
+	t.Run("install records the match", func(t *testing.T) {
+		newServer(t, digest)
+		provPath := filepath.Join(t.TempDir(), "prov.json")
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"--repo", "o/tool", "--latest", "--dest-dir", t.TempDir(), "--cache-dir", t.TempDir(), "--no-path-check", "--skip-tools-check", "--provenance-file", provPath}, &stdout, &stderr)
+		if code != exitOK {
+			t.Fatalf("exit = %d\nstderr:\n%s", code, stderr.String())
+		}
+		data, err := os.ReadFile(provPath)
+		if err != nil {
+			t.Fatalf("read provenance: %v", err)
+		}
+		var record ProvenanceRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			t.Fatalf("decode provenance: %v", err)
+		}
+		if f := record.Trust.Factors.SourceDigest; !f.Available || !f.Matched || f.Points != 10 {
+			t.Fatalf("sourceDigest factor = %+v, want available and matched", f)
+		}
+		if record.Trust.Score != 35 {
+			t.Fatalf("trust score = %d, want 35", record.Trust.Score)
+		}
+	})
+
+	t.Run("verify-only mismatch", func(t *testing.T) {
+		newServer(t, digest)
+		local := filepath.Join(t.TempDir(), assetName)
+		tampered := bytes.Clone(assetBytes)
+		tampered[len(tampered)-1] ^= 0xff
+		if err := os.WriteFile(local, tampered, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"--repo", "o/tool", "--latest", "--verify-only", local, "--cache-dir", t.TempDir(), "--skip-tools-check"}, &stdout, &stderr)
+		if code != exitChecksum {
+			t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitChecksum, stderr.String())
+		}
+		if want := "sha256 mismatch: the release API lists " + strings.TrimPrefix(digest, "sha256:"); !strings.Contains(stderr.String(), want) {
+			t.Fatalf("stderr missing %q:\n%s", want, stderr.String())
+		}
+	})
+}
+
+func TestFileURLPath(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "file:///srv/mirror/tool.tar.gz", want: "/srv/mirror/tool.tar.gz"},
+		{raw: "file://localhost/srv/mirror/tool.tar.gz", want: "/srv/mirror/tool.tar.gz"},
+		{raw: "FILE:///srv/mirror/tool%20v1.tar.gz", want: "/srv/mirror/tool v1.tar.gz"},
+		{raw: "file://fileserver/share/tool.tar.gz", wantErr: true},
+		{raw: "file:tool.tar.gz", wantErr: true},
+		{raw: "file://", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if !isFileURL(tt.raw) {
+				t.Fatalf("isFileURL(%q) = false", tt.raw)
+			}
+			got, err := fileURLPath(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("fileURLPath(%q) = %q, want error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil || got != filepath.FromSlash(tt.want) {
+				t.Fatalf("fileURLPath(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+	if isFileURL("https://example.com/file") {
+		t.Fatal("isFileURL accepted an https URL")
+	}
+}
+
+func TestRunReleaseFile(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	mirror := t.TempDir()
+	archivePath := filepath.Join(mirror, assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sums := fmt.Sprintf("%x  %s\n", sha256.Sum256(assetBytes), assetName)
+	if err := os.WriteFile(filepath.Join(mirror, "SHA256SUMS"), []byte(sums), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fileURL := func(name string) string {
+		return "file://" + filepath.ToSlash(filepath.Join(mirror, name))
+	}
+	rel := Release{TagName: "v1.0.0", Assets: []Asset{
+		{Name: assetName, BrowserDownloadUrl: fileURL(assetName), Size: int64(len(assetBytes))},
+		{Name: "SHA256SUMS", BrowserDownloadUrl: fileURL("SHA256SUMS")},
+	}}
+	relJSON, err := json.Marshal(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseFile := filepath.Join(mirror, "release.json")
+	if err := os.WriteFile(releaseFile, relJSON, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Any request to the API means the release file was not used. The
+	// same listing served from it must not reach local files.
+	var apiHits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiHits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(relJSON)
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantCode  int
+		wantError string
+		wantAPI   bool
+	}{
+		{name: "install from release file", args: []string{"--release-file", releaseFile}, wantCode: exitOK},
+		{name: "matching tag", args: []string{"--release-file", releaseFile, "--tag", "v1.0.0"}, wantCode: exitOK},
+		{name: "other tag", args: []string{"--release-file", releaseFile, "--tag", "v2.0.0"}, wantCode: exitUsage, wantError: "is v1.0.0, not --tag v2.0.0"},
+		{name: "missing release file", args: []string{"--release-file", filepath.Join(mirror, "missing.json")}, wantCode: exitUsage, wantError: "read release file"},
+		{name: "file URLs from the API", args: []string{"--latest"}, wantCode: exitNetwork, wantError: "only followed for --release-file releases", wantAPI: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiHits.Store(0)
+			destDir := t.TempDir()
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"--repo", "o/tool", "--dest-dir", destDir, "--cache-dir", t.TempDir(), "--no-path-check", "--skip-tools-check"}, tt.args...)
+			code := run(args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if tt.wantError != "" && !strings.Contains(stderr.String(), tt.wantError) {
+				t.Fatalf("stderr missing %q:\n%s", tt.wantError, stderr.String())
+			}
+			if got := apiHits.Load() > 0; got != tt.wantAPI {
+				t.Fatalf("API contacted = %v, want %v", got, tt.wantAPI)
+			}
+			if tt.wantCode == exitOK {
+				if _, err := os.Stat(filepath.Join(destDir, "tool")); err != nil {
+					t.Fatalf("tool not installed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRunInstallReceipts(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s", runtime.GOOS, runtime.GOARCH)
+	builds := map[string][]byte{"v1.0.0": []byte("tool v1\n"), "v2.0.0": []byte("tool v2\n")}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		tag, file, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/dl/"), "/")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/o/tool/releases/tags/"):
+			tag := strings.TrimPrefix(r.URL.Path, "/repos/o/tool/releases/tags/")
+			data, ok := builds[tag]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: tag, Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + tag + "/" + assetName, Size: int64(len(data))},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/" + tag + "/SHA256SUMS"},
+			}})
+		case file == assetName:
+			_, _ = w.Write(builds[tag])
+		case file == "SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sha256.Sum256(builds[tag]), assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	cacheDir := t.TempDir()
+	destDir := t.TempDir()
+	dst := filepath.Join(destDir, assetName)
+	sfetch := func(t *testing.T, wantCode int, args ...string) string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"--cache-dir", cacheDir, "--skip-tools-check", "--no-path-check"}, args...)
+		if code := run(args, &stdout, &stderr); code != wantCode {
+			t.Fatalf("sfetch %v: exit = %d, want %d\nstderr:\n%s", args, code, wantCode, stderr.String())
+		}
+		return stderr.String()
+	}
+	installed := func(t *testing.T, want string) {
+		t.Helper()
+		got, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("read installed file: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("installed = %q, want %q", got, want)
+		}
+	}
+
+	sfetch(t, exitInstall, "--rollback", "o/tool")
+	sfetch(t, exitOK, "--repo", "o/tool", "--tag", "v1.0.0", "--dest-dir", destDir)
+	sfetch(t, exitOK, "--repo", "o/tool", "--tag", "v2.0.0", "--dest-dir", destDir)
+	installed(t, "tool v2\n")
+
+	rec, err := receipt.Load(filepath.Join(cacheDir, receipt.Dir), "o/tool")
+	if err != nil {
+		t.Fatalf("load receipt: %v", err)
+	}
+	latest, _ := rec.Latest()
+	if len(rec.Installs) != 2 || latest.Tag != "v2.0.0" || latest.Asset != assetName || len(latest.Files) != 1 || latest.Files[0].Path != dst {
+		t.Fatalf("receipt = %+v", rec)
+	}
+
+	// Rolling back reinstalls v1.0.0 from the cache; a second rollback
+	// returns to v2.0.0. The API is not needed for either.
+	t.Setenv("SFETCH_API_BASE", "http://127.0.0.1:1")
+	if out := sfetch(t, exitOK, "--rollback", "o/tool"); !strings.Contains(out, "Rolling back o/tool from v2.0.0 to v1.0.0") {
+		t.Fatalf("rollback output:\n%s", out)
+	}
+	installed(t, "tool v1\n")
+	sfetch(t, exitOK, "--rollback", "--repo", "o/tool")
+	installed(t, "tool v2\n")
+
+	// A file changed since install is only removed with --force-install.
+	if err := os.WriteFile(dst, []byte("edited"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if out := sfetch(t, exitInstall, "--uninstall", "o/tool"); !strings.Contains(out, "changed since sfetch installed them") {
+		t.Fatalf("uninstall output:\n%s", out)
+	}
+	installed(t, "edited")
+	sfetch(t, exitOK, "--uninstall", "o/tool", "--force-install")
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("uninstalled file still present: %v", err)
+	}
+	sfetch(t, exitInstall, "--uninstall", "o/tool")
+}
+
+func TestRunInstallReceiptsExtractAll(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	trees := map[string][]testArchiveFile{
+		"v1.0.0": {{name: "bin/tool", mode: 0o755}, {name: "share/man/man1/tool.1", mode: 0o644}},
+		"v2.0.0": {{name: "bin/tool", mode: 0o755}, {name: "share/man/man1/tool.1", mode: 0o644}, {name: "completions/tool.bash", mode: 0o644}},
+	}
+	builds := map[string][]byte{}
+	for tag, files := range trees {
+		path := filepath.Join(t.TempDir(), assetName)
+		writeTestArchive(t, path, ArchiveFormatTarGz, files)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		builds[tag] = data
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		tag, file, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/dl/"), "/")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/o/tool/releases/tags/"):
+			tag := strings.TrimPrefix(r.URL.Path, "/repos/o/tool/releases/tags/")
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: tag, Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: base + "/dl/" + tag + "/" + assetName, Size: int64(len(builds[tag]))},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/" + tag + "/SHA256SUMS"},
+			}})
+		case file == assetName:
+			_, _ = w.Write(builds[tag])
+		case file == "SHA256SUMS":
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sha256.Sum256(builds[tag]), assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+
+	cacheDir := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "opt", "tool")
+	sfetch := func(t *testing.T, args ...string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"--cache-dir", cacheDir, "--skip-tools-check", "--no-path-check"}, args...)
+		if code := run(args, &stdout, &stderr); code != exitOK {
+			t.Fatalf("sfetch %v: exit = %d, want %d\nstderr:\n%s", args, code, exitOK, stderr.String())
+		}
+	}
+	sfetch(t, "--repo", "o/tool", "--tag", "v1.0.0", "--extract-all", dest)
+	sfetch(t, "--repo", "o/tool", "--tag", "v2.0.0", "--extract-all", dest)
+
+	// Rolling back reinstalls the v1.0.0 tree under the same directory,
+	// nested paths included.
+	t.Setenv("SFETCH_API_BASE", "http://127.0.0.1:1")
+	sfetch(t, "--rollback", "o/tool")
+	rec, err := receipt.Load(filepath.Join(cacheDir, receipt.Dir), "o/tool")
+	if err != nil {
+		t.Fatalf("load receipt: %v", err)
+	}
+	latest, _ := rec.Latest()
+	if latest.Tag != "v1.0.0" || latest.ExtractAll != dest || len(latest.Files) != len(trees["v1.0.0"]) {
+		t.Fatalf("receipt after rollback = %+v", latest)
+	}
+	for i, f := range trees["v1.0.0"] {
+		path := filepath.Join(dest, filepath.FromSlash(f.name))
+		if latest.Files[i].Path != path || latest.Files[i].Member != f.name {
+			t.Errorf("receipt file %d = %+v, want %s", i, latest.Files[i], path)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != f.name {
+			t.Errorf("%s after rollback = %q, %v", f.name, data, err)
+		}
+	}
+	// The binary must not be copied into the tree's other directories.
+	if _, err := os.Stat(filepath.Join(dest, "share", "man", "man1", "tool")); !os.IsNotExist(err) {
+		t.Errorf("stray binary copy in the man directory: %v", err)
+	}
+}
+
+func TestRunReleaseChangedDuringFetch(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archivePath := filepath.Join(t.TempDir(), assetName)
+	writeTestArchive(t, archivePath, ArchiveFormatTarGz, []testArchiveFile{{name: "tool", mode: 0o755}})
+	assetBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(assetBytes)
+	sums := []byte(fmt.Sprintf("%x  %s\n", sum, assetName))
+	sumsDigest := sha256.Sum256(sums)
+
+	// Each case swaps a file for new bytes once the release listing has been
+	// served, as an edit to the release between the two requests would.
+	tests := []struct {
+		name      string
+		mutate    func(asset, sums []byte) ([]byte, []byte)
+		wantCode  int
+		wantError string
+	}{
+		{name: "unchanged", mutate: func(a, s []byte) ([]byte, []byte) { return a, s }, wantCode: exitOK},
+		{
+			name: "checksum file replaced",
+			mutate: func(a, s []byte) ([]byte, []byte) {
+				return a, []byte(fmt.Sprintf("%s  %s\n", strings.Repeat("f", 128), assetName))
+			},
+			wantCode:  exitNetwork,
+			wantError: "release changed during fetch: SHA256SUMS is",
+		},
+		{
+			name: "asset replaced with same size",
+			mutate: func(a, s []byte) ([]byte, []byte) {
+				changed := bytes.Clone(a)
+				changed[len(changed)-1] ^= 0xff
+				return changed, s
+			},
+			wantCode:  exitNetwork,
+			wantError: "release changed during fetch: " + assetName + " has sha256",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listed atomic.Bool
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				base := "http://" + r.Host
+				served, servedSums := assetBytes, sums
+				if listed.Load() {
+					served, servedSums = tt.mutate(assetBytes, sums)
+				}
+				switch r.URL.Path {
+				case "/repos/o/tool/releases/latest":
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+						{ID: 101, Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName, Size: int64(len(assetBytes)), Digest: fmt.Sprintf("sha256:%x", sum)},
+						{ID: 102, Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS", Size: int64(len(sums)), Digest: fmt.Sprintf("sha256:%x", sumsDigest)},
+					}})
+					listed.Store(true)
+				case "/dl/" + assetName:
+					_, _ = w.Write(served)
+				case "/dl/SHA256SUMS":
+					_, _ = w.Write(servedSums)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer ts.Close()
+			t.Setenv("SFETCH_API_BASE", ts.URL)
+
+			destDir := t.TempDir()
+			provPath := filepath.Join(t.TempDir(), "provenance.json")
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"--repo", "o/tool", "--latest", "--dest-dir", destDir, "--cache-dir", t.TempDir(), "--skip-tools-check", "--no-path-check", "--provenance-file", provPath}, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			if tt.wantError != "" {
+				if !strings.Contains(stderr.String(), tt.wantError) {
+					t.Fatalf("stderr does not contain %q:\n%s", tt.wantError, stderr.String())
+				}
+				if _, err := os.Stat(filepath.Join(destDir, "tool")); !os.IsNotExist(err) {
+					t.Fatalf("binary installed from a changed release (stat err %v)", err)
+				}
+				return
+			}
+			data, err := os.ReadFile(provPath)
+			if err != nil {
+				t.Fatalf("read provenance: %v", err)
+			}
+			var record ProvenanceRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("parse provenance: %v", err)
+			}
+			if record.Asset.ID != 101 || record.Verification.Checksum.AssetID != 102 {
+				t.Errorf("asset ids = %d, %d; want 101, 102", record.Asset.ID, record.Verification.Checksum.AssetID)
+			}
+		})
+	}
+}
+
+func TestSupplementalDownloads(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool.tar.gz"},
+		{Name: "SHA256SUMS"},
+		{Name: "SHA256SUMS.minisig"},
+		{Name: "SHA256SUMS.asc"},
+		{Name: "tool.tar.gz.minisig"},
+		{Name: "tool-minisign.pub"},
+		{Name: "release-key.asc"},
+	}
+	tests := []struct {
+		name         string
+		assessment   VerificationAssessment
+		keys         signatureKeyOptions
+		skipSig      bool
+		skipChecksum bool
+		want         []string
+	}{
+		{
+			name:       "workflow A with auto-detected key",
+			assessment: VerificationAssessment{Workflow: workflowA, ChecksumFileForSig: "SHA256SUMS", SignatureFile: "SHA256SUMS.minisig", SignatureFormat: sigFormatMinisign},
+			want:       []string{"SHA256SUMS", "SHA256SUMS.minisig", "tool-minisign.pub"},
+		},
+		{
+			name:       "workflow A with local key",
+			assessment: VerificationAssessment{Workflow: workflowA, ChecksumFileForSig: "SHA256SUMS", SignatureFile: "SHA256SUMS.minisig", SignatureFormat: sigFormatMinisign},
+			keys:       signatureKeyOptions{minisignPubKey: "/keys/tool.pub"},
+			want:       []string{"SHA256SUMS", "SHA256SUMS.minisig"},
+		},
+		{
+			name: "workflow A with every signature",
+			assessment: VerificationAssessment{
+				Workflow: workflowA, ChecksumFileForSig: "SHA256SUMS", SignatureFile: "SHA256SUMS.minisig", SignatureFormat: sigFormatMinisign,
+				SignatureChecks: []SignatureCheck{{File: "SHA256SUMS.minisig", Format: sigFormatMinisign}, {File: "SHA256SUMS.asc", Format: sigFormatPGP}},
+			},
+			keys: signatureKeyOptions{minisignPubKey: "/keys/tool.pub"},
+			want: []string{"SHA256SUMS", "SHA256SUMS.minisig", "SHA256SUMS.asc", "release-key.asc"},
+		},
+		{
+			name:         "workflow B skips checksum",
+			assessment:   VerificationAssessment{Workflow: workflowB, SignatureFile: "tool.tar.gz.minisig", SignatureFormat: sigFormatMinisign, ChecksumAvailable: true, ChecksumFile: "SHA256SUMS"},
+			keys:         signatureKeyOptions{minisignKeyURL: "https://example.com/key.pub"},
+			skipChecksum: true,
+			want:         []string{"tool.tar.gz.minisig"},
 		},
 		{
-			name:    "missing_at",
-			input:   "owner/repo:path",
-			wantErr: true,
+			name:       "workflow B skip signature fetches no key",
+			assessment: VerificationAssessment{Workflow: workflowB, SignatureFile: "tool.tar.gz.minisig", SignatureFormat: sigFormatMinisign},
+			skipSig:    true,
+			want:       []string{"tool.tar.gz.minisig"},
 		},
 		{
-			name:    "missing_repo",
-			input:   "@ref:path",
-			wantErr: true,
+			name:       "workflow C",
+			assessment: VerificationAssessment{Workflow: workflowC, ChecksumFile: "SHA256SUMS"},
+			want:       []string{"SHA256SUMS"},
 		},
 		{
-			name:    "path_escape",
-			input:   "owner/repo@ref:../secrets.txt",
-			wantErr: true,
+			name:       "no verification",
+			assessment: VerificationAssessment{Workflow: workflowNone},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := supplementalDownloads(&tt.assessment, assets, tt.keys, tt.skipSig, tt.skipChecksum, "/tmp/sfetch-test")
+			var got []string
+			for _, job := range jobs {
+				got = append(got, job.asset.Name)
+				if want := filepath.Join("/tmp/sfetch-test", job.asset.Name); job.path != want {
+					t.Errorf("path for %s = %q, want %q", job.asset.Name, job.path, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("supplementalDownloads() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	const mib = 1 << 20
+	tests := []struct {
+		name    string
+		tty     bool
+		total   int64
+		chunks  int64 // bytes per write; the clock advances 1s per write
+		size    int64
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "log lines every 10%", total: 10 * mib, chunks: mib / 2, size: 10 * mib,
+			want: []string{
+				"Downloading tool.tar.gz: 10% (1.0 MB of 10.0 MB, 512.0 KB/s)\n",
+				"Downloading tool.tar.gz: 90% (9.0 MB of 10.0 MB, 512.0 KB/s)\n",
+				"Downloaded tool.tar.gz (10.0 MB in 20s)\n",
+			},
+			notWant: []string{"Downloading tool.tar.gz: 15%", "Downloading tool.tar.gz: 100%"},
+		},
+		{
+			name: "log lines by interval without size", total: -1, chunks: mib / 4, size: 4 * mib,
+			want: []string{
+				"Downloading tool.tar.gz: 2.5 MB (256.0 KB/s)\n",
+				"Downloaded tool.tar.gz (4.0 MB in 16s)\n",
+			},
+			notWant: []string{"Downloading tool.tar.gz: 1.0 MB"},
+		},
+		{
+			name: "small file is quiet", total: 4096, chunks: 1024, size: 4096,
+			notWant: []string{"tool.tar.gz"},
+		},
+		{
+			name: "terminal bar", tty: true, total: 2 * mib, chunks: mib, size: 2 * mib,
+			want: []string{
+				"\rtool.tar.gz [==========          ]  50%  1.0 MB / 2.0 MB  1.0 MB/s\x1b[K",
+				"\rtool.tar.gz [====================] 100%  2.0 MB / 2.0 MB  1.0 MB/s\x1b[K\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			clock := time.Unix(0, 0)
+			p := newProgressReporter(&out, tt.tty)
+			p.now = func() time.Time { return clock }
+
+			var dst bytes.Buffer
+			w := p.track(&dst, "tool.tar.gz", tt.total)
+			chunk := make([]byte, tt.chunks)
+			for written := int64(0); written < tt.size; written += tt.chunks {
+				clock = clock.Add(time.Second)
+				if _, err := w.Write(chunk); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+			}
+			w.finish(nil)
+
+			if int64(dst.Len()) != tt.size {
+				t.Fatalf("copied %d bytes, want %d", dst.Len(), tt.size)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("output missing %q:\n%q", s, out.String())
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(out.String(), s) {
+					t.Errorf("output unexpectedly contains %q:\n%q", s, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHashProgress(t *testing.T) {
+	const mib = 1 << 20
+	if newProgressReporter(io.Discard, false).trackHash("tool.tar.gz", 512*mib) != nil {
+		t.Fatal("hash progress drawn without a terminal")
+	}
+	if newProgressReporter(io.Discard, true).trackHash("tool.tar.gz", mib) != nil {
+		t.Fatal("hash progress drawn for a small file")
+	}
+	var off *progressReporter // --no-progress, --quiet, --json
+	if off.trackHash("tool.tar.gz", 512*mib) != nil {
+		t.Fatal("hash progress drawn with progress off")
+	}
+
+	var out bytes.Buffer
+	clock := time.Unix(0, 0)
+	p := newProgressReporter(&out, true)
+	p.now = func() time.Time { return clock }
+	h := p.trackHash("tool.tar.gz", 128*mib)
+	chunk := make([]byte, mib)
+	for i := 0; i < 128; i++ {
+		// Redraws are throttled: only every fourth write is due.
+		clock = clock.Add(progressRedrawInterval / 4)
+		if _, err := h.Write(chunk); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	h.finish()
+
+	for _, s := range []string{"\rVerifying checksum of tool.tar.gz... 0%\x1b[K", "\rVerifying checksum of tool.tar.gz... 50%\x1b[K"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("output missing %q:\n%q", s, out.String())
+		}
+	}
+	if got := strings.Count(out.String(), "Verifying checksum"); got != 32 {
+		t.Errorf("drew %d progress lines, want 32 (throttled)", got)
+	}
+	if !strings.HasSuffix(out.String(), "\r\x1b[K") {
+		t.Errorf("progress line not cleared:\n%q", out.String())
+	}
+}
+
+func TestFetchHighestRelease(t *testing.T) {
+	// Page one is full of old stable releases plus a draft and a non-semver
+	// tag; the newest stable and prerelease builds are only on page two.
+	var page1 []map[string]any
+	for i := 0; i < releasesPerPage-4; i++ {
+		page1 = append(page1, map[string]any{"tag_name": fmt.Sprintf("v0.1.%d", i)})
+	}
+	page1 = append(page1,
+		map[string]any{"tag_name": "v1.2.0"},
+		map[string]any{"tag_name": "v1.3.0-rc.1", "prerelease": true},
+		map[string]any{"tag_name": "v9.0.0", "draft": true},
+		map[string]any{"tag_name": "nightly", "prerelease": true},
+	)
+	page2 := []map[string]any{
+		{"tag_name": "v1.3.0-rc.2", "prerelease": true},
+		{"tag_name": "v1.2.1"},
+		{"tag_name": "v1.3.0-beta.1", "prerelease": true},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/tool/releases" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_ = json.NewEncoder(w).Encode(page1)
+		case "2":
+			_ = json.NewEncoder(w).Encode(page2)
+		default:
+			_ = json.NewEncoder(w).Encode([]any{})
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name              string
+		includePrerelease bool
+		wantTag           string
+		wantPrerelease    bool
+	}{
+		{name: "stable only", wantTag: "v1.2.1"},
+		{name: "prereleases included", includePrerelease: true, wantTag: "v1.3.0-rc.2", wantPrerelease: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel, err := fetchHighestRelease(context.Background(), ts.URL, "o/tool", tt.includePrerelease)
+			if err != nil {
+				t.Fatalf("fetchHighestRelease: %v", err)
+			}
+			if rel.TagName != tt.wantTag || rel.Prerelease != tt.wantPrerelease {
+				t.Fatalf("got %s (prerelease=%v), want %s (prerelease=%v)", rel.TagName, rel.Prerelease, tt.wantTag, tt.wantPrerelease)
+			}
+		})
+	}
+
+	if _, err := fetchHighestRelease(context.Background(), ts.URL, "o/missing", false); err == nil {
+		t.Fatal("expected an error for a repo without releases")
+	}
+}
+
+func TestRunURLRedirectLoop(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tool.tar.gz" {
+			http.Redirect(w, r, "/mirror/tool.tar.gz", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "/tool.tar.gz", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--url", ts.URL + "/tool.tar.gz", "--allow-http", "--follow-redirects", "--max-redirects", "20", "--dry-run", "--skip-tools-check"}, &stdout, &stderr)
+	if code == exitOK {
+		t.Fatalf("expected failure, got exit 0\nstderr:\n%s", stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "redirect loop") || strings.Contains(stderr.String(), "stopped after") {
+		t.Fatalf("want a redirect loop error, got:\n%s", stderr.String())
+	}
+}
+
+func TestNewOutputLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		verbosity int
+		jsonOut   bool
+		want      [4]bool // Warn, Info, Verbose, Debug reach stderr
+	}{
+		{name: "quiet", verbosity: verbosityQuiet, want: [4]bool{false, false, false, false}},
+		{name: "warn", verbosity: verbosityWarn, want: [4]bool{true, false, false, false}},
+		{name: "default", verbosity: verbosityNormal, want: [4]bool{true, true, false, false}},
+		{name: "verbose", verbosity: verbosityVerbose, want: [4]bool{true, true, true, false}},
+		{name: "debug", verbosity: verbosityDebug, want: [4]bool{true, true, true, true}},
+		{name: "json keeps warnings", verbosity: verbosityNormal, jsonOut: true, want: [4]bool{true, false, false, false}},
+		{name: "json with -v", verbosity: verbosityVerbose, jsonOut: true, want: [4]bool{true, false, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			logs := newOutputLog(&stderr, tt.verbosity, tt.jsonOut)
+			if logs.Error != &stderr {
+				t.Fatal("errors must always reach stderr")
+			}
+			for i, w := range []io.Writer{logs.Warn, logs.Info, logs.Verbose, logs.Debug} {
+				if got := w == &stderr; got != tt.want[i] {
+					t.Errorf("writer %d enabled = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVerbosityFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{args: nil, want: 0},
+		{args: []string{"-v"}, want: 1},
+		{args: []string{"-v", "--verbose"}, want: 2},
+		{args: []string{"-vv"}, want: 2},
+		{args: []string{"-v=false"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			level := 0
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.Var(verbosityFlag{level: &level, step: 1}, "verbose", "")
+			fs.Var(verbosityFlag{level: &level, step: 1}, "v", "")
+			fs.Var(verbosityFlag{level: &level, step: 2}, "vv", "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if level != tt.want {
+				t.Fatalf("level = %d, want %d", level, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONLogWriter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		level     string
+		write     string
+		wantLevel string
+		wantMsg   string
+	}{
+		{name: "raw error", write: "error: --repo is required\n", wantLevel: "error", wantMsg: "--repo is required"},
+		{name: "raw warning", write: "warning: no --dest-dir specified\n", wantLevel: "warn", wantMsg: "no --dest-dir specified"},
+		{name: "raw loud warning", write: "WARNING: falling back\n", wantLevel: "warn", wantMsg: "falling back"},
+		{name: "raw plain", write: "Release: v1.0.0\n", wantLevel: "info", wantMsg: "Release: v1.0.0"},
+		{name: "leveled keeps level", level: "debug", write: "warning: odd\n", wantLevel: "debug", wantMsg: "odd"},
+		{name: "multi-line message is one record", level: "error", write: "no asset matches\n  looked for: linux/amd64\n", wantLevel: "error", wantMsg: "no asset matches\n  looked for: linux/amd64"},
+		{name: "blank write", write: "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			jl := &jsonLog{out: &out, now: func() time.Time { return now }}
+			if _, err := fmt.Fprint(jl.writer(tt.level), tt.write); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if tt.wantLevel == "" {
+				if out.Len() != 0 {
+					t.Fatalf("unexpected record: %s", out.String())
+				}
+				return
+			}
+			var rec struct{ Time, Level, Msg string }
+			if err := json.Unmarshal(out.Bytes(), &rec); err != nil || strings.Count(out.String(), "\n") != 1 {
+				t.Fatalf("want one JSON line, got %q (%v)", out.String(), err)
+			}
+			if rec.Time != "2026-03-01T12:00:00Z" || rec.Level != tt.wantLevel || rec.Msg != tt.wantMsg {
+				t.Fatalf("record = %+v, want level %q msg %q", rec, tt.wantLevel, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestSelectionDebugTrace(t *testing.T) {
+	var trace bytes.Buffer
+	setDebugLog(&trace)
+	defer setDebugLog(nil)
+
+	cfg := defaults
+	cfg.AssetPatterns = nil
+	assets := []Asset{{Name: "tool-linux-amd64.tar.gz"}, {Name: "tool-linux-x86_64.tar.gz"}, {Name: "SHA256SUMS"}}
+	if _, err := pickByHeuristics(assets, &cfg, "linux", "amd64", platformHints{}); err != nil {
+		t.Fatalf("pickByHeuristics: %v", err)
+	}
+	for _, want := range []string{"asset tool-linux-amd64.tar.gz: score 12 (os 5, arch 5)", "asset tool-linux-x86_64.tar.gz: score 10 (os 5, arch 3)"} {
+		if !strings.Contains(trace.String(), want) {
+			t.Errorf("trace missing %q:\n%s", want, trace.String())
+		}
+	}
+}
+
+func TestRunLogLevelAndFormat(t *testing.T) {
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Release{TagName: "v1.0.0", Assets: []Asset{
+			{Name: assetName, BrowserDownloadUrl: base + "/dl/" + assetName},
+			{Name: "tool_plan9_mips.tar.gz", BrowserDownloadUrl: base + "/dl/other"},
+			{Name: "SHA256SUMS", BrowserDownloadUrl: base + "/dl/SHA256SUMS"},
+		}})
+	}))
+	defer ts.Close()
+	t.Setenv("SFETCH_API_BASE", ts.URL)
+	base := []string{"--repo", "o/tool", "--tag", "v1.0.0", "--dry-run", "--skip-tools-check"}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		want       []string
+		wantAbsent []string
+		jsonLines  bool
+	}{
+		{
+			name: "debug explains discovery",
+			args: []string{"--log-level", "debug"},
+			want: []string{
+				"1 match(es): " + assetName,
+				"checksum candidate " + assetName + ".sha256 ({{asset}}.sha256): not in release",
+				"checksum candidate SHA256SUMS (SHA256SUMS): found",
+				"GET " + ts.URL + "/repos/o/tool/releases/tags/v1.0.0",
+			},
+		},
+		{name: "default has no traces", want: []string{"sfetch dry-run assessment"}, wantAbsent: []string{"checksum candidate", "match(es)"}},
+		{
+			name:      "json records",
+			args:      []string{"--log-level", "debug", "--log-format", "json"},
+			jsonLines: true,
+			want:      []string{`"level":"debug","msg":"checksum candidate SHA256SUMS (SHA256SUMS): found"`, `"level":"info","msg":"\nsfetch dry-run assessment`},
 		},
+		{name: "log-level with verbose", args: []string{"--log-level", "info", "-v"}, wantCode: exitUsage, want: []string{"--log-level replaces --quiet and --verbose"}},
+		{name: "unknown level", args: []string{"--log-level", "trace"}, wantCode: exitUsage, want: []string{`invalid --log-level "trace"`}},
+		{name: "unknown format", args: []string{"--log-format", "xml"}, wantCode: exitUsage, want: []string{`invalid --log-format "xml"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append(append([]string(nil), base...), tt.args...), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, tt.wantCode, stderr.String())
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(stderr.String(), w) {
+					t.Errorf("stderr missing %q:\n%s", w, stderr.String())
+				}
+			}
+			for _, w := range tt.wantAbsent {
+				if strings.Contains(stderr.String(), w) {
+					t.Errorf("stderr has %q:\n%s", w, stderr.String())
+				}
+			}
+			if tt.jsonLines {
+				for _, line := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+					var rec map[string]string
+					if err := json.Unmarshal([]byte(line), &rec); err != nil || rec["level"] == "" || rec["time"] == "" {
+						t.Errorf("not a log record: %q (%v)", line, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseSourceResolver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"valid", `{"routes":[{"match":"acme/*","apiBase":"https://ghe.acme.internal/api/v3","downloadBase":"https://ghe.acme.internal"}]}`, ""},
+		{"download only", `{"routes":[{"match":"*/*","downloadBase":"https://proxy.internal/gh"}]}`, ""},
+		{"empty routes", `{"routes":[]}`, ""},
+		{"no bases", `{"routes":[{"match":"acme/*"}]}`, "invalid source routes"},
+		{"pattern without owner", `{"routes":[{"match":"tool","apiBase":"https://ghe.internal"}]}`, "invalid source routes"},
+		{"bad glob", `{"routes":[{"match":"acme/[","apiBase":"https://ghe.internal"}]}`, "invalid match"},
+		{"relative base", `{"routes":[{"match":"acme/*","apiBase":"https://"}]}`, "not an absolute http(s) URL"},
+		{"query in base", `{"routes":[{"match":"acme/*","downloadBase":"https://ghe.internal/?x=1"}]}`, "query or fragment"},
+		{"unknown field", `{"routes":[{"match":"acme/*","apiBase":"https://ghe.internal","token":"x"}]}`, "invalid source routes"},
+		{"not json", `routes`, "parse source routes"},
 	}
 
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := parseGitHubRawSpec(tc.input)
-			if tc.wantErr {
-				if err == nil {
-					t.Fatalf("expected error")
+			_, err := parseSourceResolver([]byte(tc.data))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
 				}
 				return
 			}
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
-			if got != tc.want {
-				t.Fatalf("parseGitHubRawSpec(%q) = %+v, want %+v", tc.input, got, tc.want)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tc.wantErr)
 			}
 		})
 	}
 }
 
-func TestMatchWithPatterns_WinDarwinFalsePositive(t *testing.T) {
+func TestSourceResolverResolve(t *testing.T) {
 	t.Parallel()
 
-	assets := []Asset{
-		{Name: "goneat_v0.5.2_darwin_amd64.tar.gz"},
-		{Name: "goneat_v0.5.2_darwin_arm64.tar.gz"},
-		{Name: "goneat_v0.5.2_linux_amd64.tar.gz"},
-		{Name: "goneat_v0.5.2_linux_arm64.tar.gz"},
-		{Name: "goneat_v0.5.2_windows_amd64.zip"},
+	resolver := SourceResolver{Routes: []SourceRoute{
+		{Match: "acme/*", APIBase: "https://ghe.acme.internal/api/v3"},
+		{Match: "*/*", DownloadBase: "https://proxy.internal"},
+	}}
+	tests := []struct {
+		repo      string
+		wantMatch string
+	}{
+		{"acme/tool", "acme/*"},
+		{"ACME/Tool", "acme/*"},
+		{"other/tool", "*/*"},
 	}
-
-	cfg := &RepoConfig{
-		BinaryName:        "goneat",
-		ArchiveExtensions: []string{".tar.gz", ".zip"},
-		AssetPatterns: []string{
-			"(?i)^{{binary}}[_-]{{osToken}}[_-]{{archToken}}.*",
-			"(?i)^{{binary}}.*{{osToken}}.*{{archToken}}.*",
-		},
+	for _, tc := range tests {
+		route, ok := resolver.resolve(tc.repo)
+		if !ok || route.Match != tc.wantMatch {
+			t.Errorf("resolve(%q) = %q, %v; want %q", tc.repo, route.Match, ok, tc.wantMatch)
+		}
 	}
-
-	// windows/arm64 must NOT match darwin_arm64 via "win" substring in "darwin".
-	got := matchWithPatterns(assets, cfg, "windows", "arm64")
-	if got != nil {
-		t.Errorf("matchWithPatterns(windows, arm64) = %q, want nil (should fall through to heuristics)", got.Name)
+	if _, ok := (SourceResolver{Routes: resolver.Routes[:1]}).resolve("other/tool"); ok {
+		t.Errorf("resolve(other/tool) matched acme/*")
 	}
 }
 
-func TestSelectAsset_WindowsArm64_EndToEnd(t *testing.T) {
+func TestSourceRouteRewriteRelease(t *testing.T) {
 	t.Parallel()
 
-	release := &Release{
-		TagName: "v0.5.2",
+	rel := Release{
+		TagName:    "v1.0.0",
+		TarballURL: "https://api.github.com/repos/acme/tool/tarball/v1.0.0",
 		Assets: []Asset{
-			{Name: "goneat_v0.5.2_darwin_amd64.tar.gz"},
-			{Name: "goneat_v0.5.2_darwin_arm64.tar.gz"},
-			{Name: "goneat_v0.5.2_linux_amd64.tar.gz"},
-			{Name: "goneat_v0.5.2_linux_arm64.tar.gz"},
-			{Name: "goneat_v0.5.2_windows_amd64.zip"},
+			{Name: "tool.tar.gz", BrowserDownloadUrl: "https://github.com/acme/tool/releases/download/v1.0.0/tool.tar.gz"},
+			{Name: "SHA256SUMS", BrowserDownloadUrl: "https://ghe.acme.internal/acme/tool/releases/download/v1.0.0/SHA256SUMS"},
 		},
 	}
+	route := SourceRoute{Match: "acme/*", APIBase: "https://ghe.acme.internal/api/v3", DownloadBase: "https://proxy.acme.internal/gh/"}
+	route.rewriteRelease(&rel)
 
-	cfg := &RepoConfig{
-		BinaryName:        "goneat",
-		ArchiveExtensions: []string{".tar.gz", ".zip"},
-		AssetPatterns: []string{
-			"(?i)^{{binary}}[_-]{{osToken}}[_-]{{archToken}}.*",
-			"(?i)^{{binary}}.*{{osToken}}.*{{archToken}}.*",
-		},
+	if want := "https://proxy.acme.internal/gh/acme/tool/releases/download/v1.0.0/tool.tar.gz"; rel.Assets[0].BrowserDownloadUrl != want {
+		t.Errorf("asset URL = %q, want %q", rel.Assets[0].BrowserDownloadUrl, want)
 	}
-
-	// No exact windows/arm64 asset exists; should fall through to heuristics
-	// and pick windows_amd64 as the best available.
-	got, err := selectAsset(release, cfg, "windows", "arm64", "", "")
-	if err != nil {
-		t.Fatalf("selectAsset(windows, arm64) error = %v", err)
+	if want := "https://ghe.acme.internal/acme/tool/releases/download/v1.0.0/SHA256SUMS"; rel.Assets[1].BrowserDownloadUrl != want {
+		t.Errorf("non-GitHub asset URL rewritten to %q", rel.Assets[1].BrowserDownloadUrl)
 	}
-	if got.Name != "goneat_v0.5.2_windows_amd64.zip" {
-		t.Errorf("selectAsset(windows, arm64) = %q, want %q", got.Name, "goneat_v0.5.2_windows_amd64.zip")
+	if want := "https://ghe.acme.internal/api/v3/repos/acme/tool/tarball/v1.0.0"; rel.TarballURL != want {
+		t.Errorf("tarball URL = %q, want %q", rel.TarballURL, want)
 	}
 }
 
-func TestResolveArchiveBinaryPath_WindowsExeInstallName(t *testing.T) {
-	t.Parallel()
-
-	dir := t.TempDir()
-	if err := os.WriteFile(filepath.Join(dir, "mytool.exe"), []byte("binary"), 0o755); err != nil {
-		t.Fatalf("write: %v", err)
+func TestRunSourceResolver(t *testing.T) {
+	// Repos matching a sources.json route fetch release metadata from the
+	// mirror and download through it; everything else uses SFETCH_API_BASE.
+	assetName := fmt.Sprintf("tool_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	releaseServer := func(tag string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, "/releases/latest") {
+				http.NotFound(w, r)
+				return
+			}
+			_, repo, _ := strings.Cut(strings.TrimSuffix(r.URL.Path, "/releases/latest"), "/repos/")
+			dl := "https://github.com/" + repo + "/releases/download/" + tag + "/"
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Release{TagName: tag, Assets: []Asset{
+				{Name: assetName, BrowserDownloadUrl: dl + assetName},
+				{Name: "SHA256SUMS", BrowserDownloadUrl: dl + "SHA256SUMS"},
+			}})
+		}))
 	}
+	defaultServer := releaseServer("v1.0.0")
+	defer defaultServer.Close()
+	mirror := releaseServer("v9.0.0")
+	defer mirror.Close()
 
-	binaryPath, err := resolveArchiveBinaryPath(dir, "mytool", "windows")
-	if err != nil {
-		t.Fatalf("resolveArchiveBinaryPath: %v", err)
+	cfgHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfgHome)
+	t.Setenv("SFETCH_API_BASE", defaultServer.URL)
+	if err := os.MkdirAll(filepath.Join(cfgHome, "sfetch"), 0o755); err != nil {
+		t.Fatal(err)
 	}
-	if !strings.HasSuffix(binaryPath, "mytool.exe") {
-		t.Fatalf("binaryPath = %q, want suffix mytool.exe", binaryPath)
+	sources := fmt.Sprintf(`{"routes":[{"match":"acme/*","apiBase":%q,"downloadBase":%q}]}`, mirror.URL+"/api", mirror.URL+"/dl")
+	if err := os.WriteFile(filepath.Join(cfgHome, "sfetch", sourceResolverFile), []byte(sources), 0o644); err != nil {
+		t.Fatal(err)
 	}
 
-	// Simulate the installName update logic from the install flow.
-	installName := "mytool"
-	if strings.HasSuffix(strings.ToLower(filepath.Base(binaryPath)), ".exe") &&
-		!strings.HasSuffix(strings.ToLower(installName), ".exe") {
-		installName += ".exe"
+	tests := []struct {
+		repo    string
+		wantTag string
+		wantURL string
+	}{
+		{"acme/tool", "v9.0.0", mirror.URL + "/dl/acme/tool/releases/download/v9.0.0/" + assetName},
+		{"other/tool", "v1.0.0", "https://github.com/other/tool/releases/download/v1.0.0/" + assetName},
 	}
-	if installName != "mytool.exe" {
-		t.Errorf("installName = %q, want %q", installName, "mytool.exe")
+	for _, tc := range tests {
+		t.Run(tc.repo, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"--repo", tc.repo, "--latest", "--dry-run", "--json", "--skip-tools-check"}, &stdout, &stderr)
+			if code != exitOK {
+				t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitOK, stderr.String())
+			}
+			var result struct {
+				Assessment AssessmentRecord `json:"assessment"`
+			}
+			if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+				t.Fatalf("parse stdout: %v\n%s", err, stdout.String())
+			}
+			if got := result.Assessment.Source.Tag; got != tc.wantTag {
+				t.Errorf("tag = %q, want %q", got, tc.wantTag)
+			}
+			if result.Assessment.Asset == nil || result.Assessment.Asset.URL != tc.wantURL {
+				t.Errorf("asset = %+v, want URL %q", result.Assessment.Asset, tc.wantURL)
+			}
+		})
 	}
+
+	t.Run("invalid config", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(cfgHome, "sfetch", sourceResolverFile), []byte(`{"routes":[{"match":"acme/*"}]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"--repo", "acme/tool", "--latest", "--dry-run", "--skip-tools-check"}, &stdout, &stderr)
+		if code != exitUsage {
+			t.Fatalf("exit = %d, want %d\nstderr:\n%s", code, exitUsage, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), "invalid source routes") {
+			t.Fatalf("stderr missing schema error:\n%s", stderr.String())
+		}
+	})
 }
@@ -8,7 +8,7 @@ import (
 	"testing"
 )
 
-func TestInstallFileWithRenameWindowsSelfUpdateWritesNewFile(t *testing.T) {
+func TestInstallFileWithRenameWindowsSelfUpdateReplacesExisting(t *testing.T) {
 	t.Parallel()
 
 	dir := t.TempDir()
@@ -18,37 +18,41 @@ func TestInstallFileWithRenameWindowsSelfUpdateWritesNewFile(t *testing.T) {
 	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
 		t.Fatalf("mkdir: %v", err)
 	}
-	if err := os.WriteFile(src, []byte("x"), 0o644); err != nil {
+	if err := os.WriteFile(dst, []byte("old"), 0o644); err != nil {
+		t.Fatalf("write dst: %v", err)
+	}
+	if err := os.WriteFile(src, []byte("new"), 0o644); err != nil {
 		t.Fatalf("write src: %v", err)
 	}
 
 	var calls []string
 	rename := func(oldPath, newPath string) error {
 		calls = append(calls, newPath)
-		if len(calls) == 1 {
-			return os.ErrPermission
-		}
-		return nil
+		return os.Rename(oldPath, newPath)
 	}
 
 	cls := AssetClassification{Type: AssetTypeRaw, NeedsChmod: true}
-	installed, err := installFileWithRename(src, dst, cls, true, rename)
+	installed, err := installFileWithRename(src, dst, cls, installOptions{selfUpdate: true}, rename)
 	if err != nil {
 		t.Fatalf("installFileWithRename: %v", err)
 	}
-
-	want := dst + ".new"
-	if installed != want {
-		t.Fatalf("installed path: got %q want %q", installed, want)
+	if installed != dst {
+		t.Fatalf("installed path: got %q want %q", installed, dst)
+	}
+	if len(calls) != 1 || calls[0] != dst+".new" {
+		t.Fatalf("rename targets: got %v want [%s]", calls, dst+".new")
 	}
 
-	if len(calls) != 2 {
-		t.Fatalf("expected 2 rename attempts, got %d", len(calls))
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read dst: %v", err)
 	}
-	if calls[0] != dst {
-		t.Fatalf("first rename target: got %q want %q", calls[0], dst)
+	if string(got) != "new" {
+		t.Fatalf("dst content: got %q want %q", got, "new")
 	}
-	if calls[1] != want {
-		t.Fatalf("second rename target: got %q want %q", calls[1], want)
+	for _, leftover := range []string{dst + ".new", dst + ".bak"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, stat err=%v", leftover, err)
+		}
 	}
 }
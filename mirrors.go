@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// mirrorListFlag collects --mirror values in the order given. It implements
+// flag.Value, so the flag can be repeated.
+type mirrorListFlag struct {
+	urls *[]string
+}
+
+func (f mirrorListFlag) String() string {
+	if f.urls == nil {
+		return ""
+	}
+	return strings.Join(*f.urls, ",")
+}
+
+func (f mirrorListFlag) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("empty mirror URL")
+	}
+	*f.urls = append(*f.urls, value)
+	return nil
+}
+
+var (
+	mirrorMu sync.Mutex
+	// downloadMirrors are the --mirror base URLs, tried in order when the
+	// primary download host fails.
+	downloadMirrors []string
+)
+
+// setDownloadMirrors installs the --mirror list.
+func setDownloadMirrors(mirrors []string) {
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	downloadMirrors = append([]string(nil), mirrors...)
+}
+
+func currentMirrors() []string {
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	return downloadMirrors
+}
+
+// validateMirrorURL checks a --mirror base: an absolute https URL (http with
+// --allow-http) without query or fragment.
+func validateMirrorURL(raw string, allowHTTP bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid --mirror %q: %w", raw, err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid --mirror %q: must be an absolute URL", raw)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+	case "http":
+		if !allowHTTP {
+			return fmt.Errorf("--mirror %q uses http; pass --allow-http to permit it", raw)
+		}
+	default:
+		return fmt.Errorf("invalid --mirror %q: scheme must be https", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid --mirror %q: query and fragment are not allowed", raw)
+	}
+	return nil
+}
+
+// mirrorURL rewrites original onto mirror: the scheme and host are replaced
+// and the mirror's path, if any, is prepended. With mirror
+// https://mirror.example/gh, https://github.com/o/r/releases/download/v1/x
+// becomes https://mirror.example/gh/o/r/releases/download/v1/x.
+func mirrorURL(mirror, original string) (string, error) {
+	m, err := url.Parse(mirror)
+	if err != nil {
+		return "", err
+	}
+	o, err := url.Parse(original)
+	if err != nil {
+		return "", err
+	}
+	o.Scheme = m.Scheme
+	o.Host = m.Host
+	o.User = m.User
+	o.Path = strings.TrimRight(m.Path, "/") + o.Path
+	o.RawPath = ""
+	return o.String(), nil
+}
@@ -17,11 +17,15 @@ const (
 type ArchiveFormat = model.ArchiveFormat
 
 const (
-	ArchiveFormatTarGz  = model.ArchiveFormatTarGz
-	ArchiveFormatTarXz  = model.ArchiveFormatTarXz
-	ArchiveFormatTarBz2 = model.ArchiveFormatTarBz2
-	ArchiveFormatTar    = model.ArchiveFormatTar
-	ArchiveFormatZip    = model.ArchiveFormatZip
+	ArchiveFormatTarGz    = model.ArchiveFormatTarGz
+	ArchiveFormatTarXz    = model.ArchiveFormatTarXz
+	ArchiveFormatTarBz2   = model.ArchiveFormatTarBz2
+	ArchiveFormatTar      = model.ArchiveFormatTar
+	ArchiveFormatZip      = model.ArchiveFormatZip
+	ArchiveFormatSevenZip = model.ArchiveFormatSevenZip
+	ArchiveFormatGz       = model.ArchiveFormatGz
+	ArchiveFormatXz       = model.ArchiveFormatXz
+	ArchiveFormatBz2      = model.ArchiveFormatBz2
 )
 
 type SignatureFormats = model.SignatureFormats
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// warnIfNotOnPath prints a warning and shell-specific guidance when dir, the
+// --install directory, is not on $PATH, so a first install does not end with
+// "command not found". An empty dir skips the check (--no-path-check).
+func warnIfNotOnPath(stderr io.Writer, hints *hintSink, dir string) {
+	if dir == "" || dirOnPath(dir, os.Getenv("PATH"), runtime.GOOS) {
+		return
+	}
+	display := userBinDirDisplay()
+	shell, command := pathGuidance(os.Getenv("SHELL"), runtime.GOOS)
+	_, _ = fmt.Fprintf(stderr, "warning: %s is not on your PATH\n", display) //nolint:errcheck
+	hints.emit(hintNotOnPath, display, shell, command)
+}
+
+// dirOnPath reports whether dir is one of the entries in pathEnv. Entries are
+// compared after cleaning; Windows paths compare case-insensitively.
+func dirOnPath(dir, pathEnv, goos string) bool {
+	sep := ":"
+	if goos == "windows" {
+		sep = ";"
+	}
+	want := filepath.Clean(dir)
+	for _, entry := range strings.Split(pathEnv, sep) {
+		if entry == "" {
+			continue
+		}
+		got := filepath.Clean(entry)
+		if got == want || (goos == "windows" && strings.EqualFold(got, want)) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathGuidance returns the shell named by $SHELL and a command that adds the
+// user bin directory to its PATH permanently. Shells sfetch does not know get
+// a POSIX profile line.
+func pathGuidance(shellEnv, goos string) (shell, command string) {
+	if goos == "windows" {
+		return "PowerShell", `[Environment]::SetEnvironmentVariable("Path", "$env:USERPROFILE\bin;" + [Environment]::GetEnvironmentVariable("Path", "User"), "User")`
+	}
+	switch shell = filepath.Base(shellEnv); shell {
+	case "bash":
+		return shell, `echo 'export PATH="$HOME/.local/bin:$PATH"' >> ~/.bashrc && source ~/.bashrc`
+	case "zsh":
+		return shell, `echo 'export PATH="$HOME/.local/bin:$PATH"' >> ~/.zshrc && source ~/.zshrc`
+	case "fish":
+		return shell, "fish_add_path ~/.local/bin"
+	default:
+		return "your shell", `echo 'export PATH="$HOME/.local/bin:$PATH"' >> ~/.profile, then log in again`
+	}
+}
@@ -0,0 +1,128 @@
+package update
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCheckInterval is how often a CLI should look for a new release
+// when it has no stronger opinion: once a day.
+const DefaultCheckInterval = 24 * time.Hour
+
+// CheckState is what an update check remembers between runs. Persist it
+// with SaveCheckState after every check, whether or not it notified.
+type CheckState struct {
+	// LastCheck is when the latest release was last looked up.
+	LastCheck time.Time `json:"lastCheck"`
+	// LastNotified is the normalized version the user was last told
+	// about; the same version is not announced twice.
+	LastNotified string `json:"lastNotified,omitempty"`
+}
+
+// CheckResult is the outcome of DecideUpdateCheck.
+type CheckResult struct {
+	// Notify reports whether to tell the user that Latest is available.
+	Notify bool
+	// Current and Latest are display forms ("v1.2.3") of the versions
+	// compared.
+	Current string
+	Latest  string
+	// State is the state to persist for the next check.
+	State CheckState
+}
+
+// CheckDue reports whether interval has passed since state.LastCheck, so the
+// caller should fetch the latest release tag and call DecideUpdateCheck. A
+// LastCheck in the future (a clock that jumped back) counts as due.
+func CheckDue(state CheckState, now time.Time, interval time.Duration) bool {
+	if state.LastCheck.IsZero() || state.LastCheck.After(now) {
+		return true
+	}
+	return now.Sub(state.LastCheck) >= interval
+}
+
+// DecideUpdateCheck decides whether to announce latest, a release tag the
+// caller fetched, to a user running current.
+//
+// It notifies only when the check is due (see CheckDue), latest is newer
+// than current, and latest is not the version last announced. Versions that
+// cannot be compared ("dev", non-semver tags) never notify. When the check
+// is not due, the state is returned unchanged; otherwise LastCheck becomes
+// now.
+func DecideUpdateCheck(current, latest string, state CheckState, now time.Time, interval time.Duration) CheckResult {
+	result := CheckResult{
+		Current: FormatVersionDisplay(current),
+		Latest:  FormatVersionDisplay(latest),
+		State:   state,
+	}
+	if !CheckDue(state, now, interval) {
+		return result
+	}
+	result.State.LastCheck = now
+
+	currentNorm, currentOK := NormalizeVersion(current)
+	latestNorm, latestOK := NormalizeVersion(latest)
+	if !currentOK || !latestOK {
+		return result
+	}
+	if cmp, err := CompareSemver(latestNorm, currentNorm); err != nil || cmp <= 0 {
+		return result
+	}
+	if latestNorm == state.LastNotified {
+		return result
+	}
+	result.Notify = true
+	result.State.LastNotified = latestNorm
+	return result
+}
+
+// LoadCheckState reads a state file written by SaveCheckState. A missing
+// file is the zero state, so the first check is always due.
+func LoadCheckState(path string) (CheckState, error) {
+	var state CheckState
+	// #nosec G304 -- path is the caller's own state file
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("read update check state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return CheckState{}, fmt.Errorf("parse update check state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// SaveCheckState writes state to path as JSON, creating its directory.
+// The file is replaced atomically so concurrent runs never read half of it.
+func SaveCheckState(path string, state CheckState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal update check state: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create update check state dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write update check state: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after a successful rename
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write update check state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write update check state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write update check state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckDue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		lastCheck time.Time
+		want      bool
+	}{
+		{"never checked", time.Time{}, true},
+		{"just checked", now.Add(-time.Minute), false},
+		{"just under interval", now.Add(-DefaultCheckInterval + time.Second), false},
+		{"exactly interval", now.Add(-DefaultCheckInterval), true},
+		{"long ago", now.Add(-72 * time.Hour), true},
+		{"clock jumped back", now.Add(time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckDue(CheckState{LastCheck: tt.lastCheck}, now, DefaultCheckInterval); got != tt.want {
+				t.Fatalf("CheckDue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecideUpdateCheck(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	stale := now.Add(-25 * time.Hour)
+
+	tests := []struct {
+		name         string
+		current      string
+		latest       string
+		state        CheckState
+		wantNotify   bool
+		wantCheck    time.Time
+		wantNotified string
+	}{
+		{name: "newer release, first check", current: "v0.2.0", latest: "v0.3.0", wantNotify: true, wantCheck: now, wantNotified: "0.3.0"},
+		{name: "newer release, stale check", current: "0.2.0", latest: "v0.2.1", state: CheckState{LastCheck: stale}, wantNotify: true, wantCheck: now, wantNotified: "0.2.1"},
+		{name: "within interval", current: "v0.2.0", latest: "v0.3.0", state: CheckState{LastCheck: recent}, wantCheck: recent},
+		{name: "already notified", current: "v0.2.0", latest: "v0.3.0", state: CheckState{LastCheck: stale, LastNotified: "0.3.0"}, wantCheck: now, wantNotified: "0.3.0"},
+		{name: "newer than last notified", current: "v0.2.0", latest: "v0.4.0", state: CheckState{LastCheck: stale, LastNotified: "0.3.0"}, wantNotify: true, wantCheck: now, wantNotified: "0.4.0"},
+		{name: "up to date", current: "v0.3.0", latest: "v0.3.0", wantCheck: now},
+		{name: "ahead of latest", current: "v0.4.0-rc1", latest: "v0.3.0", wantCheck: now},
+		{name: "prerelease to its release", current: "v0.3.0-rc1", latest: "v0.3.0", wantNotify: true, wantCheck: now, wantNotified: "0.3.0"},
+		{name: "dev build", current: "dev", latest: "v0.3.0", wantCheck: now},
+		{name: "non-semver tag", current: "v0.2.0", latest: "nightly", wantCheck: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DecideUpdateCheck(tt.current, tt.latest, tt.state, now, DefaultCheckInterval)
+			if got.Notify != tt.wantNotify {
+				t.Errorf("Notify = %v, want %v", got.Notify, tt.wantNotify)
+			}
+			if !got.State.LastCheck.Equal(tt.wantCheck) {
+				t.Errorf("LastCheck = %v, want %v", got.State.LastCheck, tt.wantCheck)
+			}
+			if got.State.LastNotified != tt.wantNotified {
+				t.Errorf("LastNotified = %q, want %q", got.State.LastNotified, tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestDecideUpdateCheckDisplay(t *testing.T) {
+	got := DecideUpdateCheck("0.2.0", "0.3.0", CheckState{}, time.Now(), DefaultCheckInterval)
+	if got.Current != "v0.2.0" || got.Latest != "v0.3.0" {
+		t.Fatalf("display = %q, %q; want v0.2.0, v0.3.0", got.Current, got.Latest)
+	}
+}
+
+func TestDecideUpdateCheckNotifiesOnce(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var state CheckState
+	var notified []int
+	// Check hourly for three days with the same latest release.
+	for hour := 0; hour < 72; hour++ {
+		now := start.Add(time.Duration(hour) * time.Hour)
+		if !CheckDue(state, now, DefaultCheckInterval) {
+			continue
+		}
+		res := DecideUpdateCheck("v1.0.0", "v1.1.0", state, now, DefaultCheckInterval)
+		if res.Notify {
+			notified = append(notified, hour)
+		}
+		state = res.State
+	}
+	if len(notified) != 1 || notified[0] != 0 {
+		t.Fatalf("notified at hours %v, want only [0]", notified)
+	}
+}
+
+func TestCheckStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "update-check.json")
+
+	state, err := LoadCheckState(path)
+	if err != nil {
+		t.Fatalf("load missing state: %v", err)
+	}
+	if state != (CheckState{}) {
+		t.Fatalf("missing state = %+v, want zero", state)
+	}
+
+	want := CheckState{LastCheck: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), LastNotified: "0.3.0"}
+	if err := SaveCheckState(path, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := LoadCheckState(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !got.LastCheck.Equal(want.LastCheck) || got.LastNotified != want.LastNotified {
+		t.Fatalf("round trip = %+v, want %+v", got, want)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("state dir holds %d entries, want only the state file", len(entries))
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("write corrupt state: %v", err)
+	}
+	if _, err := LoadCheckState(path); err == nil {
+		t.Fatal("expected error for corrupt state")
+	}
+}
@@ -9,6 +9,9 @@
 // checksum verification, or installation. It focuses on deciding whether an
 // update should proceed given a current version and a target release tag.
 //
+// DecideUpdateCheck and CheckState support a periodic "newer release
+// available" note: at most one lookup per interval, one note per release.
+//
 // Version model
 //   - Supports semver-like strings in the form "vMAJOR.MINOR[.PATCH]" with optional
 //     prerelease/build metadata (e.g., "v0.2.5-rc1", "v1.0.0+build123").
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -227,7 +228,7 @@ func TestFetchExpectedHash_HonorsExplicitTokenEnvMissing(t *testing.T) {
 	gh.SetResolver(gh.EnvVarResolver{Name: missingVar})
 	t.Cleanup(func() { gh.SetResolver(nil) })
 
-	_, err := fetchExpectedHash("0.4.6", "sfetch_darwin_arm64.tar.gz")
+	_, err := fetchExpectedHash(context.Background(), "0.4.6", "sfetch_darwin_arm64.tar.gz")
 	if err == nil {
 		t.Fatal("expected error for missing --token-env target, got nil")
 	}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// progressThreshold keeps small files (keys, signatures, checksums)
+	// quiet: progress is reported only once a download is known to be, or
+	// has grown, at least this large.
+	progressThreshold = 1 << 20
+
+	// progressLogInterval is the longest gap between progress lines when
+	// stderr is not a terminal; a line is also written at every 10%.
+	progressLogInterval = 10 * time.Second
+
+	// progressRedrawInterval throttles redraws of the terminal progress bar.
+	progressRedrawInterval = 100 * time.Millisecond
+
+	progressBarWidth = 20
+
+	// hashProgressThreshold is the smallest file whose hashing is shown;
+	// anything smaller hashes in a fraction of a second.
+	hashProgressThreshold = 64 << 20
+)
+
+// progressReporter decides where and how download progress is written. A nil
+// reporter disables progress.
+type progressReporter struct {
+	mu  sync.Mutex // serializes output from concurrent downloads
+	out io.Writer
+	tty bool
+	now func() time.Time
+}
+
+func newProgressReporter(out io.Writer, tty bool) *progressReporter {
+	return &progressReporter{out: out, tty: tty, now: time.Now}
+}
+
+var (
+	downloadProgressMu sync.Mutex
+	downloadProgress   *progressReporter
+)
+
+// setDownloadProgress installs the reporter used by every download. Pass nil
+// to disable progress (--no-progress).
+func setDownloadProgress(p *progressReporter) {
+	downloadProgressMu.Lock()
+	defer downloadProgressMu.Unlock()
+	downloadProgress = p
+}
+
+func currentDownloadProgress() *progressReporter {
+	downloadProgressMu.Lock()
+	defer downloadProgressMu.Unlock()
+	return downloadProgress
+}
+
+// stderrIsTerminal reports whether w is a terminal, which selects the
+// redrawn progress bar over periodic log lines.
+func stderrIsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// copyWithProgress copies src to dst like io.Copy, reporting progress for
+// name through the installed reporter. total is the expected size, or -1
+// when the server sent no Content-Length.
+func copyWithProgress(dst io.Writer, src io.Reader, name string, total int64) (int64, error) {
+	p := currentDownloadProgress()
+	if p == nil {
+		return io.Copy(dst, src)
+	}
+	pw := p.track(dst, name, total)
+	n, err := io.Copy(pw, src)
+	pw.finish(err)
+	return n, err
+}
+
+// progressWriter wraps a download's destination and counts bytes written.
+type progressWriter struct {
+	p           *progressReporter
+	dst         io.Writer
+	name        string
+	total       int64
+	written     int64
+	start       time.Time
+	lastReport  time.Time
+	nextPercent int64
+	reported    bool
+}
+
+func (p *progressReporter) track(dst io.Writer, name string, total int64) *progressWriter {
+	now := p.now()
+	return &progressWriter{p: p, dst: dst, name: name, total: total, start: now, lastReport: now, nextPercent: 10}
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.dst.Write(b)
+	w.written += int64(n)
+	w.report()
+	return n, err
+}
+
+func (w *progressWriter) active() bool {
+	return w.total >= progressThreshold || w.written >= progressThreshold
+}
+
+func (w *progressWriter) percent() int64 {
+	if w.total <= 0 {
+		return -1
+	}
+	return w.written * 100 / w.total
+}
+
+func (w *progressWriter) report() {
+	if !w.active() {
+		return
+	}
+	now := w.p.now()
+	if w.p.tty {
+		if w.reported && now.Sub(w.lastReport) < progressRedrawInterval {
+			return
+		}
+	} else {
+		pct := w.percent()
+		due := now.Sub(w.lastReport) >= progressLogInterval
+		if pct >= 0 && pct >= w.nextPercent {
+			due = true
+		}
+		if !due || pct >= 100 {
+			// The final line is written by finish.
+			return
+		}
+		if pct >= 0 {
+			w.nextPercent = pct/10*10 + 10
+		}
+	}
+	w.lastReport = now
+	w.reported = true
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+	if w.p.tty {
+		_, _ = fmt.Fprintf(w.p.out, "\r%s\x1b[K", w.line(now)) //nolint:errcheck
+	} else {
+		_, _ = fmt.Fprintf(w.p.out, "Downloading %s\n", w.line(now)) //nolint:errcheck
+	}
+}
+
+// finish writes the closing line for a download that reported progress.
+func (w *progressWriter) finish(err error) {
+	if !w.reported && (!w.active() || err != nil) {
+		return
+	}
+	now := w.p.now()
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+	switch {
+	case w.p.tty && err != nil:
+		_, _ = fmt.Fprintln(w.p.out) //nolint:errcheck
+	case w.p.tty:
+		_, _ = fmt.Fprintf(w.p.out, "\r%s\x1b[K\n", w.line(now)) //nolint:errcheck
+	case err == nil:
+		_, _ = fmt.Fprintf(w.p.out, "Downloaded %s (%s in %s)\n", w.name, formatSize(w.written), now.Sub(w.start).Round(100*time.Millisecond)) //nolint:errcheck
+	}
+}
+
+// line renders the progress text. On a terminal it is
+// "name [=====     ]  25%  6.8 MB / 27.1 MB  4.2 MB/s"; in logs it is
+// "name: 25% (6.8 MB of 27.1 MB, 4.2 MB/s)". The bar and percentage are left
+// out when the size is unknown.
+func (w *progressWriter) line(now time.Time) string {
+	rate := "-"
+	if elapsed := now.Sub(w.start).Seconds(); elapsed > 0 {
+		rate = formatSize(int64(float64(w.written)/elapsed)) + "/s"
+	}
+	pct := w.percent()
+	if pct > 100 {
+		pct = 100
+	}
+	if !w.p.tty {
+		if pct < 0 {
+			return fmt.Sprintf("%s: %s (%s)", w.name, formatSize(w.written), rate)
+		}
+		return fmt.Sprintf("%s: %d%% (%s of %s, %s)", w.name, pct, formatSize(w.written), formatSize(w.total), rate)
+	}
+	if pct < 0 {
+		return fmt.Sprintf("%s  %s  %s", w.name, formatSize(w.written), rate)
+	}
+	filled := int(pct) * progressBarWidth / 100
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	return fmt.Sprintf("%s [%s] %3d%%  %s / %s  %s", w.name, bar, pct, formatSize(w.written), formatSize(w.total), rate)
+}
+
+// hashProgress redraws "Verifying checksum of name... N%" on a terminal while
+// a large file is hashed, so a multi-hundred-MB asset does not look hung
+// between the download bar and the verification result.
+type hashProgress struct {
+	p        *progressReporter
+	name     string
+	total    int64
+	read     int64
+	lastDraw time.Time
+	drawn    bool
+}
+
+// trackHash returns a writer that counts the bytes of a total-byte file as
+// they are hashed, or nil when nothing should be drawn: progress is off, the
+// output is not a terminal, or the file is small.
+func (p *progressReporter) trackHash(name string, total int64) *hashProgress {
+	if p == nil || !p.tty || total < hashProgressThreshold {
+		return nil
+	}
+	return &hashProgress{p: p, name: name, total: total}
+}
+
+func (h *hashProgress) Write(b []byte) (int, error) {
+	h.read += int64(len(b))
+	now := h.p.now()
+	if h.drawn && now.Sub(h.lastDraw) < progressRedrawInterval {
+		return len(b), nil
+	}
+	h.lastDraw, h.drawn = now, true
+	h.p.mu.Lock()
+	defer h.p.mu.Unlock()
+	_, _ = fmt.Fprintf(h.p.out, "\rVerifying checksum of %s... %d%%\x1b[K", h.name, min(h.read*100/h.total, 100)) //nolint:errcheck
+	return len(b), nil
+}
+
+// finish clears the progress line so the verification result starts on a
+// clean line.
+func (h *hashProgress) finish() {
+	if !h.drawn {
+		return
+	}
+	h.p.mu.Lock()
+	defer h.p.mu.Unlock()
+	_, _ = fmt.Fprint(h.p.out, "\r\x1b[K") //nolint:errcheck
+}
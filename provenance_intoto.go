@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Provenance output formats selectable via --provenance-format.
+const (
+	provenanceFormatSfetch = "sfetch" // native ProvenanceRecord (schemas/provenance.schema.json)
+	provenanceFormatInToto = "intoto" // in-toto Statement v1 wrapping the sfetch predicate
+	provenanceFormatSLSA   = "slsa"   // in-toto Statement v1 with a SLSA v1 provenance predicate
+)
+
+const (
+	inTotoStatementType    = "https://in-toto.io/Statement/v1"
+	sfetchPredicateTypeURI = "https://github.com/3leaps/sfetch/schemas/provenance-predicate.schema.json"
+)
+
+// InTotoStatement is an in-toto attestation Statement (v1) whose predicate
+// carries the sfetch verification and trust data for the fetched asset.
+// Predicate schema: schemas/provenance-predicate.schema.json
+type InTotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []InTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     SfetchPredicate `json:"predicate"`
+}
+
+type InTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// SfetchPredicate is the ProvenanceRecord body without the $schema envelope.
+type SfetchPredicate struct {
+	Version           string                      `json:"version"`
+	Timestamp         string                      `json:"timestamp"`
+	SfetchVersion     string                      `json:"sfetchVersion"`
+	Source            ProvenanceSource            `json:"source"`
+	Asset             ProvenanceAsset             `json:"asset"`
+	Verification      ProvenanceVerify            `json:"verification"`
+	TrustLevel        string                      `json:"trustLevel,omitempty"`
+	Trust             TrustScore                  `json:"trust"`
+	Warnings          []string                    `json:"warnings,omitempty"`
+	Flags             ProvenanceFlags             `json:"flags,omitempty"`
+	ExtractedBinaries []ProvenanceExtractedBinary `json:"extractedBinaries,omitempty"`
+	Timings           *Timings                    `json:"timings,omitempty"`
+	FetchLog          []FetchRecord               `json:"fetchLog,omitempty"`
+}
+
+func normalizeProvenanceFormat(raw string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", provenanceFormatSfetch:
+		return provenanceFormatSfetch, nil
+	case provenanceFormatInToto, "in-toto":
+		return provenanceFormatInToto, nil
+	case provenanceFormatSLSA:
+		return provenanceFormatSLSA, nil
+	default:
+		return "", fmt.Errorf("invalid --provenance-format %q (allowed: %s, %s, %s)", raw, provenanceFormatSfetch, provenanceFormatInToto, provenanceFormatSLSA)
+	}
+}
+
+// inTotoDigestName maps sfetch's algorithm names to the in-toto DigestSet
+// keys (sha3_256, blake2b, ...); names in-toto shares pass through.
+func inTotoDigestName(algo string) string {
+	switch algo {
+	case "sha3-256":
+		return "sha3_256"
+	case "sha3-512":
+		return "sha3_512"
+	case "blake2b-512":
+		return "blake2b"
+	default:
+		return algo
+	}
+}
+
+// buildInTotoStatement wraps a provenance record into an in-toto Statement.
+// The subject digest is the checksum sfetch computed; run refuses this
+// format for a dry-run that has none.
+func buildInTotoStatement(record *ProvenanceRecord) *InTotoStatement {
+	digest := map[string]string{}
+	if cs := record.Asset.ComputedChecksum; cs != nil && cs.Value != "" {
+		digest[inTotoDigestName(cs.Algorithm)] = cs.Value
+	}
+
+	return &InTotoStatement{
+		Type: inTotoStatementType,
+		Subject: []InTotoSubject{
+			{Name: record.Asset.Name, Digest: digest},
+		},
+		PredicateType: sfetchPredicateTypeURI,
+		Predicate: SfetchPredicate{
+			Version:           record.Version,
+			Timestamp:         record.Timestamp,
+			SfetchVersion:     record.SfetchVersion,
+			Source:            record.Source,
+			Asset:             record.Asset,
+			Verification:      record.Verification,
+			TrustLevel:        record.TrustLevel,
+			Trust:             record.Trust,
+			Warnings:          record.Warnings,
+			Flags:             record.Flags,
+			ExtractedBinaries: record.ExtractedBinaries,
+			Timings:           record.Timings,
+			FetchLog:          record.FetchLog,
+		},
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/url"
+	"path"
+)
+
+const (
+	slsaPredicateTypeURI = "https://slsa.dev/provenance/v1"
+	// slsaBuildType identifies sfetch's "build": fetching a release asset and
+	// verifying it. externalParameters and internalParameters below are its
+	// shape (schemas/slsa-provenance.schema.json).
+	slsaBuildType = "https://github.com/3leaps/sfetch/slsa/fetch/v1"
+	slsaBuilderID = "https://github.com/3leaps/sfetch"
+)
+
+// SLSAStatement is an in-toto Statement (v1) carrying a SLSA v1 provenance
+// predicate for the fetched asset (--provenance-format slsa).
+type SLSAStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []InTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     SLSAProvenance  `json:"predicate"`
+}
+
+// SLSAProvenance is the SLSA v1 provenance predicate.
+type SLSAProvenance struct {
+	BuildDefinition SLSABuildDefinition `json:"buildDefinition"`
+	RunDetails      SLSARunDetails      `json:"runDetails"`
+}
+
+type SLSABuildDefinition struct {
+	BuildType            string                   `json:"buildType"`
+	ExternalParameters   SLSAExternalParameters   `json:"externalParameters"`
+	InternalParameters   SLSAInternalParameters   `json:"internalParameters"`
+	ResolvedDependencies []SLSAResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// SLSAExternalParameters are what the user asked for: where the asset came
+// from and the verification flags that loosen or tighten policy.
+type SLSAExternalParameters struct {
+	Source ProvenanceSource `json:"source"`
+	Asset  string           `json:"asset"`
+	Flags  ProvenanceFlags  `json:"flags,omitempty"`
+}
+
+// SLSAInternalParameters are what sfetch decided: the verification outcome
+// and trust score.
+type SLSAInternalParameters struct {
+	Verification ProvenanceVerify `json:"verification"`
+	TrustLevel   string           `json:"trustLevel,omitempty"`
+	Trust        TrustScore       `json:"trust"`
+	Warnings     []string         `json:"warnings,omitempty"`
+}
+
+// SLSAResourceDescriptor is an in-toto ResourceDescriptor.
+type SLSAResourceDescriptor struct {
+	URI         string            `json:"uri,omitempty"`
+	Digest      map[string]string `json:"digest,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type SLSARunDetails struct {
+	Builder  SLSABuilder  `json:"builder"`
+	Metadata SLSAMetadata `json:"metadata"`
+}
+
+type SLSABuilder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type SLSAMetadata struct {
+	FinishedOn string `json:"finishedOn,omitempty"`
+}
+
+// buildSLSAStatement maps a provenance record onto SLSA v1 provenance. The
+// verification materials (asset, checksum manifest, signature) become
+// resolvedDependencies; only the asset has a digest, since sfetch hashes
+// nothing else. As with intoto, run refuses a dry-run with no digest.
+func buildSLSAStatement(record *ProvenanceRecord) *SLSAStatement {
+	digest := map[string]string{}
+	if cs := record.Asset.ComputedChecksum; cs != nil && cs.Value != "" {
+		digest[inTotoDigestName(cs.Algorithm)] = cs.Value
+	}
+
+	deps := []SLSAResourceDescriptor{{Name: record.Asset.Name, URI: record.Asset.URL, Digest: digest}}
+	if f := record.Asset.ServedFrom; f != "" {
+		deps[0].URI = f
+	}
+	if len(digest) == 0 {
+		deps[0].Digest = nil
+	}
+	if cs := record.Verification.Checksum; cs.File != "" {
+		deps = append(deps, SLSAResourceDescriptor{
+			Name:        cs.File,
+			URI:         materialURI(record.Asset.URL, cs.File, cs.ServedFrom),
+			Annotations: map[string]string{"role": "checksum", "verified": boolString(cs.Verified)},
+		})
+	}
+	sigs := record.Verification.Signatures
+	if len(sigs) == 0 {
+		sigs = []ProvenanceSigStatus{record.Verification.Signature}
+	}
+	for _, sig := range sigs {
+		if sig.File == "" {
+			continue
+		}
+		annotations := map[string]string{"role": "signature", "format": sig.Format, "verified": boolString(sig.Verified)}
+		if sig.KeySource != "" {
+			annotations["keySource"] = sig.KeySource
+		}
+		deps = append(deps, SLSAResourceDescriptor{
+			Name:        sig.File,
+			URI:         materialURI(record.Asset.URL, sig.File, sig.ServedFrom),
+			Annotations: annotations,
+		})
+	}
+
+	builder := SLSABuilder{ID: slsaBuilderID}
+	if record.SfetchVersion != "" {
+		builder.Version = map[string]string{"sfetch": record.SfetchVersion}
+	}
+
+	return &SLSAStatement{
+		Type:          inTotoStatementType,
+		Subject:       []InTotoSubject{{Name: record.Asset.Name, Digest: digest}},
+		PredicateType: slsaPredicateTypeURI,
+		Predicate: SLSAProvenance{
+			BuildDefinition: SLSABuildDefinition{
+				BuildType: slsaBuildType,
+				ExternalParameters: SLSAExternalParameters{
+					Source: record.Source,
+					Asset:  record.Asset.Name,
+					Flags:  record.Flags,
+				},
+				InternalParameters: SLSAInternalParameters{
+					Verification: record.Verification,
+					TrustLevel:   record.TrustLevel,
+					Trust:        record.Trust,
+					Warnings:     record.Warnings,
+				},
+				ResolvedDependencies: deps,
+			},
+			RunDetails: SLSARunDetails{
+				Builder:  builder,
+				Metadata: SLSAMetadata{FinishedOn: record.Timestamp},
+			},
+		},
+	}
+}
+
+// materialURI locates a release file that sits next to the asset: the
+// mirror URL that served it, else the asset URL with its last path element
+// replaced. It returns "" when neither is known.
+func materialURI(assetURL, name, servedFrom string) string {
+	if servedFrom != "" {
+		return servedFrom
+	}
+	u, err := url.Parse(assetURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	u.Path = path.Join(path.Dir(u.Path), name)
+	u.RawQuery = ""
+	return u.String()
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
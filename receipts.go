@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/3leaps/sfetch/internal/cache"
+	"github.com/3leaps/sfetch/internal/receipt"
+)
+
+// receiptsDir keeps install receipts next to the cache whose verified assets
+// --rollback reinstalls from.
+func receiptsDir(cacheDir string) string {
+	return filepath.Join(cacheDir, receipt.Dir)
+}
+
+// recordInstall appends an install receipt for repo: the tag, asset, and
+// the path and SHA-256 of every installed file. extractAll is the
+// --extract-all directory, if any.
+func recordInstall(cacheDir, repo, tag, asset, assetSHA256 string, installed []installedBinary, selfUpdate bool, extractAll string) error {
+	in := receipt.Install{
+		Tag:         tag,
+		Asset:       asset,
+		AssetSHA256: assetSHA256,
+		SelfUpdate:  selfUpdate,
+		InstalledAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if extractAll != "" {
+		root, err := filepath.Abs(extractAll)
+		if err != nil {
+			return fmt.Errorf("record install: %w", err)
+		}
+		in.ExtractAll = root
+	}
+	for _, b := range installed {
+		p, err := filepath.Abs(b.path)
+		if err != nil {
+			return fmt.Errorf("record install: %w", err)
+		}
+		sum, err := cache.FileSHA256(p)
+		if err != nil {
+			return fmt.Errorf("record install: %w", err)
+		}
+		in.Files = append(in.Files, receipt.File{Path: p, SHA256: sum, Member: b.member})
+	}
+
+	dir := receiptsDir(cacheDir)
+	r, err := receipt.Load(dir, repo)
+	if err != nil {
+		return err
+	}
+	r.Add(in)
+	return r.Save(dir)
+}
+
+// uninstallRecorded removes the files the newest receipt of repo lists and
+// forgets the repo. Files changed since install are kept unless force.
+func uninstallRecorded(cacheDir, repo string, force bool, status io.Writer) error {
+	dir := receiptsDir(cacheDir)
+	r, err := receipt.Load(dir, repo)
+	if err != nil {
+		return err
+	}
+	latest, ok := r.Latest()
+	if !ok {
+		return fmt.Errorf("no install receipt for %s in %s", repo, dir)
+	}
+	removed, missing, err := receipt.RemoveFiles(latest, force)
+	var modified *receipt.ModifiedError
+	if errors.As(err, &modified) {
+		return fmt.Errorf("not removing files %s (pass --force-install to remove them anyway)", modified)
+	}
+	for _, p := range removed {
+		_, _ = fmt.Fprintf(status, "Removed %s\n", p) //nolint:errcheck
+	}
+	for _, p := range missing {
+		_, _ = fmt.Fprintf(status, "note: %s was already gone\n", p) //nolint:errcheck
+	}
+	if err != nil {
+		return err
+	}
+	if err := receipt.Delete(dir, repo); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(status, "Uninstalled %s %s\n", repo, latest.Tag) //nolint:errcheck
+	return nil
+}
+
+// rollbackRecorded reinstalls the version of repo installed before the
+// current one, from its verified copy in the cache, to the paths its receipt
+// lists. The rollback is recorded too, so a second one undoes the first.
+func rollbackRecorded(cacheDir, repo string, force bool, status, warn io.Writer, nextSteps *hintSink) error {
+	dir := receiptsDir(cacheDir)
+	r, err := receipt.Load(dir, repo)
+	if err != nil {
+		return err
+	}
+	current, ok := r.Latest()
+	if !ok {
+		return fmt.Errorf("no install receipt for %s in %s", repo, dir)
+	}
+	prev, ok := r.Previous()
+	if !ok {
+		return fmt.Errorf("no version of %s before %s is recorded", repo, current.Tag)
+	}
+	if len(prev.Files) == 0 {
+		return fmt.Errorf("the receipt for %s %s lists no files", repo, prev.Tag)
+	}
+
+	idx, err := cache.Load(cacheDir)
+	if err != nil {
+		return err
+	}
+	entry, ok := idx.Get(repo, prev.Tag, prev.Asset)
+	if !ok {
+		return fmt.Errorf("no verified cached copy of %s for %s@%s (reinstall it with --repo %s --tag %s)", prev.Asset, repo, prev.Tag, repo, prev.Tag)
+	}
+	cachedPath, err := cache.Resolve(cacheDir, entry)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "sfetch-*")
+	if err != nil {
+		return fmt.Errorf("mkdir temp: %w", err)
+	}
+	defer os.RemoveAll(tmpDir) //nolint:errcheck // best-effort cleanup of temp dir
+
+	// Install from a temp copy so raw assets are not moved out of the cache.
+	assetPath := filepath.Join(tmpDir, prev.Asset)
+	if err := copyFile(cachedPath, assetPath); err != nil {
+		return fmt.Errorf("copy cached asset: %w", err)
+	}
+	cfg := getConfig(repo)
+	classification, _, err := classifyAsset(prev.Asset, cfg, "")
+	if err != nil {
+		return err
+	}
+
+	target := installTarget{selfUpdate: prev.SelfUpdate, force: force}
+	var extraDirs []string
+	if prev.ExtractAll != "" {
+		// An --extract-all receipt lists a nested tree: reinstall the whole
+		// archive under the same directory, keeping its layout.
+		target.extractAll = prev.ExtractAll
+	} else {
+		// Files outside the first file's directory were --dest-dir copies.
+		primaryDir := filepath.Dir(prev.Files[0].Path)
+		var primary []receipt.File
+		for _, f := range prev.Files {
+			d := filepath.Dir(f.Path)
+			if d == primaryDir {
+				primary = append(primary, f)
+			} else if !slices.Contains(extraDirs, d) {
+				extraDirs = append(extraDirs, d)
+			}
+		}
+		if len(primary) == 1 {
+			target.output = primary[0].Path
+		} else {
+			target.destDir = primaryDir
+		}
+		for _, f := range primary {
+			if f.Member != "" {
+				target.binaries = append(target.binaries, f.Member)
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintf(status, "Rolling back %s from %s to %s (cached %s)\n", repo, current.Tag, prev.Tag, prev.Asset) //nolint:errcheck
+	installed, err := installReleaseAsset(assetPath, &Asset{Name: prev.Asset}, classification, cfg, runtime.GOOS, tmpDir, target, warn, nextSteps)
+	if err == nil {
+		var copies []installedBinary
+		copies, err = copyToDestDirs(installed, extraDirs, classification, tmpDir, installOptions{force: force})
+		installed = append(installed, copies...)
+	}
+	if err != nil {
+		return err
+	}
+	reportInstalled(status, installed)
+	return recordInstall(cacheDir, repo, prev.Tag, prev.Asset, entry.SHA256, installed, prev.SelfUpdate, prev.ExtractAll)
+}
+
+// receiptCommand holds the flags of --uninstall and receipt-based --rollback.
+type receiptCommand struct {
+	uninstall  string   // --uninstall owner/repo
+	rollback   bool     // --rollback
+	repo       string   // --repo
+	args       []string // positional arguments: --rollback owner/repo
+	cacheDir   string
+	force      bool // --force-install
+	selfUpdate bool
+}
+
+// runReceiptCommand runs --uninstall, or --rollback from the receipts. It
+// reports handled=false for a --rollback that names no repo, which falls
+// through to the <path>.bak rollback and its usage error.
+func runReceiptCommand(c receiptCommand, status, stderr, warn io.Writer, nextSteps *hintSink) (code int, handled bool) {
+	repo := c.uninstall
+	switch {
+	case c.uninstall != "" && c.rollback:
+		_, _ = fmt.Fprintln(stderr, "error: --uninstall and --rollback are mutually exclusive") //nolint:errcheck
+		return exitUsage, true
+	case c.uninstall != "" && (c.selfUpdate || c.repo != "" || len(c.args) > 0):
+		_, _ = fmt.Fprintln(stderr, "error: --uninstall takes the repo itself; drop --self-update, --repo and positional arguments") //nolint:errcheck
+		return exitUsage, true
+	case c.rollback:
+		repo = c.repo
+		if len(c.args) > 0 {
+			if repo != "" || len(c.args) > 1 {
+				_, _ = fmt.Fprintln(stderr, "error: --rollback takes one owner/repo") //nolint:errcheck
+				return exitUsage, true
+			}
+			repo = c.args[0]
+		}
+		if repo == "" {
+			return exitOK, false
+		}
+	}
+	if !isRepoSlug(repo) {
+		_, _ = fmt.Fprintf(stderr, "error: %q is not owner/repo\n", repo) //nolint:errcheck
+		return exitUsage, true
+	}
+
+	var err error
+	if c.rollback {
+		err = rollbackRecorded(c.cacheDir, repo, c.force, status, warn, nextSteps)
+	} else {
+		err = uninstallRecorded(c.cacheDir, repo, c.force, status)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err) //nolint:errcheck
+		return exitInstall, true
+	}
+	return exitOK, true
+}
+
+// isRepoSlug reports whether s has the owner/repo shape.
+func isRepoSlug(s string) bool {
+	owner, name, ok := strings.Cut(s, "/")
+	return ok && owner != "" && name != "" && !strings.ContainsAny(name, "/:")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/3leaps/sfetch/pkg/update"
+)
+
+// --channel values.
+const (
+	channelStable     = "stable"
+	channelPrerelease = "prerelease"
+)
+
+const (
+	releasesPerPage = 100
+	// maxReleasePages bounds the walk over /releases; the newest releases
+	// come first, so older history beyond it rarely matters.
+	maxReleasePages = 10
+)
+
+// fetchHighestRelease lists the repository's releases and returns the one
+// with the highest semver tag (pkg/update.CompareSemver ordering). Drafts are
+// always skipped, prereleases unless includePrerelease, and tags that are not
+// semver-like are ignored.
+func fetchHighestRelease(ctx context.Context, baseURL, repo string, includePrerelease bool) (Release, error) {
+	listURL, err := url.Parse(fmt.Sprintf("%s/repos/%s/releases", baseURL, repo))
+	if err != nil {
+		return Release{}, fmt.Errorf("parsing releases URL: %w", err)
+	}
+
+	var best Release
+	var bestVersion string
+	for page := 1; page <= maxReleasePages; page++ {
+		q := listURL.Query()
+		q.Set("per_page", strconv.Itoa(releasesPerPage))
+		q.Set("page", strconv.Itoa(page))
+		listURL.RawQuery = q.Encode()
+
+		releases, err := fetchReleasePage(ctx, listURL.String())
+		if err != nil {
+			return Release{}, err
+		}
+		for _, rel := range releases {
+			if rel.Draft || (rel.Prerelease && !includePrerelease) {
+				continue
+			}
+			v, ok := update.NormalizeVersion(rel.TagName)
+			if !ok {
+				continue
+			}
+			if bestVersion != "" {
+				cmp, err := update.CompareSemver(v, bestVersion)
+				if err != nil || cmp <= 0 {
+					continue
+				}
+			}
+			best, bestVersion = rel, v
+		}
+		if len(releases) < releasesPerPage {
+			break
+		}
+	}
+	if bestVersion == "" {
+		kind := "stable releases"
+		if includePrerelease {
+			kind = "releases"
+		}
+		return Release{}, fmt.Errorf("no published %s with a semver tag found for %s", kind, repo)
+	}
+
+	if len(best.Assets) == truncatedAssetCount && best.AssetsURL != "" {
+		more, err := fetchReleaseAssets(ctx, best.AssetsURL)
+		if err != nil {
+			return Release{}, err
+		}
+		best.Assets = mergeAssets(best.Assets, more)
+	}
+	return best, nil
+}
+
+func fetchReleasePage(ctx context.Context, pageURL string) ([]Release, error) {
+	resp, err := httpGetWithAuthContext(ctx, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching releases: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only response, close error non-critical
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("release list request failed %d: %s", resp.StatusCode, string(body))
+	}
+	var releases []Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("parsing release list: %w", err)
+	}
+	return releases, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// releaseNotesMaxLines and releaseNotesMaxBytes cap the release notes shown
+// before a self-update or with --show-release-notes; longer notes end with
+// a pointer to the release page.
+const (
+	releaseNotesMaxLines = 40
+	releaseNotesMaxBytes = 4000
+)
+
+// formatReleaseNotes renders rel's name and notes for the terminal. The
+// Markdown body is passed through as plain text, minus control characters
+// a release author could use to rewrite the terminal. It returns "" when
+// the release has neither a name nor notes.
+func formatReleaseNotes(rel *Release, releaseURL string) string {
+	name := strings.TrimSpace(plainText(rel.Name))
+	body := strings.TrimSpace(plainText(rel.Body))
+	if name == "" && body == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	if name != "" && name != rel.TagName {
+		_, _ = fmt.Fprintf(&sb, "Release %s: %s\n", rel.TagName, name)
+	} else {
+		_, _ = fmt.Fprintf(&sb, "Release %s\n", rel.TagName)
+	}
+	if body == "" {
+		sb.WriteString("  (no release notes)\n")
+		return sb.String()
+	}
+
+	lines := strings.Split(body, "\n")
+	shown, size := 0, 0
+	for _, line := range lines {
+		if shown == releaseNotesMaxLines || size+len(line) > releaseNotesMaxBytes {
+			break
+		}
+		_, _ = fmt.Fprintf(&sb, "  %s\n", strings.TrimRight(line, " \t"))
+		shown++
+		size += len(line) + 1
+	}
+	if shown == 0 {
+		// A single huge line: cut it at a rune boundary.
+		line := lines[0][:releaseNotesMaxBytes]
+		for !utf8.ValidString(line) {
+			line = line[:len(line)-1]
+		}
+		_, _ = fmt.Fprintf(&sb, "  %s…\n", line)
+		shown = 1
+	}
+	if rest := len(lines) - shown; rest > 0 || len(body) > releaseNotesMaxBytes {
+		note := "  … release notes truncated"
+		if rest > 0 {
+			note += fmt.Sprintf(" (%d more lines)", rest)
+		}
+		if releaseURL != "" {
+			note += "; full notes: " + releaseURL
+		}
+		sb.WriteString(note + "\n")
+	}
+	return sb.String()
+}
+
+// releasePageURL is the GitHub page of repo's release tag.
+func releasePageURL(repo, tag string) string {
+	return fmt.Sprintf("https://github.com/%s/releases/tag/%s", repo, tag)
+}
+
+// plainText normalizes line endings and drops control characters other
+// than newline and tab.
+func plainText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
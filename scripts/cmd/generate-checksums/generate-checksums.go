@@ -1,29 +1,47 @@
-// Command generate-checksums writes checksum files for release artifacts using stdlib hashes.
+// Command generate-checksums writes checksum files for release artifacts.
 package main
 
 import (
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
-	"hash"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/3leaps/sfetch/internal/verify"
 )
 
 type checksumJob struct {
 	algo    string
 	outFile string
-	newHash func() hash.Hash
+}
+
+// checksumFiles names the checksum file written for each algorithm, using
+// the names sfetch's checksum detection recognizes. B2SUMS follows GNU
+// b2sum, whose default digest is BLAKE2b-512.
+var checksumFiles = map[string]string{
+	"sha256":      "SHA256SUMS",
+	"sha512":      "SHA512SUMS",
+	"sha3-256":    "SHA3-256SUMS",
+	"sha3-512":    "SHA3-512SUMS",
+	"blake2b-256": "B2-256SUMS",
+	"blake2b-512": "B2SUMS",
+}
+
+// algoAliases maps other spellings accepted by -algos to checksumFiles keys.
+var algoAliases = map[string]string{
+	"sha3_256": "sha3-256",
+	"sha3_512": "sha3-512",
+	"blake2b":  "blake2b-512",
+	"b2":       "blake2b-512",
 }
 
 func main() {
 	dir := flag.String("dir", "dist/release", "directory containing release artifacts")
-	algos := flag.String("algos", "sha256,sha512", "comma-separated list of hash algorithms (sha256, sha512)")
+	algos := flag.String("algos", "sha256,sha512", "comma-separated list of hash algorithms (sha256, sha512, sha3-256, sha3-512, blake2b-256, blake2b-512)")
 	flag.Parse()
 
 	if err := run(*dir, *algos); err != nil {
@@ -94,25 +112,17 @@ func jobsFromAlgos(list string) ([]checksumJob, error) {
 		if algo == "" {
 			continue
 		}
+		if alias, ok := algoAliases[algo]; ok {
+			algo = alias
+		}
 		if _, ok := seen[algo]; ok {
 			continue
 		}
-		switch algo {
-		case "sha256":
-			jobs = append(jobs, checksumJob{
-				algo:    "sha256",
-				outFile: "SHA256SUMS",
-				newHash: sha256.New,
-			})
-		case "sha512":
-			jobs = append(jobs, checksumJob{
-				algo:    "sha512",
-				outFile: "SHA512SUMS",
-				newHash: sha512.New,
-			})
-		default:
+		outFile, ok := checksumFiles[algo]
+		if !ok {
 			return nil, fmt.Errorf("unsupported hash algorithm %q", algo)
 		}
+		jobs = append(jobs, checksumJob{algo: algo, outFile: outFile})
 		seen[algo] = struct{}{}
 	}
 	return jobs, nil
@@ -139,16 +149,11 @@ func filterFiles(entries []os.DirEntry) []string {
 func skipFile(name string) bool {
 	lower := strings.ToLower(name)
 
-	// Skip signatures and existing checksum outputs
+	// Skip signatures and existing checksum outputs (SHA256SUMS, B2SUMS,
+	// tool.tar.gz.sha512, ...)
 	if strings.HasSuffix(lower, ".asc") ||
 		strings.HasSuffix(lower, ".minisig") ||
-		strings.HasSuffix(lower, ".sha256") ||
-		strings.HasSuffix(lower, ".sha256.txt") ||
-		strings.HasSuffix(lower, ".sha512") ||
-		strings.HasSuffix(lower, ".sha512.txt") ||
-		strings.HasPrefix(lower, "sha256sums") ||
-		strings.HasPrefix(lower, "sha2-512sums") ||
-		strings.HasPrefix(lower, "sha512sums") {
+		verify.DetectChecksumAlgorithm(name, "") != "" {
 		return true
 	}
 
@@ -179,7 +184,7 @@ func writeChecksums(dir string, files []string, job checksumJob) error {
 	defer outFile.Close() //nolint:errcheck // error checked via sync below
 
 	for _, name := range files {
-		sum, err := computeFileHash(filepath.Join(dir, name), job.newHash)
+		sum, err := computeFileHash(filepath.Join(dir, name), job.algo)
 		if err != nil {
 			return err
 		}
@@ -190,12 +195,15 @@ func writeChecksums(dir string, files []string, job checksumJob) error {
 	return nil
 }
 
-func computeFileHash(path string, newHash func() hash.Hash) (string, error) {
+func computeFileHash(path, algo string) (string, error) {
 	data, err := os.ReadFile(path) // #nosec G304 -- SDR-001: build tool reading release assets
 	if err != nil {
 		return "", fmt.Errorf("read %s: %w", path, err)
 	}
-	h := newHash()
+	h, err := verify.NewHash(algo)
+	if err != nil {
+		return "", err
+	}
 	if _, err := h.Write(data); err != nil {
 		return "", fmt.Errorf("hash %s: %w", path, err)
 	}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
 	"encoding/hex"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 func TestRunGeneratesChecksumsAndSkipsNonArtifacts(t *testing.T) {
@@ -63,6 +67,61 @@ func TestRunGeneratesChecksumsAndSkipsNonArtifacts(t *testing.T) {
 	}
 }
 
+func TestRunModernAlgorithms(t *testing.T) {
+	b2 := blake2b.Sum512([]byte("one"))
+	b2short := blake2b.Sum256([]byte("one"))
+	s3 := sha3.Sum256([]byte("one"))
+	s3long := sha3.Sum512([]byte("one"))
+	s512 := sha512.Sum512([]byte("one"))
+
+	tests := []struct {
+		algos string
+		want  map[string]string // checksum file -> digest of sfetch_one
+	}{
+		{algos: "sha512", want: map[string]string{"SHA512SUMS": hex.EncodeToString(s512[:])}},
+		{algos: "sha3-256,sha3_512", want: map[string]string{"SHA3-256SUMS": hex.EncodeToString(s3[:]), "SHA3-512SUMS": hex.EncodeToString(s3long[:])}},
+		{algos: "blake2b", want: map[string]string{"B2SUMS": hex.EncodeToString(b2[:])}},
+		{algos: "blake2b-256,b2", want: map[string]string{"B2-256SUMS": hex.EncodeToString(b2short[:]), "B2SUMS": hex.EncodeToString(b2[:])}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algos, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "sfetch_one"), []byte("one"), 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			// Checksum files from an earlier run are not artifacts.
+			for _, old := range []string{"B2SUMS", "SHA3-512SUMS", "sfetch_one.sha512"} {
+				if err := os.WriteFile(filepath.Join(dir, old), []byte("old"), 0o644); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+			}
+
+			if err := run(dir, tt.algos); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			for file, digest := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, file))
+				if err != nil {
+					t.Fatalf("read %s: %v", file, err)
+				}
+				if got, want := string(data), digest+"  sfetch_one\n"; got != want {
+					t.Errorf("%s = %q, want %q", file, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunRejectsUnknownAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sfetch_one"), []byte("one"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := run(dir, "md5"); err == nil || !strings.Contains(err.Error(), `unsupported hash algorithm "md5"`) {
+		t.Fatalf("run error = %v", err)
+	}
+}
+
 func hashHex(data string) string {
 	sum := sha256.Sum256([]byte(data))
 	return strings.ToLower(hex.EncodeToString(sum[:]))
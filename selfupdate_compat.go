@@ -5,3 +5,19 @@ import "github.com/3leaps/sfetch/internal/selfupdate"
 func computeSelfUpdatePath(dir string) (string, error) {
 	return selfupdate.ComputeTargetPath(dir)
 }
+
+// selfUpdateInstall describes the binary at path for the package-manager
+// check; tests replace it to simulate system installs.
+var selfUpdateInstall = func(path string) selfupdate.Install {
+	return selfupdate.Install{Path: path, OwnerUID: selfupdate.FileOwner(path)}
+}
+
+// packageManagedInstall reports whether a self-update would replace a binary
+// a package manager owns, with the reason to show.
+func packageManagedInstall(path string) (bool, string) {
+	return selfupdate.DetectPackageManaged(selfUpdateInstall(path))
+}
+
+func selfUpdateEmbeddedVersion(path string) (string, error) {
+	return selfupdate.EmbeddedVersion(path)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultMaxAssetSize is the --max-size default: well above any real release
+// asset, but a release pointing at a multi-GB file cannot fill a CI disk.
+const defaultMaxAssetSize int64 = 2 << 30
+
+// assetSizeTolerance is how far a server may stray from the size it was
+// expected to send: a Content-Length further than this from the listed size
+// refuses the download, and a body this far past its declared size aborts it.
+const assetSizeTolerance int64 = 1 << 20
+
+// defaultMaxExtractSize is the --max-extract-size default: how far a
+// compressed single-file asset or an archive may expand on disk, so a small
+// decompression bomb that passes --max-size cannot fill it.
+const defaultMaxExtractSize int64 = 4 << 30
+
+var (
+	maxAssetSizeMu sync.Mutex
+	maxAssetSize   = defaultMaxAssetSize
+	maxExtractSize = defaultMaxExtractSize
+)
+
+// setMaxAssetSize sets the --max-size limit; 0 disables it.
+func setMaxAssetSize(n int64) {
+	maxAssetSizeMu.Lock()
+	defer maxAssetSizeMu.Unlock()
+	maxAssetSize = n
+}
+
+func currentMaxAssetSize() int64 {
+	maxAssetSizeMu.Lock()
+	defer maxAssetSizeMu.Unlock()
+	return maxAssetSize
+}
+
+// setMaxExtractSize sets the --max-extract-size limit; 0 disables it.
+func setMaxExtractSize(n int64) {
+	maxAssetSizeMu.Lock()
+	defer maxAssetSizeMu.Unlock()
+	maxExtractSize = n
+}
+
+func currentMaxExtractSize() int64 {
+	maxAssetSizeMu.Lock()
+	defer maxAssetSizeMu.Unlock()
+	return maxExtractSize
+}
+
+// errAssetTooLarge marks a download refused or aborted by --max-size.
+var errAssetTooLarge = errors.New("asset exceeds --max-size")
+
+// errExtractTooLarge marks a decompression or extraction stopped by
+// --max-extract-size.
+var errExtractTooLarge = errors.New("extracted size exceeds --max-extract-size")
+
+// errAssetSizeMismatch marks a download whose size strays from the size it
+// was declared with by more than assetSizeTolerance.
+var errAssetSizeMismatch = errors.New("download size mismatch")
+
+// parseByteSize parses a --max-size value: a byte count with an optional
+// K, M, G or T suffix (binary multiples; a trailing B or iB is accepted).
+func parseByteSize(s string) (int64, error) {
+	raw := strings.TrimSpace(s)
+	v := strings.ToUpper(raw)
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "B"), "I")
+	shift := 0
+	if v != "" {
+		switch v[len(v)-1] {
+		case 'K':
+			shift = 10
+		case 'M':
+			shift = 20
+		case 'G':
+			shift = 30
+		case 'T':
+			shift = 40
+		}
+		if shift > 0 {
+			v = v[:len(v)-1]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 || n > (1<<62)>>shift {
+		return 0, fmt.Errorf("invalid size %q (use bytes or a K, M, G or T suffix, e.g. 500M)", raw)
+	}
+	return n << shift, nil
+}
+
+// checkListedSize refuses an asset whose listed size is over --max-size
+// before anything is downloaded.
+func checkListedSize(asset *Asset) error {
+	if limit := currentMaxAssetSize(); limit > 0 && asset.Size > limit {
+		return fmt.Errorf("%w: %s is listed at %s (limit %s; raise --max-size to fetch it)", errAssetTooLarge, asset.Name, formatSize(asset.Size), formatSize(limit))
+	}
+	return nil
+}
+
+// sizeGuard bounds one response body: how many bytes may be read before the
+// transfer is aborted, and the size it is expected to have.
+type sizeGuard struct {
+	name     string
+	declared int64 // listed size, else Content-Length; -1 when unknown
+	limit    int64 // abort past this many bytes; -1 for no limit
+	note     string
+}
+
+// guardResponseSize checks resp's Content-Length against --max-size and
+// against listed, the size the release API gave (0 when none), before the
+// body is read. A Content-Length within assetSizeTolerance of listed is
+// allowed but noted for the provenance record.
+func guardResponseSize(resp *http.Response, name string, listed int64) (*sizeGuard, error) {
+	maxSize := currentMaxAssetSize()
+	cl := resp.ContentLength
+	if maxSize > 0 && cl > maxSize {
+		return nil, fmt.Errorf("%w: %s is %s per Content-Length (limit %s; raise --max-size to fetch it)", errAssetTooLarge, name, formatSize(cl), formatSize(maxSize))
+	}
+	g := &sizeGuard{name: name, declared: cl, limit: -1}
+	if listed > 0 {
+		g.declared = listed
+		if cl >= 0 && cl != listed {
+			if absInt64(cl-listed) > assetSizeTolerance {
+				return nil, fmt.Errorf("%w: %s: server declares %d bytes but the release lists %d", errAssetSizeMismatch, name, cl, listed)
+			}
+			g.note = fmt.Sprintf("%s: Content-Length %d differs from the listed size %d", name, cl, listed)
+		}
+	}
+	if g.declared >= 0 {
+		g.limit = g.declared + assetSizeTolerance
+	}
+	if maxSize > 0 && (g.limit < 0 || g.limit > maxSize) {
+		g.limit = maxSize
+	}
+	return g, nil
+}
+
+// readLimit is how many bytes to read from the body: one past the limit, so
+// an overrun is seen without reading the rest of the stream.
+func (g *sizeGuard) readLimit() int64 {
+	if g.limit < 0 {
+		return -1
+	}
+	return g.limit + 1
+}
+
+// check judges the n bytes received. Past the limit is an error; a smaller
+// difference from the declared size is noted.
+func (g *sizeGuard) check(n int64) error {
+	if g.limit >= 0 && n > g.limit {
+		if g.limit == currentMaxAssetSize() {
+			return fmt.Errorf("%w: %s sent more than %s; transfer aborted", errAssetTooLarge, g.name, formatSize(g.limit))
+		}
+		return fmt.Errorf("%w: %s sent more than %d bytes but declared %d; transfer aborted", errAssetSizeMismatch, g.name, g.limit, g.declared)
+	}
+	if g.declared >= 0 && n != g.declared {
+		note := fmt.Sprintf("%s: received %d bytes but %d were declared", g.name, n, g.declared)
+		if g.note != "" {
+			note = g.note + "; " + note
+		}
+		g.note = note
+	}
+	return nil
+}
+
+// extractLimitError reports that name expands past limit bytes.
+func extractLimitError(name string, limit int64) error {
+	return fmt.Errorf("%w: %s expands past %s (raise --max-extract-size to allow it)", errExtractTooLarge, name, formatSize(limit))
+}
+
+// copyExtracted copies decompressed data from src to dst, reading at most
+// one byte past --max-extract-size so an oversized stream fails without
+// being written out.
+func copyExtracted(dst io.Writer, src io.Reader, name string) error {
+	return newExtractBudget(name).copy(dst, src)
+}
+
+// extractBudget counts the bytes an archive has written out so far against
+// --max-extract-size, so the limit holds for the archive as a whole rather
+// than for each member.
+type extractBudget struct {
+	name  string
+	limit int64 // 0 when --max-extract-size is off
+	used  int64
+}
+
+func newExtractBudget(name string) *extractBudget {
+	return &extractBudget{name: name, limit: currentMaxExtractSize()}
+}
+
+// copy copies src to dst, reading at most one byte past what is left of the
+// budget.
+func (b *extractBudget) copy(dst io.Writer, src io.Reader) error {
+	if b.limit <= 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, b.limit-b.used+1))
+	b.used += n
+	if err != nil {
+		return err
+	}
+	if b.used > b.limit {
+		return extractLimitError(b.name, b.limit)
+	}
+	return nil
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
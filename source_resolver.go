@@ -0,0 +1,140 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// sourceResolverFile routes owner/repo patterns to internal GitHub mirrors,
+// so an organization can proxy every release lookup and download without
+// per-command flags. It lives next to repos.json.
+const sourceResolverFile = "sources.json"
+
+//go:embed schemas/sources.schema.json
+var sourcesSchemaJSON []byte
+
+// SourceRoute sends the repos matching Match, a glob over owner/repo such as
+// "acme/*" or "*/*", to a mirror. APIBase replaces the GitHub API base (and
+// SFETCH_API_BASE); DownloadBase replaces https://github.com in release
+// asset URLs.
+type SourceRoute struct {
+	Match        string `json:"match"`
+	APIBase      string `json:"apiBase,omitempty"`
+	DownloadBase string `json:"downloadBase,omitempty"`
+}
+
+// SourceResolver is the parsed sources.json. The first matching route wins.
+type SourceResolver struct {
+	Routes []SourceRoute `json:"routes"`
+}
+
+// resolve returns the first route whose pattern matches repo. Matching is
+// case-insensitive, like GitHub's repo names.
+func (r SourceResolver) resolve(repo string) (SourceRoute, bool) {
+	repo = strings.ToLower(repo)
+	for _, route := range r.Routes {
+		if ok, _ := path.Match(strings.ToLower(route.Match), repo); ok {
+			return route, true
+		}
+	}
+	return SourceRoute{}, false
+}
+
+// apiBase returns the API base for the route, or fallback when the route
+// only rewrites downloads.
+func (route SourceRoute) apiBase(fallback string) string {
+	if route.APIBase == "" {
+		return fallback
+	}
+	return strings.TrimRight(route.APIBase, "/")
+}
+
+// rewriteRelease points the release's GitHub download URLs at the route's
+// DownloadBase and its API URLs (source archives, asset pages) at APIBase.
+// URLs on other hosts, such as a mirror API that already rewrote them, are
+// left alone.
+func (route SourceRoute) rewriteRelease(rel *Release) {
+	rewrite := func(raw, from, to string) string {
+		if to == "" || !strings.HasPrefix(raw, from+"/") {
+			return raw
+		}
+		if u, err := mirrorURL(to, raw); err == nil {
+			return u
+		}
+		return raw
+	}
+	for i := range rel.Assets {
+		rel.Assets[i].BrowserDownloadUrl = rewrite(rel.Assets[i].BrowserDownloadUrl, defaultCDNBase, route.DownloadBase)
+	}
+	rel.TarballURL = rewrite(rel.TarballURL, defaultAPIBase, route.APIBase)
+	rel.ZipballURL = rewrite(rel.ZipballURL, defaultAPIBase, route.APIBase)
+	rel.AssetsURL = rewrite(rel.AssetsURL, defaultAPIBase, route.APIBase)
+}
+
+// loadSourceResolver reads sources.json from the user config directory. A
+// missing file means no routing. Unlike repos.json, a broken file is an
+// error rather than a warning: ignoring it would send requests the
+// organization meant to keep internal to github.com.
+func loadSourceResolver() (SourceResolver, error) {
+	p := userConfigPath(sourceResolverFile)
+	if p == "" {
+		return SourceResolver{}, nil
+	}
+	// #nosec G304 -- path is the user's own config file
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return SourceResolver{}, nil
+	}
+	if err != nil {
+		return SourceResolver{}, fmt.Errorf("read %s: %w", p, err)
+	}
+	resolver, err := parseSourceResolver(data)
+	if err != nil {
+		return SourceResolver{}, fmt.Errorf("%s: %w", p, err)
+	}
+	return resolver, nil
+}
+
+// parseSourceResolver validates data against schemas/sources.schema.json,
+// then checks each pattern and base URL.
+func parseSourceResolver(data []byte) (SourceResolver, error) {
+	schema, err := compileEmbeddedSchema("sources.schema.json", sourcesSchemaJSON)
+	if err != nil {
+		return SourceResolver{}, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return SourceResolver{}, fmt.Errorf("parse source routes: %w", err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		return SourceResolver{}, fmt.Errorf("invalid source routes: %w", err)
+	}
+	var resolver SourceResolver
+	if err := json.Unmarshal(data, &resolver); err != nil {
+		return SourceResolver{}, fmt.Errorf("parse source routes: %w", err)
+	}
+	for i, route := range resolver.Routes {
+		if _, err := path.Match(route.Match, ""); err != nil {
+			return SourceResolver{}, fmt.Errorf("route %d: invalid match %q: %w", i, route.Match, err)
+		}
+		for _, base := range []string{route.APIBase, route.DownloadBase} {
+			if base == "" {
+				continue
+			}
+			u, err := url.Parse(base)
+			if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+				return SourceResolver{}, fmt.Errorf("route %d: %q is not an absolute http(s) URL", i, base)
+			}
+			if u.RawQuery != "" || u.Fragment != "" {
+				return SourceResolver{}, fmt.Errorf("route %d: %q must not have a query or fragment", i, base)
+			}
+		}
+	}
+	return resolver, nil
+}
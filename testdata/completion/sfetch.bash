@@ -0,0 +1,29 @@
+# bash completion for sfetch (generated by sfetch --completion bash)
+_sfetch() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    COMPREPLY=()
+    case "$prev" in
+        -asset-type|--asset-type) COMPREPLY=($(compgen -W "archive raw package" -- "$cur")); return ;;
+        -cache-dir|--cache-dir) COMPREPLY=($(compgen -d -- "$cur")); return ;;
+        -channel|--channel) COMPREPLY=($(compgen -W "stable prerelease" -- "$cur")); return ;;
+        -completion|--completion) COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur")); return ;;
+        -dest-dir|--dest-dir) COMPREPLY=($(compgen -d -- "$cur")); return ;;
+        -from-lockfile|--from-lockfile) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -gpg-bin|--gpg-bin) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -libc|--libc) COMPREPLY=($(compgen -W "auto musl gnu" -- "$cur")); return ;;
+        -lockfile|--lockfile) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -log-format|--log-format) COMPREPLY=($(compgen -W "text json" -- "$cur")); return ;;
+        -log-level|--log-level) COMPREPLY=($(compgen -W "debug info warn error" -- "$cur")); return ;;
+        -minisign-key|--minisign-key) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -output|--output) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -pgp-key-file|--pgp-key-file) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -provenance-file|--provenance-file) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -provenance-format|--provenance-format) COMPREPLY=($(compgen -W "sfetch intoto slsa" -- "$cur")); return ;;
+        -repo|--repo) COMPREPLY=($(compgen -W "3leaps/sfetch BurntSushi/ripgrep" -- "$cur")); return ;;
+        -self-update-dir|--self-update-dir) COMPREPLY=($(compgen -d -- "$cur")); return ;;
+        -verify-minisign-pubkey|--verify-minisign-pubkey) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -allowed-content-types|--allowed-content-types|-arch|--arch|-asset-match|--asset-match|-asset-regex|--asset-regex|-binaries|--binaries|-binary-name|--binary-name|-checksum-file|--checksum-file|-checksum-url|--checksum-url|-deadline|--deadline|-expect-sha256|--expect-sha256|-extract-all|--extract-all|-github-raw|--github-raw|-http-proxy|--http-proxy|-https-proxy|--https-proxy|-key|--key|-keyserver|--keyserver|-max-concurrency|--max-concurrency|-max-extract-size|--max-extract-size|-max-redirects|--max-redirects|-max-size|--max-size|-minisign-comment-regex|--minisign-comment-regex|-minisign-key-asset|--minisign-key-asset|-minisign-key-url|--minisign-key-url|-mirror|--mirror|-no-proxy|--no-proxy|-on-missing-key|--on-missing-key|-pgp-key-asset|--pgp-key-asset|-pgp-key-fingerprint|--pgp-key-fingerprint|-pgp-key-url|--pgp-key-url|-pin-minisign-key|--pin-minisign-key|-pin-pgp-fingerprint|--pin-pgp-fingerprint|-proxy|--proxy|-release-file|--release-file|-sig-url|--sig-url|-source-archive|--source-archive|-tag|--tag|-timeout|--timeout|-token-env|--token-env|-trust-minimum|--trust-minimum|-trust-policy|--trust-policy|-uninstall|--uninstall|-url|--url|-verify-file|--verify-file|-verify-only|--verify-only) return ;;
+    esac
+    COMPREPLY=($(compgen -W "--all-binaries --allow-http --allow-unknown-content-type --allowed-content-types --arch --assert-version --asset-match --asset-regex --asset-type --assume-yes --backup --binaries --binary-name --cache-dir --channel --checksum-base64 --checksum-file --checksum-only --checksum-url --completion --deadline --dest-dir --diff --dry-run --expect-sha256 --extract-all --follow-redirects --force-install --from-lockfile --github-raw --gpg-bin --help-extended --helpextended --http-proxy --https-proxy --include-prerelease --insecure --install --interactive --json --key --keyserver --latest --libc --lockfile --log-format --log-level --match-url --max-concurrency --max-extract-size --max-redirects --max-size --minisign-comment-regex --minisign-key --minisign-key-asset --minisign-key-url --mirror --no-path-check --no-progress --no-proxy --offline --on-missing-key --output --pgp-key-asset --pgp-key-file --pgp-key-fingerprint --pgp-key-url --pin-minisign-key --pin-pgp-fingerprint --prefer-newer-asset --prefer-per-asset --provenance --provenance-file --provenance-format --provenance-next-to-binary --provenance-omit-legacy-trust --proxy --quiet --refresh --refresh-keys --reject-expired-keys --release-file --repo --require-minisign --require-signature --rollback --self-update --self-update-dir --self-update-force --self-verify --show-release-notes --show-trust-anchors --show-update-config --sig-over-digest --sig-url --skip-checksum --skip-sig --skip-tools-check --source-archive --strict-checksum-choice --strict-keys --tag --timeout --timings --token-env --trace-provenance --trust-minimum --trust-policy --uninstall --url --use-gh-auth --use-gpg-binary --v --validate-update-config --verbose --verify-all-signatures --verify-file --verify-minisign-pubkey --verify-only --version --version-extended --vv --y --yes" -- "$cur"))
+}
+complete -o filenames -F _sfetch sfetch
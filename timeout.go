@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultRequestTimeout is --timeout's default.
+	defaultRequestTimeout = 30 * time.Second
+	// selfVerifyLookupTimeout bounds --self-verify's optional SHA256SUMS
+	// lookup unless --timeout is given, so offline machines answer quickly.
+	selfVerifyLookupTimeout = 2 * time.Second
+)
+
+// idleTimeoutTransport enforces --timeout per request: a request fails when
+// no response arrives within timeout, or when the body stalls for timeout
+// between reads. A slow but steady download is never cut off; --deadline
+// bounds the whole run instead. A zero timeout disables the timer, but
+// errors still name the flag that cancelled the request.
+type idleTimeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	var timer *time.Timer
+	if t.timeout > 0 {
+		cause := fmt.Errorf("no data from %s for %s (--timeout): %w", req.URL.Host, t.timeout, context.DeadlineExceeded)
+		timer = time.AfterFunc(t.timeout, func() { cancel(cause) })
+	}
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		stopped := ctx.Err() != nil
+		if timer != nil {
+			timer.Stop()
+		}
+		cause := context.Cause(ctx)
+		cancel(nil)
+		if stopped {
+			return nil, cause
+		}
+		return nil, err
+	}
+	resp.Body = &idleTimeoutBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel, timer: timer, timeout: t.timeout}
+	return resp, nil
+}
+
+// idleTimeoutBody restarts the --timeout timer after every read and reports
+// why the request was cancelled instead of a bare "context canceled".
+type idleTimeoutBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && b.timer != nil {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && err != io.EOF && b.ctx.Err() != nil {
+		err = context.Cause(b.ctx)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
+
+// withDeadline bounds ctx by --deadline; d <= 0 leaves it unbounded.
+// Requests cut off by it fail with an error naming the flag.
+func withDeadline(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, d, fmt.Errorf("--deadline %s exceeded: %w", d, context.DeadlineExceeded))
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// timingStep is a stage of a release fetch measured by --timings.
+type timingStep int
+
+const (
+	stepDownload  timingStep = iota // asset, checksum files, signatures, release keys
+	stepHash                        // digesting the asset and checking it against the checksum file
+	stepSignature                   // key resolution and signature verification
+	stepExtract                     // extracting and installing the binaries
+	timingSteps
+)
+
+// stepTimings accumulates the time spent in each step. A nil *stepTimings
+// (no --timings) records nothing.
+type stepTimings [timingSteps]time.Duration
+
+// track starts timing step; call the returned func when the step ends.
+func (t *stepTimings) track(step timingStep) func() {
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { t[step] += time.Since(start) }
+}
+
+// Timings is the --timings breakdown reported under "timings" in --json
+// output and provenance records, in milliseconds. A step that did not run
+// reports 0.
+type Timings struct {
+	DownloadMs  float64 `json:"downloadMs"`
+	HashMs      float64 `json:"hashMs"`
+	SignatureMs float64 `json:"signatureMs"`
+	ExtractMs   float64 `json:"extractMs"`
+}
+
+// result converts t for output; nil without --timings.
+func (t *stepTimings) result() *Timings {
+	if t == nil {
+		return nil
+	}
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	return &Timings{
+		DownloadMs:  ms(t[stepDownload]),
+		HashMs:      ms(t[stepHash]),
+		SignatureMs: ms(t[stepSignature]),
+		ExtractMs:   ms(t[stepExtract]),
+	}
+}
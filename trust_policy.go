@@ -0,0 +1,119 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+//go:embed schemas/trust-policy.schema.json
+var trustPolicySchemaJSON []byte
+
+// TrustPolicy sets the points each trust factor earns and the scores at
+// which trust levels begin. --trust-policy loads one from JSON; fields the
+// file omits keep their defaultTrustPolicy values.
+type TrustPolicy struct {
+	Points     TrustPolicyPoints     `json:"points"`
+	Thresholds TrustPolicyThresholds `json:"thresholds"`
+}
+
+// TrustPolicyPoints are the per-factor points of computeTrustScore.
+// Penalties are negative.
+type TrustPolicyPoints struct {
+	Signature            int `json:"signature"`
+	SignatureMultiFormat int `json:"signatureMultiFormat"`
+	SignatureSkipped     int `json:"signatureSkipped"`
+	Checksum             int `json:"checksum"`
+	ChecksumSkipped      int `json:"checksumSkipped"`
+	HTTPS                int `json:"https"`
+	StrongAlgorithm      int `json:"strongAlgorithm"`
+	WeakAlgorithm        int `json:"weakAlgorithm"`
+	SourceDigest         int `json:"sourceDigest"`
+}
+
+// TrustPolicyThresholds are the lowest scores of the minimal-and-above
+// levels; a score of 0 is always bypassed.
+type TrustPolicyThresholds struct {
+	Low    int `json:"low"`
+	Medium int `json:"medium"`
+	High   int `json:"high"`
+}
+
+// defaultTrustPolicy is the built-in policy; without --trust-policy scores
+// are computed with it.
+var defaultTrustPolicy = TrustPolicy{
+	Points: TrustPolicyPoints{
+		Signature:            70,
+		SignatureMultiFormat: 5,
+		SignatureSkipped:     -20,
+		Checksum:             40,
+		ChecksumSkipped:      -15,
+		HTTPS:                25,
+		StrongAlgorithm:      5,
+		WeakAlgorithm:        -10,
+		SourceDigest:         10,
+	},
+	Thresholds: TrustPolicyThresholds{
+		Low:    30,
+		Medium: 60,
+		High:   85,
+	},
+}
+
+// level maps score onto a trust level using p's thresholds.
+func (p TrustPolicy) level(score int) TrustLevel {
+	switch {
+	case score <= 0:
+		return TrustBypassed
+	case score < p.Thresholds.Low:
+		return TrustMinimal
+	case score < p.Thresholds.Medium:
+		return TrustLow
+	case score < p.Thresholds.High:
+		return TrustMedium
+	default:
+		return TrustHigh
+	}
+}
+
+// validate checks what the schema cannot: thresholds must rise strictly
+// from low to high.
+func (p TrustPolicy) validate() error {
+	t := p.Thresholds
+	if t.Low >= t.Medium || t.Medium >= t.High {
+		return fmt.Errorf("thresholds must increase: low %d < medium %d < high %d", t.Low, t.Medium, t.High)
+	}
+	return nil
+}
+
+func loadTrustPolicy(path string) (TrustPolicy, error) {
+	// #nosec G304 -- path is the policy named on the command line
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TrustPolicy{}, fmt.Errorf("read trust policy: %w", err)
+	}
+	return parseTrustPolicy(path, data)
+}
+
+func parseTrustPolicy(path string, data []byte) (TrustPolicy, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return TrustPolicy{}, fmt.Errorf("parse trust policy %s: %w", path, err)
+	}
+	schema, err := compileEmbeddedSchema("trust-policy.schema.json", trustPolicySchemaJSON)
+	if err != nil {
+		return TrustPolicy{}, err
+	}
+	if err := schema.Validate(doc); err != nil {
+		return TrustPolicy{}, fmt.Errorf("invalid trust policy %s: %w", path, err)
+	}
+	policy := defaultTrustPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return TrustPolicy{}, fmt.Errorf("parse trust policy %s: %w", path, err)
+	}
+	if err := policy.validate(); err != nil {
+		return TrustPolicy{}, fmt.Errorf("invalid trust policy %s: %w", path, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/3leaps/sfetch/pkg/update"
+)
+
+const (
+	// updateCheckEnv disables the "newer sfetch available" note when set
+	// to any non-empty value.
+	updateCheckEnv = "SFETCH_NO_UPDATE_CHECK"
+	// updateCheckStateFile, in the cache directory, remembers when sfetch
+	// last looked for a release and which one it last announced.
+	updateCheckStateFile = "update-check.json"
+	// updateCheckTimeout bounds the release lookup so an offline machine
+	// does not stall a run that already succeeded.
+	updateCheckTimeout = 2 * time.Second
+)
+
+// notifyUpdateAvailable prints a one-line note when a release newer than
+// the running sfetch exists. It looks at most once per
+// update.DefaultCheckInterval and announces each release once; failures are
+// silent (debug log only) and still count as a check. Dev builds never
+// check.
+func notifyUpdateAvailable(ctx context.Context, cacheDir string, w io.Writer) {
+	if os.Getenv(updateCheckEnv) != "" {
+		return
+	}
+	if _, ok := update.NormalizeVersion(version); !ok {
+		return
+	}
+	path := filepath.Join(cacheDir, updateCheckStateFile)
+	state, err := update.LoadCheckState(path)
+	if err != nil {
+		debugf("update check: %v; starting over", err)
+		state = update.CheckState{}
+	}
+	now := time.Now()
+	if !update.CheckDue(state, now, update.DefaultCheckInterval) {
+		return
+	}
+
+	latest := ""
+	if ucfg, err := loadEmbeddedUpdateTarget(); err == nil {
+		lookupCtx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+		defer cancel()
+		url := fmt.Sprintf("%s/repos/%s/releases/latest", apiBaseURLWithDefault(ucfg.Source.APIBase), ucfg.Repo.ID)
+		if rel, err := fetchRelease(lookupCtx, url); err == nil {
+			latest = rel.TagName
+		} else {
+			debugf("update check: %v", err)
+		}
+	}
+
+	res := update.DecideUpdateCheck(version, latest, state, now, update.DefaultCheckInterval)
+	if err := update.SaveCheckState(path, res.State); err != nil {
+		debugf("update check: %v", err)
+	}
+	if res.Notify {
+		_, _ = fmt.Fprintf(w, "note: sfetch %s is available (current %s); run sfetch --self-update to update, or set %s=1 to silence this\n", res.Latest, res.Current, updateCheckEnv) //nolint:errcheck
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// urlSidecarSuffixes are the verification files looked for next to a --url
+// download, by convention <url>.sha256, <url>.minisig and <url>.asc. A
+// minisign signature is preferred when both are published.
+var urlSidecarSuffixes = []struct {
+	suffix string
+	format string // signature format; "" for the checksum
+}{
+	{".sha256", ""},
+	{".minisig", sigFormatMinisign},
+	{".asc", sigFormatPGP},
+}
+
+// urlSidecarFiles are the sidecars found for a --url download, as local
+// paths. Empty fields mean the sidecar was not published, not reachable,
+// or not wanted (--skip-sig, --skip-checksum).
+type urlSidecarFiles struct {
+	checksum  string
+	signature string
+	sigFormat string
+}
+
+// sidecarURL appends suffix to the path of target, keeping any query.
+func sidecarURL(target, suffix string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	u.Path += suffix
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// fetchURLSidecars downloads the sidecars of target into tmpDir, best effort:
+// a missing or unreachable sidecar is reported as absent, never as an error.
+// Sidecars get the same https and redirect rules as the download itself, but
+// any content type, since servers label signatures inconsistently.
+func fetchURLSidecars(ctx context.Context, target, assetName string, opts urlFetchOptions, tmpDir string, skipSig, skipChecksum bool) urlSidecarFiles {
+	opts.allowUnknownContentType = true
+
+	var found urlSidecarFiles
+	for _, sc := range urlSidecarSuffixes {
+		isChecksum := sc.format == ""
+		if (isChecksum && skipChecksum) || (!isChecksum && (skipSig || found.signature != "")) {
+			continue
+		}
+		src, err := sidecarURL(target, sc.suffix)
+		if err != nil {
+			continue
+		}
+		dest := filepath.Join(tmpDir, assetName+sc.suffix)
+		if _, err := downloadURL(ctx, src, dest, opts); err != nil {
+			_ = os.Remove(dest)
+			continue
+		}
+		if isChecksum {
+			found.checksum = dest
+		} else {
+			found.signature = dest
+			found.sigFormat = sc.format
+		}
+	}
+	return found
+}
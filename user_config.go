@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// userRepoConfigFile holds per-repo RepoConfig overrides, keyed by
+// owner/repo. Precedence: CLI flags > user repo config > compiled-in
+// repoConfigs > inferred defaults.
+const userRepoConfigFile = "repos.json"
+
+//go:embed schemas/repo-config.schema.json
+var repoConfigSchemaJSON []byte
+
+// userConfigPath returns the location of name under $XDG_CONFIG_HOME/sfetch
+// (or ~/.config/sfetch), or "" when no config directory can be determined.
+func userConfigPath(name string) string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "sfetch", name)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".config", "sfetch", name)
+}
+
+// compileEmbeddedSchema compiles one of the schemas embedded from schemas/.
+func compileEmbeddedSchema(name string, data []byte) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(name, doc); err != nil {
+		return nil, fmt.Errorf("load %s: %w", name, err)
+	}
+	schema, err := c.Compile(name)
+	if err != nil {
+		return nil, fmt.Errorf("compile %s: %w", name, err)
+	}
+	return schema, nil
+}
+
+var (
+	userRepoConfigsOnce     sync.Once
+	userRepoConfigs         map[string]RepoConfig
+	userRepoConfigsWarnings []string
+)
+
+// loadUserRepoConfigs reads the user's repos.json once. Invalid entries are
+// skipped and reported through loadUserRepoConfigsWarnings.
+func loadUserRepoConfigs() map[string]RepoConfig {
+	userRepoConfigsOnce.Do(func() {
+		path := userConfigPath(userRepoConfigFile)
+		if path == "" {
+			return
+		}
+		// #nosec G304 -- path is the user's own config file
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		if err != nil {
+			userRepoConfigsWarnings = append(userRepoConfigsWarnings, fmt.Sprintf("ignoring repo config: read %s: %v", path, err))
+			return
+		}
+		configs, problems, err := parseUserRepoConfigs(data)
+		if err != nil {
+			userRepoConfigsWarnings = append(userRepoConfigsWarnings, fmt.Sprintf("ignoring repo config %s: %v", path, err))
+			return
+		}
+		for _, p := range problems {
+			userRepoConfigsWarnings = append(userRepoConfigsWarnings, fmt.Sprintf("%s: skipping %s", path, p))
+		}
+		userRepoConfigs = configs
+	})
+	return userRepoConfigs
+}
+
+// loadUserRepoConfigsWarnings loads repos.json if needed and returns any
+// problems found in it.
+func loadUserRepoConfigsWarnings() []string {
+	loadUserRepoConfigs()
+	return userRepoConfigsWarnings
+}
+
+// parseUserRepoConfigs accepts either a map keyed by owner/repo or an array
+// of RepoConfig objects that each carry a "repo" field. Each entry is
+// validated against schemas/repo-config.schema.json; invalid entries are
+// returned as problems and left out. Keys are lowercased, matching GitHub's
+// case-insensitive repo names.
+func parseUserRepoConfigs(data []byte) (map[string]RepoConfig, []string, error) {
+	entries := map[string]map[string]interface{}{}
+	var problems []string
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var list []map[string]interface{}
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, nil, fmt.Errorf("parse repo config: %w", err)
+		}
+		for i, entry := range list {
+			repo, _ := entry["repo"].(string)
+			if repo == "" {
+				problems = append(problems, fmt.Sprintf("entry %d: missing \"repo\"", i))
+				continue
+			}
+			delete(entry, "repo")
+			entries[repo] = entry
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, nil, fmt.Errorf("parse repo config: %w", err)
+		}
+	default:
+		return nil, nil, fmt.Errorf("parse repo config: expected a JSON object or array")
+	}
+
+	schema, err := compileEmbeddedSchema("repo-config.schema.json", repoConfigSchemaJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repos := make([]string, 0, len(entries))
+	for repo := range entries {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	configs := make(map[string]RepoConfig, len(entries))
+	for _, repo := range repos {
+		if strings.Count(repo, "/") != 1 || strings.HasPrefix(repo, "/") || strings.HasSuffix(repo, "/") {
+			problems = append(problems, fmt.Sprintf("%q: expected owner/repo", repo))
+			continue
+		}
+		entry := entries[repo]
+		if err := schema.Validate(entry); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", repo, err))
+			continue
+		}
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", repo, err))
+			continue
+		}
+		var cfg RepoConfig
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", repo, err))
+			continue
+		}
+		configs[strings.ToLower(repo)] = cfg
+	}
+	return configs, problems, nil
+}
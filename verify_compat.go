@@ -2,10 +2,14 @@ package main
 
 import (
 	"fmt"
+	"hash"
 
 	"github.com/3leaps/sfetch/internal/verify"
 )
 
+// errChecksumNotListed marks a checksum file without an entry for the asset.
+var errChecksumNotListed = verify.ErrChecksumNotListed
+
 func detectChecksumType(filename string) string {
 	return verify.DetectChecksumType(filename)
 }
@@ -14,6 +18,14 @@ func detectChecksumAlgorithm(filename, defaultAlgo string) string {
 	return verify.DetectChecksumAlgorithm(filename, defaultAlgo)
 }
 
+func weakChecksumAlgorithm(algo string) bool {
+	return verify.WeakAlgorithm(algo)
+}
+
+func newChecksumHash(algo string) (hash.Hash, error) {
+	return verify.NewHash(algo)
+}
+
 func formatSize(bytes int64) string {
 	return verify.FormatSize(bytes)
 }
@@ -22,6 +34,10 @@ func findChecksumSignature(assets []Asset, cfg *RepoConfig) (*Asset, string) {
 	return verify.FindChecksumSignature(assets, cfg)
 }
 
+func findChecksumSignatures(assets []Asset, cfg *RepoConfig) []verify.ChecksumSignature {
+	return verify.FindChecksumSignatures(assets, cfg)
+}
+
 func signatureFormatFromExtension(filename string, formats SignatureFormats) string {
 	switch verify.SignatureFormatFromExtension(filename, formats) {
 	case verify.FormatBinary:
@@ -39,6 +55,14 @@ func extractChecksum(data []byte, algo, assetName string) (string, error) {
 	return verify.ExtractChecksum(data, algo, assetName)
 }
 
+func extractChecksumAllowBase64(data []byte, algo, assetName string, allowBase64 bool) (string, error) {
+	return verify.ExtractChecksumWithOptions(data, algo, assetName, verify.ChecksumOptions{AllowBase64: allowBase64})
+}
+
+func checksumAlgorithmFromContent(data []byte, algo, assetName string, allowBase64 bool) string {
+	return verify.ChecksumAlgorithmFromContent(data, algo, assetName, verify.ChecksumOptions{AllowBase64: allowBase64})
+}
+
 func normalizeHexKey(input string) (string, error) {
 	return verify.NormalizeHexKey(input)
 }
@@ -60,10 +84,26 @@ func loadSignature(path string) (signatureData, error) {
 	}
 }
 
-func verifyMinisignSignature(contentToVerify []byte, sigPath, pubKeyPath string) error {
-	return verify.VerifyMinisignSignature(contentToVerify, sigPath, pubKeyPath)
+type minisignOptions = verify.MinisignOptions
+
+type pgpOptions = verify.PGPOptions
+
+func verifyMinisignSignature(contentToVerify []byte, sigPath, pubKeyPath string, opts minisignOptions) (string, error) {
+	return verify.VerifyMinisignSignature(contentToVerify, sigPath, pubKeyPath, opts)
+}
+
+func verifyMinisignSignatureFile(path, sigPath, pubKeyPath string, opts minisignOptions) (string, error) {
+	return verify.VerifyMinisignSignatureFile(path, sigPath, pubKeyPath, opts)
+}
+
+func verifyPGPSignature(assetPath, sigPath, pubKeyPath string, opts pgpOptions) ([]string, error) {
+	return verify.VerifyPGPSignature(assetPath, sigPath, pubKeyPath, opts)
+}
+
+func normalizePGPFingerprint(raw string) (string, error) {
+	return verify.NormalizePGPFingerprint(raw)
 }
 
-func verifyPGPSignature(assetPath, sigPath, pubKeyPath, gpgBin string) error {
-	return verify.VerifyPGPSignature(assetPath, sigPath, pubKeyPath, gpgBin)
+func pgpKeyFingerprints(keyData []byte) ([]string, error) {
+	return verify.PGPKeyFingerprints(keyData)
 }
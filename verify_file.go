@@ -0,0 +1,532 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileVerification describes how verifyFile checks one local file against a
+// release: the assessed workflow, the keys, and the flags that relax or
+// tighten it. Supplemental artifacts (checksums, signatures, keys) are
+// fetched into tmpDir when they are not there already.
+type fileVerification struct {
+	assets     []Asset
+	assessment *VerificationAssessment
+	keys       signatureKeyOptions
+	assetName  string // name the checksum file lists the file under
+	tmpDir     string
+
+	hashAlgo       string // cache hash when no checksum file picks one
+	checksumBase64 bool
+	expectedSHA256 string // --expect-sha256 or lockfile digest; "" to skip
+	expectedSource string // named in the mismatch error
+	ed25519Key     string // --key, for raw ed25519 signatures
+
+	skipSig          bool
+	skipChecksum     bool
+	sigOverDigest    bool
+	requireSignature bool
+	onMissingKey     string       // --on-missing-key
+	trustPolicy      TrustPolicy  // rescores a fallback to checksum-only
+	timings          *stepTimings // --timings; nil when off
+}
+
+// fileVerificationResult is what verifyFile learned about the file.
+type fileVerificationResult struct {
+	hash               string // digest under the checksum (or cache) algorithm
+	sha256             string
+	signatureVerified  bool
+	fellBackToChecksum bool // --on-missing-key fallback-checksum dropped the signature
+}
+
+// --on-missing-key policies for a signature whose key cannot be obtained.
+const (
+	onMissingKeyFail             = "fail"
+	onMissingKeyFallbackChecksum = "fallback-checksum"
+)
+
+// keyResolutionError is a failure to obtain the key for a signature, as
+// opposed to a signature that does not verify. It is what
+// --on-missing-key fallback-checksum tolerates.
+type keyResolutionError struct {
+	format string
+	err    error
+}
+
+func (e *keyResolutionError) Error() string {
+	return fmt.Sprintf("error: %s signature verification blocked: no usable key: %s",
+		signatureFormatLabel(e.format), strings.TrimPrefix(e.err.Error(), "error: "))
+}
+
+func (e *keyResolutionError) Unwrap() error { return e.err }
+
+// verifyFile runs the assessed workflow against the file at assetPath, which
+// may be anywhere on disk. It prints progress and failures to logs and
+// returns the exit code: exitChecksum for a digest mismatch, exitSignature
+// for a signature that does not verify, exitOK when every check passed.
+// Key source and --sig-over-digest findings are recorded on in.assessment.
+func verifyFile(ctx context.Context, assetPath string, in fileVerification, logs outputLog, hints *hintSink) (fileVerificationResult, int) {
+	var res fileVerificationResult
+	var err error
+	assessment := in.assessment
+	keys := in.keys
+	tmpDir := in.tmpDir
+
+	var sigAsset *Asset
+	var sigPath string
+	var checksumPath string
+	var checksumBytes []byte
+
+	// Execute verification based on assessed workflow
+	switch assessment.Workflow {
+	case workflowNone:
+		// No verification artifacts - just download and proceed.
+		_, _ = fmt.Fprintln(logs.Info, "Note: no verification artifacts provided by the source") //nolint:errcheck
+
+	case workflowInsecure:
+		// Verification bypass - just download and proceed.
+		_, _ = fmt.Fprintln(logs.Error, "WARNING: verification bypass enabled (--insecure)") //nolint:errcheck
+
+	case workflowA:
+		// Workflow A: Verify signature over checksum file, then verify hash
+		_, _ = fmt.Fprintf(logs.Info, "Detected checksum-level signature: %s\n", assessment.SignatureFile) //nolint:errcheck
+
+		// Find and download the checksum file
+		checksumAsset := findAssetByName(in.assets, assessment.ChecksumFileForSig)
+		if checksumAsset == nil {
+			_, _ = fmt.Fprintf(logs.Error, "error: checksum file %s not found\n", assessment.ChecksumFileForSig) //nolint:errcheck
+			return res, exitGeneric
+		}
+
+		checksumPath = filepath.Join(tmpDir, checksumAsset.Name)
+		if err := downloadIfMissing(ctx, checksumAsset, checksumPath); err != nil {
+			_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+			return res, exitNetwork
+		}
+
+		// Download checksum signature
+		sigAsset = findAssetByName(in.assets, assessment.SignatureFile)
+		sigPath = filepath.Join(tmpDir, sigAsset.Name)
+		if err := downloadIfMissing(ctx, sigAsset, sigPath); err != nil {
+			_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+			return res, exitNetwork
+		}
+
+		// Read checksum file for verification
+		// #nosec G304 -- SDR-001: temp checksum path
+		checksumBytes, err = os.ReadFile(checksumPath)
+		if err != nil {
+			_, _ = fmt.Fprintf(logs.Error, "read checksum: %v\n", err) //nolint:errcheck
+			return res, exitInstall
+		}
+
+		// Verify checksum file signature (not asset signature)
+		doneSig := in.timings.track(stepSignature)
+		if !in.skipSig && len(assessment.SignatureChecks) > 1 {
+			if err := verifyAllChecksumSignatures(ctx, assessment.SignatureChecks, in.assets, checksumPath, keys, tmpDir, logs); err != nil {
+				_, _ = fmt.Fprintf(logs.Error, "error: %v\n", err) //nolint:errcheck
+				return res, exitSignature
+			}
+			res.signatureVerified = true
+		} else if !in.skipSig {
+			sigWarnings, trustedComment, err := verifyChecksumSignature(ctx, assessment.SignatureFormat, checksumPath, sigPath, keys, in.assets, tmpDir)
+			switch {
+			case err != nil && in.fallBackToChecksum(err, true, logs):
+				res.fellBackToChecksum = true
+			case err != nil:
+				_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+				hints.emitForError(err)
+				return res, exitSignature
+			default:
+				for _, w := range sigWarnings {
+					_, _ = fmt.Fprintf(logs.Warn, "warning: %s\n", w) //nolint:errcheck
+				}
+				_, _ = fmt.Fprintf(logs.Info, "%s checksum signature verified OK\n", signatureFormatLabel(assessment.SignatureFormat)) //nolint:errcheck
+				res.signatureVerified = true
+				if trustedComment != "" {
+					_, _ = fmt.Fprintf(logs.Info, "Trusted comment: %s\n", trustedComment) //nolint:errcheck
+				}
+			}
+		}
+		doneSig()
+		if !in.skipSig && !res.fellBackToChecksum {
+			assessment.KeySource, assessment.KeyPin = keys.provenanceKey(assessment.SignatureFormat)
+			keys.logKeySource(logs.Verbose, assessment.SignatureFormat)
+		}
+
+	case workflowB:
+		// Workflow B: Per-asset signature
+		sigAsset = findAssetByName(in.assets, assessment.SignatureFile)
+		if sigAsset == nil {
+			_, _ = fmt.Fprintf(logs.Error, "error: signature file %s not found\n", assessment.SignatureFile) //nolint:errcheck
+			return res, exitGeneric
+		}
+
+		sigPath = filepath.Join(tmpDir, sigAsset.Name)
+		if err := downloadIfMissing(ctx, sigAsset, sigPath); err != nil {
+			_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+			return res, exitNetwork
+		}
+
+		// Load checksum file if available
+		if assessment.ChecksumAvailable && !in.skipChecksum {
+			checksumAsset := findAssetByName(in.assets, assessment.ChecksumFile)
+			if checksumAsset != nil {
+				checksumPath = filepath.Join(tmpDir, checksumAsset.Name)
+				if err := downloadIfMissing(ctx, checksumAsset, checksumPath); err != nil {
+					_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+					return res, exitNetwork
+				}
+				// #nosec G304 -- SDR-001: temp checksum path
+				checksumBytes, err = os.ReadFile(checksumPath)
+				if err != nil {
+					_, _ = fmt.Fprintf(logs.Error, "read checksum: %v\n", err) //nolint:errcheck
+					return res, exitInstall
+				}
+			}
+		}
+
+	case workflowC:
+		// Workflow C: Checksum-only (no signature)
+		_, _ = fmt.Fprintf(logs.Info, "Using checksum-only verification (no signature available)\n") //nolint:errcheck
+
+		checksumAsset := findAssetByName(in.assets, assessment.ChecksumFile)
+		if checksumAsset == nil {
+			_, _ = fmt.Fprintf(logs.Error, "error: checksum file %s not found\n", assessment.ChecksumFile) //nolint:errcheck
+			return res, exitGeneric
+		}
+
+		checksumPath = filepath.Join(tmpDir, checksumAsset.Name)
+		if err := downloadIfMissing(ctx, checksumAsset, checksumPath); err != nil {
+			_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+			return res, exitNetwork
+		}
+
+		// #nosec G304 -- SDR-001: temp checksum path
+		checksumBytes, err = os.ReadFile(checksumPath)
+		if err != nil {
+			_, _ = fmt.Fprintf(logs.Error, "read checksum: %v\n", err) //nolint:errcheck
+			return res, exitInstall
+		}
+	}
+
+	doneHash := in.timings.track(stepHash)
+	// Compute hash for caching (and verification if checksum file exists)
+	hashAlgo := in.hashAlgo
+	if checksumBytes != nil && assessment.ChecksumAlgorithm != "" {
+		hashAlgo = assessment.ChecksumAlgorithm
+		// The checksum file decides the algorithm. When its name does not
+		// say (checksums.txt), the entry for the asset does.
+		if detectChecksumAlgorithm(assessment.ChecksumFile, "") == "" {
+			hashAlgo = checksumAlgorithmFromContent(checksumBytes, hashAlgo, in.assetName, in.checksumBase64)
+			if hashAlgo != assessment.ChecksumAlgorithm {
+				_, _ = fmt.Fprintf(logs.Verbose, "%s lists a %s digest for %s\n", assessment.ChecksumFile, hashAlgo, in.assetName) //nolint:errcheck
+				assessment.setChecksumAlgorithm(hashAlgo)
+			}
+		}
+	}
+	actualHash, assetSHA256, _, err := hashFile(assetPath, hashAlgo)
+	if err != nil {
+		_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+		return res, exitInstall
+	}
+	res.hash, res.sha256 = actualHash, assetSHA256
+	// The digest GitHub lists for the asset is checked whatever the
+	// workflow, including none. Downloads already compare it; this also
+	// covers --verify-only files and cached copies.
+	if listed := listedSHA256(assessment.SelectedAsset); listed != "" {
+		if assetSHA256 != listed {
+			_, _ = fmt.Fprintf(logs.Error, "sha256 mismatch: the release API lists %s for %s, got %s\n", listed, assessment.SelectedAsset.Name, assetSHA256) //nolint:errcheck
+			return res, exitChecksum
+		}
+		assessment.Trust.Factors.SourceDigest.Matched = true
+		_, _ = fmt.Fprintln(logs.Verbose, "SHA-256 matches the digest listed by the release API") //nolint:errcheck
+	}
+	if in.expectedSHA256 != "" && assetSHA256 != in.expectedSHA256 {
+		_, _ = fmt.Fprintf(logs.Error, "sha256 mismatch: expected %s, got %s (%s)\n", in.expectedSHA256, assetSHA256, in.expectedSource) //nolint:errcheck
+		return res, exitChecksum
+	}
+
+	// Verify checksum if checksum file was found
+	if checksumBytes != nil {
+		expectedHash, err := extractChecksumAllowBase64(checksumBytes, hashAlgo, in.assetName, in.checksumBase64)
+		if err != nil {
+			if assessment.Workflow == workflowA && errors.Is(err, errChecksumNotListed) {
+				// The signature is good, so the manifest is the publisher's;
+				// it just does not vouch for this file. A swapped or injected
+				// asset looks exactly like this.
+				_, _ = fmt.Fprintf(logs.Error, "error: signed checksum file %s does not cover %s; the signature verified, but nothing it signs vouches for this asset (possible tampering)\n", assessment.ChecksumFileForSig, in.assetName) //nolint:errcheck
+				return res, exitChecksum
+			}
+			_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+			return res, exitChecksum
+		}
+		if actualHash != strings.ToLower(expectedHash) {
+			_, _ = fmt.Fprintf(logs.Error, "checksum mismatch: expected %s, got %s\n", expectedHash, actualHash) //nolint:errcheck
+			return res, exitChecksum
+		}
+		_, _ = fmt.Fprintln(logs.Verbose, "Checksum verified OK") //nolint:errcheck
+	}
+	doneHash()
+
+	// Workflow B: Verify per-asset signature
+	if assessment.Workflow == workflowB && !in.skipSig {
+		doneSig := in.timings.track(stepSignature)
+		sigData, err := loadSignature(sigPath)
+		if err != nil {
+			_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+			return res, exitSignature
+		}
+
+		// Under --sig-over-digest a signature that fails over the asset bytes
+		// is retried over the asset's SHA-256 hex digest.
+		overDigest := false
+		verifiedOK := func(label string) {
+			if overDigest {
+				label += " (over the asset's SHA-256 digest)"
+			}
+			_, _ = fmt.Fprintln(logs.Info, label) //nolint:errcheck
+			res.signatureVerified = true
+		}
+
+		switch sigData.format {
+		case sigFormatPGP:
+			var pgpWarnings []string
+			err := keys.withKey(ctx, sigFormatPGP, keys.pgpKeyPath, in.assets, tmpDir, func(pgpKeyPath string) error {
+				var err error
+				pgpWarnings, err = verifyPGPSignature(assetPath, sigPath, pgpKeyPath, keys.pgpOptions())
+				if err != nil && in.sigOverDigest {
+					subjectPath := filepath.Join(tmpDir, in.assetName+".sha256-digest")
+					overDigest = verifyOverDigest(assetSHA256, func(subject []byte) error {
+						if err := os.WriteFile(subjectPath, subject, 0o600); err != nil {
+							return err
+						}
+						w, err := verifyPGPSignature(subjectPath, sigPath, pgpKeyPath, keys.pgpOptions())
+						pgpWarnings = w
+						return err
+					})
+					if overDigest {
+						err = nil
+					}
+				}
+				return err
+			})
+			var keyErr *keyResolutionError
+			if errors.As(err, &keyErr) {
+				if in.fallBackToChecksum(err, checksumBytes != nil, logs) {
+					res.fellBackToChecksum = true
+					break
+				}
+				_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+				hints.emitForError(err)
+				return res, exitSignature
+			}
+			if err != nil {
+				_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+				return res, exitSignature
+			}
+			for _, w := range pgpWarnings {
+				_, _ = fmt.Fprintf(logs.Warn, "warning: %s\n", w) //nolint:errcheck
+			}
+			verifiedOK("PGP signature verified OK")
+			assessment.KeySource, assessment.KeyPin = keys.provenanceKey(sigFormatPGP)
+			keys.logKeySource(logs.Verbose, sigFormatPGP)
+
+		case sigFormatMinisign:
+			var trustedComment string
+			err := keys.withKey(ctx, sigFormatMinisign, keys.minisignKeyPath, in.assets, tmpDir, func(minisignKeyPath string) error {
+				var err error
+				trustedComment, err = verifyMinisignSignatureFile(assetPath, sigPath, minisignKeyPath, keys.minisignOptions())
+				if err != nil && in.sigOverDigest {
+					overDigest = verifyOverDigest(assetSHA256, func(subject []byte) error {
+						c, err := verifyMinisignSignature(subject, sigPath, minisignKeyPath, keys.minisignOptions())
+						trustedComment = c
+						return err
+					})
+					if overDigest {
+						err = nil
+					}
+				}
+				return err
+			})
+			var keyErr *keyResolutionError
+			if errors.As(err, &keyErr) {
+				if in.fallBackToChecksum(err, checksumBytes != nil, logs) {
+					res.fellBackToChecksum = true
+					break
+				}
+				_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+				hints.emitForError(err)
+				return res, exitSignature
+			}
+			if err != nil {
+				_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+				return res, exitSignature
+			}
+			verifiedOK("Minisign signature verified OK")
+			assessment.KeySource, assessment.KeyPin = keys.provenanceKey(sigFormatMinisign)
+			keys.logKeySource(logs.Verbose, sigFormatMinisign)
+			if trustedComment != "" {
+				_, _ = fmt.Fprintf(logs.Info, "Trusted comment: %s\n", trustedComment) //nolint:errcheck
+			}
+
+		case sigFormatBinary:
+			normalizedKey, err := normalizeHexKey(in.ed25519Key)
+			if err != nil {
+				_, _ = fmt.Fprintln(logs.Error, err) //nolint:errcheck
+				return res, exitUsage
+			}
+			pubKeyBytes, err := hex.DecodeString(normalizedKey)
+			if err != nil {
+				_, _ = fmt.Fprintln(logs.Error, "invalid ed25519 key provided") //nolint:errcheck
+				return res, exitUsage
+			}
+			if len(pubKeyBytes) != ed25519.PublicKeySize {
+				_, _ = fmt.Fprintf(logs.Error, "invalid pubkey size: %d\n", len(pubKeyBytes)) //nolint:errcheck
+				return res, exitUsage
+			}
+			pub := ed25519.PublicKey(pubKeyBytes)
+			// Raw ed25519 signs the whole message; there is no prehashed
+			// form, so this is the one path that loads the asset.
+			// #nosec G304 -- SDR-001: asset path chosen by sfetch or --verify-only
+			assetBytes, err := os.ReadFile(assetPath)
+			if err != nil {
+				_, _ = fmt.Fprintf(logs.Error, "read asset: %v\n", err) //nolint:errcheck
+				return res, exitInstall
+			}
+			if !ed25519.Verify(pub, assetBytes, sigData.bytes) {
+				if in.sigOverDigest {
+					overDigest = verifyOverDigest(assetSHA256, func(subject []byte) error {
+						if !ed25519.Verify(pub, subject, sigData.bytes) {
+							return errors.New("signature verification failed")
+						}
+						return nil
+					})
+				}
+				if !overDigest {
+					_, _ = fmt.Fprintln(logs.Error, "signature verification failed") //nolint:errcheck
+					return res, exitSignature
+				}
+			}
+			verifiedOK("Signature verified OK")
+
+		default:
+			_, _ = fmt.Fprintln(logs.Error, "error: unsupported signature format") //nolint:errcheck
+			return res, exitSignature
+		}
+
+		doneSig()
+		if overDigest {
+			assessment.SignatureOverDigest = true
+			_, _ = fmt.Fprintln(logs.Warn, "warning: the signature covers the asset's SHA-256 digest string, not its bytes (--sig-over-digest)") //nolint:errcheck
+		}
+	}
+	if in.requireSignature && !res.signatureVerified {
+		_, _ = fmt.Fprintln(logs.Error, "error: --require-signature specified but no signature was verified") //nolint:errcheck
+		return res, exitTrust
+	}
+	return res, exitOK
+}
+
+// fallBackToChecksum applies --on-missing-key fallback-checksum to err. When
+// err is a keyResolutionError and the file is checked against a checksum
+// (verified), the run continues as Workflow C: it warns loudly, records the
+// degradation in the assessment warnings and rescores trust without the
+// signature. It reports whether it did. A key pin (from a repo config; the
+// flags are rejected together at parse time) always keeps the run closed,
+// like checkPinCoverage does for a release without a pinned signature.
+func (in fileVerification) fallBackToChecksum(err error, verified bool, logs outputLog) bool {
+	var keyErr *keyResolutionError
+	if in.onMissingKey != onMissingKeyFallbackChecksum || !verified || !errors.As(err, &keyErr) {
+		return false
+	}
+	if in.keys.pinMinisignKey != "" || in.keys.pinPGPFingerprint != "" {
+		_, _ = fmt.Fprintln(logs.Error, "error: --on-missing-key fallback-checksum does not apply while a key pin is configured") //nolint:errcheck
+		return false
+	}
+	a := in.assessment
+	warning := fmt.Sprintf("--on-missing-key fallback-checksum: %s signature %s was not verified (%s); fell back to checksum-only verification (workflow C)",
+		signatureFormatLabel(keyErr.format), a.SignatureFile, strings.TrimPrefix(keyErr.err.Error(), "error: "))
+	_, _ = fmt.Fprintf(logs.Error, "WARNING: %s\n", warning) //nolint:errcheck
+	a.Warnings = append(a.Warnings, warning)
+	a.Workflow = workflowC
+
+	f := a.Trust.Factors
+	a.Trust = computeTrustScore(trustScoreInput{
+		ChecksumVerifiable: f.Checksum.Verifiable,
+		ChecksumValidated:  f.Checksum.Validated,
+		ChecksumSkipped:    f.Checksum.Skipped,
+		ChecksumAlgorithm:  f.Checksum.Algorithm,
+		HTTPSUsed:          f.Transport.HTTPS,
+
+		SourceDigestAvailable: f.SourceDigest.Available,
+		SourceDigestMatched:   f.SourceDigest.Matched,
+	}, in.trustPolicy)
+	a.TrustLevel = legacyTrustLevelFromTrust(a.Trust)
+	return true
+}
+
+// setChecksumAlgorithm records the algorithm the checksum file turned out to
+// use. sfetch scores every SHA-2, SHA-3 and BLAKE2b digest alike, so only the
+// names in the trust factors change, not the points.
+func (assessment *VerificationAssessment) setChecksumAlgorithm(algo string) {
+	assessment.ChecksumAlgorithm = algo
+	assessment.Trust.Factors.Checksum.Algorithm = algo
+	if assessment.Trust.Factors.Algorithm.Name != "" {
+		assessment.Trust.Factors.Algorithm.Name = algo
+	}
+}
+
+// normalizeExpectSHA256 lower-cases an --expect-sha256 value and reports
+// whether it is empty or 64 hex characters.
+func normalizeExpectSHA256(raw string) (string, bool) {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	if v == "" {
+		return "", true
+	}
+	decoded, err := hex.DecodeString(v)
+	return v, err == nil && len(decoded) == sha256.Size
+}
+
+// hashFile streams the file at path through the algo digest and, in the same
+// pass, SHA-256 (used for caching, --expect-sha256 and provenance). It
+// returns both hex digests and the number of bytes read. Large files show
+// hashing progress on a terminal.
+func hashFile(path, algo string) (digest, sha256Hex string, size int64, err error) {
+	h, err := newChecksumHash(algo)
+	if err != nil {
+		return "", "", 0, err
+	}
+	// #nosec G304 -- SDR-001: asset path chosen by sfetch or --verify-only
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("read asset: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+	var w io.Writer = h
+	sum := h
+	if algo != "sha256" {
+		sum = sha256.New()
+		w = io.MultiWriter(h, sum)
+	}
+	if info, err := f.Stat(); err == nil {
+		if hp := currentDownloadProgress().trackHash(filepath.Base(path), info.Size()); hp != nil {
+			w = io.MultiWriter(w, hp)
+			defer hp.finish()
+		}
+	}
+	size, err = io.Copy(w, f)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("read asset: %w", err)
+	}
+	digest = hex.EncodeToString(h.Sum(nil))
+	return digest, hex.EncodeToString(sum.Sum(nil)), size, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// verifyURLs is --verify-only with --checksum-url and/or --sig-url instead
+// of --repo: the local file is checked against artifacts at explicit URLs.
+type verifyURLs struct {
+	file        string // --verify-only
+	checksumURL string // --checksum-url
+	sigURL      string // --sig-url
+}
+
+// checksumSigSuffixes are the signature extensions a checksum manifest's
+// signature may carry; findChecksumSignatures pairs only these.
+var checksumSigSuffixes = []string{".minisig", ".asc", ".sig"}
+
+// assetSigSuffixes are the per-asset signature extensions.
+var assetSigSuffixes = []string{".minisig", ".asc", ".sig.ed25519", ".sig"}
+
+// validate checks that both URLs are absolute http(s) URLs (http only with
+// --allow-http) and that --sig-url names a signature format.
+func (v verifyURLs) validate(allowHTTP bool) error {
+	for _, f := range []struct{ name, raw string }{{"--checksum-url", v.checksumURL}, {"--sig-url", v.sigURL}} {
+		if f.raw == "" {
+			continue
+		}
+		u, err := url.Parse(f.raw)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid %s %q: must be an absolute URL", f.name, f.raw)
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "https":
+		case "http":
+			if !allowHTTP {
+				return fmt.Errorf("%s %q uses http; pass --allow-http to permit it", f.name, f.raw)
+			}
+		default:
+			return fmt.Errorf("invalid %s %q: scheme must be https", f.name, f.raw)
+		}
+	}
+	if v.sigURL != "" && v.sigSuffix() == "" {
+		suffixes := assetSigSuffixes
+		if v.checksumURL != "" {
+			suffixes = checksumSigSuffixes
+		}
+		return fmt.Errorf("--sig-url %q must end in %s", v.sigURL, strings.Join(suffixes, ", "))
+	}
+	return nil
+}
+
+// urlBase is the last path element of raw, ignoring any query.
+func urlBase(raw string) string {
+	if u, err := url.Parse(raw); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(raw)
+}
+
+// sigSuffix returns the signature extension of --sig-url, or "" when it has
+// none that can sign what it is paired with.
+func (v verifyURLs) sigSuffix() string {
+	suffixes := assetSigSuffixes
+	if v.checksumURL != "" {
+		suffixes = checksumSigSuffixes
+	}
+	name := strings.ToLower(urlBase(v.sigURL))
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// release builds the release the usual verification runs against: the
+// local file, the checksum manifest named after --checksum-url, and the
+// signature renamed to pair with the manifest (or, without one, with the
+// file). Like releaseFromCache, it stands in for the API response.
+func (v verifyURLs) release() Release {
+	rel := Release{Assets: []Asset{{Name: filepath.Base(v.file)}}}
+	if v.checksumURL != "" {
+		rel.Assets = append(rel.Assets, Asset{Name: v.checksumName(), BrowserDownloadUrl: v.checksumURL})
+	}
+	if v.sigURL != "" {
+		rel.Assets = append(rel.Assets, Asset{Name: v.sigName(), BrowserDownloadUrl: v.sigURL})
+	}
+	return rel
+}
+
+func (v verifyURLs) checksumName() string {
+	return urlBase(v.checksumURL)
+}
+
+func (v verifyURLs) sigName() string {
+	signed := filepath.Base(v.file)
+	if v.checksumURL != "" {
+		signed = v.checksumName()
+	}
+	return signed + v.sigSuffix()
+}
+
+// configure points cfg's candidate lists at exactly the assets release()
+// built, so manifest and signature are found whatever their file names.
+func (v verifyURLs) configure(cfg *RepoConfig) {
+	cfg.ChecksumCandidates = nil
+	cfg.ChecksumSigCandidates = nil
+	cfg.SignatureCandidates = nil
+	if v.checksumURL != "" {
+		cfg.ChecksumCandidates = []string{v.checksumName()}
+		if v.sigURL != "" {
+			cfg.ChecksumSigCandidates = []string{v.sigName()}
+		}
+		return
+	}
+	if v.sigURL != "" {
+		cfg.SignatureCandidates = []string{v.sigName()}
+	}
+}
+
+// describe names the artifacts for the "Verified" status line.
+func (v verifyURLs) describe() string {
+	var parts []string
+	if v.checksumURL != "" {
+		parts = append(parts, v.checksumURL)
+	}
+	if v.sigURL != "" {
+		parts = append(parts, v.sigURL)
+	}
+	return strings.Join(parts, " and ")
+}